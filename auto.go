@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+	"github.com/adaptive-scale/superclass/pkg/extractor"
+	log "github.com/sirupsen/logrus"
+)
+
+// AutoClassificationResponse is a classification along with the model that was picked for it
+type AutoClassificationResponse struct {
+	Provider classifier.Provider `json:"provider"`
+	Model    string              `json:"model"`
+	ClassificationResponse
+}
+
+// apiKeyForProvider returns the API key configured in the environment for a provider
+func apiKeyForProvider(provider classifier.Provider) string {
+	switch provider {
+	case classifier.Anthropic:
+		return os.Getenv("ANTHROPIC_API_KEY")
+	case classifier.Azure:
+		return os.Getenv("AZURE_OPENAI_API_KEY")
+	case classifier.Gemini:
+		return os.Getenv("GEMINI_API_KEY")
+	default:
+		return os.Getenv("OPENAI_API_KEY")
+	}
+}
+
+// formFloat64 reads a float form value, falling back to the default when absent or invalid
+func formFloat64(r *http.Request, key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(r.FormValue(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// formInt reads an int form value, falling back to the default when absent or invalid
+func formInt(r *http.Request, key string, defaultValue int) int {
+	if value, err := strconv.Atoi(r.FormValue(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func (s *Server) handleClassifyAuto(w http.ResponseWriter, r *http.Request) {
+	logger := log.WithFields(log.Fields{
+		"handler": "classify_auto",
+		"method":  r.Method,
+		"remote":  r.RemoteAddr,
+	})
+
+	if r.Method != http.MethodPost {
+		logger.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		logger.WithError(err).Error("Failed to parse form")
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	var classificationReq ClassificationRequest
+	if categoriesJSON := r.FormValue("categories"); categoriesJSON != "" {
+		if err := json.Unmarshal([]byte(categoriesJSON), &classificationReq.Categories); err != nil {
+			logger.WithError(err).Error("Failed to parse categories")
+			http.Error(w, "Invalid categories format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	fh, ok := firstFile(r, "file")
+	if !ok {
+		logger.Error("Failed to get file from form")
+		http.Error(w, "Failed to get file from form", http.StatusBadRequest)
+		return
+	}
+	if !s.extensionAllowed(fh.Filename) {
+		logger.Warn("File extension not allowed")
+		http.Error(w, "File type not allowed", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	contentType := classifier.ContentTypeFromString(r.FormValue("content_type"))
+	constraints := classifier.ModelConstraints{
+		MaxCostPerThousandTokens: formFloat64(r, "max_cost_per_1k", 1.0),
+		MaxLatencyMs:             formInt(r, "max_latency_ms", 60000),
+		MinTokenLimit:            formInt(r, "min_token_limit", 0),
+	}
+
+	recommendations := classifier.RecommendModel(contentType, constraints)
+	if len(recommendations) == 0 {
+		logger.WithField("constraints", constraints).Warn("No model satisfies the constraints")
+		http.Error(w, "No model satisfies the given content type and constraints", http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Take the best recommendation whose provider the server holds credentials for
+	var info classifier.ModelInfo
+	var apiKey string
+	for _, model := range recommendations {
+		candidate, _ := classifier.GetModelInfo(model)
+		if key := apiKeyForProvider(candidate.Provider); key != "" {
+			info, apiKey = candidate, key
+			break
+		}
+	}
+	if apiKey == "" {
+		logger.WithField("candidate_count", len(recommendations)).Warn("No recommended model has provider credentials")
+		http.Error(w, "No provider with configured credentials offers a model satisfying the constraints", http.StatusServiceUnavailable)
+		return
+	}
+	config := classifier.NewModelConfig(info.Type, apiKey)
+
+	logger = logger.WithFields(log.Fields{
+		"content_type":    contentType,
+		"selected_model":  info.Type,
+		"provider":        info.Provider,
+		"candidate_count": len(recommendations),
+	})
+	logger.Info("Selected model automatically")
+
+	tempFile, err := s.saveUpload(fh)
+	if err != nil {
+		logger.WithError(err).Error("Failed to store upload")
+		http.Error(w, "Failed to save file", http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		if err := os.Remove(tempFile); err != nil {
+			logger.WithError(err).Warn("Failed to remove temporary file")
+		}
+	}()
+
+	response := AutoClassificationResponse{
+		Provider: info.Provider,
+		Model:    string(info.Type),
+	}
+
+	reservation, ok := s.reserveBudget(w)
+	if !ok {
+		return
+	}
+	defer s.releaseBudget(reservation)
+
+	options := s.defaults
+	options.Categories = classificationReq.Categories
+	result, err := extractor.ExtractAndClassifyWithOptions(tempFile, info.Provider, config, options)
+	status := http.StatusOK
+	if err != nil {
+		logger.WithError(err).Error("Classification failed")
+		var ok bool
+		if status, ok = classificationErrorStatus(err); !ok {
+			status = http.StatusBadGateway
+		}
+		response.Error = classificationErrorMessage(err)
+	} else {
+		s.recordCost(reservation, string(info.Type), result.Text, result.Classification)
+		response.Category = result.Classification.Category
+		response.Confidence = result.Classification.Confidence
+		response.Summary = result.Classification.Summary
+		response.Keywords = result.Classification.Keywords
+		response.Usage = result.Classification.Usage
+		response.EstimatedCost = result.Classification.EstimatedCost
+		response.Metadata = result.Classification.Metadata
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.WithError(err).Error("Failed to encode response")
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
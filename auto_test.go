@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+)
+
+// serveAllProviders starts one stub answering OpenAI, Anthropic and Gemini requests with
+// status and routes every provider to it. It returns the paths requested.
+func serveAllProviders(t *testing.T, status int) *[]string {
+	t.Helper()
+	content, _ := json.Marshal(`{"category":"Contract","confidence":0.8,"summary":"s","keywords":["k"]}`)
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		switch {
+		case status != http.StatusOK:
+			w.Write([]byte(`{"error":{"message":"invalid request"}}`))
+		case strings.HasSuffix(r.URL.Path, "/v1/messages"):
+			w.Write([]byte(`{"content":[{"type":"text","text":` + string(content) + `}],"stop_reason":"end_turn"}`))
+		case strings.HasSuffix(r.URL.Path, ":generateContent"):
+			w.Write([]byte(`{"candidates":[{"content":{"parts":[{"text":` + string(content) + `}]},"finishReason":"STOP"}]}`))
+		default:
+			w.Write([]byte(`{"choices":[{"message":{"content":` + string(content) + `},"finish_reason":"stop"}]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	for _, provider := range []classifier.Provider{classifier.OpenAI, classifier.Anthropic, classifier.Gemini} {
+		t.Setenv(classifier.BaseURLEnvVars[provider], server.URL)
+	}
+	return &paths
+}
+
+// setProviderKeys sets the API key environment variable of each provider, empty for
+// providers not listed
+func setProviderKeys(t *testing.T, providers ...classifier.Provider) {
+	t.Helper()
+	for _, name := range []string{"OPENAI_API_KEY", "ANTHROPIC_API_KEY", "GEMINI_API_KEY"} {
+		t.Setenv(name, "")
+	}
+	for _, provider := range providers {
+		switch provider {
+		case classifier.Anthropic:
+			t.Setenv("ANTHROPIC_API_KEY", "key")
+		case classifier.Gemini:
+			t.Setenv("GEMINI_API_KEY", "key")
+		default:
+			t.Setenv("OPENAI_API_KEY", "key")
+		}
+	}
+}
+
+// classifyAuto posts a contract to /classify/auto with the given form fields
+func classifyAuto(t *testing.T, s *Server, fields map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := newUploadRequest(t, "/classify/auto", "contract.txt", []byte("This agreement is made between the parties."), fields)
+	s.handleClassifyAuto(rec, req)
+	return rec
+}
+
+func TestClassifyAutoPicksCheapestModel(t *testing.T) {
+	serveAllProviders(t, http.StatusOK)
+	setProviderKeys(t, classifier.OpenAI, classifier.Anthropic, classifier.Gemini)
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{})
+
+	rec := classifyAuto(t, s, map[string]string{"content_type": "legal_document", "max_cost_per_1k": "0.05"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	var response AutoClassificationResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("response is not JSON: %v", err)
+	}
+	chosen, ok := classifier.GetModelInfo(classifier.ModelType(response.Model))
+	if !ok || chosen.Provider != response.Provider || response.Category != "Contract" {
+		t.Fatalf("response = %+v, want a classification by a registered model", response)
+	}
+	if chosen.Cost.InputPerThousandTokens > 0.05 {
+		t.Errorf("chose %s at $%v per 1K tokens, over the $0.05 limit", response.Model, chosen.Cost.InputPerThousandTokens)
+	}
+	for _, model := range classifier.RecommendModel(classifier.LegalDocument, classifier.ModelConstraints{
+		MaxCostPerThousandTokens: 0.05,
+		MaxLatencyMs:             60000,
+	}) {
+		if info := classifier.ModelRegistry[model]; info.Cost.InputPerThousandTokens < chosen.Cost.InputPerThousandTokens {
+			t.Errorf("chose %s although %s is cheaper", response.Model, model)
+		}
+	}
+}
+
+func TestClassifyAutoRejectsUnsatisfiableConstraints(t *testing.T) {
+	setProviderKeys(t, classifier.OpenAI)
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{})
+	rec := classifyAuto(t, s, map[string]string{"max_cost_per_1k": "0"})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", rec.Code)
+	}
+}
+
+func TestClassifyAutoSkipsProvidersWithoutCredentials(t *testing.T) {
+	paths := serveAllProviders(t, http.StatusOK)
+	setProviderKeys(t, classifier.Anthropic)
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{})
+
+	rec := classifyAuto(t, s, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	var response AutoClassificationResponse
+	json.Unmarshal(rec.Body.Bytes(), &response)
+	if response.Provider != classifier.Anthropic {
+		t.Errorf("chose %s (%s), want the only provider with a key", response.Model, response.Provider)
+	}
+	if len(*paths) != 1 || !strings.HasSuffix((*paths)[0], "/v1/messages") {
+		t.Errorf("requested %v, want one Anthropic request", *paths)
+	}
+}
+
+func TestClassifyAutoWithoutAnyCredentials(t *testing.T) {
+	paths := serveAllProviders(t, http.StatusOK)
+	setProviderKeys(t)
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{})
+
+	rec := classifyAuto(t, s, nil)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503 (body %s)", rec.Code, rec.Body)
+	}
+	if len(*paths) != 0 {
+		t.Errorf("requested %v without credentials", *paths)
+	}
+}
+
+func TestClassifyAutoReportsProviderFailure(t *testing.T) {
+	serveAllProviders(t, http.StatusBadRequest)
+	setProviderKeys(t, classifier.OpenAI, classifier.Anthropic, classifier.Gemini)
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{})
+
+	rec := classifyAuto(t, s, nil)
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502 (body %s)", rec.Code, rec.Body)
+	}
+	var response AutoClassificationResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.Error == "" {
+		t.Errorf("response %s carries no error", rec.Body)
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+	"github.com/adaptive-scale/superclass/pkg/extractor"
+	"github.com/adaptive-scale/superclass/pkg/pool"
+	log "github.com/sirupsen/logrus"
+)
+
+// BatchResult is the classification outcome for a single file of a batch upload
+type BatchResult struct {
+	Filename string `json:"filename"`
+	// DuplicateOf names the earlier file with identical contents whose result was reused
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+	ClassificationResponse
+}
+
+// csvHeader is the column layout of CSV batch responses
+var csvHeader = []string{"filename", "category", "confidence", "keywords", "error"}
+
+// saveUpload copies an uploaded multipart file into the upload directory and
+// returns the temporary path. The original extension is preserved so the
+// extractor registry can pick the right extractor.
+func (s *Server) saveUpload(fh *multipart.FileHeader) (string, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer file.Close()
+
+	out, err := os.CreateTemp(s.uploadDir, "*-"+filepath.Base(fh.Filename))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, file); err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+	return out.Name(), nil
+}
+
+// firstFile returns the first uploaded file for the given form field
+func firstFile(r *http.Request, field string) (*multipart.FileHeader, bool) {
+	if r.MultipartForm == nil || len(r.MultipartForm.File[field]) == 0 {
+		return nil, false
+	}
+	return r.MultipartForm.File[field][0], true
+}
+
+// uploadHash returns the hex SHA-256 of an uploaded file's contents
+func uploadHash(fh *multipart.FileHeader) (string, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// groupUploads groups the indexes of batch files by content and extension, first
+// occurrence first, so each distinct file is classified once. The extension is part of
+// the key because it selects the extractor. Without batchDedup, or for files that are
+// not allowed or cannot be read, every file forms its own group.
+func (s *Server) groupUploads(files []*multipart.FileHeader) [][]int {
+	groups := make([][]int, 0, len(files))
+	byKey := make(map[string]int)
+	for i, fh := range files {
+		if s.batchDedup && s.extensionAllowed(fh.Filename) {
+			if hash, err := uploadHash(fh); err == nil {
+				key := hash + "\x00" + strings.ToLower(filepath.Ext(fh.Filename))
+				if g, ok := byKey[key]; ok {
+					groups[g] = append(groups[g], i)
+					continue
+				}
+				byKey[key] = len(groups)
+			}
+		}
+		groups = append(groups, []int{i})
+	}
+	return groups
+}
+
+// duplicateResult reuses result for a later file with the same contents
+func duplicateResult(result BatchResult, fh *multipart.FileHeader) BatchResult {
+	result.DuplicateOf = result.Filename
+	result.Filename = fh.Filename
+	return result
+}
+
+// classifyUpload saves, extracts and classifies a single uploaded file
+func (s *Server) classifyUpload(fh *multipart.FileHeader, options classifier.ClassificationOptions) BatchResult {
+	logger := log.WithFields(log.Fields{
+		"function": "classifyUpload",
+		"filename": fh.Filename,
+		"size":     fh.Size,
+	})
+
+	result := BatchResult{Filename: fh.Filename}
+
+	if !s.extensionAllowed(fh.Filename) {
+		logger.Warn("File extension not allowed")
+		result.Error = "file type not allowed"
+		return result
+	}
+
+	tempFile, err := s.saveUpload(fh)
+	if err != nil {
+		logger.WithError(err).Error("Failed to store upload")
+		result.Error = err.Error()
+		return result
+	}
+	defer func() {
+		if err := os.Remove(tempFile); err != nil {
+			logger.WithError(err).Warn("Failed to remove temporary file")
+		}
+	}()
+
+	if s.promptFilename {
+		options.Filename = fh.Filename
+	}
+	result.ClassificationResponse = s.classifyFile(tempFile, options)
+	return result
+}
+
+// classifyFile extracts and classifies a file on disk, reporting failures in the response
+func (s *Server) classifyFile(path string, options classifier.ClassificationOptions) ClassificationResponse {
+	reservation, ok := s.tryReserveBudget()
+	if !ok {
+		return ClassificationResponse{Error: s.budgetExhaustedMessage(s.budget.ResetIn())}
+	}
+	defer s.releaseBudget(reservation)
+
+	extracted, err := extractor.ExtractAndClassifyWithOptions(path, s.provider, s.config, options)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"function": "classifyFile",
+			"path":     path,
+		}).WithError(err).Error("Classification failed")
+		return ClassificationResponse{Error: err.Error()}
+	}
+	s.recordCost(reservation, s.config.Model, extracted.Text, extracted.Classification)
+
+	// Uploads are stored under a randomized name, so prefer the name sent with the file
+	filename := options.Filename
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+	return ClassificationResponse{
+		Category:      extracted.Classification.Category,
+		Confidence:    extracted.Classification.Confidence,
+		Summary:       extracted.Classification.Summary,
+		Keywords:      extracted.Classification.Keywords,
+		Usage:         extracted.Classification.Usage,
+		EstimatedCost: extracted.Classification.EstimatedCost,
+		Metadata:      extracted.Classification.Metadata,
+		Warnings:      extracted.Warnings,
+		TypePath:      extracted.TypePath,
+		NeedsReview:   s.flagForReview(&ReviewItem{Filename: filename, Classification: extracted.Classification}),
+	}
+}
+
+// wantsCSV reports whether the client asked for CSV output via ?format=csv or the Accept header
+func wantsCSV(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.EqualFold(format, "csv")
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/csv")
+}
+
+// writeBatchCSV writes one CSV row per batch result
+func writeBatchCSV(w io.Writer, results []BatchResult) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, result := range results {
+		record := []string{
+			result.Filename,
+			result.Category,
+			strconv.FormatFloat(result.Confidence, 'f', -1, 64),
+			strings.Join(result.Keywords, "; "),
+			result.Error,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// parseBatchForm parses a multipart batch upload, writing an error response and
+// returning false when the request is invalid
+func (s *Server) parseBatchForm(w http.ResponseWriter, r *http.Request, logger *log.Entry) ([]*multipart.FileHeader, classifier.ClassificationOptions, bool) {
+	options := s.defaults
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		logger.WithError(err).Error("Failed to parse form")
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return nil, options, false
+	}
+
+	var classificationReq ClassificationRequest
+	if categoriesJSON := r.FormValue("categories"); categoriesJSON != "" {
+		if err := json.Unmarshal([]byte(categoriesJSON), &classificationReq.Categories); err != nil {
+			logger.WithError(err).Error("Failed to parse categories")
+			http.Error(w, "Invalid categories format", http.StatusBadRequest)
+			return nil, options, false
+		}
+	}
+
+	files := r.MultipartForm.File["files"]
+	if len(files) == 0 {
+		logger.Warn("No files in batch request")
+		http.Error(w, "No files provided", http.StatusBadRequest)
+		return nil, options, false
+	}
+
+	options.Categories = classificationReq.Categories
+	options.ContextHint = r.FormValue("context")
+	return files, options, true
+}
+
+func (s *Server) handleClassifyBatch(w http.ResponseWriter, r *http.Request) {
+	logger := log.WithFields(log.Fields{
+		"handler": "classify_batch",
+		"method":  r.Method,
+		"remote":  r.RemoteAddr,
+	})
+
+	if r.Method != http.MethodPost {
+		logger.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	files, options, ok := s.parseBatchForm(w, r, logger)
+	if !ok {
+		return
+	}
+
+	groups := s.groupUploads(files)
+	logger = logger.WithFields(log.Fields{
+		"file_count":   len(files),
+		"unique_count": len(groups),
+	})
+	logger.Info("Processing batch upload")
+
+	results := make([]BatchResult, len(files))
+	p := pool.New(s.batchConcurrency)
+	for _, group := range groups {
+		p.Submit(func() error {
+			result := s.classifyUpload(files[group[0]], options)
+			results[group[0]] = result
+			for _, i := range group[1:] {
+				results[i] = duplicateResult(result, files[i])
+			}
+			return nil
+		})
+	}
+	p.Wait()
+
+	logger.Info("Batch classification completed")
+
+	if wantsCSV(r) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="classifications.csv"`)
+		if err := writeBatchCSV(w, results); err != nil {
+			logger.WithError(err).Error("Failed to write CSV response")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		logger.WithError(err).Error("Failed to encode response")
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleClassifyStreamBatch classifies a batch upload and streams one NDJSON line per
+// file as soon as it is done, so clients see progress before the whole batch finishes
+func (s *Server) handleClassifyStreamBatch(w http.ResponseWriter, r *http.Request) {
+	logger := log.WithFields(log.Fields{
+		"handler": "classify_stream_batch",
+		"method":  r.Method,
+		"remote":  r.RemoteAddr,
+	})
+
+	if r.Method != http.MethodPost {
+		logger.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	files, options, ok := s.parseBatchForm(w, r, logger)
+	if !ok {
+		return
+	}
+
+	groups := s.groupUploads(files)
+	logger = logger.WithFields(log.Fields{
+		"file_count":   len(files),
+		"unique_count": len(groups),
+	})
+	logger.Info("Streaming batch classification")
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	// Results are written in completion order, so each line carries its filename
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+	p := pool.New(s.batchConcurrency)
+	for _, group := range groups {
+		p.Submit(func() error {
+			result := s.classifyUpload(files[group[0]], options)
+			mu.Lock()
+			defer mu.Unlock()
+			if err := encoder.Encode(result); err != nil {
+				return err
+			}
+			for _, i := range group[1:] {
+				if err := encoder.Encode(duplicateResult(result, files[i])); err != nil {
+					return err
+				}
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		})
+	}
+	if errs := p.Wait(); len(errs) > 0 {
+		logger.WithError(errs[0]).Warn("Client went away while streaming")
+		return
+	}
+
+	logger.Info("Streaming batch classification completed")
+}
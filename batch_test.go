@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+)
+
+// newBatchRequest builds a multipart batch upload of the given files, in order
+func newBatchRequest(t *testing.T, target string, names []string, contents [][]byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for i, name := range names {
+		part, err := form.CreateFormFile("files", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(contents[i])
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestClassifyBatchBoundsConcurrencyAndKeepsOrder(t *testing.T) {
+	const files, concurrency = 12, 3
+	documentID := regexp.MustCompile(`document (\d+)`)
+
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		body, _ := io.ReadAll(r.Body)
+		match := documentID.FindSubmatch(body)
+		if match == nil {
+			t.Errorf("request does not name a document: %s", body)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// Earlier documents take longer, so they finish after later ones
+		n, _ := strconv.Atoi(string(match[1]))
+		time.Sleep(time.Duration(files-n) * 2 * time.Millisecond)
+
+		content, _ := json.Marshal(fmt.Sprintf(`{"category":"Document %d","confidence":0.9,"keywords":[]}`, n))
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":%s},"finish_reason":"stop"}]}`, content)
+	}))
+	defer provider.Close()
+
+	// Lifting the per-model limit leaves the batch pool as the only bound
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{
+		Endpoint:              provider.URL,
+		APIKey:                "key",
+		MaxRetries:            -1,
+		MaxConcurrentRequests: -1,
+	})
+	s.batchConcurrency = concurrency
+
+	names := make([]string, files)
+	contents := make([][]byte, files)
+	for i := range names {
+		names[i] = fmt.Sprintf("doc%02d.txt", i)
+		contents[i] = []byte(fmt.Sprintf("This is document %d of the batch.", i))
+	}
+	rec := httptest.NewRecorder()
+	s.handleClassifyBatch(rec, newBatchRequest(t, "/classify/batch", names, contents))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+
+	var results []BatchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("response is not JSON: %v", err)
+	}
+	if len(results) != files {
+		t.Fatalf("%d results, want %d", len(results), files)
+	}
+	for i, result := range results {
+		if result.Filename != names[i] || result.Category != fmt.Sprintf("Document %d", i) {
+			t.Errorf("result %d = %s classified as %q, want %s as Document %d", i, result.Filename, result.Category, names[i], i)
+		}
+	}
+	if peak > concurrency {
+		t.Errorf("%d provider requests in flight at once, want at most %d", peak, concurrency)
+	}
+}
+
+func TestClassifyBatchDeduplicatesByContentAndExtension(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		fmt.Fprint(w, openAIReply("Notes"))
+	}))
+	defer provider.Close()
+
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{
+		Endpoint:              provider.URL,
+		APIKey:                "key",
+		MaxRetries:            -1,
+		MaxConcurrentRequests: -1,
+	})
+	s.allowedExtensions = map[string]bool{".txt": true, ".md": true}
+
+	// Every file has the same bytes; only the extension tells them apart
+	names := []string{"a.txt", "b.TXT", "c.md", "d.svg"}
+	contents := make([][]byte, len(names))
+	for i := range contents {
+		contents[i] = []byte("Meeting notes for the quarterly review.")
+	}
+	rec := httptest.NewRecorder()
+	s.handleClassifyBatch(rec, newBatchRequest(t, "/classify/batch", names, contents))
+
+	var results []BatchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("response is not JSON: %v (body %s)", err, rec.Body)
+	}
+	if len(results) != len(names) {
+		t.Fatalf("%d results, want %d", len(results), len(names))
+	}
+	want := []struct{ duplicateOf, err string }{
+		{"", ""},
+		{"a.txt", ""},
+		{"", ""},
+		{"", "file type not allowed"},
+	}
+	for i, result := range results {
+		if result.DuplicateOf != want[i].duplicateOf || result.Error != want[i].err {
+			t.Errorf("%s: duplicate of %q with error %q, want duplicate of %q with error %q",
+				result.Filename, result.DuplicateOf, result.Error, want[i].duplicateOf, want[i].err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("%d provider requests, want 2 (one per allowed extension)", calls)
+	}
+}
+
+func TestClassifyBatchCSV(t *testing.T) {
+	provider := serveProvider(t, http.StatusOK, openAIReply("Invoice"))
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{Endpoint: provider.URL, APIKey: "key", MaxRetries: -1})
+	s.allowedExtensions = map[string]bool{".txt": true}
+
+	tests := []struct {
+		name   string
+		target string
+		accept string
+	}{
+		{name: "format parameter", target: "/classify/batch?format=csv"},
+		{name: "accept header", target: "/classify/batch", accept: "text/csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newBatchRequest(t, tt.target, []string{"march, 2024.txt", "logo.svg"},
+				[][]byte{[]byte("Invoice for March"), []byte("<svg/>")})
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			s.handleClassifyBatch(rec, req)
+			if ct := rec.Header().Get("Content-Type"); ct != "text/csv" {
+				t.Fatalf("Content-Type = %q, want text/csv (body %s)", ct, rec.Body)
+			}
+
+			records, err := csv.NewReader(rec.Body).ReadAll()
+			if err != nil {
+				t.Fatalf("response is not CSV: %v", err)
+			}
+			want := [][]string{
+				csvHeader,
+				{"march, 2024.txt", "Invoice", "0.9", "k", ""},
+				{"logo.svg", "", "0", "", "file type not allowed"},
+			}
+			if len(records) != len(want) {
+				t.Fatalf("%d rows, want %d: %q", len(records), len(want), records)
+			}
+			for i := range want {
+				if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+					t.Errorf("row %d = %q, want %q", i, records[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestClassifyStreamBatchWritesResultsAsTheyFinish(t *testing.T) {
+	release := make(chan struct{})
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if bytes.Contains(body, []byte("slow document")) {
+			<-release
+		}
+		fmt.Fprint(w, openAIReply("Report"))
+	}))
+	defer provider.Close()
+	defer close(release)
+
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{
+		Endpoint:              provider.URL,
+		APIKey:                "key",
+		MaxRetries:            -1,
+		MaxConcurrentRequests: -1,
+	})
+	s.batchConcurrency = 2
+	server := httptest.NewServer(http.HandlerFunc(s.handleClassifyStreamBatch))
+	defer server.Close()
+
+	req := newBatchRequest(t, server.URL, []string{"slow.txt", "fast.txt"},
+		[][]byte{[]byte("This is the slow document."), []byte("This is the fast document.")})
+	req.RequestURI = ""
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+	}
+
+	lines := make(chan BatchResult)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var result BatchResult
+			if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+				t.Errorf("line is not JSON: %v: %s", err, scanner.Text())
+				return
+			}
+			lines <- result
+		}
+	}()
+
+	// The fast file arrives while the slow one is still being classified
+	select {
+	case result := <-lines:
+		if result.Filename != "fast.txt" || result.Category != "Report" {
+			t.Errorf("first line = %+v, want fast.txt classified as Report", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no result streamed while a file was still being classified")
+	}
+	release <- struct{}{}
+	if result := <-lines; result.Filename != "slow.txt" {
+		t.Errorf("second line = %+v, want slow.txt", result)
+	}
+	if result, ok := <-lines; ok {
+		t.Errorf("unexpected extra line %+v", result)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+	log "github.com/sirupsen/logrus"
+)
+
+// BudgetTracker accumulates estimated spend per key over a daily UTC window
+type BudgetTracker struct {
+	mu          sync.Mutex
+	limit       float64
+	now         func() time.Time
+	windowStart time.Time
+	spent       map[string]float64
+	// settledCost and settledCount give the average cost of a classification, which
+	// Reserve holds against the budget while a classification runs
+	settledCost  float64
+	settledCount int
+}
+
+// BudgetReservation is budget held by Reserve for a classification in flight
+type BudgetReservation struct {
+	key      string
+	window   time.Time
+	estimate float64
+	done     bool
+}
+
+// NewBudgetTracker creates a tracker allowing limit USD per key per UTC day
+func NewBudgetTracker(limit float64) *BudgetTracker {
+	return &BudgetTracker{
+		limit: limit,
+		now:   time.Now,
+		spent: make(map[string]float64),
+	}
+}
+
+// resetIfExpired starts a new window once the current UTC day has passed. Callers hold mu.
+func (b *BudgetTracker) resetIfExpired() {
+	day := b.now().UTC().Truncate(24 * time.Hour)
+	if day.After(b.windowStart) {
+		b.windowStart = day
+		b.spent = make(map[string]float64)
+	}
+}
+
+// Reserve reports whether key still has budget left in the current window and, if so,
+// holds the average classification cost against it until the reservation is settled or
+// released. Checking and holding happen under one lock, so concurrent callers see each
+// other's reservations and cannot all pass the last of the budget.
+func (b *BudgetTracker) Reserve(key string) (*BudgetReservation, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resetIfExpired()
+	if b.spent[key] >= b.limit {
+		return nil, false
+	}
+	var estimate float64
+	if b.settledCount > 0 {
+		estimate = b.settledCost / float64(b.settledCount)
+	}
+	b.spent[key] += estimate
+	return &BudgetReservation{key: key, window: b.windowStart, estimate: estimate}, true
+}
+
+// Settle replaces the amount held by r with the actual cost and returns the total spent
+// in the current window. Settling or releasing r again has no effect.
+func (b *BudgetTracker) Settle(r *BudgetReservation, cost float64) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resetIfExpired()
+	if r.done {
+		return b.spent[r.key]
+	}
+	b.unhold(r)
+	b.spent[r.key] += cost
+	b.settledCost += cost
+	b.settledCount++
+	return b.spent[r.key]
+}
+
+// Release returns the amount held by r to the budget, for classifications that failed.
+// Releasing a settled reservation has no effect.
+func (b *BudgetTracker) Release(r *BudgetReservation) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resetIfExpired()
+	if !r.done {
+		b.unhold(r)
+	}
+}
+
+// unhold removes the amount held by r, unless its window has already been reset.
+// Callers hold mu.
+func (b *BudgetTracker) unhold(r *BudgetReservation) {
+	r.done = true
+	if r.window.Equal(b.windowStart) {
+		b.spent[r.key] -= r.estimate
+	}
+}
+
+// Add records cost against key and returns the total spent in the current window
+func (b *BudgetTracker) Add(key string, cost float64) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resetIfExpired()
+	b.spent[key] += cost
+	return b.spent[key]
+}
+
+// ResetIn returns the time until the current window ends
+func (b *BudgetTracker) ResetIn() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resetIfExpired()
+	return b.windowStart.Add(24 * time.Hour).Sub(b.now())
+}
+
+// globalBudgetKey is the budget shared by all callers. Requests are not authenticated
+// yet, so every classification counts against it.
+const globalBudgetKey = ""
+
+// reserveBudget reserves budget for one classification, or writes a 429 response and
+// returns false once the daily budget is spent. The reservation is nil when no budget
+// is configured.
+func (s *Server) reserveBudget(w http.ResponseWriter) (*BudgetReservation, bool) {
+	reservation, ok := s.tryReserveBudget()
+	if ok {
+		return reservation, true
+	}
+	resetIn := s.budget.ResetIn()
+	log.WithField("reset_in", resetIn.String()).Warn("Daily budget exhausted")
+	w.Header().Set("Retry-After", strconv.Itoa(int(resetIn.Seconds())+1))
+	http.Error(w, s.budgetExhaustedMessage(resetIn), http.StatusTooManyRequests)
+	return nil, false
+}
+
+// tryReserveBudget reserves budget for one classification, returning false once the
+// daily budget is spent. The reservation is nil when no budget is configured.
+func (s *Server) tryReserveBudget() (*BudgetReservation, bool) {
+	if s.budget == nil {
+		return nil, true
+	}
+	return s.budget.Reserve(globalBudgetKey)
+}
+
+// releaseBudget returns an unsettled reservation to the budget
+func (s *Server) releaseBudget(reservation *BudgetReservation) {
+	if s.budget != nil && reservation != nil {
+		s.budget.Release(reservation)
+	}
+}
+
+// budgetExhaustedMessage explains why classification was refused and when it resumes
+func (s *Server) budgetExhaustedMessage(resetIn time.Duration) string {
+	return fmt.Sprintf("daily budget of $%.2f exhausted, resets in %s", s.budget.limit, resetIn.Round(time.Minute))
+}
+
+// recordCost charges the cost of a classification to the budget, settling reservation
+// when there is one. The cost the provider computed from the reported token usage is
+// preferred, since model names such as Azure deployments cannot be priced.
+func (s *Server) recordCost(reservation *BudgetReservation, model, text string, classification *classifier.Classification) {
+	if s.budget == nil || classification == nil {
+		return
+	}
+	cost := classification.EstimatedCost
+	if cost == 0 {
+		cost = classifier.EstimateClassificationCost(classifier.ModelType(model), text, classification)
+	}
+	var spent float64
+	if reservation != nil {
+		spent = s.budget.Settle(reservation, cost)
+	} else {
+		spent = s.budget.Add(globalBudgetKey, cost)
+	}
+	log.WithFields(log.Fields{
+		"estimated_cost": cost,
+		"spent_today":    spent,
+		"daily_budget":   s.budget.limit,
+	}).Debug("Recorded classification cost")
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+)
+
+// usageReply is an OpenAI-compatible completion reporting model and promptTokens of usage
+func usageReply(model string, promptTokens int) string {
+	content, _ := json.Marshal(`{"category":"Invoice","confidence":0.9,"summary":"s","keywords":["k"]}`)
+	return fmt.Sprintf(`{"model":%q,"choices":[{"message":{"content":%s},"finish_reason":"stop"}],"usage":{"prompt_tokens":%d,"completion_tokens":0,"total_tokens":%d}}`,
+		model, content, promptTokens, promptTokens)
+}
+
+func TestBudgetTrackerResetsDaily(t *testing.T) {
+	now := time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC)
+	b := NewBudgetTracker(1)
+	b.now = func() time.Time { return now }
+
+	if spent := b.Add(globalBudgetKey, 1.5); spent != 1.5 {
+		t.Fatalf("spent = %v, want 1.5", spent)
+	}
+	if _, ok := b.Reserve(globalBudgetKey); ok {
+		t.Fatal("reserved budget after the limit was exceeded")
+	}
+	if resetIn := b.ResetIn(); resetIn != time.Hour {
+		t.Errorf("ResetIn = %v, want 1h", resetIn)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := b.Reserve(globalBudgetKey); !ok {
+		t.Error("budget not available after midnight UTC")
+	}
+}
+
+func TestBudgetReservationsHoldAverageCost(t *testing.T) {
+	b := NewBudgetTracker(1)
+	first, _ := b.Reserve(globalBudgetKey)
+	b.Settle(first, 0.5)
+
+	// Each reservation holds the average cost of 0.5, so only one more fits
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var granted []*BudgetReservation
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if r, ok := b.Reserve(globalBudgetKey); ok {
+				mu.Lock()
+				granted = append(granted, r)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(granted) != 1 {
+		t.Fatalf("%d concurrent reservations granted, want 1", len(granted))
+	}
+
+	if spent := b.Settle(granted[0], 0.25); spent != 0.75 {
+		t.Errorf("spent = %v after settling for 0.25, want 0.75", spent)
+	}
+	b.Release(granted[0])
+	if spent := b.Settle(granted[0], 0.25); spent != 0.75 {
+		t.Errorf("spent = %v after settling and releasing again, want 0.75", spent)
+	}
+
+	failed, ok := b.Reserve(globalBudgetKey)
+	if !ok {
+		t.Fatal("no budget left after the settlement freed part of the reservation")
+	}
+	b.Release(failed)
+	if spent := b.Add(globalBudgetKey, 0); spent != 0.75 {
+		t.Errorf("spent = %v after releasing a reservation, want 0.75", spent)
+	}
+}
+
+func TestClassifyRejectedOnceBudgetSpent(t *testing.T) {
+	provider := serveProvider(t, http.StatusOK, usageReply(string(classifier.GPT4), 1000))
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{
+		Endpoint:   provider.URL,
+		APIKey:     "key",
+		Model:      string(classifier.GPT4),
+		MaxRetries: -1,
+	})
+	cost := classifier.EstimateCost(classifier.GPT4, 1000, 0)
+	s.budget = NewBudgetTracker(cost / 2)
+
+	text := []byte("Invoice 42: please pay $40 by Friday.")
+	rec := httptest.NewRecorder()
+	s.handleClassify(rec, newUploadRequest(t, "/classify", "invoice.txt", text, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleClassify(rec, newUploadRequest(t, "/classify", "invoice.txt", text, nil))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: status = %d, want 429 (body %s)", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("429 response has no Retry-After header")
+	}
+}
+
+func TestBudgetChargesProviderReportedCost(t *testing.T) {
+	// Azure deployment names say nothing about the model, the response does
+	provider := serveProvider(t, http.StatusOK, usageReply(string(classifier.GPT4), 2000))
+	s := NewServer(t.TempDir(), classifier.Azure, classifier.ModelConfig{
+		Endpoint:   provider.URL,
+		APIKey:     "key",
+		Model:      "prod-deployment",
+		MaxRetries: -1,
+	})
+	s.budget = NewBudgetTracker(100)
+
+	rec := httptest.NewRecorder()
+	s.handleClassify(rec, newUploadRequest(t, "/classify", "invoice.txt", []byte("Invoice 42: please pay $40 by Friday."), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	want := classifier.EstimateCost(classifier.GPT4, 2000, 0)
+	if spent := s.budget.Add(globalBudgetKey, 0); want == 0 || spent != want {
+		t.Errorf("spent = %v, want the reported usage priced as gpt-4: %v", spent, want)
+	}
+}
+
+func TestBatchDoesNotOvershootBudget(t *testing.T) {
+	provider := serveProvider(t, http.StatusOK, usageReply(string(classifier.GPT4), 1000))
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{
+		Endpoint:              provider.URL,
+		APIKey:                "key",
+		Model:                 string(classifier.GPT4),
+		MaxRetries:            -1,
+		MaxConcurrentRequests: -1,
+	})
+	s.batchConcurrency = 6
+	cost := classifier.EstimateCost(classifier.GPT4, 1000, 0)
+	s.budget = NewBudgetTracker(2 * cost)
+	earlier, _ := s.budget.Reserve(globalBudgetKey)
+	s.budget.Settle(earlier, cost)
+
+	names := make([]string, 6)
+	contents := make([][]byte, 6)
+	for i := range names {
+		names[i] = fmt.Sprintf("invoice%d.txt", i)
+		contents[i] = []byte(fmt.Sprintf("Invoice %d: please pay $40 by Friday.", i))
+	}
+	rec := httptest.NewRecorder()
+	s.handleClassifyBatch(rec, newBatchRequest(t, "/classify/batch", names, contents))
+
+	var results []BatchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("response is not JSON: %v (body %s)", err, rec.Body)
+	}
+	classified := 0
+	for _, result := range results {
+		if result.Error == "" {
+			classified++
+		}
+	}
+	if classified != 1 {
+		t.Errorf("%d files classified, want only the 1 the remaining budget covers", classified)
+	}
+	if spent := s.budget.Add(globalBudgetKey, 0); spent > 2*cost {
+		t.Errorf("spent %v, over the budget of %v", spent, 2*cost)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/adaptive-scale/superclass/pkg/extractor"
+	"github.com/adaptive-scale/superclass/pkg/pool"
+	log "github.com/sirupsen/logrus"
+)
+
+// runCLI dispatches a CLI subcommand. It returns false when args do not name one,
+// in which case the HTTP server is started instead.
+func runCLI(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	var err error
+	switch args[0] {
+	case "classify":
+		err = runClassify(args[1:])
+	case "classify-dir":
+		err = runClassifyDir(args[1:])
+	default:
+		return false
+	}
+
+	if err != nil {
+		log.WithError(err).Error("Command failed")
+		os.Exit(1)
+	}
+	return true
+}
+
+// isSupportedFile reports whether the registry (or the plain text special case) can extract path
+func isSupportedFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".txt" {
+		return true
+	}
+	_, err := extractor.DefaultRegistry.Get(ext)
+	return err == nil
+}
+
+// collectFiles returns the supported files under root, descending into
+// subdirectories only when recursive is set. Unsupported files are skipped with a warning.
+func collectFiles(root string, recursive bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isSupportedFile(path) {
+			log.WithField("path", path).Warn("Skipping unsupported file")
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	return files, err
+}
+
+// splitCategories parses a comma-separated --categories flag
+func splitCategories(value string) []string {
+	var categories []string
+	for _, category := range strings.Split(value, ",") {
+		if category = strings.TrimSpace(category); category != "" {
+			categories = append(categories, category)
+		}
+	}
+	return categories
+}
+
+// runClassify classifies a single file, or stdin with --stdin, and prints the result as JSON
+func runClassify(args []string) error {
+	flags := flag.NewFlagSet("classify", flag.ContinueOnError)
+	file := flags.String("file", "", "file to classify")
+	stdin := flags.Bool("stdin", false, "read the document from stdin")
+	ext := flags.String("ext", "", "extension used to pick the extractor for stdin input, e.g. .txt")
+	categories := flags.String("categories", "", "comma-separated list of categories")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	switch {
+	case *stdin && *ext == "":
+		return errors.New("--ext is required with --stdin")
+	case !*stdin && *file == "":
+		return errors.New("either --file or --stdin is required")
+	}
+
+	// Keep stdout free for results
+	log.SetOutput(os.Stderr)
+
+	server := NewServerFromEnv()
+	if err := server.validateCredentials(); err != nil {
+		return err
+	}
+
+	options := server.defaults
+	if *categories != "" {
+		options.Categories = splitCategories(*categories)
+	}
+
+	var response ClassificationResponse
+	if *stdin {
+		result, err := extractor.ExtractAndClassifyReader(os.Stdin, *ext, server.provider, server.config, options)
+		if err != nil {
+			return err
+		}
+		response = ClassificationResponse{
+			Category:      result.Classification.Category,
+			Confidence:    result.Classification.Confidence,
+			Summary:       result.Classification.Summary,
+			Summaries:     result.Classification.Summaries,
+			Keywords:      result.Classification.Keywords,
+			Usage:         result.Classification.Usage,
+			EstimatedCost: result.Classification.EstimatedCost,
+			Metadata:      result.Classification.Metadata,
+			TypePath:      result.TypePath,
+		}
+	} else {
+		response = server.classifyFile(*file, options)
+		if response.Error != "" {
+			return errors.New(response.Error)
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(response)
+}
+
+func runClassifyDir(args []string) error {
+	flags := flag.NewFlagSet("classify-dir", flag.ContinueOnError)
+	root := flags.String("path", "", "directory to classify")
+	recursive := flags.Bool("recursive", false, "descend into subdirectories")
+	out := flags.String("out", "", "NDJSON output file (default: stdout)")
+	categories := flags.String("categories", "", "comma-separated list of categories")
+	concurrency := flags.Int("concurrency", 0, "files classified in parallel (default: BATCH_CONCURRENCY)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *root == "" {
+		return errors.New("--path is required")
+	}
+
+	// Keep stdout free for results
+	log.SetOutput(os.Stderr)
+
+	server := NewServerFromEnv()
+	if err := server.validateCredentials(); err != nil {
+		return err
+	}
+	if *concurrency > 0 {
+		server.batchConcurrency = *concurrency
+	}
+
+	options := server.defaults
+	if *categories != "" {
+		options.Categories = splitCategories(*categories)
+	}
+
+	files, err := collectFiles(*root, *recursive)
+	if err != nil {
+		return fmt.Errorf("failed to walk %s: %w", *root, err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	log.WithFields(log.Fields{
+		"path":        *root,
+		"file_count":  len(files),
+		"concurrency": server.batchConcurrency,
+	}).Info("Classifying directory")
+
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+	p := pool.New(server.batchConcurrency)
+	for _, path := range files {
+		p.Submit(func() error {
+			result := BatchResult{
+				Filename:               path,
+				ClassificationResponse: server.classifyFile(path, options),
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			return encoder.Encode(result)
+		})
+	}
+	if errs := p.Wait(); len(errs) > 0 {
+		return fmt.Errorf("failed to write results: %w", errors.Join(errs...))
+	}
+
+	log.WithField("file_count", len(files)).Info("Directory classification completed")
+	return nil
+}
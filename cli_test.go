@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+)
+
+// useCLIEnvironment configures the environment read by the CLI to classify with an
+// OpenAI stub
+func useCLIEnvironment(t *testing.T) {
+	t.Helper()
+	serveAllProviders(t, http.StatusOK)
+	setProviderKeys(t, classifier.OpenAI)
+	t.Setenv("MODEL_PROVIDER", "openai")
+	t.Setenv("MODEL_ENDPOINT", "")
+	t.Setenv("UPLOAD_DIR", t.TempDir())
+}
+
+// writeTree creates the given files, keyed by slash-separated path, under a new directory
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestClassifyDir(t *testing.T) {
+	useCLIEnvironment(t)
+	root := writeTree(t, map[string]string{
+		"contract.txt":        "This agreement is made between the parties.",
+		"archive/old.md":      "# Lease\n\nThe tenant agrees to pay rent.",
+		"archive/binary.xyz1": "not a supported format",
+	})
+
+	tests := []struct {
+		name      string
+		recursive bool
+		want      []string
+	}{
+		{name: "top level only", want: []string{"contract.txt"}},
+		{name: "recursive", recursive: true, want: []string{"archive/old.md", "contract.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "results.ndjson")
+			args := []string{"--path", root, "--out", out, "--concurrency", "2"}
+			if tt.recursive {
+				args = append(args, "--recursive")
+			}
+			if err := runClassifyDir(args); err != nil {
+				t.Fatalf("runClassifyDir: %v", err)
+			}
+
+			f, err := os.Open(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			var got []string
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				var result BatchResult
+				if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+					t.Fatalf("line is not JSON: %v: %s", err, scanner.Text())
+				}
+				if result.Category != "Contract" || result.Error != "" {
+					t.Errorf("%s classified as %q with error %q, want Contract", result.Filename, result.Category, result.Error)
+				}
+				rel, _ := filepath.Rel(root, result.Filename)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("files classified = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if err := runClassifyDir(nil); err == nil {
+		t.Error("runClassifyDir without --path succeeded")
+	}
+}
+
+// withStdio runs fn with stdin reading input and returns what it wrote to stdout
+func withStdio(t *testing.T, input string, fn func() error) (string, error) {
+	t.Helper()
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousIn, previousOut := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = stdinR, stdoutW
+	defer func() { os.Stdin, os.Stdout = previousIn, previousOut }()
+
+	go func() {
+		stdinW.Write([]byte(input))
+		stdinW.Close()
+	}()
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(stdoutR)
+		output <- string(data)
+	}()
+
+	err = fn()
+	stdoutW.Close()
+	stdinR.Close()
+	return <-output, err
+}
+
+func TestClassifyStdin(t *testing.T) {
+	useCLIEnvironment(t)
+
+	out, err := withStdio(t, "# Lease\n\nThe tenant agrees to pay rent.", func() error {
+		return runClassify([]string{"--stdin", "--ext", "md"})
+	})
+	if err != nil {
+		t.Fatalf("runClassify --stdin: %v", err)
+	}
+	var response ClassificationResponse
+	if err := json.Unmarshal([]byte(out), &response); err != nil {
+		t.Fatalf("stdout is not a JSON result: %v: %q", err, out)
+	}
+	if response.Category != "Contract" {
+		t.Errorf("category = %q, want Contract", response.Category)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "stdin without extension", args: []string{"--stdin"}, want: "--ext is required"},
+		{name: "no input", args: nil, want: "either --file or --stdin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runClassify(tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("runClassify(%q) = %v, want an error containing %q", tt.args, err, tt.want)
+			}
+		})
+	}
+}
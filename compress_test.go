@@ -0,0 +1,88 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+)
+
+func TestWithBodyLimitsCompressesAcceptedResponses(t *testing.T) {
+	s := newTestServer(t, openAIReply("Invoice"))
+	s.gzipResponses = true
+	handler := s.withBodyLimits(http.HandlerFunc(s.handleClassify))
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		wantGzip       bool
+	}{
+		{name: "gzip accepted", acceptEncoding: "br, gzip;q=0.8", wantGzip: true},
+		{name: "identity only", acceptEncoding: "identity"},
+		{name: "no header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newUploadRequest(t, "/classify", "invoice.txt", []byte("Invoice 42, total due $120."), nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+			}
+
+			var body io.Reader = rec.Body
+			gzipped := rec.Header().Get("Content-Encoding") == "gzip"
+			if gzipped != tt.wantGzip {
+				t.Fatalf("Content-Encoding = %q, want gzip %v", rec.Header().Get("Content-Encoding"), tt.wantGzip)
+			}
+			if gzipped {
+				gz, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("response is not gzip: %v", err)
+				}
+				body = gz
+			}
+			data, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("reading response: %v", err)
+			}
+			if !strings.Contains(string(data), `"category":"Invoice"`) {
+				t.Errorf("response = %s, want the classification", data)
+			}
+		})
+	}
+}
+
+func TestWithBodyLimitsRejectsLargeRequests(t *testing.T) {
+	calls := 0
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(openAIReply("Invoice")))
+	}))
+	defer provider.Close()
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{Endpoint: provider.URL, APIKey: "key", MaxRetries: -1})
+	s.maxRequestBytes = 1024
+	handler := s.withBodyLimits(http.HandlerFunc(s.handleClassify))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newUploadRequest(t, "/classify", "small.txt", []byte("Invoice 42."), nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("small upload status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newUploadRequest(t, "/classify", "large.txt", []byte(strings.Repeat("Invoice 42. ", 200)), nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("large upload status = %d, want 400 (body %s)", rec.Code, rec.Body)
+	}
+	if calls != 1 {
+		t.Errorf("provider called %d times, want only for the small upload", calls)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+	"github.com/adaptive-scale/superclass/pkg/extension/pdf"
+	"github.com/adaptive-scale/superclass/pkg/extractor"
+)
+
+func TestClassifyEncryptedPDF(t *testing.T) {
+	data, err := os.ReadFile("pkg/extractor/testdata/encrypted.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Extraction fails before the provider is called
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{APIKey: "key", MaxRetries: -1})
+
+	rec := httptest.NewRecorder()
+	s.handleClassify(rec, newUploadRequest(t, "/classify", "locked.pdf", data, nil))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422 (body %s)", rec.Code, rec.Body)
+	}
+	var response ClassificationResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("response is not JSON: %v", err)
+	}
+	if response.Error != classificationErrorMessage(extractor.ErrEncryptedDocument) {
+		t.Errorf("error = %q, want the encrypted document message", response.Error)
+	}
+}
+
+func TestClassifyPDFWithConfiguredPassword(t *testing.T) {
+	data, err := os.ReadFile("pkg/extractor/testdata/password.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PDF_PASSWORD", "secret")
+	configureExtractors()
+	t.Cleanup(func() {
+		if e, err := extractor.DefaultRegistry.Get(".pdf"); err == nil {
+			e.(*pdf.Extractor).Password = ""
+		}
+	})
+
+	var prompt string
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		prompt = string(body)
+		fmt.Fprint(w, openAIReply("Finance"))
+	}))
+	defer provider.Close()
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{Endpoint: provider.URL, APIKey: "key", MaxRetries: -1})
+
+	rec := httptest.NewRecorder()
+	s.handleClassify(rec, newUploadRequest(t, "/classify", "report.pdf", data, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	if !strings.Contains(prompt, "Quarterly revenue report") {
+		t.Errorf("provider was not sent the decrypted text: %s", prompt)
+	}
+}
+
+func TestClassificationErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+		ok   bool
+	}{
+		{err: fmt.Errorf("%w: invalid password", extractor.ErrEncryptedDocument), want: http.StatusUnprocessableEntity, ok: true},
+		{err: fmt.Errorf("%w: index out of range", extractor.ErrMalformedDocument), want: http.StatusBadRequest, ok: true},
+		{err: classifier.ErrRefused, want: http.StatusUnprocessableEntity, ok: true},
+		{err: classifier.ErrResponseTruncated, want: http.StatusBadGateway, ok: true},
+		{err: classifier.ErrClassificationTimeout, want: http.StatusGatewayTimeout, ok: true},
+		{err: errors.New("provider unavailable")},
+	}
+	for _, tt := range tests {
+		got, ok := classificationErrorStatus(tt.err)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("classificationErrorStatus(%v) = %d, %v, want %d, %v", tt.err, got, ok, tt.want, tt.ok)
+		}
+	}
+}
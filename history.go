@@ -0,0 +1,219 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+	log "github.com/sirupsen/logrus"
+)
+
+// HistoryRecord is a stored classification together with the text it was computed from
+type HistoryRecord struct {
+	ID             string                     `json:"id"`
+	ParentID       string                     `json:"parent_id,omitempty"`
+	Filename       string                     `json:"filename"`
+	Provider       classifier.Provider        `json:"provider"`
+	Model          string                     `json:"model"`
+	Categories     []string                   `json:"categories,omitempty"`
+	Classification *classifier.Classification `json:"classification"`
+	Text           string                     `json:"-"`
+	CreatedAt      time.Time                  `json:"created_at"`
+}
+
+// HistoryStore persists classification records
+type HistoryStore interface {
+	// Add stores the record, assigning an ID when it has none
+	Add(record *HistoryRecord) error
+	// Get returns the record with the given ID
+	Get(id string) (*HistoryRecord, bool)
+}
+
+// MemoryHistoryStore keeps the most recent records in memory
+type MemoryHistoryStore struct {
+	mu         sync.RWMutex
+	maxRecords int
+	records    map[string]*HistoryRecord
+	order      []string
+}
+
+// NewMemoryHistoryStore creates an in-memory store holding at most maxRecords records
+func NewMemoryHistoryStore(maxRecords int) *MemoryHistoryStore {
+	return &MemoryHistoryStore{
+		maxRecords: maxRecords,
+		records:    make(map[string]*HistoryRecord),
+	}
+}
+
+// Add stores the record, evicting the oldest one when the store is full
+func (m *MemoryHistoryStore) Add(record *HistoryRecord) error {
+	if record.ID == "" {
+		id, err := newRecordID()
+		if err != nil {
+			return err
+		}
+		record.ID = id
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.maxRecords > 0 && len(m.order) >= m.maxRecords {
+		oldest := m.order[0]
+		m.order = m.order[1:]
+		delete(m.records, oldest)
+	}
+	m.records[record.ID] = record
+	m.order = append(m.order, record.ID)
+	return nil
+}
+
+// Get returns the record with the given ID
+func (m *MemoryHistoryStore) Get(id string) (*HistoryRecord, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	record, ok := m.records[id]
+	return record, ok
+}
+
+func newRecordID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// recordHistory stores a classification when history is enabled and returns its ID
+func (s *Server) recordHistory(record *HistoryRecord) string {
+	if s.history == nil {
+		return ""
+	}
+	if err := s.history.Add(record); err != nil {
+		log.WithError(err).Warn("Failed to record classification history")
+		return ""
+	}
+	return record.ID
+}
+
+// configForProvider returns the model configuration for classifying with provider. The
+// server configuration applies to its own provider only: for any other provider the
+// endpoint, headers, authentication and model are reset to that provider's defaults, so
+// its API key is never sent to the configured endpoint.
+func (s *Server) configForProvider(provider classifier.Provider) classifier.ModelConfig {
+	if provider == s.provider {
+		return s.config
+	}
+	return classifier.ModelConfig{
+		APIKey:         apiKeyForProvider(provider),
+		Seed:           s.config.Seed,
+		MaxRetries:     s.config.MaxRetries,
+		RetryBaseDelay: s.config.RetryBaseDelay,
+	}
+}
+
+// ReclassifyRequest selects the categories and model used to re-run a stored classification
+type ReclassifyRequest struct {
+	Categories []string `json:"categories,omitempty"`
+	Provider   string   `json:"provider,omitempty"`
+	Model      string   `json:"model,omitempty"`
+}
+
+func (s *Server) handleReclassify(w http.ResponseWriter, r *http.Request) {
+	logger := log.WithFields(log.Fields{
+		"handler": "reclassify",
+		"method":  r.Method,
+		"remote":  r.RemoteAddr,
+		"id":      r.PathValue("id"),
+	})
+
+	if r.Method != http.MethodPost {
+		logger.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.history == nil {
+		http.Error(w, "History is disabled", http.StatusNotFound)
+		return
+	}
+
+	original, ok := s.history.Get(r.PathValue("id"))
+	if !ok {
+		logger.Warn("History record not found")
+		http.Error(w, "History record not found", http.StatusNotFound)
+		return
+	}
+
+	var req ReclassifyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.WithError(err).Error("Failed to parse request")
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	provider := s.provider
+	if req.Provider != "" {
+		provider = classifier.ProviderFromString(req.Provider)
+	}
+	config := s.configForProvider(provider)
+	if req.Model != "" {
+		config.Model = req.Model
+	}
+
+	clf, err := classifier.NewClassifier(provider, config)
+	if err != nil {
+		logger.WithError(err).Error("Failed to create classifier")
+		http.Error(w, "Failed to create classifier", http.StatusInternalServerError)
+		return
+	}
+
+	reservation, ok := s.reserveBudget(w)
+	if !ok {
+		return
+	}
+	defer s.releaseBudget(reservation)
+
+	options := s.defaults
+	options.Categories = req.Categories
+	classification, err := clf.ClassifyWithOptions(original.Text, options)
+	if err != nil {
+		logger.WithError(err).Error("Reclassification failed")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(ClassificationResponse{Error: err.Error()})
+		return
+	}
+
+	s.recordCost(reservation, config.Model, original.Text, classification)
+
+	record := &HistoryRecord{
+		ParentID:       original.ID,
+		Filename:       original.Filename,
+		Provider:       provider,
+		Model:          config.Model,
+		Categories:     req.Categories,
+		Classification: classification,
+		Text:           original.Text,
+	}
+	s.recordHistory(record)
+
+	logger.WithFields(log.Fields{
+		"new_id":   record.ID,
+		"category": classification.Category,
+	}).Info("Reclassification completed")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(record); err != nil {
+		logger.WithError(err).Error("Failed to encode response")
+	}
+}
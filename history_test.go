@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+)
+
+// openAIReply is an OpenAI chat completion classifying the text as category
+func openAIReply(category string) string {
+	content, _ := json.Marshal(`{"category":"` + category + `","confidence":0.9,"summary":"s","keywords":["k"]}`)
+	return `{"choices":[{"message":{"content":` + string(content) + `},"finish_reason":"stop"}]}`
+}
+
+// newReclassifyRequest builds a reclassify request for the history record id
+func newReclassifyRequest(id, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/history/"+id+"/reclassify", strings.NewReader(body))
+	req.SetPathValue("id", id)
+	return req
+}
+
+// newHistoryServer returns a server classifying with the OpenAI-compatible provider
+// at endpoint, holding one history record for text
+func newHistoryServer(t *testing.T, endpoint, text string) (*Server, *HistoryRecord) {
+	t.Helper()
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{
+		Endpoint:   endpoint,
+		APIKey:     "server-key",
+		MaxRetries: -1,
+	})
+	s.history = NewMemoryHistoryStore(10)
+	original := &HistoryRecord{
+		Filename:       "contract.txt",
+		Provider:       classifier.OpenAI,
+		Classification: &classifier.Classification{Category: "Legal"},
+		Text:           text,
+	}
+	s.recordHistory(original)
+	return s, original
+}
+
+func TestReclassifyRecordsLinkedRecord(t *testing.T) {
+	var prompt string
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for _, message := range body.Messages {
+			prompt += message.Content
+		}
+		w.Write([]byte(openAIReply("Contract")))
+	}))
+	defer provider.Close()
+
+	s, original := newHistoryServer(t, provider.URL, "This agreement is made between the parties.")
+	rec := httptest.NewRecorder()
+	s.handleReclassify(rec, newReclassifyRequest(original.ID, `{"categories":["Contract","Invoice"]}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+
+	var record HistoryRecord
+	if err := json.Unmarshal(rec.Body.Bytes(), &record); err != nil {
+		t.Fatalf("response is not JSON: %v", err)
+	}
+	if record.ID == "" || record.ID == original.ID || record.ParentID != original.ID {
+		t.Fatalf("record %q with parent %q, want a new record linked to %q", record.ID, record.ParentID, original.ID)
+	}
+	if record.Classification == nil || record.Classification.Category != "Contract" {
+		t.Errorf("classification = %+v, want Contract", record.Classification)
+	}
+	stored, ok := s.history.Get(record.ID)
+	if !ok {
+		t.Fatal("new record is not in the history store")
+	}
+	if stored.Text != original.Text || stored.Filename != original.Filename {
+		t.Errorf("stored record %+v does not carry the original text and filename", stored)
+	}
+	if !strings.Contains(prompt, original.Text) || !strings.Contains(prompt, "Invoice") {
+		t.Errorf("provider prompt lacks the stored text or the new categories: %s", prompt)
+	}
+	if got, _ := s.history.Get(original.ID); got.Classification.Category != "Legal" {
+		t.Errorf("original record changed to %q", got.Classification.Category)
+	}
+}
+
+func TestReclassifyUnknownRecord(t *testing.T) {
+	s, _ := newHistoryServer(t, "http://127.0.0.1:0", "text")
+	rec := httptest.NewRecorder()
+	s.handleReclassify(rec, newReclassifyRequest("missing", ""))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestReclassifyOtherProviderUsesItsOwnEndpoint(t *testing.T) {
+	configured := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("configured endpoint called with Authorization %q and x-api-key %q",
+			r.Header.Get("Authorization"), r.Header.Get("x-api-key"))
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer configured.Close()
+
+	var apiKey string
+	anthropic := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.Header.Get("x-api-key")
+		content, _ := json.Marshal(`{"category":"Contract","confidence":0.8,"keywords":[]}`)
+		w.Write([]byte(`{"content":[{"type":"text","text":` + string(content) + `}],"stop_reason":"end_turn"}`))
+	}))
+	defer anthropic.Close()
+	t.Setenv("ANTHROPIC_API_KEY", "anthropic-key")
+	t.Setenv("ANTHROPIC_BASE_URL", anthropic.URL)
+
+	s, original := newHistoryServer(t, configured.URL, "This agreement is made between the parties.")
+	s.config.Headers = map[string]string{"X-Tenant": "acme"}
+	rec := httptest.NewRecorder()
+	s.handleReclassify(rec, newReclassifyRequest(original.ID, `{"provider":"anthropic"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	if apiKey != "anthropic-key" {
+		t.Errorf("Anthropic called with key %q, want anthropic-key", apiKey)
+	}
+	var record HistoryRecord
+	json.Unmarshal(rec.Body.Bytes(), &record)
+	if record.Provider != classifier.Anthropic || record.Model != "" {
+		t.Errorf("record provider %q model %q, want anthropic with its default model", record.Provider, record.Model)
+	}
+}
+
+func TestReclassifyAppliesServerDefaults(t *testing.T) {
+	provider := serveProvider(t, http.StatusOK, openAIReply("Invoice"))
+	s, original := newHistoryServer(t, provider.URL, "Invoice 42: please pay $40 by Friday.")
+	s.defaults.ExcludeCategories = []string{"Invoice"}
+
+	rec := httptest.NewRecorder()
+	s.handleReclassify(rec, newReclassifyRequest(original.ID, ""))
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502 for a category excluded by the server defaults (body %s)", rec.Code, rec.Body)
+	}
+}
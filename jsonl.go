@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+	"github.com/adaptive-scale/superclass/pkg/pool"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxJSONLLineBytes bounds a single NDJSON record
+const maxJSONLLineBytes = 4 << 20
+
+// JSONLRecord is one line of a /classify/jsonl upload
+type JSONLRecord struct {
+	ID         string   `json:"id"`
+	Text       string   `json:"text"`
+	Categories []string `json:"categories,omitempty"`
+}
+
+// JSONLResult is one line of a /classify/jsonl response. Line is the 1-based input
+// line, which identifies records that could not be parsed.
+type JSONLResult struct {
+	ID             string                     `json:"id"`
+	Line           int                        `json:"line"`
+	Classification *classifier.Classification `json:"classification,omitempty"`
+	Error          string                     `json:"error,omitempty"`
+}
+
+// handleClassifyJSONL classifies every line of an NDJSON upload of {id, text, categories?}
+// records and streams one {id, classification} line per record in completion order
+func (s *Server) handleClassifyJSONL(w http.ResponseWriter, r *http.Request) {
+	logger := log.WithFields(log.Fields{
+		"handler": "classify_jsonl",
+		"method":  r.Method,
+		"remote":  r.RemoteAddr,
+	})
+
+	if r.Method != http.MethodPost {
+		logger.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := jsonlBody(r)
+	if err != nil {
+		logger.WithError(err).Error("Failed to read upload")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer body.Close()
+
+	// The whole upload is read before responding; HTTP/1 handlers cannot reliably read
+	// the request body once the response has started
+	lines, err := readJSONLLines(body)
+	if err != nil {
+		logger.WithError(err).Error("Failed to read NDJSON")
+		http.Error(w, "Failed to read NDJSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(lines) == 0 {
+		http.Error(w, "No records provided", http.StatusBadRequest)
+		return
+	}
+
+	clf, err := classifier.NewClassifier(s.provider, s.config)
+	if err != nil {
+		logger.WithError(err).Error("Failed to create classifier")
+		http.Error(w, "Failed to create classifier", http.StatusInternalServerError)
+		return
+	}
+
+	logger = logger.WithField("record_count", len(lines))
+	logger.Info("Classifying NDJSON records")
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+	p := pool.New(s.batchConcurrency)
+	for _, line := range lines {
+		p.Submit(func() error {
+			result := s.classifyJSONLRecord(clf, line.number, line.data)
+			mu.Lock()
+			defer mu.Unlock()
+			if err := encoder.Encode(result); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		})
+	}
+	if errs := p.Wait(); len(errs) > 0 {
+		logger.WithError(errs[0]).Warn("Client went away while streaming")
+		return
+	}
+
+	logger.Info("NDJSON classification completed")
+}
+
+// classifyJSONLRecord parses and classifies a single NDJSON line
+func (s *Server) classifyJSONLRecord(clf classifier.Classifier, lineNumber int, line []byte) JSONLResult {
+	result := JSONLResult{Line: lineNumber}
+
+	var record JSONLRecord
+	if err := json.Unmarshal(line, &record); err != nil {
+		result.Error = fmt.Sprintf("invalid record: %v", err)
+		return result
+	}
+	result.ID = record.ID
+	if strings.TrimSpace(record.Text) == "" {
+		result.Error = "text is empty"
+		return result
+	}
+	reservation, ok := s.tryReserveBudget()
+	if !ok {
+		result.Error = s.budgetExhaustedMessage(s.budget.ResetIn())
+		return result
+	}
+	defer s.releaseBudget(reservation)
+
+	options := s.defaults
+	options.Categories = record.Categories
+	classification, err := clf.ClassifyWithOptions(record.Text, options)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"function": "classifyJSONLRecord",
+			"id":       record.ID,
+			"line":     lineNumber,
+		}).WithError(err).Error("Classification failed")
+		result.Error = err.Error()
+		return result
+	}
+	s.recordCost(reservation, s.config.Model, record.Text, classification)
+
+	result.Classification = classification
+	return result
+}
+
+// jsonlBody returns the NDJSON upload: the "file" field of a multipart form, or the raw request body
+func jsonlBody(r *http.Request) (io.ReadCloser, error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.Body, nil
+	}
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		return nil, fmt.Errorf("failed to parse form: %w", err)
+	}
+	fh, ok := firstFile(r, "file")
+	if !ok {
+		return nil, fmt.Errorf("no file provided")
+	}
+	return fh.Open()
+}
+
+// jsonlLine is a non-blank input line with its 1-based position
+type jsonlLine struct {
+	number int
+	data   []byte
+}
+
+// readJSONLLines returns the non-blank lines of an NDJSON stream
+func readJSONLLines(r io.Reader) ([]jsonlLine, error) {
+	var lines []jsonlLine
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLLineBytes)
+	for number := 1; scanner.Scan(); number++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		lines = append(lines, jsonlLine{number: number, data: append([]byte(nil), scanner.Bytes()...)})
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestClassifyJSONL(t *testing.T) {
+	s := newTestServer(t, openAIReply("Invoice"))
+
+	input := strings.Join([]string{
+		`{"id":"a","text":"Invoice #12 total due"}`,
+		``,
+		`not json`,
+		`{"id":"b","text":"  "}`,
+		`{"id":"c","text":"Payment reminder","categories":["Invoice","Letter"]}`,
+	}, "\n")
+	rec := httptest.NewRecorder()
+	s.handleClassifyJSONL(rec, httptest.NewRequest(http.MethodPost, "/classify/jsonl", strings.NewReader(input)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q, want application/x-ndjson", ct)
+	}
+
+	// Results stream in completion order, so key them by input line
+	var results []JSONLResult
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var result JSONLResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatalf("response line %q is not a result: %v", scanner.Text(), err)
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Line < results[j].Line })
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want one per non-blank line: %+v", len(results), results)
+	}
+
+	for _, i := range []int{0, 3} {
+		if results[i].Classification == nil || results[i].Classification.Category != "Invoice" {
+			t.Errorf("record %q = %+v, want an Invoice classification", results[i].ID, results[i])
+		}
+	}
+	if results[1].Line != 3 || !strings.HasPrefix(results[1].Error, "invalid record") {
+		t.Errorf("malformed line = %+v, want an invalid record error on line 3", results[1])
+	}
+	if results[2].ID != "b" || results[2].Error != "text is empty" {
+		t.Errorf("blank text = %+v, want a text is empty error", results[2])
+	}
+}
+
+func TestClassifyJSONLRejectsEmptyUploads(t *testing.T) {
+	s := newTestServer(t, openAIReply("Invoice"))
+
+	rec := httptest.NewRecorder()
+	s.handleClassifyJSONL(rec, httptest.NewRequest(http.MethodPost, "/classify/jsonl", strings.NewReader("\n\n")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+
+	// Multipart uploads carry the records in the file field
+	req := newUploadRequest(t, "/classify/jsonl", "records.jsonl", []byte(`{"id":"a","text":"Invoice"}`), nil)
+	rec = httptest.NewRecorder()
+	s.handleClassifyJSONL(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"id":"a"`) {
+		t.Errorf("multipart upload = %d %s, want a result for record a", rec.Code, rec.Body)
+	}
+}
@@ -23,6 +23,10 @@ func init() {
 }
 
 func main() {
+	if runCLI(os.Args[1:]) {
+		return
+	}
+
 	log.Debug("Starting application initialization")
 
 	// Log all environment variables in debug mode
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+	"github.com/adaptive-scale/superclass/pkg/extractor"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	previous := extractor.DefaultMetrics
+	extractor.DefaultMetrics = extractor.NewExtractionMetrics()
+	t.Cleanup(func() { extractor.DefaultMetrics = previous })
+
+	data := []byte("# Report\n\nQuarterly figures.\n")
+	path := filepath.Join(t.TempDir(), "report.md")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := extractor.ExtractText(path); err != nil {
+		t.Fatalf("ExtractText: %v", err)
+	}
+
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{})
+	rec := httptest.NewRecorder()
+	s.handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want the Prometheus text format", got)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		`superclass_extraction_duration_seconds_count{extension=".md"} 1`,
+		fmt.Sprintf(`superclass_extraction_input_bytes_total{extension=".md"} %d`, len(data)),
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("/metrics is missing %q:\n%s", line, body)
+		}
+	}
+	if !strings.Contains(body, `superclass_extraction_output_bytes_total{extension=".md"} `) {
+		t.Errorf("/metrics has no output bytes for .md:\n%s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleMetrics(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware wraps an http.Handler with cross-cutting behaviour such as limits or logging
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h with the given middlewares. The first middleware is the outermost,
+// so requests pass through them in the order given.
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// Use appends middlewares to the chain applied to every route. It must be called before Start.
+func (s *Server) Use(middlewares ...Middleware) {
+	s.middlewares = append(s.middlewares, middlewares...)
+}
+
+// normalizeRoutePrefix turns "api/v1/" into "/api/v1"; an empty or "/" prefix mounts routes at the root
+func normalizeRoutePrefix(prefix string) string {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
+// routes registers every endpoint under the configured route prefix
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	handle := func(pattern string, handler http.HandlerFunc) {
+		mux.HandleFunc(s.routePrefix+pattern, handler)
+	}
+
+	handle("/classify", s.handleClassify)
+	handle("/classify/batch", s.handleClassifyBatch)
+	handle("/classify/stream-batch", s.handleClassifyStreamBatch)
+	handle("/classify/auto", s.handleClassifyAuto)
+	handle("/classify/multi-score", s.handleClassifyMultiScore)
+	handle("/classify/jsonl", s.handleClassifyJSONL)
+	handle("/history/{id}/reclassify", s.handleReclassify)
+	handle("/review", s.handleReview)
+	handle("/health", s.handleHealth)
+	handle("/health/ready", s.handleReady)
+	handle("/formats", s.handleFormats)
+	handle("/metrics", s.handleMetrics)
+	if !s.disableUI {
+		mux.HandleFunc("GET "+s.routePrefix+"/{$}", s.handleUI)
+	}
+	return mux
+}
+
+// Handler returns the server's routes wrapped in the body limit middleware and any
+// middlewares added with Use
+func (s *Server) Handler() http.Handler {
+	return Chain(s.routes(), append([]Middleware{s.withBodyLimits}, s.middlewares...)...)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name+" in")
+				next.ServeHTTP(w, r)
+				order = append(order, name+" out")
+			})
+		}
+	}
+	handler := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}), trace("outer"), trace("inner"))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	want := []string{"outer in", "inner in", "handler", "inner out", "outer out"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %q, want %q", order, want)
+	}
+}
+
+func TestNormalizeRoutePrefix(t *testing.T) {
+	for prefix, want := range map[string]string{
+		"":        "",
+		"/":       "",
+		"api/v1/": "/api/v1",
+		" /api/ ": "/api",
+		"/api/v1": "/api/v1",
+	} {
+		if got := normalizeRoutePrefix(prefix); got != want {
+			t.Errorf("normalizeRoutePrefix(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+}
+
+func TestHandlerMountsRoutesUnderPrefix(t *testing.T) {
+	s := newTestServer(t, openAIReply("Invoice"))
+	s.routePrefix = normalizeRoutePrefix("api/v1")
+	s.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Served-By", "test")
+			next.ServeHTTP(w, r)
+		})
+	})
+	handler := s.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("prefixed health status = %d, want 200", rec.Code)
+	}
+	if rec.Header().Get("X-Served-By") != "test" {
+		t.Error("middleware added with Use did not run")
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unprefixed health status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/adaptive-scale/superclass/pkg/extractor"
+	log "github.com/sirupsen/logrus"
+)
+
+// handleClassifyMultiScore scores a document against every supplied category rather than
+// only returning the best match, so candidate taxonomies can be compared
+func (s *Server) handleClassifyMultiScore(w http.ResponseWriter, r *http.Request) {
+	logger := log.WithFields(log.Fields{
+		"handler": "classify_multi_score",
+		"method":  r.Method,
+		"remote":  r.RemoteAddr,
+	})
+
+	if r.Method != http.MethodPost {
+		logger.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		logger.WithError(err).Error("Failed to parse form")
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	var classificationReq ClassificationRequest
+	if err := json.Unmarshal([]byte(r.FormValue("categories")), &classificationReq.Categories); err != nil || len(classificationReq.Categories) == 0 {
+		logger.WithError(err).Error("Missing or invalid categories")
+		http.Error(w, "categories must be a non-empty JSON array", http.StatusBadRequest)
+		return
+	}
+
+	fh, ok := firstFile(r, "file")
+	if !ok {
+		logger.Error("Failed to get file from form")
+		http.Error(w, "Failed to get file from form", http.StatusBadRequest)
+		return
+	}
+	if !s.extensionAllowed(fh.Filename) {
+		logger.Warn("File extension not allowed")
+		http.Error(w, "File type not allowed", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	logger = logger.WithFields(log.Fields{
+		"filename":       fh.Filename,
+		"category_count": len(classificationReq.Categories),
+	})
+
+	tempFile, err := s.saveUpload(fh)
+	if err != nil {
+		logger.WithError(err).Error("Failed to store upload")
+		http.Error(w, "Failed to save file", http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		if err := os.Remove(tempFile); err != nil {
+			logger.WithError(err).Warn("Failed to remove temporary file")
+		}
+	}()
+
+	reservation, ok := s.reserveBudget(w)
+	if !ok {
+		return
+	}
+	defer s.releaseBudget(reservation)
+
+	options := s.defaults
+	options.Categories = classificationReq.Categories
+	options.ScoreAllCategories = true
+	result, err := extractor.ExtractAndClassifyWithOptions(tempFile, s.provider, s.config, options)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		logger.WithError(err).Error("Classification failed")
+		status, ok := classificationErrorStatus(err)
+		if !ok {
+			status = http.StatusBadGateway
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ClassificationResponse{Error: classificationErrorMessage(err)})
+		return
+	}
+
+	s.recordCost(reservation, s.config.Model, result.Text, result.Classification)
+
+	response := ClassificationResponse{
+		Category:      result.Classification.Category,
+		Confidence:    result.Classification.Confidence,
+		Summary:       result.Classification.Summary,
+		Keywords:      result.Classification.Keywords,
+		Usage:         result.Classification.Usage,
+		EstimatedCost: result.Classification.EstimatedCost,
+		Metadata:      result.Classification.Metadata,
+		Scores:        result.Classification.Scores,
+		TypePath:      result.TypePath,
+	}
+
+	logger.WithField("scores", response.Scores).Info("Multi-score classification completed")
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.WithError(err).Error("Failed to encode response")
+	}
+}
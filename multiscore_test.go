@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+)
+
+func TestMultiScoreErrorStatus(t *testing.T) {
+	scored := `{"category":"Invoice","confidence":0.9,"summary":"s","keywords":["k"],"scores":{"Invoice":0.9,"Contract":0.1}}`
+	encoded, _ := json.Marshal(scored)
+
+	tests := []struct {
+		name          string
+		status        int
+		reply         string
+		minTextLength int
+		want          int
+	}{
+		{
+			name:   "success",
+			status: http.StatusOK,
+			reply:  `{"choices":[{"message":{"content":` + string(encoded) + `},"finish_reason":"stop"}]}`,
+			want:   http.StatusOK,
+		},
+		{
+			name:          "insufficient text",
+			status:        http.StatusOK,
+			reply:         `{}`,
+			minTextLength: 1000,
+			want:          http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "refused",
+			status: http.StatusOK,
+			reply:  `{"choices":[{"message":{"content":""},"finish_reason":"content_filter"}]}`,
+			want:   http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "truncated",
+			status: http.StatusOK,
+			reply:  `{"choices":[{"message":{"content":"{\"category\":"},"finish_reason":"length"}]}`,
+			want:   http.StatusBadGateway,
+		},
+		{
+			name:   "provider error",
+			status: http.StatusBadRequest,
+			reply:  `{"error":{"message":"bad request"}}`,
+			want:   http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := serveProvider(t, tt.status, tt.reply)
+			s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{
+				Endpoint:   provider.URL,
+				APIKey:     "key",
+				MaxRetries: -1,
+			})
+			s.defaults.MinTextLength = tt.minTextLength
+
+			req := newUploadRequest(t, "/classify/multi-score", "invoice.txt", []byte("Invoice 42: please pay $40 by Friday."),
+				map[string]string{"categories": `["Invoice","Contract"]`})
+			rec := httptest.NewRecorder()
+			s.handleClassifyMultiScore(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body)
+			}
+			var response ClassificationResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("response is not JSON: %v", err)
+			}
+			if tt.want == http.StatusOK && response.Scores["Invoice"] != 0.9 {
+				t.Errorf("scores = %v, want Invoice 0.9", response.Scores)
+			}
+			if tt.want != http.StatusOK && response.Error == "" {
+				t.Error("error is empty")
+			}
+		})
+	}
+}
@@ -2,27 +2,35 @@ package classifier
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// defaultAnthropicEndpoint is the Messages API used unless an endpoint is configured
+const defaultAnthropicEndpoint = "https://api.anthropic.com/v1/messages"
+
 // AnthropicClassifier handles content classification using Anthropic's Claude models
 type AnthropicClassifier struct {
-	apiKey     string
-	model      string
-	endpoint   string
-	parameters map[string]interface{}
+	apiKey        string
+	model         string
+	endpoint      string
+	parameters    map[string]interface{}
+	promptCaching bool
+	headers       map[string]string
+	retry         retryOverrides
 }
 
 // NewAnthropicClassifier creates a new Anthropic classifier
 func NewAnthropicClassifier(config ModelConfig) *AnthropicClassifier {
 	endpoint := config.Endpoint
 	if endpoint == "" {
-		endpoint = "https://api.anthropic.com/v1/messages"
+		endpoint = providerEndpoint(Anthropic, "/v1/messages", defaultAnthropicEndpoint)
 	}
 
 	model := config.Model
@@ -31,10 +39,13 @@ func NewAnthropicClassifier(config ModelConfig) *AnthropicClassifier {
 	}
 
 	return &AnthropicClassifier{
-		apiKey:     config.APIKey,
-		model:      model,
-		endpoint:   endpoint,
-		parameters: config.Parameters,
+		apiKey:        config.APIKey,
+		model:         model,
+		endpoint:      endpoint,
+		parameters:    config.Parameters,
+		promptCaching: config.EnablePromptCaching != nil && *config.EnablePromptCaching,
+		headers:       config.Headers,
+		retry:         retryOverridesFor(config),
 	}
 }
 
@@ -52,26 +63,91 @@ func (c *AnthropicClassifier) Configure(config ModelConfig) error {
 	if config.Parameters != nil {
 		c.parameters = config.Parameters
 	}
+	if config.EnablePromptCaching != nil {
+		c.promptCaching = *config.EnablePromptCaching
+	}
+	if config.Headers != nil {
+		c.headers = config.Headers
+	}
+	c.retry.update(config)
 	return nil
 }
 
+// modelName returns the model classification requests are sent to
+func (c *AnthropicClassifier) modelName() string { return c.model }
+
 type anthropicRequest struct {
-	Model       string             `json:"model"`
-	Messages    []anthropicMessage `json:"messages"`
-	System      string             `json:"system,omitempty"`
-	MaxTokens   int                `json:"max_tokens,omitempty"`
-	Temperature float64            `json:"temperature,omitempty"`
+	Model     string                 `json:"model"`
+	Messages  []anthropicMessage     `json:"messages"`
+	System    []anthropicSystemBlock `json:"system,omitempty"`
+	MaxTokens int                    `json:"max_tokens,omitempty"`
+	// Temperature is a pointer so that a temperature of 0 is sent
+	Temperature *float64 `json:"temperature,omitempty"`
 }
 
+type anthropicSystemBlock struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+type anthropicCacheControl struct {
+	Type string `json:"type"`
+}
+
+// anthropicPromptCachingBeta is the beta flag that enables prompt caching
+const anthropicPromptCachingBeta = "prompt-caching-2024-07-31"
+
 type anthropicMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role string `json:"role"`
+	// Content is either a plain string or a list of anthropicContentBlock
+	Content interface{} `json:"content"`
+}
+
+type anthropicContentBlock struct {
+	Type   string                   `json:"type"`
+	Text   string                   `json:"text,omitempty"`
+	Source *anthropicDocumentSource `json:"source,omitempty"`
+}
+
+type anthropicDocumentSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// anthropicNativeMediaTypes lists the document types Claude can read directly
+var anthropicNativeMediaTypes = map[string]bool{
+	"application/pdf": true,
+}
+
+// buildUserContent returns the user message content, attaching the original
+// document as a base64 content block when native reading is requested and supported
+func buildUserContent(content string, options ClassificationOptions) interface{} {
+	if !options.NativeDocument || options.Document == nil || !anthropicNativeMediaTypes[options.Document.MediaType] {
+		return buildPrompt(content, options)
+	}
+	return []anthropicContentBlock{
+		{
+			Type: "document",
+			Source: &anthropicDocumentSource{
+				Type:      "base64",
+				MediaType: options.Document.MediaType,
+				Data:      base64.StdEncoding.EncodeToString(options.Document.Data),
+			},
+		},
+		{
+			Type: "text",
+			Text: buildPrompt(attachedDocumentText, options),
+		},
+	}
 }
 
 type anthropicResponse struct {
 	Content []struct {
 		Text string `json:"text"`
 	} `json:"content"`
+	StopReason string `json:"stop_reason"`
 }
 
 // Classify takes text content and returns classification details
@@ -88,49 +164,40 @@ func (c *AnthropicClassifier) ClassifyWithOptions(content string, options Classi
 		"has_categories": len(options.Categories) > 0,
 	})
 	logger.Debug("Starting content classification")
+	start := time.Now()
 
 	if c.apiKey == "" {
 		logger.Error("Anthropic API key is required")
 		return nil, fmt.Errorf("Anthropic API key is required")
 	}
 
-	var prompt string
-	if len(options.Categories) > 0 {
-		categoriesStr := strings.Join(options.Categories, ", ")
-		prompt = fmt.Sprintf(`Analyze the following text and classify it into one of these categories: %s
-
-Provide a JSON response with these fields:
-	- category: One of the categories listed above that best matches the content
-	- confidence: A confidence score between 0 and 1 indicating how well the content matches the chosen category
-	- summary: A brief summary of the content (max 100 words)
-	- keywords: Up to 5 key terms or phrases from the content
+	userContent := buildUserContent(content, options)
+	_, nativeDocument := userContent.([]anthropicContentBlock)
 
-Text to analyze:
-%s`, categoriesStr, content)
-	} else {
-		prompt = fmt.Sprintf(`Analyze the following text and provide a JSON response with these fields:
-	- category: The main category/topic of the content
-	- confidence: A confidence score between 0 and 1
-	- summary: A brief summary of the content (max 100 words)
-	- keywords: Up to 5 key terms or phrases from the content
+	systemBlock := anthropicSystemBlock{
+		Type: "text",
+		Text: systemMessage(options),
+	}
+	if c.promptCaching {
+		systemBlock.CacheControl = &anthropicCacheControl{Type: "ephemeral"}
+	}
 
-Text to analyze:
-%s`, content)
+	temperature := 0.3 // default temperature
+	if temp, ok := c.parameters["temperature"].(float64); ok {
+		temperature = temp
 	}
 
 	reqBody := anthropicRequest{
-		Model: c.model,
+		Model:  c.model,
+		System: []anthropicSystemBlock{systemBlock},
 		Messages: []anthropicMessage{
-			{
-				Role:    "system",
-				Content: "You are a content classification expert. Always respond in valid JSON format.",
-			},
 			{
 				Role:    "user",
-				Content: prompt,
+				Content: userContent,
 			},
 		},
-		MaxTokens: c.parameters["max_tokens"].(int),
+		MaxTokens:   IntParameter(c.parameters, "max_tokens", defaultMaxTokens),
+		Temperature: &temperature,
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -140,12 +207,19 @@ Text to analyze:
 	}
 
 	logger.WithFields(logrus.Fields{
-		"request_body":          string(jsonBody),
+		"request_body":          loggableBody(jsonBody),
 		"model":                 c.model,
+		"temperature":           temperature,
+		"prompt_caching":        c.promptCaching,
+		"native_document":       nativeDocument,
 		"predefined_categories": options.Categories,
 	}).Debug("Request payload prepared")
 
-	req, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonBody))
+	endpoint := c.endpoint
+	if endpoint == "" {
+		endpoint = defaultAnthropicEndpoint
+	}
+	req, err := http.NewRequestWithContext(requestContext(options), "POST", endpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		logger.WithError(err).Error("Failed to create request")
 		return nil, fmt.Errorf("error creating request: %w", err)
@@ -154,9 +228,12 @@ Text to analyze:
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-api-key", c.apiKey)
 	req.Header.Set("anthropic-version", "2023-06-01")
+	if c.promptCaching {
+		req.Header.Set("anthropic-beta", anthropicPromptCachingBeta)
+	}
+	setCustomHeaders(req, c.headers, logger)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, retries, err := sendRequest(req, c.retry, logger)
 	if err != nil {
 		logger.WithError(err).Error("API request failed")
 		return nil, fmt.Errorf("error making request: %w", err)
@@ -169,42 +246,44 @@ Text to analyze:
 	}
 
 	var anthropicResp anthropicResponse
-	if err := json.NewDecoder(resp.Body).Decode(&anthropicResp); err != nil {
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.WithError(err).Error("Failed to read response body")
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	if err := json.Unmarshal(respBody, &anthropicResp); err != nil {
 		logger.WithError(err).Error("Failed to decode response")
 		return nil, fmt.Errorf("error decoding response: %w", err)
 	}
 
+	if err := checkCompletion(anthropicResp.StopReason, "", logger); err != nil {
+		return nil, err
+	}
+
 	if len(anthropicResp.Content) == 0 {
 		logger.Error("No classification result received")
 		return nil, fmt.Errorf("no classification result received")
 	}
 
 	var classification Classification
-	if err := json.Unmarshal([]byte(anthropicResp.Content[0].Text), &classification); err != nil {
+	if err := json.Unmarshal([]byte(extractJSON(anthropicResp.Content[0].Text)), &classification); err != nil {
 		logger.WithFields(logrus.Fields{
 			"raw_content": anthropicResp.Content[0].Text,
 		}).WithError(err).Error("Failed to parse classification")
 		return nil, fmt.Errorf("error parsing classification: %w", err)
 	}
+	if options.DebugIncludeRaw {
+		classification.RawResponse = anthropicResp.Content[0].Text
+	}
+
+	postprocess(&classification, content, options)
 
-	// Validate category if predefined categories were provided
-	if len(options.Categories) > 0 {
-		categoryValid := false
-		for _, validCategory := range options.Categories {
-			if strings.EqualFold(classification.Category, validCategory) {
-				classification.Category = validCategory // Use exact case from predefined list
-				categoryValid = true
-				break
-			}
-		}
-		if !categoryValid {
-			logger.WithFields(logrus.Fields{
-				"received_category": classification.Category,
-				"valid_categories":  options.Categories,
-			}).Error("Classification returned invalid category")
-			return nil, fmt.Errorf("classifier returned invalid category: %s", classification.Category)
-		}
+	// Validate category if predefined categories or a taxonomy were provided
+	if err := validateCategory(&classification, options, logger); err != nil {
+		return nil, err
 	}
+	classification.Metadata = newMetadata(start, retries, Anthropic, c.model)
+	classification.setUsage(parseUsage(respBody), c.model)
 
 	logger.WithFields(logrus.Fields{
 		"category":                   classification.Category,
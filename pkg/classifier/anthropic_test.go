@@ -0,0 +1,241 @@
+package classifier
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// anthropicReply is a minimal successful Messages API response carrying a classification
+var anthropicReply = `{"content":[{"type":"text","text":` + contentJSON() + `}],"stop_reason":"end_turn"}`
+
+func TestDeterministicSamplingSettingsSent(t *testing.T) {
+	seed := 42
+	parameters := map[string]interface{}{"temperature": 0.0}
+
+	t.Run("openai", func(t *testing.T) {
+		var body map[string]interface{}
+		server := captureBody(t, gptReply, &body)
+		c := NewGPTClassifier(ModelConfig{Endpoint: server.URL, APIKey: "key", Seed: &seed, Parameters: parameters})
+		if _, err := c.Classify("some text"); err != nil {
+			t.Fatalf("Classify: %v", err)
+		}
+		if got, ok := body["seed"]; !ok || got != 42.0 {
+			t.Errorf("seed = %v, want 42", got)
+		}
+		if got, ok := body["temperature"]; !ok || got != 0.0 {
+			t.Errorf("temperature = %v (sent: %v), want 0", got, ok)
+		}
+	})
+
+	t.Run("anthropic", func(t *testing.T) {
+		var body map[string]interface{}
+		server := captureBody(t, anthropicReply, &body)
+		c := NewAnthropicClassifier(ModelConfig{Endpoint: server.URL, APIKey: "key", Parameters: parameters})
+		if _, err := c.Classify("some text"); err != nil {
+			t.Fatalf("Classify: %v", err)
+		}
+		if got, ok := body["temperature"]; !ok || got != 0.0 {
+			t.Errorf("temperature = %v (sent: %v), want 0", got, ok)
+		}
+	})
+}
+
+func TestAnthropicTemperatureFromParameters(t *testing.T) {
+	var body map[string]interface{}
+	server := captureBody(t, anthropicReply, &body)
+	c := NewAnthropicClassifier(ModelConfig{
+		Endpoint:   server.URL,
+		APIKey:     "key",
+		Parameters: map[string]interface{}{"temperature": 0.7},
+	})
+	if _, err := c.Classify("some text"); err != nil {
+		t.Fatalf("Classify: %v", err)
+	}
+	if got := body["temperature"]; got != 0.7 {
+		t.Errorf("temperature = %v, want 0.7", got)
+	}
+}
+
+func TestAnthropicMaxTokens(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]interface{}
+		want       float64
+	}{
+		{name: "nil parameters", parameters: nil, want: defaultMaxTokens},
+		{name: "float64", parameters: map[string]interface{}{"max_tokens": 512.0}, want: 512},
+		{name: "int", parameters: map[string]interface{}{"max_tokens": 256}, want: 256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]interface{}
+			server := captureBody(t, anthropicReply, &body)
+			c := NewAnthropicClassifier(ModelConfig{Endpoint: server.URL, APIKey: "key", Parameters: tt.parameters})
+			if _, err := c.Classify("some text"); err != nil {
+				t.Fatalf("Classify: %v", err)
+			}
+			if got := body["max_tokens"]; got != tt.want {
+				t.Errorf("max_tokens = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// roundTripFunc answers requests without a network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// useTransport sends every provider request of the test through rt
+func useTransport(t *testing.T, rt http.RoundTripper) {
+	t.Helper()
+	httpClientMu.Lock()
+	previous := httpClient
+	httpClient = &http.Client{Transport: rt}
+	httpClientMu.Unlock()
+	t.Cleanup(func() {
+		httpClientMu.Lock()
+		httpClient = previous
+		httpClientMu.Unlock()
+	})
+}
+
+func TestAnthropicEndpoint(t *testing.T) {
+	t.Setenv("ANTHROPIC_BASE_URL", "")
+
+	tests := []struct {
+		name     string
+		endpoint string
+		want     string
+	}{
+		{name: "configured", endpoint: "https://gateway.internal/anthropic/v1/messages", want: "https://gateway.internal/anthropic/v1/messages"},
+		{name: "default", endpoint: "", want: defaultAnthropicEndpoint},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			useTransport(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				got = req.URL.String()
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     http.Header{"Content-Type": {"application/json"}},
+					Body:       io.NopCloser(strings.NewReader(anthropicReply)),
+					Request:    req,
+				}, nil
+			}))
+
+			c := NewAnthropicClassifier(ModelConfig{Endpoint: tt.endpoint, APIKey: "key"})
+			if _, err := c.Classify("some text"); err != nil {
+				t.Fatalf("Classify: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("request sent to %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnthropicPromptCachingConfigure(t *testing.T) {
+	var beta string
+	var body []byte
+	useTransport(t, roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		beta = req.Header.Get("anthropic-beta")
+		body, _ = io.ReadAll(req.Body)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(anthropicReply)),
+			Request:    req,
+		}, nil
+	}))
+
+	on, off := true, false
+	c := NewAnthropicClassifier(ModelConfig{Endpoint: "https://anthropic.test/v1/messages", APIKey: "key", EnablePromptCaching: &on})
+	steps := []struct {
+		name   string
+		config ModelConfig
+		want   bool
+	}{
+		{name: "enabled at construction", want: true},
+		{name: "unrelated update keeps caching", config: ModelConfig{APIKey: "other-key"}, want: true},
+		{name: "disabled", config: ModelConfig{EnablePromptCaching: &off}, want: false},
+		{name: "enabled again", config: ModelConfig{EnablePromptCaching: &on}, want: true},
+	}
+	for _, step := range steps {
+		if err := c.Configure(step.config); err != nil {
+			t.Fatalf("%s: Configure: %v", step.name, err)
+		}
+		if _, err := c.Classify("some text"); err != nil {
+			t.Fatalf("%s: Classify: %v", step.name, err)
+		}
+		if got := beta == anthropicPromptCachingBeta; got != step.want {
+			t.Errorf("%s: anthropic-beta = %q, want caching %v", step.name, beta, step.want)
+		}
+		if got := strings.Contains(string(body), `"cache_control"`); got != step.want {
+			t.Errorf("%s: cache_control sent = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestAnthropicNativeDocument(t *testing.T) {
+	pdf := []byte("%PDF-1.4 invoice")
+	tests := []struct {
+		name       string
+		options    ClassificationOptions
+		wantBlocks bool
+	}{
+		{
+			name:       "native pdf",
+			options:    ClassificationOptions{NativeDocument: true, Document: &Document{MediaType: "application/pdf", Data: pdf}},
+			wantBlocks: true,
+		},
+		{name: "not requested", options: ClassificationOptions{Document: &Document{MediaType: "application/pdf", Data: pdf}}},
+		{name: "unsupported type", options: ClassificationOptions{NativeDocument: true, Document: &Document{MediaType: "application/rtf", Data: pdf}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]interface{}
+			server := captureBody(t, anthropicReply, &body)
+			c := NewAnthropicClassifier(ModelConfig{Endpoint: server.URL, APIKey: "key"})
+			if _, err := c.ClassifyWithOptions("extracted invoice text", tt.options); err != nil {
+				t.Fatalf("ClassifyWithOptions: %v", err)
+			}
+			messages, _ := body["messages"].([]interface{})
+			if len(messages) != 1 {
+				t.Fatalf("%d messages, want 1", len(messages))
+			}
+			content := messages[0].(map[string]interface{})["content"]
+
+			if text, ok := content.(string); ok {
+				if tt.wantBlocks {
+					t.Fatalf("content sent as text, want blocks: %s", text)
+				}
+				if !strings.Contains(text, "extracted invoice text") {
+					t.Errorf("text prompt lacks the extracted text: %s", text)
+				}
+				return
+			}
+			var blocks []anthropicContentBlock
+			encoded, _ := json.Marshal(content)
+			if err := json.Unmarshal(encoded, &blocks); err != nil {
+				t.Fatalf("content is neither text nor blocks: %s", encoded)
+			}
+			if !tt.wantBlocks {
+				t.Fatalf("content sent as blocks: %s", encoded)
+			}
+			if len(blocks) != 2 || blocks[0].Type != "document" || blocks[1].Type != "text" {
+				t.Fatalf("blocks = %+v, want a document then the prompt", blocks)
+			}
+			if source := blocks[0].Source; source.MediaType != "application/pdf" || source.Data != base64.StdEncoding.EncodeToString(pdf) {
+				t.Errorf("document source = %+v, want the base64 PDF", source)
+			}
+			if !strings.Contains(blocks[1].Text, attachedDocumentText) || strings.Contains(blocks[1].Text, "extracted invoice text") {
+				t.Errorf("prompt should refer to the attachment instead of the extracted text:\n%s", blocks[1].Text)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package classifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// AuthScheme selects how the custom classifier authenticates its requests
+type AuthScheme string
+
+const (
+	// AuthBearer sends the API key as "Authorization: Bearer <key>" (the default)
+	AuthBearer AuthScheme = "bearer"
+	// AuthAPIKeyHeader sends the API key as-is in AuthConfig.Header
+	AuthAPIKeyHeader AuthScheme = "api_key_header"
+	// AuthHMAC signs the request body with HMAC-SHA256 and sends the hex digest in AuthConfig.Header
+	AuthHMAC AuthScheme = "hmac"
+)
+
+// Default header names for the header-based schemes
+const (
+	defaultAPIKeyHeader = "X-API-Key"
+	defaultHMACHeader   = "X-Signature"
+)
+
+// AuthConfig configures request authentication for the custom classifier
+type AuthConfig struct {
+	// Scheme is the authentication scheme (default: AuthBearer)
+	Scheme AuthScheme
+	// Header carries the key or signature for AuthAPIKeyHeader and AuthHMAC
+	// (default: X-API-Key and X-Signature respectively)
+	Header string
+	// Secret is the HMAC signing key (default: the API key)
+	Secret string
+}
+
+// AuthSchemeFromString converts a scheme name such as "hmac" to an AuthScheme.
+// Unknown values map to AuthBearer.
+func AuthSchemeFromString(scheme string) AuthScheme {
+	switch strings.ToLower(strings.ReplaceAll(scheme, "-", "_")) {
+	case "api_key_header", "api_key", "header":
+		return AuthAPIKeyHeader
+	case "hmac":
+		return AuthHMAC
+	default:
+		return AuthBearer
+	}
+}
+
+// SignHMAC returns the hex-encoded HMAC-SHA256 of body keyed with secret
+func SignHMAC(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// apply sets the authentication header on req for the given body and API key
+func (a AuthConfig) apply(req *http.Request, body []byte, apiKey string) error {
+	switch a.Scheme {
+	case AuthAPIKeyHeader:
+		if apiKey != "" {
+			req.Header.Set(a.headerOr(defaultAPIKeyHeader), apiKey)
+		}
+	case AuthHMAC:
+		secret := a.Secret
+		if secret == "" {
+			secret = apiKey
+		}
+		if secret == "" {
+			return fmt.Errorf("HMAC authentication requires a secret")
+		}
+		req.Header.Set(a.headerOr(defaultHMACHeader), SignHMAC(body, secret))
+	default:
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+	}
+	return nil
+}
+
+func (a AuthConfig) headerOr(defaultHeader string) string {
+	if a.Header != "" {
+		return a.Header
+	}
+	return defaultHeader
+}
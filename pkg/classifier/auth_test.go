@@ -0,0 +1,83 @@
+package classifier
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSignHMACKnownVector(t *testing.T) {
+	// RFC 4231, test case 2
+	got := SignHMAC([]byte("what do ya want for nothing?"), "Jefe")
+	want := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if got != want {
+		t.Errorf("SignHMAC = %s, want %s", got, want)
+	}
+}
+
+func TestCustomClassifierAuthSchemes(t *testing.T) {
+	tests := []struct {
+		name   string
+		auth   AuthConfig
+		header string
+		// want returns the expected header value for the request body
+		want func(body []byte) string
+	}{
+		{
+			name:   "bearer",
+			header: "Authorization",
+			want:   func([]byte) string { return "Bearer api-key" },
+		},
+		{
+			name:   "api key header",
+			auth:   AuthConfig{Scheme: AuthAPIKeyHeader, Header: "X-Gateway-Key"},
+			header: "X-Gateway-Key",
+			want:   func([]byte) string { return "api-key" },
+		},
+		{
+			name:   "hmac with secret",
+			auth:   AuthConfig{Scheme: AuthHMAC, Secret: "signing-secret"},
+			header: defaultHMACHeader,
+			want:   func(body []byte) string { return SignHMAC(body, "signing-secret") },
+		},
+		{
+			name:   "hmac keyed with the API key",
+			auth:   AuthConfig{Scheme: AuthHMAC, Header: "X-Body-Signature"},
+			header: "X-Body-Signature",
+			want:   func(body []byte) string { return SignHMAC(body, "api-key") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got, want, authorization string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				got, want = r.Header.Get(tt.header), tt.want(body)
+				authorization = r.Header.Get("Authorization")
+				w.Write([]byte(`{"content":` + contentJSON() + `}`))
+			}))
+			defer server.Close()
+
+			c := NewCustomClassifier(ModelConfig{Endpoint: server.URL, APIKey: "api-key", Auth: tt.auth, MaxRetries: -1})
+			if _, err := c.Classify("some text"); err != nil {
+				t.Fatalf("Classify: %v", err)
+			}
+			if got != want {
+				t.Errorf("%s = %q, want %q", tt.header, got, want)
+			}
+			if tt.header != "Authorization" && authorization != "" {
+				t.Errorf("Authorization = %q, want it unset", authorization)
+			}
+		})
+	}
+}
+
+func TestHMACRequiresSecret(t *testing.T) {
+	c := NewCustomClassifier(ModelConfig{Endpoint: "http://127.0.0.1:0", Auth: AuthConfig{Scheme: AuthHMAC}, MaxRetries: -1})
+	_, err := c.Classify("some text")
+	if err == nil || !strings.Contains(err.Error(), "requires a secret") {
+		t.Errorf("error = %v, want the missing HMAC secret reported", err)
+	}
+}
@@ -4,8 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -15,16 +16,26 @@ type AzureClassifier struct {
 	apiKey     string
 	model      string
 	endpoint   string
+	seed       *int
+	headers    map[string]string
 	parameters map[string]interface{}
+	retry      retryOverrides
 }
 
 // NewAzureClassifier creates a new Azure OpenAI classifier
 func NewAzureClassifier(config ModelConfig) *AzureClassifier {
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = providerEndpoint(Azure, "", "")
+	}
 	return &AzureClassifier{
 		apiKey:     config.APIKey,
 		model:      config.Model,
-		endpoint:   config.Endpoint,
+		endpoint:   endpoint,
+		seed:       config.Seed,
+		headers:    config.Headers,
 		parameters: config.Parameters,
+		retry:      retryOverridesFor(config),
 	}
 }
 
@@ -39,15 +50,28 @@ func (c *AzureClassifier) Configure(config ModelConfig) error {
 	if config.Model != "" {
 		c.model = config.Model
 	}
+	if config.Seed != nil {
+		c.seed = config.Seed
+	}
+	if config.Headers != nil {
+		c.headers = config.Headers
+	}
 	if config.Parameters != nil {
 		c.parameters = config.Parameters
 	}
+	c.retry.update(config)
 	return nil
 }
 
+// modelName returns the deployment classification requests are sent to
+func (c *AzureClassifier) modelName() string { return c.model }
+
 type azureRequest struct {
 	Messages   []azureMessage         `json:"messages"`
 	Model      string                 `json:"model"`
+	Seed       *int                   `json:"seed,omitempty"`
+	Stop       []string               `json:"stop,omitempty"`
+	LogitBias  map[string]float64     `json:"logit_bias,omitempty"`
 	Parameters map[string]interface{} `json:"parameters,omitempty"`
 }
 
@@ -57,10 +81,14 @@ type azureMessage struct {
 }
 
 type azureResponse struct {
+	// Model is the model behind the deployment, e.g. gpt-35-turbo-0125
+	Model   string `json:"model"`
 	Choices []struct {
 		Message struct {
 			Content string `json:"content"`
+			Refusal string `json:"refusal"`
 		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
 }
 
@@ -78,41 +106,20 @@ func (c *AzureClassifier) ClassifyWithOptions(content string, options Classifica
 		"has_categories": len(options.Categories) > 0,
 	})
 	logger.Debug("Starting content classification")
+	start := time.Now()
 
 	if c.endpoint == "" {
 		logger.Error("Azure endpoint URL is required")
 		return nil, fmt.Errorf("Azure endpoint URL is required")
 	}
 
-	var prompt string
-	if len(options.Categories) > 0 {
-		categoriesStr := strings.Join(options.Categories, ", ")
-		prompt = fmt.Sprintf(`Analyze the following text and classify it into one of these categories: %s
-
-Provide a JSON response with these fields:
-	- category: One of the categories listed above that best matches the content
-	- confidence: A confidence score between 0 and 1 indicating how well the content matches the chosen category
-	- summary: A brief summary of the content (max 100 words)
-	- keywords: Up to 5 key terms or phrases from the content
-
-Text to analyze:
-%s`, categoriesStr, content)
-	} else {
-		prompt = fmt.Sprintf(`Analyze the following text and provide a JSON response with these fields:
-	- category: The main category/topic of the content
-	- confidence: A confidence score between 0 and 1
-	- summary: A brief summary of the content (max 100 words)
-	- keywords: Up to 5 key terms or phrases from the content
-
-Text to analyze:
-%s`, content)
-	}
+	prompt := buildPrompt(content, options)
 
 	reqBody := azureRequest{
 		Messages: []azureMessage{
 			{
 				Role:    "system",
-				Content: "You are a content classification expert. Always respond in valid JSON format.",
+				Content: systemMessage(options),
 			},
 			{
 				Role:    "user",
@@ -120,7 +127,10 @@ Text to analyze:
 			},
 		},
 		Model:      c.model,
-		Parameters: c.parameters,
+		Seed:       c.seed,
+		Stop:       stringsParameter(c.parameters, "stop"),
+		LogitBias:  floatMapParameter(c.parameters, "logit_bias"),
+		Parameters: withoutParameters(c.parameters, "stop", "logit_bias"),
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -130,12 +140,12 @@ Text to analyze:
 	}
 
 	logger.WithFields(log.Fields{
-		"request_body":          string(jsonBody),
+		"request_body":          loggableBody(jsonBody),
 		"model":                 c.model,
 		"predefined_categories": options.Categories,
 	}).Debug("Request payload prepared")
 
-	req, err := http.NewRequest("POST", c.endpoint, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(requestContext(options), "POST", c.endpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		logger.WithError(err).Error("Failed to create request")
 		return nil, fmt.Errorf("error creating request: %w", err)
@@ -143,9 +153,9 @@ Text to analyze:
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("api-key", c.apiKey)
+	setCustomHeaders(req, c.headers, logger)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, retries, err := sendRequest(req, c.retry, logger)
 	if err != nil {
 		logger.WithError(err).Error("API request failed")
 		return nil, fmt.Errorf("error making request: %w", err)
@@ -158,7 +168,12 @@ Text to analyze:
 	}
 
 	var azureResp azureResponse
-	if err := json.NewDecoder(resp.Body).Decode(&azureResp); err != nil {
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.WithError(err).Error("Failed to read response body")
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	if err := json.Unmarshal(respBody, &azureResp); err != nil {
 		logger.WithError(err).Error("Failed to decode response")
 		return nil, fmt.Errorf("error decoding response: %w", err)
 	}
@@ -167,33 +182,34 @@ Text to analyze:
 		logger.Error("No classification result received")
 		return nil, fmt.Errorf("no classification result received")
 	}
+	if err := checkCompletion(azureResp.Choices[0].FinishReason, azureResp.Choices[0].Message.Refusal, logger); err != nil {
+		return nil, err
+	}
 
 	var classification Classification
-	if err := json.Unmarshal([]byte(azureResp.Choices[0].Message.Content), &classification); err != nil {
+	if err := json.Unmarshal([]byte(extractJSON(azureResp.Choices[0].Message.Content)), &classification); err != nil {
 		logger.WithFields(log.Fields{
 			"raw_content": azureResp.Choices[0].Message.Content,
 		}).WithError(err).Error("Failed to parse classification")
 		return nil, fmt.Errorf("error parsing classification: %w", err)
 	}
+	if options.DebugIncludeRaw {
+		classification.RawResponse = azureResp.Choices[0].Message.Content
+	}
+
+	postprocess(&classification, content, options)
 
-	// Validate category if predefined categories were provided
-	if len(options.Categories) > 0 {
-		categoryValid := false
-		for _, validCategory := range options.Categories {
-			if strings.EqualFold(classification.Category, validCategory) {
-				classification.Category = validCategory // Use exact case from predefined list
-				categoryValid = true
-				break
-			}
-		}
-		if !categoryValid {
-			logger.WithFields(log.Fields{
-				"received_category": classification.Category,
-				"valid_categories":  options.Categories,
-			}).Error("Classification returned invalid category")
-			return nil, fmt.Errorf("classifier returned invalid category: %s", classification.Category)
-		}
+	// Validate category if predefined categories or a taxonomy were provided
+	if err := validateCategory(&classification, options, logger); err != nil {
+		return nil, err
+	}
+	classification.Metadata = newMetadata(start, retries, Azure, c.model)
+	// The deployment name is arbitrary, so usage is priced by the model the response reports
+	pricedAs := azureResp.Model
+	if pricedAs == "" {
+		pricedAs = c.model
 	}
+	classification.setUsage(parseUsage(respBody), pricedAs)
 
 	logger.WithFields(log.Fields{
 		"category":                   classification.Category,
@@ -0,0 +1,78 @@
+package classifier
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// captureBody starts a provider stub that records the decoded request body and answers with reply
+func captureBody(t *testing.T, reply string, body *map[string]interface{}) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, body); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		w.Write([]byte(reply))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAzureStopAndLogitBiasSentOnce(t *testing.T) {
+	var body map[string]interface{}
+	server := captureBody(t, gptReply, &body)
+
+	c := NewAzureClassifier(ModelConfig{
+		Endpoint: server.URL,
+		APIKey:   "key",
+		Model:    "deployment",
+		Parameters: map[string]interface{}{
+			"stop":        []interface{}{"\n\n", "END"},
+			"logit_bias":  map[string]interface{}{"50256": -100.0},
+			"temperature": 0.2,
+		},
+	})
+	if _, err := c.Classify("some text"); err != nil {
+		t.Fatalf("Classify: %v", err)
+	}
+
+	if got, want := body["stop"], []interface{}{"\n\n", "END"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stop = %v, want %v", got, want)
+	}
+	if got, want := body["logit_bias"], map[string]interface{}{"50256": -100.0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("logit_bias = %v, want %v", got, want)
+	}
+	parameters, _ := body["parameters"].(map[string]interface{})
+	if got, want := parameters, map[string]interface{}{"temperature": 0.2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parameters = %v, want %v", got, want)
+	}
+}
+
+func TestGPTStopAndLogitBias(t *testing.T) {
+	var body map[string]interface{}
+	server := captureBody(t, gptReply, &body)
+
+	c := NewGPTClassifier(ModelConfig{
+		Endpoint: server.URL,
+		APIKey:   "key",
+		Parameters: map[string]interface{}{
+			"stop":       "END",
+			"logit_bias": map[string]int{"1234": 5},
+		},
+	})
+	if _, err := c.Classify("some text"); err != nil {
+		t.Fatalf("Classify: %v", err)
+	}
+
+	if got, want := body["stop"], []interface{}{"END"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stop = %v, want %v", got, want)
+	}
+	if got, want := body["logit_bias"], map[string]interface{}{"1234": 5.0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("logit_bias = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package classifier
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Category is an entry of a category taxonomy
+type Category struct {
+	// Name is the canonical category name returned to callers
+	Name string `json:"name"`
+	// Aliases are alternative names the model may return for this category
+	Aliases []string `json:"aliases,omitempty"`
+	// Deprecated categories are no longer offered to the model. When the model still
+	// returns one it is mapped to ReplacedBy, or rejected if there is no replacement.
+	Deprecated bool `json:"deprecated,omitempty"`
+	// ReplacedBy names the category that replaces a deprecated one
+	ReplacedBy string `json:"replaced_by,omitempty"`
+	// Description explains the category; the embedding classifier embeds it in place of the name
+	Description string `json:"description,omitempty"`
+	// Parent names the broader category this one belongs to, forming a hierarchy such
+	// as Finance > Invoice
+	Parent string `json:"parent,omitempty"`
+}
+
+// CategorySet is a taxonomy of categories with aliases and deprecated entries
+type CategorySet struct {
+	categories []Category
+	byName     map[string]Category
+	index      map[string]string // lowercased name or alias to canonical name
+}
+
+// NewCategorySet builds a taxonomy, rejecting duplicate names or aliases and
+// replacements that do not refer to an active category
+func NewCategorySet(categories ...Category) (*CategorySet, error) {
+	set := &CategorySet{
+		categories: categories,
+		byName:     make(map[string]Category, len(categories)),
+		index:      make(map[string]string),
+	}
+
+	for _, category := range categories {
+		if category.Name == "" {
+			return nil, fmt.Errorf("category name must not be empty")
+		}
+		set.byName[category.Name] = category
+		for _, name := range append([]string{category.Name}, category.Aliases...) {
+			key := strings.ToLower(strings.TrimSpace(name))
+			if existing, ok := set.index[key]; ok {
+				return nil, fmt.Errorf("category name %q of %s is already used by %s", name, category.Name, existing)
+			}
+			set.index[key] = category.Name
+		}
+	}
+
+	for _, category := range categories {
+		if category.Parent != "" {
+			if _, ok := set.byName[category.Parent]; !ok {
+				return nil, fmt.Errorf("category %s has unknown parent %s", category.Name, category.Parent)
+			}
+			if set.Path(category.Name) == nil {
+				return nil, fmt.Errorf("category %s is its own ancestor", category.Name)
+			}
+		}
+		if category.ReplacedBy == "" {
+			continue
+		}
+		replacement, ok := set.byName[category.ReplacedBy]
+		if !ok || replacement.Deprecated {
+			return nil, fmt.Errorf("category %s is replaced by %s, which is not an active category", category.Name, category.ReplacedBy)
+		}
+	}
+	return set, nil
+}
+
+// Normalize resolves a category returned by the model to its canonical name. Aliases
+// map to their category and deprecated categories to their replacement; ok is false
+// for unknown categories and deprecated ones without a replacement.
+func (s *CategorySet) Normalize(raw string) (string, bool) {
+	name, ok := s.index[strings.ToLower(strings.TrimSpace(raw))]
+	if !ok {
+		return "", false
+	}
+	category := s.byName[name]
+	if category.Deprecated {
+		if category.ReplacedBy == "" {
+			return "", false
+		}
+		return category.ReplacedBy, true
+	}
+	return name, true
+}
+
+// Description returns the description of the named category, or "" for unknown
+// categories and a nil set
+func (s *CategorySet) Description(name string) string {
+	if s == nil {
+		return ""
+	}
+	return s.byName[name].Description
+}
+
+// Path returns the names from the root of the hierarchy down to the named category,
+// e.g. [Finance Invoice]. It returns nil for unknown categories, a nil set and
+// categories whose parents form a cycle.
+func (s *CategorySet) Path(name string) []string {
+	if s == nil {
+		return nil
+	}
+	var path []string
+	for name != "" {
+		category, ok := s.byName[name]
+		if !ok || len(path) > len(s.categories) {
+			return nil
+		}
+		path = append([]string{category.Name}, path...)
+		name = category.Parent
+	}
+	return path
+}
+
+// Active returns the names of the categories that are not deprecated, in taxonomy order
+func (s *CategorySet) Active() []string {
+	var names []string
+	for _, category := range s.categories {
+		if !category.Deprecated {
+			names = append(names, category.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,130 @@
+package classifier
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// taxonomy is an invoice taxonomy with an alias, a replaced category and a retired one
+func taxonomy(t *testing.T) *CategorySet {
+	t.Helper()
+	set, err := NewCategorySet(
+		Category{Name: "Invoice", Aliases: []string{"Bill", "Tax Invoice"}},
+		Category{Name: "Receipt"},
+		Category{Name: "Statement", Deprecated: true, ReplacedBy: "Invoice"},
+		Category{Name: "Fax", Deprecated: true},
+	)
+	if err != nil {
+		t.Fatalf("NewCategorySet: %v", err)
+	}
+	return set
+}
+
+func TestCategorySetNormalize(t *testing.T) {
+	set := taxonomy(t)
+
+	tests := []struct {
+		raw  string
+		want string
+		ok   bool
+	}{
+		{raw: "Invoice", want: "Invoice", ok: true},
+		{raw: " bill ", want: "Invoice", ok: true},
+		{raw: "TAX INVOICE", want: "Invoice", ok: true},
+		{raw: "statement", want: "Invoice", ok: true},
+		{raw: "Fax", ok: false},
+		{raw: "Contract", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := set.Normalize(tt.raw)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Normalize(%q) = %q, %v, want %q, %v", tt.raw, got, ok, tt.want, tt.ok)
+		}
+	}
+	if got, want := set.Active(), []string{"Invoice", "Receipt"}; !slices.Equal(got, want) {
+		t.Errorf("Active() = %q, want %q", got, want)
+	}
+}
+
+func TestNewCategorySetRejectsInconsistentTaxonomies(t *testing.T) {
+	tests := []struct {
+		name       string
+		categories []Category
+	}{
+		{name: "empty name", categories: []Category{{}}},
+		{name: "duplicate name", categories: []Category{{Name: "Invoice"}, {Name: "invoice"}}},
+		{name: "alias of another category", categories: []Category{{Name: "Invoice"}, {Name: "Receipt", Aliases: []string{"Invoice"}}}},
+		{name: "unknown replacement", categories: []Category{{Name: "Statement", Deprecated: true, ReplacedBy: "Invoice"}}},
+		{name: "deprecated replacement", categories: []Category{
+			{Name: "Statement", Deprecated: true, ReplacedBy: "Bill"},
+			{Name: "Bill", Deprecated: true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCategorySet(tt.categories...); err == nil {
+				t.Error("NewCategorySet() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestClassifyWithCategorySet(t *testing.T) {
+	options := ClassificationOptions{CategorySet: taxonomy(t)}
+
+	classification, prompt := classifyWith(t, `{"category":"bill","confidence":0.9,"summary":"s","keywords":["k"]}`, "Amount due: $40", options)
+	if classification.Category != "Invoice" {
+		t.Errorf("category = %q, want the alias mapped to Invoice", classification.Category)
+	}
+	if !strings.Contains(prompt, "Receipt") {
+		t.Errorf("prompt does not offer the active categories:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "Statement") || strings.Contains(prompt, "Fax") {
+		t.Errorf("prompt offers deprecated categories:\n%s", prompt)
+	}
+
+	classification, _ = classifyWith(t, `{"category":"Statement","confidence":0.9,"summary":"s","keywords":["k"]}`, "Balance brought forward", options)
+	if classification.Category != "Invoice" {
+		t.Errorf("category = %q, want the deprecated category replaced by Invoice", classification.Category)
+	}
+
+	server := serveJSON(t, `{"choices":[{"message":{"content":"{\"category\":\"Fax\",\"confidence\":0.9,\"summary\":\"s\",\"keywords\":[\"k\"]}"},"finish_reason":"stop"}]}`)
+	c := NewGPTClassifier(ModelConfig{Endpoint: server.URL, APIKey: "key", MaxRetries: -1})
+	if _, err := c.ClassifyWithOptions("Cover sheet", options); err == nil || !strings.Contains(err.Error(), "invalid category") {
+		t.Errorf("retired category error = %v, want an invalid category error", err)
+	}
+}
+
+func TestCategorySetPath(t *testing.T) {
+	set, err := NewCategorySet(
+		Category{Name: "Finance"},
+		Category{Name: "Invoice", Parent: "Finance"},
+		Category{Name: "Tax Invoice", Parent: "Invoice"},
+	)
+	if err != nil {
+		t.Fatalf("NewCategorySet: %v", err)
+	}
+	if got := set.Path("Tax Invoice"); !slices.Equal(got, []string{"Finance", "Invoice", "Tax Invoice"}) {
+		t.Errorf("Path(Tax Invoice) = %q", got)
+	}
+	if got := set.Path("Finance"); !slices.Equal(got, []string{"Finance"}) {
+		t.Errorf("Path(Finance) = %q", got)
+	}
+	if got := set.Path("Memo"); got != nil {
+		t.Errorf("Path(Memo) = %q, want nil for an unknown category", got)
+	}
+	if got := (*CategorySet)(nil).Path("Invoice"); got != nil {
+		t.Errorf("nil set Path = %q, want nil", got)
+	}
+
+	if _, err := NewCategorySet(Category{Name: "Invoice", Parent: "Finance"}); err == nil || !strings.Contains(err.Error(), "unknown parent") {
+		t.Errorf("unknown parent: error = %v", err)
+	}
+	if _, err := NewCategorySet(
+		Category{Name: "A", Parent: "B"},
+		Category{Name: "B", Parent: "A"},
+	); err == nil || !strings.Contains(err.Error(), "own ancestor") {
+		t.Errorf("parent cycle: error = %v", err)
+	}
+}
@@ -1,6 +1,12 @@
 package classifier
 
 import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
 	log "github.com/sirupsen/logrus"
 )
 
@@ -10,6 +16,89 @@ type Classification struct {
 	Confidence float64  `json:"confidence"`
 	Summary    string   `json:"summary"`
 	Keywords   []string `json:"keywords"`
+	// Summaries holds additional summaries keyed by requested length (e.g. "short", "medium")
+	Summaries map[string]string `json:"summaries,omitempty"`
+	// Scores holds a confidence for every requested category when ScoreAllCategories is set
+	Scores map[string]float64 `json:"scores,omitempty"`
+	// Untouched model message content, populated when DebugIncludeRaw is set
+	RawResponse string `json:"raw_response,omitempty"`
+	// Usage is the token usage reported by the provider. It is nil for providers that do
+	// not report usage, such as custom endpoints.
+	Usage *TokenUsage `json:"usage,omitempty"`
+	// EstimatedCost is the price of Usage in USD, from the model's registry pricing. It is
+	// 0 when no usage was reported or the model is not in the registry.
+	EstimatedCost float64 `json:"estimated_cost,omitempty"`
+	// Metadata describes how the classification was obtained
+	Metadata *Metadata `json:"metadata,omitempty"`
+}
+
+// Validate reports the first problem with a parsed classification: a confidence outside
+// [0,1], an empty category or missing keywords
+func (c *Classification) Validate() error {
+	if math.IsNaN(c.Confidence) || c.Confidence < 0 || c.Confidence > 1 {
+		return fmt.Errorf("confidence %v is outside [0,1]", c.Confidence)
+	}
+	if strings.TrimSpace(c.Category) == "" {
+		return fmt.Errorf("category is empty")
+	}
+	if c.Keywords == nil {
+		return fmt.Errorf("keywords are missing")
+	}
+	return nil
+}
+
+// Normalize clamps the confidence into [0,1], trims the category, collapses the
+// whitespace within keywords, drops empty keywords and replaces nil keywords with an
+// empty list
+func (c *Classification) Normalize() {
+	switch {
+	case math.IsNaN(c.Confidence) || c.Confidence < 0:
+		c.Confidence = 0
+	case c.Confidence > 1:
+		c.Confidence = 1
+	}
+
+	c.Category = strings.TrimSpace(c.Category)
+
+	keywords := make([]string, 0, len(c.Keywords))
+	for _, keyword := range c.Keywords {
+		if keyword = strings.Join(strings.Fields(keyword), " "); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	c.Keywords = keywords
+}
+
+// Metadata records the provider call that produced a classification
+type Metadata struct {
+	// DurationMs is the wall-clock time of the classification in milliseconds
+	DurationMs float64 `json:"duration_ms"`
+	// Retries is the number of times the provider request was retried
+	Retries  int      `json:"retries"`
+	Provider Provider `json:"provider"`
+	Model    string   `json:"model"`
+	// Heuristic marks a low-confidence result of the local keyword classifier
+	Heuristic bool `json:"heuristic,omitempty"`
+	// FallbackReason is the primary classifier's error when the result came from the local fallback
+	FallbackReason string `json:"fallback_reason,omitempty"`
+}
+
+// newMetadata builds the metadata for a classification that started at start
+func newMetadata(start time.Time, retries int, provider Provider, model string) *Metadata {
+	return &Metadata{
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+		Retries:    retries,
+		Provider:   provider,
+		Model:      model,
+	}
+}
+
+// setUsage records the provider-reported usage and prices it as model
+func (c *Classification) setUsage(usage *TokenUsage, model string) {
+	c.Usage = usage
+	if usage != nil {
+		c.EstimatedCost = EstimateCost(pricedModel(model), usage.PromptTokens, usage.CompletionTokens)
+	}
 }
 
 // ModelConfig contains configuration for the AI model
@@ -22,14 +111,153 @@ type ModelConfig struct {
 	APIKey string
 	// Additional model-specific parameters
 	Parameters map[string]interface{}
+	// Headers are extra HTTP headers added to every provider request (e.g. tracing or
+	// tenant headers). Authentication headers cannot be overridden.
+	Headers map[string]string
 	// Optional list of predefined categories to classify into
 	PredefinedCategories []string
+	// OpenAI organization ID sent as the OpenAI-Organization header
+	Organization string
+	// OpenAI project ID sent as the OpenAI-Project header
+	Project string
+	// EnablePromptCaching marks the system prompt as cacheable (Anthropic only). It is a
+	// pointer so that Configure can turn caching off; nil leaves the current setting.
+	EnablePromptCaching *bool
+	// Auth selects how requests are authenticated (custom provider only, default: bearer token)
+	Auth AuthConfig
+	// CustomFields renames the request and response fields of the custom provider's API
+	CustomFields CustomFields
+	// VisionModel is used instead of Model for image inputs (OpenAI only, default gpt-4o)
+	VisionModel string
+	// Seed requests deterministic sampling from providers that support it (OpenAI, Azure).
+	// Combined with a temperature of 0 this yields near-reproducible classifications.
+	// Anthropic has no seed, but honors a temperature of 0.
+	Seed *int
+	// MaxConcurrentRequests bounds the requests in flight to this provider and model across
+	// all classifiers; further classifications wait for a slot. 0 uses the model's
+	// ConcurrentRequests from the registry, with the provider's default model when Model
+	// is empty, and a negative value removes the limit.
+	MaxConcurrentRequests int
+	// MaxRetries is the number of times a request failing with 429, 500, 502, 503 or a
+	// network error is retried. 0 uses the shared setting from ConfigureRetries and a
+	// negative value disables retries.
+	MaxRetries int
+	// RetryBaseDelay is the backoff before the first retry, doubled for every further
+	// retry (0 uses the shared setting from ConfigureRetries)
+	RetryBaseDelay time.Duration
 }
 
 // ClassificationOptions contains options for classification
 type ClassificationOptions struct {
 	// List of categories to classify into. If empty, classifier will determine category freely.
 	Categories []string
+	// CategorySet is a taxonomy used when Categories is empty. Its active categories are
+	// offered to the model and the result is normalized through its aliases and replacements.
+	CategorySet *CategorySet
+	// Optional category suggestions added to the prompt when Categories is empty
+	CategoryHints []string
+	// Filename of the source document, given to the model as metadata since names such
+	// as "2023_Q4_invoice.pdf" are often a strong signal. Directories are stripped.
+	Filename string
+	// ContextHint is free-form context about the document's origin given to the model,
+	// e.g. "uploaded to the accounts payable inbox"
+	ContextHint string
+	// DefendAgainstInjection encloses the document text in <document> tags and tells the
+	// model to treat it as data, so instructions embedded in a document are not followed
+	DefendAgainstInjection bool
+	// StripInjectionPhrases replaces obvious injection attempts in the document text, such
+	// as "ignore previous instructions", with "[removed]" before prompting
+	StripInjectionPhrases bool
+	// ExcludeCategories are never returned. They are removed from the offered categories,
+	// named in the prompt, and a classification choosing one is retried.
+	ExcludeCategories []string
+	// PreviewPages extracts only the first pages or slides of PDFs and presentations (0 means all)
+	PreviewPages int
+	// Pages selects the PDF pages or presentation slides to extract, e.g. "1-3,7"
+	Pages string
+	// Sheets selects the spreadsheet sheets to extract by name
+	Sheets []string
+	// OutputLanguage asks the model to write the summary and keywords in this language,
+	// e.g. "French" or "fr"
+	OutputLanguage string
+	// AutoOutputLanguage sets OutputLanguage to the detected language of the document
+	// when OutputLanguage is empty and the document is not in English
+	AutoOutputLanguage bool
+	// PreviousVersion is the path of an earlier version of a PDF. When set, only the pages
+	// whose text changed since that version are extracted.
+	PreviousVersion string
+	// PreviewChars classifies only the first characters of the extracted text (0 means all).
+	// Either preview limit trades accuracy for speed and cost.
+	PreviewChars int
+	// MinTextLength skips classification of documents whose extracted text has fewer
+	// non-whitespace characters, since near-empty text yields meaningless results
+	// (0 disables the check)
+	MinTextLength int
+	// UseFormatHints fills CategoryHints from the document format when no categories are given
+	UseFormatHints bool
+	// UseFrontMatterHints fills CategoryHints from the categories and tags in a document's
+	// front-matter when no categories are given. Front-matter hints take precedence over format hints.
+	UseFrontMatterHints bool
+	// DebugIncludeRaw attaches the raw provider message content to the result
+	DebugIncludeRaw bool
+	// MaxKeywords is the keyword count requested in the prompt and enforced on the
+	// result (0 requests 5 and does not cap)
+	MaxKeywords int
+	// KeywordNgramMax asks for key phrases of up to this many words, so multi-word
+	// concepts stay intact; longer keywords are dropped from the result (0 requests
+	// terms or phrases of any length)
+	KeywordNgramMax int
+	// MaxSummaryWords is the summary length requested in the prompt and enforced on the
+	// result (0 requests 100 words and does not cap)
+	MaxSummaryWords int
+	// SummaryLengths requests additional summaries, e.g. "short" and "medium", returned
+	// in Classification.Summaries alongside the default Summary
+	SummaryLengths []string
+	// PreserveModelCasing keeps the category exactly as the model wrote it when it matches
+	// an entry of Categories case-insensitively, instead of using the entry's casing
+	PreserveModelCasing bool
+	// ScoreAllCategories asks the model for a confidence score for every category in Categories
+	ScoreAllCategories bool
+	// CategoryFloors sets a minimum confidence per category. A chosen category scoring below
+	// its floor is replaced by the best-scoring alternative that meets its own floor. Floors
+	// need per-category scores, so setting them requests scores like ScoreAllCategories.
+	CategoryFloors map[string]float64
+	// CategoryPriors weights categories by how common they are. When the chosen category
+	// and the best-scoring alternative are within PriorTieMargin of each other, the one
+	// with the higher prior wins. Categories without a prior weigh 0. Priors need
+	// per-category scores, so setting them requests scores like ScoreAllCategories.
+	CategoryPriors map[string]float64
+	// PriorTieMargin is the confidence difference within which CategoryPriors break a tie
+	// (default: 0.05)
+	PriorTieMargin float64
+	// LocalKeywords replaces the model's keywords with locally extracted ones. Local
+	// keywords are also used whenever the model returns none.
+	LocalKeywords bool
+	// NativeDocument sends Document to providers that can read it directly (Anthropic PDFs)
+	// instead of the extracted text
+	NativeDocument bool
+	// Vision classifies images directly with a vision-capable model (OpenAI) instead of OCR text
+	Vision bool
+	// Document is the original file, attached when NativeDocument or Vision is set
+	Document *Document
+	// Timeout bounds the provider classification; the provider request is cancelled when
+	// it expires (0 means no limit)
+	Timeout time.Duration
+	// Context cancels the provider request when it is done (default: never cancelled)
+	Context context.Context
+	// LocalFallback classifies with LocalFallbackClassifier when the provider fails or
+	// times out. Such results carry Metadata.Heuristic and a capped confidence.
+	LocalFallback bool
+	// LocalRules maps categories to keywords for the local fallback classifier
+	LocalRules map[string][]string
+}
+
+// Document is an original input file passed to providers that can read it natively
+type Document struct {
+	// MediaType of the file, e.g. application/pdf
+	MediaType string
+	// Data holds the raw file contents
+	Data []byte
 }
 
 // Classifier defines the interface that all model classifiers must implement
@@ -50,6 +278,11 @@ const (
 	Azure     Provider = "azure"
 	Anthropic Provider = "anthropic"
 	Custom    Provider = "custom"
+	Gemini    Provider = "gemini"
+	// Embedding routes content by embedding similarity instead of a generative model
+	Embedding Provider = "embedding"
+	// Local marks results of the keyword-based LocalFallbackClassifier
+	Local Provider = "local"
 )
 
 // NewClassifier creates a new classifier instance for the specified provider
@@ -75,10 +308,19 @@ func NewClassifier(provider Provider, config ModelConfig) (Classifier, error) {
 	case Custom:
 		logger.Debug("Creating custom classifier")
 		classifier = NewCustomClassifier(config)
+	case Gemini:
+		logger.Debug("Creating Google Gemini classifier")
+		classifier = NewGeminiClassifier(config)
+	case Embedding:
+		logger.Debug("Creating embedding classifier")
+		embedder := NewOpenAIEmbedder(config)
+		classifier = NewEmbeddingClassifier(embedder, embedder.model, nil)
 	default:
 		logger.Debug("Using default OpenAI GPT classifier")
 		classifier = NewGPTClassifier(config)
 	}
+	classifier = newLimitingClassifier(classifier, provider, config)
+	classifier = fallbackClassifier{excludingClassifier{classifier}}
 
 	logger.WithFields(log.Fields{
 		"endpoint":     config.Endpoint,
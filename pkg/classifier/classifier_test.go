@@ -0,0 +1,456 @@
+package classifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// classificationJSON is the classification the mocked providers answer with
+const classificationJSON = `{"category":"Invoice","confidence":0.9,"summary":"s","keywords":["k"]}`
+
+// serveJSON starts a provider stub that answers every request with body
+func serveJSON(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// classifyWith classifies text with a GPT classifier whose provider answers with the
+// model message content, and returns the result along with the prompt sent
+func classifyWith(t *testing.T, content, text string, options ClassificationOptions) (*Classification, string) {
+	t.Helper()
+	encoded, _ := json.Marshal(content)
+	var body struct {
+		Messages []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		w.Write([]byte(`{"choices":[{"message":{"content":` + string(encoded) + `},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	c := NewGPTClassifier(ModelConfig{Endpoint: server.URL, APIKey: "key", MaxRetries: -1})
+	classification, err := c.ClassifyWithOptions(text, options)
+	if err != nil {
+		t.Fatalf("ClassifyWithOptions: %v", err)
+	}
+	var prompt string
+	if len(body.Messages) > 0 {
+		prompt = body.Messages[len(body.Messages)-1].Content
+	}
+	return classification, prompt
+}
+
+func TestOutputLimits(t *testing.T) {
+	reply := `{"category":"Report","confidence":0.8,` +
+		`"summary":"The quarterly report shows revenue growth across all regions and lower costs.",` +
+		`"keywords":["revenue","growth","regions","costs","quarterly","report"]}`
+
+	classification, prompt := classifyWith(t, reply, "Quarterly report text", ClassificationOptions{MaxKeywords: 3, MaxSummaryWords: 5})
+	if got := strings.Join(classification.Keywords, ","); got != "revenue,growth,regions" {
+		t.Errorf("keywords = %q, want the first 3", got)
+	}
+	if classification.Summary != "The quarterly report shows revenue" {
+		t.Errorf("summary = %q, want the first 5 words", classification.Summary)
+	}
+	if !strings.Contains(prompt, "(max 5 words)") || !strings.Contains(prompt, "Up to 3 key terms") {
+		t.Errorf("prompt does not request the limits:\n%s", prompt)
+	}
+
+	// Without limits the model output is kept and the prompt uses the defaults
+	classification, prompt = classifyWith(t, reply, "Quarterly report text", ClassificationOptions{})
+	if len(classification.Keywords) != 6 || !strings.HasSuffix(classification.Summary, "lower costs.") {
+		t.Errorf("unlimited result trimmed: %+v", classification)
+	}
+	if !strings.Contains(prompt, "(max 100 words)") || !strings.Contains(prompt, "Up to 5 key terms") {
+		t.Errorf("prompt does not request the default limits:\n%s", prompt)
+	}
+}
+
+func TestKeywordNgramMax(t *testing.T) {
+	reply := `{"category":"Research","confidence":0.8,"summary":"s",` +
+		`"keywords":["machine  learning","neural\tnetworks","gradient","large language model training"]}`
+
+	classification, prompt := classifyWith(t, reply, "A paper on machine learning", ClassificationOptions{KeywordNgramMax: 2, MaxKeywords: 4})
+	if got := strings.Join(classification.Keywords, ","); got != "machine learning,neural networks,gradient" {
+		t.Errorf("keywords = %q, want phrases of at most 2 words with whitespace collapsed", got)
+	}
+	if !strings.Contains(prompt, "Up to 4 key phrases from the content, each of 1 to 2 words.") {
+		t.Errorf("prompt does not ask for key phrases:\n%s", prompt)
+	}
+
+	classification, prompt = classifyWith(t, reply, "A paper on machine learning", ClassificationOptions{KeywordNgramMax: 1})
+	if got := strings.Join(classification.Keywords, ","); got != "gradient" {
+		t.Errorf("keywords = %q, want only single words", got)
+	}
+	if !strings.Contains(prompt, "Up to 5 single-word key terms") {
+		t.Errorf("prompt does not ask for single words:\n%s", prompt)
+	}
+
+	// Without a limit every phrase is kept
+	classification, _ = classifyWith(t, reply, "A paper on machine learning", ClassificationOptions{})
+	if len(classification.Keywords) != 4 {
+		t.Errorf("keywords = %q, want all 4", classification.Keywords)
+	}
+}
+
+func TestBuildPromptLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ClassificationOptions
+		want    []string
+	}{
+		{name: "open defaults", want: []string{"(max 100 words)", "Up to 5 key terms"}},
+		{name: "open limits", options: ClassificationOptions{MaxSummaryWords: 30, MaxKeywords: 8}, want: []string{"(max 30 words)", "Up to 8 key terms"}},
+		{name: "categories defaults", options: ClassificationOptions{Categories: []string{"Invoice", "Receipt"}}, want: []string{"(max 100 words)", "Up to 5 key terms"}},
+		{name: "categories limits", options: ClassificationOptions{Categories: []string{"Invoice", "Receipt"}, MaxSummaryWords: 20, MaxKeywords: 2}, want: []string{"(max 20 words)", "Up to 2 key terms"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt := buildPrompt("Invoice 42", tt.options)
+			for _, want := range tt.want {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("prompt lacks %q:\n%s", want, prompt)
+				}
+			}
+		})
+	}
+}
+
+func TestDocumentMetadataInPrompt(t *testing.T) {
+	options := ClassificationOptions{Filename: "/tmp/uploads/2024-Q3-invoice.pdf", ContextHint: "from the billing inbox"}
+	for _, categories := range [][]string{nil, {"Invoice", "Receipt"}} {
+		options.Categories = categories
+		prompt := buildPrompt("Total due: $40", options)
+		want := "Document metadata (use it as a hint alongside the text):\n\t- Filename: 2024-Q3-invoice.pdf\n\t- Context: from the billing inbox\n"
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt with categories %v lacks the metadata block:\n%s", categories, prompt)
+		}
+		if strings.Contains(prompt, "/tmp/uploads") {
+			t.Errorf("prompt leaks the upload directory:\n%s", prompt)
+		}
+	}
+
+	if prompt := buildPrompt("Total due: $40", ClassificationOptions{}); strings.Contains(prompt, "Document metadata") {
+		t.Errorf("prompt without metadata has a metadata block:\n%s", prompt)
+	}
+}
+
+func TestOutputLanguageInPrompt(t *testing.T) {
+	french := "La facture pour les travaux est jointe. Le paiement est dû à la fin du mois, avec les frais de livraison."
+	tests := []struct {
+		name    string
+		content string
+		options ClassificationOptions
+		want    string
+	}{
+		{
+			name:    "language code",
+			content: "Total due: $40",
+			options: ClassificationOptions{OutputLanguage: "de"},
+			want:    "Write the summary and keywords in German.",
+		},
+		{
+			name:    "fixed categories keep their names",
+			content: "Total due: $40",
+			options: ClassificationOptions{OutputLanguage: "Japanese", Categories: []string{"Invoice"}},
+			want:    "Write the summary and keywords in Japanese, but keep the category exactly as listed above.",
+		},
+		{
+			name:    "detected",
+			content: french,
+			options: ClassificationOptions{AutoOutputLanguage: true},
+			want:    "Write the summary and keywords in French.",
+		},
+		{
+			name:    "explicit language wins over detection",
+			content: french,
+			options: ClassificationOptions{AutoOutputLanguage: true, OutputLanguage: "es"},
+			want:    "Write the summary and keywords in Spanish.",
+		},
+		{
+			name:    "English is not requested",
+			content: "The invoice for the consulting work is attached, and the payment is due by the end of this month.",
+			options: ClassificationOptions{AutoOutputLanguage: true},
+		},
+		{
+			name:    "detection disabled",
+			content: french,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt := buildPrompt(tt.content, tt.options)
+			if tt.want == "" {
+				if strings.Contains(prompt, "Write the summary and keywords in") {
+					t.Errorf("prompt requests an output language:\n%s", prompt)
+				}
+				return
+			}
+			if !strings.Contains(prompt, tt.want) {
+				t.Errorf("prompt lacks %q:\n%s", tt.want, prompt)
+			}
+		})
+	}
+}
+
+func TestSummaryLengths(t *testing.T) {
+	reply := `{"category":"Report","confidence":0.8,"summary":"Revenue grew.","keywords":["revenue"],` +
+		`"summaries":{"Short":"Revenue grew.","medium":"Revenue grew in every region. Costs fell.","long":"unrequested"}}`
+
+	classification, prompt := classifyWith(t, reply, "Quarterly report text", ClassificationOptions{SummaryLengths: []string{"short", "medium", "executive"}})
+	want := map[string]string{"short": "Revenue grew.", "medium": "Revenue grew in every region. Costs fell."}
+	if !maps.Equal(classification.Summaries, want) {
+		t.Errorf("summaries = %q, want %q keyed as requested", classification.Summaries, want)
+	}
+	for _, field := range []string{`"short" (a single sentence)`, `"medium" (one paragraph`, `"executive"`} {
+		if !strings.Contains(prompt, field) {
+			t.Errorf("prompt does not request %s:\n%s", field, prompt)
+		}
+	}
+
+	// Summaries are neither requested nor returned by default
+	classification, prompt = classifyWith(t, reply, "Quarterly report text", ClassificationOptions{})
+	if strings.Contains(prompt, "summaries") {
+		t.Errorf("prompt requests summaries by default:\n%s", prompt)
+	}
+	if classification.Summary != "Revenue grew." {
+		t.Errorf("summary = %q, want the default summary kept", classification.Summary)
+	}
+}
+
+func TestClassificationValidate(t *testing.T) {
+	tests := []struct {
+		name           string
+		classification Classification
+		wantErr        string
+	}{
+		{name: "valid", classification: Classification{Category: "Invoice", Confidence: 0.9, Keywords: []string{}}},
+		{name: "confidence above one", classification: Classification{Category: "Invoice", Confidence: 1.5, Keywords: []string{}}, wantErr: "outside [0,1]"},
+		{name: "negative confidence", classification: Classification{Category: "Invoice", Confidence: -0.1, Keywords: []string{}}, wantErr: "outside [0,1]"},
+		{name: "NaN confidence", classification: Classification{Category: "Invoice", Confidence: math.NaN(), Keywords: []string{}}, wantErr: "outside [0,1]"},
+		{name: "blank category", classification: Classification{Category: "  ", Confidence: 0.5, Keywords: []string{}}, wantErr: "category is empty"},
+		{name: "missing keywords", classification: Classification{Category: "Invoice", Confidence: 0.5}, wantErr: "keywords are missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.classification.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Validate() = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClassificationNormalizedAfterParsing(t *testing.T) {
+	// Models occasionally answer with percentages, padding and blank keywords
+	reply := `{"category":"  Invoice ","confidence":87,"summary":"s","keywords":["  cloud\n storage ","",   " "]}`
+
+	classification, _ := classifyWith(t, reply, "Invoice text", ClassificationOptions{})
+	if err := classification.Validate(); err != nil {
+		t.Errorf("normalized classification invalid: %v", err)
+	}
+	if classification.Category != "Invoice" || classification.Confidence != 1 {
+		t.Errorf("category %q with confidence %v, want Invoice clamped to 1", classification.Category, classification.Confidence)
+	}
+	if got := strings.Join(classification.Keywords, "|"); got != "cloud storage" {
+		t.Errorf("keywords = %q, want the blank ones dropped and whitespace collapsed", got)
+	}
+}
+
+func TestPreserveModelCasing(t *testing.T) {
+	reply := `{"category":"finance","confidence":0.9,"summary":"s","keywords":["k"]}`
+	tests := []struct {
+		name     string
+		preserve bool
+		want     string
+	}{
+		{name: "predefined casing", want: "Finance"},
+		{name: "model casing", preserve: true, want: "finance"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := ClassificationOptions{Categories: []string{"Finance", "Legal"}, PreserveModelCasing: tt.preserve}
+			classification, _ := classifyWith(t, reply, "Quarterly budget", options)
+			if classification.Category != tt.want {
+				t.Errorf("category = %q, want %q", classification.Category, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategoryFloors(t *testing.T) {
+	options := ClassificationOptions{
+		Categories:     []string{"Invoice", "Receipt", "Quote"},
+		CategoryFloors: map[string]float64{"invoice": 0.8, "Quote": 0.9, "Receipt": 0.5},
+	}
+	tests := []struct {
+		name           string
+		reply          string
+		wantCategory   string
+		wantConfidence float64
+	}{
+		{
+			name:           "above its floor",
+			reply:          `{"category":"Invoice","confidence":0.85,"summary":"s","keywords":["k"],"scores":{"Invoice":0.85,"Receipt":0.6,"Quote":0.1}}`,
+			wantCategory:   "Invoice",
+			wantConfidence: 0.85,
+		},
+		{
+			name:           "runner-up meeting its floor",
+			reply:          `{"category":"Invoice","confidence":0.6,"summary":"s","keywords":["k"],"scores":{"Invoice":0.6,"Receipt":0.55,"Quote":0.7}}`,
+			wantCategory:   "Receipt",
+			wantConfidence: 0.55,
+		},
+		{
+			name:           "no alternative qualifies",
+			reply:          `{"category":"Invoice","confidence":0.6,"summary":"s","keywords":["k"],"scores":{"Invoice":0.6,"Receipt":0.3,"Quote":0.7}}`,
+			wantCategory:   "Invoice",
+			wantConfidence: 0.6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classification, prompt := classifyWith(t, tt.reply, "Amount due: $40", options)
+			if classification.Category != tt.wantCategory || classification.Confidence != tt.wantConfidence {
+				t.Errorf("result = %s at %v, want %s at %v", classification.Category, classification.Confidence, tt.wantCategory, tt.wantConfidence)
+			}
+			if !strings.Contains(prompt, "- scores:") {
+				t.Errorf("floors do not request per-category scores:\n%s", prompt)
+			}
+		})
+	}
+}
+
+func TestCategoryPriors(t *testing.T) {
+	// Quote has no prior and weighs 0
+	priors := map[string]float64{"receipt": 0.7, "Invoice": 0.2}
+	scores := func(invoice, receipt, quote float64) string {
+		return fmt.Sprintf(`{"category":"Invoice","confidence":%v,"summary":"s","keywords":["k"],"scores":{"Invoice":%v,"Receipt":%v,"Quote":%v}}`,
+			invoice, invoice, receipt, quote)
+	}
+	tests := []struct {
+		name           string
+		reply          string
+		margin         float64
+		floors         map[string]float64
+		wantCategory   string
+		wantConfidence float64
+	}{
+		{name: "near tie goes to the higher prior", reply: scores(0.62, 0.6, 0.1), wantCategory: "Receipt", wantConfidence: 0.6},
+		{name: "clear winner kept", reply: scores(0.7, 0.6, 0.1), wantCategory: "Invoice", wantConfidence: 0.7},
+		{name: "wider margin", reply: scores(0.7, 0.6, 0.1), margin: 0.15, wantCategory: "Receipt", wantConfidence: 0.6},
+		{name: "runner-up with a lower prior", reply: scores(0.62, 0.1, 0.6), wantCategory: "Invoice", wantConfidence: 0.62},
+		{name: "runner-up below its floor", reply: scores(0.62, 0.61, 0.3), floors: map[string]float64{"Receipt": 0.9}, wantCategory: "Invoice", wantConfidence: 0.62},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := ClassificationOptions{
+				Categories:     []string{"Invoice", "Receipt", "Quote"},
+				CategoryPriors: priors,
+				PriorTieMargin: tt.margin,
+				CategoryFloors: tt.floors,
+			}
+			classification, prompt := classifyWith(t, tt.reply, "Amount due: $40", options)
+			if classification.Category != tt.wantCategory || classification.Confidence != tt.wantConfidence {
+				t.Errorf("result = %s at %v, want %s at %v", classification.Category, classification.Confidence, tt.wantCategory, tt.wantConfidence)
+			}
+			if !strings.Contains(prompt, "- scores:") {
+				t.Errorf("priors do not request per-category scores:\n%s", prompt)
+			}
+		})
+	}
+}
+
+// countingClassifier echoes the content as the summary and counts the calls it receives
+type countingClassifier struct {
+	calls int
+}
+
+func (c *countingClassifier) Classify(content string) (*Classification, error) {
+	return c.ClassifyWithOptions(content, ClassificationOptions{})
+}
+
+func (c *countingClassifier) ClassifyWithOptions(content string, options ClassificationOptions) (*Classification, error) {
+	c.calls++
+	return &Classification{Category: "Log", Summary: content}, nil
+}
+
+func (c *countingClassifier) Configure(config ModelConfig) error { return nil }
+
+func TestIncrementalClassifier(t *testing.T) {
+	inner := &countingClassifier{}
+	c := NewIncrementalClassifier(inner, 10)
+
+	steps := []struct {
+		name        string
+		id, content string
+		want        bool
+		wantSummary string
+	}{
+		{name: "first classification", id: "app.log", content: "boot ok\n", want: true, wantSummary: "boot ok\n"},
+		{name: "small append", id: "app.log", content: "boot ok\nping\n", want: false, wantSummary: "boot ok\n"},
+		{name: "append past threshold", id: "app.log", content: "boot ok\nping\nerror: disk full\n", want: true, wantSummary: "boot ok\nping\nerror: disk full\n"},
+		{name: "edited prefix", id: "app.log", content: "BOOT ok\nping\nerror: disk full\n", want: true, wantSummary: "BOOT ok\nping\nerror: disk full\n"},
+		{name: "truncated", id: "app.log", content: "BOOT ok\n", want: true, wantSummary: "BOOT ok\n"},
+		{name: "other document", id: "db.log", content: "BOOT ok\n", want: true, wantSummary: "BOOT ok\n"},
+	}
+	for _, step := range steps {
+		classification, classified, err := c.ClassifyDocument(step.id, step.content, ClassificationOptions{})
+		if err != nil {
+			t.Fatalf("%s: ClassifyDocument: %v", step.name, err)
+		}
+		if classified != step.want || classification.Summary != step.wantSummary {
+			t.Errorf("%s: classified = %v with summary %q, want %v with %q", step.name, classified, classification.Summary, step.want, step.wantSummary)
+		}
+	}
+	if inner.calls != 5 {
+		t.Errorf("%d classifier calls, want 5", inner.calls)
+	}
+
+	c.Forget("db.log")
+	if _, classified, _ := c.ClassifyDocument("db.log", "BOOT ok\n", ClassificationOptions{}); !classified {
+		t.Error("forgotten document was not re-classified")
+	}
+}
+
+func TestLocalKeywordFallback(t *testing.T) {
+	text := "Invoice for cloud storage fees, support fees and a cloud storage discount."
+	local := "cloud storage discount|cloud storage fees"
+
+	tests := []struct {
+		name    string
+		reply   string
+		options ClassificationOptions
+		want    string
+	}{
+		{name: "model keywords kept", reply: `{"category":"Invoice","confidence":0.9,"keywords":["billing"]}`, options: ClassificationOptions{MaxKeywords: 2}, want: "billing"},
+		{name: "missing keywords filled locally", reply: `{"category":"Invoice","confidence":0.9}`, options: ClassificationOptions{MaxKeywords: 2}, want: local},
+		{name: "local keywords requested", reply: `{"category":"Invoice","confidence":0.9,"keywords":["billing"]}`, options: ClassificationOptions{MaxKeywords: 2, LocalKeywords: true}, want: local},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classification, _ := classifyWith(t, tt.reply, text, tt.options)
+			if got := strings.Join(classification.Keywords, "|"); got != tt.want {
+				t.Errorf("keywords = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package classifier
+
+import (
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// concurrencyLimiter bounds the in-flight requests to one provider and model
+type concurrencyLimiter struct {
+	slots chan struct{}
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*concurrencyLimiter)
+)
+
+// concurrencyLimit returns the number of concurrent requests allowed for model:
+// maxConcurrent when set, otherwise the registry's ConcurrentRequests. It returns 0 for
+// no limit.
+func concurrencyLimit(model string, maxConcurrent int) int {
+	if maxConcurrent != 0 {
+		return max(maxConcurrent, 0)
+	}
+	return ModelRegistry[ModelType(model)].Cost.ConcurrentRequests
+}
+
+// modelNamer is implemented by classifiers that report the model they send requests
+// to, with the provider default applied
+type modelNamer interface {
+	modelName() string
+}
+
+// limiterFor returns the limiter shared by all classifiers of provider and model,
+// replacing it when the limit changed. Requests already holding a slot of a replaced
+// limiter release it there.
+func limiterFor(provider Provider, model string, limit int) *concurrencyLimiter {
+	key := fmt.Sprintf("%s/%s", provider, model)
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	limiter, ok := limiters[key]
+	if !ok || cap(limiter.slots) != limit {
+		limiter = &concurrencyLimiter{slots: make(chan struct{}, limit)}
+		limiters[key] = limiter
+	}
+	return limiter
+}
+
+// limitingClassifier queues classifications beyond the concurrency limit of its
+// provider and model until a running one finishes
+type limitingClassifier struct {
+	Classifier
+	provider Provider
+
+	mu            sync.Mutex
+	maxConcurrent int
+	model         string
+	limiter       *concurrencyLimiter // nil when the model has no limit
+}
+
+// newLimitingClassifier wraps classifier with the shared limiter of provider and the
+// model classifier resolved from config
+func newLimitingClassifier(classifier Classifier, provider Provider, config ModelConfig) Classifier {
+	c := &limitingClassifier{
+		Classifier:    classifier,
+		provider:      provider,
+		maxConcurrent: config.MaxConcurrentRequests,
+	}
+	c.resolveLimiter()
+	return c
+}
+
+// resolveLimiter looks up the limiter for the model the wrapped classifier currently
+// uses. c.mu must be held or c not yet shared.
+func (c *limitingClassifier) resolveLimiter() {
+	c.model = ""
+	if namer, ok := c.Classifier.(modelNamer); ok {
+		c.model = namer.modelName()
+	}
+	c.limiter = nil
+	if limit := concurrencyLimit(c.model, c.maxConcurrent); limit > 0 {
+		c.limiter = limiterFor(c.provider, c.model, limit)
+	}
+}
+
+// Configure updates the wrapped classifier, then moves to the limiter of its new model
+// and MaxConcurrentRequests
+func (c *limitingClassifier) Configure(config ModelConfig) error {
+	err := c.Classifier.Configure(config)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if config.MaxConcurrentRequests != 0 {
+		c.maxConcurrent = config.MaxConcurrentRequests
+	}
+	c.resolveLimiter()
+	return err
+}
+
+// ClassifyWithOptions waits for a free slot, then classifies content. It gives up
+// waiting when options.Context is done.
+func (c *limitingClassifier) ClassifyWithOptions(content string, options ClassificationOptions) (*Classification, error) {
+	c.mu.Lock()
+	limiter, model := c.limiter, c.model
+	c.mu.Unlock()
+	if limiter == nil {
+		return c.Classifier.ClassifyWithOptions(content, options)
+	}
+
+	select {
+	case limiter.slots <- struct{}{}:
+	default:
+		log.WithFields(log.Fields{
+			"function": "ClassifyWithOptions",
+			"provider": c.provider,
+			"model":    model,
+			"limit":    cap(limiter.slots),
+		}).Debug("Concurrency limit reached, waiting for a slot")
+		ctx := requestContext(options)
+		select {
+		case limiter.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	defer func() { <-limiter.slots }()
+	return c.Classifier.ClassifyWithOptions(content, options)
+}
+
+// Classify takes text content and returns classification details
+func (c *limitingClassifier) Classify(content string) (*Classification, error) {
+	return c.ClassifyWithOptions(content, ClassificationOptions{})
+}
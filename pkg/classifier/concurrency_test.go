@@ -0,0 +1,148 @@
+package classifier
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// limiterOf returns the limiting layer of a classifier built by NewClassifier
+func limiterOf(t *testing.T, c Classifier) *limitingClassifier {
+	t.Helper()
+	limiting, ok := c.(fallbackClassifier).Classifier.(excludingClassifier).Classifier.(*limitingClassifier)
+	if !ok {
+		t.Fatalf("classifier %T has no limiting layer", c)
+	}
+	return limiting
+}
+
+func TestLimiterKeyedOnResolvedModel(t *testing.T) {
+	c, err := NewClassifier(OpenAI, ModelConfig{APIKey: "key"})
+	if err != nil {
+		t.Fatalf("NewClassifier: %v", err)
+	}
+	limiting := limiterOf(t, c)
+	want := ModelRegistry[GPT35Turbo].Cost.ConcurrentRequests
+	if limiting.model != string(GPT35Turbo) || limiting.limiter == nil || cap(limiting.limiter.slots) != want {
+		t.Fatalf("limited as %q, want the default model %s with %d slots", limiting.model, GPT35Turbo, want)
+	}
+	if limiting.limiter != limiterFor(OpenAI, string(GPT35Turbo), want) {
+		t.Error("limiter is not the one shared by the default model")
+	}
+}
+
+func TestConfigureUpdatesLimiter(t *testing.T) {
+	c, err := NewClassifier(OpenAI, ModelConfig{APIKey: "key", Model: string(GPT35Turbo)})
+	if err != nil {
+		t.Fatalf("NewClassifier: %v", err)
+	}
+	limiting := limiterOf(t, c)
+
+	if err := c.Configure(ModelConfig{Model: string(GPT4)}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	gpt4Limit := ModelRegistry[GPT4].Cost.ConcurrentRequests
+	if limiting.model != string(GPT4) || limiting.limiter != limiterFor(OpenAI, string(GPT4), gpt4Limit) {
+		t.Errorf("limited as %q after changing the model, want %s", limiting.model, GPT4)
+	}
+
+	if err := c.Configure(ModelConfig{MaxConcurrentRequests: 2}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	if limiting.model != string(GPT4) || limiting.limiter == nil || cap(limiting.limiter.slots) != 2 {
+		t.Errorf("limit of %q not updated to 2", limiting.model)
+	}
+
+	if err := c.Configure(ModelConfig{MaxConcurrentRequests: -1}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	if limiting.limiter != nil {
+		t.Errorf("limit of %d remains after removing it", cap(limiting.limiter.slots))
+	}
+}
+
+func TestLimiterBoundsRequestsInFlight(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		w.Write([]byte(gptReply))
+	}))
+	defer server.Close()
+
+	// A model outside the registry has no limit of its own, so only MaxConcurrentRequests applies
+	c, err := NewClassifier(OpenAI, ModelConfig{
+		Endpoint:              server.URL,
+		APIKey:                "key",
+		Model:                 "in-house-model",
+		MaxConcurrentRequests: 2,
+		MaxRetries:            -1,
+	})
+	if err != nil {
+		t.Fatalf("NewClassifier: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Classify("some text"); err != nil {
+				t.Errorf("Classify: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak != 2 {
+		t.Errorf("%d requests in flight at once, want 2", peak)
+	}
+}
+
+func TestLimiterSharedPerProviderAndModel(t *testing.T) {
+	newLimiter := func(provider Provider, model string) *limitingClassifier {
+		t.Helper()
+		c, err := NewClassifier(provider, ModelConfig{APIKey: "key", Model: model, MaxConcurrentRequests: 3})
+		if err != nil {
+			t.Fatalf("NewClassifier: %v", err)
+		}
+		return limiterOf(t, c)
+	}
+
+	first := newLimiter(OpenAI, "shared-model")
+	if second := newLimiter(OpenAI, "shared-model"); second.limiter != first.limiter {
+		t.Error("classifiers of the same provider and model use separate limiters")
+	}
+	if other := newLimiter(OpenAI, "other-model"); other.limiter == first.limiter {
+		t.Error("different models share a limiter")
+	}
+	if other := newLimiter(Custom, "shared-model"); other.limiter == first.limiter {
+		t.Error("different providers share a limiter")
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	registered := ModelRegistry[GPT35Turbo].Cost.ConcurrentRequests
+	for _, tt := range []struct {
+		model         string
+		maxConcurrent int
+		want          int
+	}{
+		{string(GPT35Turbo), 0, registered},
+		{string(GPT35Turbo), 7, 7},
+		{string(GPT35Turbo), -1, 0},
+		{"unregistered-model", 0, 0},
+	} {
+		if got := concurrencyLimit(tt.model, tt.maxConcurrent); got != tt.want {
+			t.Errorf("concurrencyLimit(%q, %d) = %d, want %d", tt.model, tt.maxConcurrent, got, tt.want)
+		}
+	}
+}
@@ -3,9 +3,13 @@ package classifier
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -15,7 +19,24 @@ type CustomClassifier struct {
 	apiKey     string
 	model      string
 	endpoint   string
+	headers    map[string]string
 	parameters map[string]interface{}
+	auth       AuthConfig
+	fields     CustomFields
+	retry      retryOverrides
+}
+
+// CustomFields names the fields of a custom provider's API that differ from the
+// defaults. Empty names keep the default.
+type CustomFields struct {
+	// ResponseContentPath is the dot-separated path of the completion text in the
+	// response, e.g. "result.text" or "choices.0.message.content" (default: content)
+	ResponseContentPath string
+	// ModelField, MessagesField and ParametersField name the request fields carrying the
+	// model, the chat messages and the extra parameters (default: model, messages, parameters)
+	ModelField      string
+	MessagesField   string
+	ParametersField string
 }
 
 // customMessage represents a message in the custom API request
@@ -24,25 +45,29 @@ type customMessage struct {
 	Content string `json:"content"`
 }
 
-// customRequest represents the request structure for the custom API
-type customRequest struct {
-	Model      string                 `json:"model"`
-	Messages   []customMessage        `json:"messages"`
-	Parameters map[string]interface{} `json:"parameters,omitempty"`
-}
-
 // customResponse represents the response structure from the custom API
 type customResponse struct {
 	Content string `json:"content"`
+	// FinishReason and Refusal are optional and follow the OpenAI conventions
+	FinishReason string `json:"finish_reason,omitempty"`
+	Refusal      string `json:"refusal,omitempty"`
 }
 
 // NewCustomClassifier creates a new custom classifier instance
 func NewCustomClassifier(config ModelConfig) *CustomClassifier {
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = providerEndpoint(Custom, "", "")
+	}
 	return &CustomClassifier{
 		apiKey:     config.APIKey,
 		model:      config.Model,
-		endpoint:   config.Endpoint,
+		endpoint:   endpoint,
+		headers:    config.Headers,
 		parameters: config.Parameters,
+		auth:       config.Auth,
+		fields:     config.CustomFields,
+		retry:      retryOverridesFor(config),
 	}
 }
 
@@ -57,12 +82,25 @@ func (c *CustomClassifier) Configure(config ModelConfig) error {
 	if config.Model != "" {
 		c.model = config.Model
 	}
+	if config.Headers != nil {
+		c.headers = config.Headers
+	}
 	if config.Parameters != nil {
 		c.parameters = config.Parameters
 	}
+	if config.Auth.Scheme != "" {
+		c.auth = config.Auth
+	}
+	if config.CustomFields != (CustomFields{}) {
+		c.fields = config.CustomFields
+	}
+	c.retry.update(config)
 	return nil
 }
 
+// modelName returns the model named in classification requests
+func (c *CustomClassifier) modelName() string { return c.model }
+
 // Classify takes text content and returns classification details
 func (c *CustomClassifier) Classify(content string) (*Classification, error) {
 	return c.ClassifyWithOptions(content, ClassificationOptions{})
@@ -77,49 +115,30 @@ func (c *CustomClassifier) ClassifyWithOptions(content string, options Classific
 		"has_categories": len(options.Categories) > 0,
 	})
 	logger.Debug("Starting content classification")
+	start := time.Now()
 
 	if c.endpoint == "" {
 		logger.Error("Custom endpoint URL is required")
 		return nil, fmt.Errorf("Custom endpoint URL is required")
 	}
 
-	var prompt string
-	if len(options.Categories) > 0 {
-		categoriesStr := strings.Join(options.Categories, ", ")
-		prompt = fmt.Sprintf(`Analyze the following text and classify it into one of these categories: %s
-
-Provide a JSON response with these fields:
-	- category: One of the categories listed above that best matches the content
-	- confidence: A confidence score between 0 and 1 indicating how well the content matches the chosen category
-	- summary: A brief summary of the content (max 100 words)
-	- keywords: Up to 5 key terms or phrases from the content
+	prompt := buildPrompt(content, options)
 
-Text to analyze:
-%s`, categoriesStr, content)
-	} else {
-		prompt = fmt.Sprintf(`Analyze the following text and provide a JSON response with these fields:
-	- category: The main category/topic of the content
-	- confidence: A confidence score between 0 and 1
-	- summary: A brief summary of the content (max 100 words)
-	- keywords: Up to 5 key terms or phrases from the content
-
-Text to analyze:
-%s`, content)
-	}
-
-	reqBody := customRequest{
-		Model: c.model,
-		Messages: []customMessage{
+	reqBody := map[string]interface{}{
+		fieldName(c.fields.ModelField, "model"): c.model,
+		fieldName(c.fields.MessagesField, "messages"): []customMessage{
 			{
 				Role:    "system",
-				Content: "You are a content classification expert. Always respond in valid JSON format.",
+				Content: systemMessage(options),
 			},
 			{
 				Role:    "user",
 				Content: prompt,
 			},
 		},
-		Parameters: c.parameters,
+	}
+	if len(c.parameters) > 0 {
+		reqBody[fieldName(c.fields.ParametersField, "parameters")] = c.parameters
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -129,24 +148,26 @@ Text to analyze:
 	}
 
 	logger.WithFields(log.Fields{
-		"request_body":          string(jsonBody),
+		"request_body":          loggableBody(jsonBody),
 		"model":                 c.model,
 		"predefined_categories": options.Categories,
 	}).Debug("Request payload prepared")
 
-	req, err := http.NewRequest("POST", c.endpoint, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(requestContext(options), "POST", c.endpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		logger.WithError(err).Error("Failed to create request")
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	if c.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	setCustomHeaders(req, c.headers, logger)
+	// Authentication is applied last so custom headers cannot replace it
+	if err := c.auth.apply(req, jsonBody, c.apiKey); err != nil {
+		logger.WithError(err).Error("Failed to authenticate request")
+		return nil, err
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, retries, err := sendRequest(req, c.retry, logger)
 	if err != nil {
 		logger.WithError(err).Error("API request failed")
 		return nil, fmt.Errorf("error making request: %w", err)
@@ -159,37 +180,48 @@ Text to analyze:
 	}
 
 	var customResp customResponse
-	if err := json.NewDecoder(resp.Body).Decode(&customResp); err != nil {
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.WithError(err).Error("Failed to read response body")
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	// With a configured content path, a top-level content field of another type is not
+	// an error; the decoder still fills the remaining fields
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal(respBody, &customResp); err != nil && !(c.fields.ResponseContentPath != "" && errors.As(err, &typeErr)) {
 		logger.WithError(err).Error("Failed to decode response")
 		return nil, fmt.Errorf("error decoding response: %w", err)
 	}
+	if c.fields.ResponseContentPath != "" {
+		if customResp.Content, err = responseField(respBody, c.fields.ResponseContentPath); err != nil {
+			logger.WithError(err).Error("Failed to find response content")
+			return nil, err
+		}
+	}
+
+	if err := checkCompletion(customResp.FinishReason, customResp.Refusal, logger); err != nil {
+		return nil, err
+	}
 
 	var classification Classification
-	if err := json.Unmarshal([]byte(customResp.Content), &classification); err != nil {
+	if err := json.Unmarshal([]byte(extractJSON(customResp.Content)), &classification); err != nil {
 		logger.WithFields(log.Fields{
 			"raw_content": customResp.Content,
 		}).WithError(err).Error("Failed to parse classification")
 		return nil, fmt.Errorf("error parsing classification: %w", err)
 	}
+	if options.DebugIncludeRaw {
+		classification.RawResponse = customResp.Content
+	}
 
-	// Validate category if predefined categories were provided
-	if len(options.Categories) > 0 {
-		categoryValid := false
-		for _, validCategory := range options.Categories {
-			if strings.EqualFold(classification.Category, validCategory) {
-				classification.Category = validCategory // Use exact case from predefined list
-				categoryValid = true
-				break
-			}
-		}
-		if !categoryValid {
-			logger.WithFields(log.Fields{
-				"received_category": classification.Category,
-				"valid_categories":  options.Categories,
-			}).Error("Classification returned invalid category")
-			return nil, fmt.Errorf("classifier returned invalid category: %s", classification.Category)
-		}
+	postprocess(&classification, content, options)
+
+	// Validate category if predefined categories or a taxonomy were provided
+	if err := validateCategory(&classification, options, logger); err != nil {
+		return nil, err
 	}
+	// Custom endpoints have no agreed usage format or pricing, so no usage is reported
+	classification.Metadata = newMetadata(start, retries, Custom, c.model)
 
 	logger.WithFields(log.Fields{
 		"category":                   classification.Category,
@@ -202,6 +234,50 @@ Text to analyze:
 	return &classification, nil
 }
 
+// fieldName returns name, or fallback when name is empty
+func fieldName(name, fallback string) string {
+	if name == "" {
+		return fallback
+	}
+	return name
+}
+
+// responseField returns the value at the dot-separated path in the JSON body. Numeric
+// segments index arrays. A string value is returned as is; any other value is returned
+// as JSON, so gateways that return the classification as an object also work.
+func responseField(body []byte, path string) (string, error) {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "", fmt.Errorf("error decoding response: %w", err)
+	}
+	for _, segment := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[segment]
+			if !ok {
+				return "", fmt.Errorf("response has no field %q at %q", segment, path)
+			}
+			value = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(v) {
+				return "", fmt.Errorf("response has no element %q at %q", segment, path)
+			}
+			value = v[index]
+		default:
+			return "", fmt.Errorf("response has no field %q at %q", segment, path)
+		}
+	}
+	if text, ok := value.(string); ok {
+		return text, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 /* Example implementation:
 
 func (c *CustomClassifier) Classify(content string) (*Classification, error) {
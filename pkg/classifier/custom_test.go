@@ -0,0 +1,87 @@
+package classifier
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCustomFieldNames(t *testing.T) {
+	var body map[string]interface{}
+	server := captureBody(t, `{"result":{"choices":[{"text":`+contentJSON()+`}]}}`, &body)
+
+	c := NewCustomClassifier(ModelConfig{
+		Endpoint:   server.URL,
+		Model:      "house-model",
+		Parameters: map[string]interface{}{"temperature": 0.0},
+		CustomFields: CustomFields{
+			ResponseContentPath: "result.choices.0.text",
+			ModelField:          "engine",
+			MessagesField:       "conversation",
+			ParametersField:     "options",
+		},
+		MaxRetries: -1,
+	})
+	result, err := c.Classify("some text")
+	if err != nil {
+		t.Fatalf("Classify: %v", err)
+	}
+	if result.Category != "Invoice" {
+		t.Errorf("category = %q, want Invoice read from the configured content path", result.Category)
+	}
+	if body["engine"] != "house-model" {
+		t.Errorf("engine = %v, want the model", body["engine"])
+	}
+	if messages, _ := body["conversation"].([]interface{}); len(messages) != 2 {
+		t.Errorf("conversation = %v, want the system and user messages", body["conversation"])
+	}
+	if _, ok := body["options"].(map[string]interface{}); !ok {
+		t.Errorf("options = %v, want the parameters", body["options"])
+	}
+	for _, field := range []string{"model", "messages", "parameters"} {
+		if _, ok := body[field]; ok {
+			t.Errorf("request has default field %q alongside the renamed one", field)
+		}
+	}
+}
+
+func TestCustomDefaultFieldNames(t *testing.T) {
+	var body map[string]interface{}
+	server := captureBody(t, `{"content":`+contentJSON()+`}`, &body)
+
+	c := NewCustomClassifier(ModelConfig{Endpoint: server.URL, Model: "house-model", MaxRetries: -1})
+	if _, err := c.Classify("some text"); err != nil {
+		t.Fatalf("Classify: %v", err)
+	}
+	if body["model"] != "house-model" || body["messages"] == nil {
+		t.Errorf("request = %v, want the default model and messages fields", body)
+	}
+	if _, ok := body["parameters"]; ok {
+		t.Error("request has parameters, want them omitted when none are configured")
+	}
+}
+
+func TestResponseField(t *testing.T) {
+	body := []byte(`{"content":{"category":"Invoice"},"choices":[{"message":{"content":"text"}}]}`)
+	tests := []struct {
+		path, want, err string
+	}{
+		{path: "choices.0.message.content", want: "text"},
+		{path: "content", want: `{"category":"Invoice"}`},
+		{path: "choices.1.message", err: `no element "1"`},
+		{path: "choices.first", err: `no element "first"`},
+		{path: "result.text", err: `no field "result"`},
+		{path: "choices.0.message.content.text", err: `no field "text"`},
+	}
+	for _, tt := range tests {
+		got, err := responseField(body, tt.path)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("responseField(%q) error = %v, want %q", tt.path, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("responseField(%q) = %q, %v, want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
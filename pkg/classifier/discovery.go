@@ -0,0 +1,265 @@
+package classifier
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CategoryDiscoverer proposes a category taxonomy for a collection of documents. Each
+// document is classified without a fixed category list, and the free-form categories
+// the model chooses are merged into a small set of labels.
+type CategoryDiscoverer struct {
+	classifier Classifier
+	// Embedder, when set, groups similar labels by the cosine similarity of their
+	// embeddings. Without it labels are grouped by the words they share.
+	Embedder Embedder
+	// Options are used for every per-document classification; Categories and
+	// CategorySet are ignored
+	Options ClassificationOptions
+	// Concurrency is the number of documents classified at once
+	Concurrency int
+}
+
+// NewCategoryDiscoverer creates a discoverer that labels documents with classifier
+func NewCategoryDiscoverer(classifier Classifier) *CategoryDiscoverer {
+	return &CategoryDiscoverer{classifier: classifier, Concurrency: 4}
+}
+
+// labelGroup is a set of equivalent labels and the number of documents given one of them
+type labelGroup struct {
+	labels map[string]int // label to document count
+	count  int
+	vector []float64 // mean embedding of the labels, when an Embedder is set
+}
+
+// name returns the most frequent label of the group, breaking ties alphabetically
+func (g *labelGroup) name() string {
+	best := ""
+	for label, count := range g.labels {
+		if best == "" || count > g.labels[best] || (count == g.labels[best] && label < best) {
+			best = label
+		}
+	}
+	return best
+}
+
+// DiscoverCategories classifies contents and returns about targetCount proposed
+// categories, most common first. Labels differing only in case, spacing or a plural
+// "s" are always merged; beyond that, the most similar groups of labels are merged
+// until targetCount remain. Documents that fail to classify are skipped.
+func (d *CategoryDiscoverer) DiscoverCategories(contents []string, targetCount int) ([]string, error) {
+	logger := log.WithFields(log.Fields{
+		"function":     "DiscoverCategories",
+		"documents":    len(contents),
+		"target_count": targetCount,
+	})
+	if targetCount <= 0 {
+		return nil, fmt.Errorf("target category count must be positive, got %d", targetCount)
+	}
+	if len(contents) == 0 {
+		return nil, fmt.Errorf("no documents to discover categories from")
+	}
+
+	labels, err := d.labelDocuments(contents, logger)
+	if err != nil {
+		return nil, err
+	}
+	groups := groupLabels(labels)
+	logger.WithField("distinct_labels", len(groups)).Debug("Collected free-form categories")
+
+	if len(groups) > targetCount {
+		if d.Embedder != nil {
+			if err := d.embedGroups(groups); err != nil {
+				logger.WithError(err).Warn("Failed to embed labels, grouping them by shared words")
+				for _, g := range groups {
+					g.vector = nil
+				}
+			}
+		}
+		groups = mergeGroups(groups, targetCount)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].count != groups[j].count {
+			return groups[i].count > groups[j].count
+		}
+		return groups[i].name() < groups[j].name()
+	})
+	categories := make([]string, len(groups))
+	for i, g := range groups {
+		categories[i] = g.name()
+	}
+	logger.WithField("categories", categories).Debug("Discovered categories")
+	return categories, nil
+}
+
+// labelDocuments classifies every document without categories and returns the chosen
+// labels, failing only when no document could be classified
+func (d *CategoryDiscoverer) labelDocuments(contents []string, logger *log.Entry) ([]string, error) {
+	options := d.Options
+	options.Categories = nil
+	options.CategorySet = nil
+
+	labels := make([]string, len(contents))
+	errs := make([]error, len(contents))
+	slots := make(chan struct{}, max(d.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i, content := range contents {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			classification, err := d.classifier.ClassifyWithOptions(content, options)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			labels[i] = strings.Join(strings.Fields(classification.Category), " ")
+		}()
+	}
+	wg.Wait()
+
+	var result []string
+	var lastErr error
+	for i, label := range labels {
+		if errs[i] != nil {
+			logger.WithError(errs[i]).WithField("document", i).Warn("Failed to classify document, skipping it")
+			lastErr = errs[i]
+			continue
+		}
+		if label != "" {
+			result = append(result, label)
+		}
+	}
+	if len(result) == 0 {
+		if lastErr != nil {
+			return nil, fmt.Errorf("no document could be classified: %w", lastErr)
+		}
+		return nil, fmt.Errorf("no document was given a category")
+	}
+	return result, nil
+}
+
+// labelKey folds the differences between labels that name the same category
+func labelKey(label string) string {
+	tokens := words(label)
+	for i, token := range tokens {
+		if len(token) > 3 && strings.HasSuffix(token, "s") && !strings.HasSuffix(token, "ss") {
+			tokens[i] = strings.TrimSuffix(token, "s")
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
+// groupLabels counts labels, putting labels with the same labelKey in one group
+func groupLabels(labels []string) []*labelGroup {
+	byKey := make(map[string]*labelGroup)
+	var groups []*labelGroup
+	for _, label := range labels {
+		key := labelKey(label)
+		g, ok := byKey[key]
+		if !ok {
+			g = &labelGroup{labels: make(map[string]int)}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.labels[label]++
+		g.count++
+	}
+	return groups
+}
+
+// embedGroups sets the vector of every group to the embedding of its name
+func (d *CategoryDiscoverer) embedGroups(groups []*labelGroup) error {
+	names := make([]string, len(groups))
+	for i, g := range groups {
+		names[i] = g.name()
+	}
+	vectors, err := d.Embedder.Embed(names)
+	if err != nil {
+		return err
+	}
+	if len(vectors) != len(groups) {
+		return fmt.Errorf("embedder returned %d vectors for %d labels", len(vectors), len(groups))
+	}
+	for i, g := range groups {
+		g.vector = vectors[i]
+	}
+	return nil
+}
+
+// mergeGroups repeatedly merges the two most similar groups until targetCount remain.
+// When no two remaining groups are similar at all, the least common groups are dropped
+// instead, since merging unrelated labels would produce a meaningless category.
+func mergeGroups(groups []*labelGroup, targetCount int) []*labelGroup {
+	for len(groups) > targetCount {
+		bestI, bestJ, bestScore := -1, -1, 0.0
+		for i := range groups {
+			for j := i + 1; j < len(groups); j++ {
+				if score := groupSimilarity(groups[i], groups[j]); score > bestScore {
+					bestI, bestJ, bestScore = i, j, score
+				}
+			}
+		}
+		if bestI < 0 {
+			sort.SliceStable(groups, func(i, j int) bool { return groups[i].count > groups[j].count })
+			return groups[:targetCount]
+		}
+
+		a, b := groups[bestI], groups[bestJ]
+		if a.vector != nil && b.vector != nil {
+			merged := make([]float64, len(a.vector))
+			for k := range merged {
+				merged[k] = (a.vector[k]*float64(a.count) + b.vector[k]*float64(b.count)) / float64(a.count+b.count)
+			}
+			a.vector = merged
+		}
+		for label, count := range b.labels {
+			a.labels[label] += count
+		}
+		a.count += b.count
+		groups = append(groups[:bestJ], groups[bestJ+1:]...)
+	}
+	return groups
+}
+
+// groupSimilarity is the cosine similarity of the group embeddings when both have one,
+// otherwise the share of label words the groups have in common
+func groupSimilarity(a, b *labelGroup) float64 {
+	if a.vector != nil && b.vector != nil {
+		return cosineSimilarity(a.vector, b.vector)
+	}
+	wordsA, wordsB := groupWords(a), groupWords(b)
+	shared := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			shared++
+		}
+	}
+	union := len(wordsA) + len(wordsB) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// labelStopWords are ignored when comparing labels by their words
+var labelStopWords = map[string]bool{"a": true, "an": true, "and": true, "for": true, "of": true, "or": true, "the": true, "to": true}
+
+// groupWords returns the folded words of all labels in g
+func groupWords(g *labelGroup) map[string]bool {
+	result := make(map[string]bool)
+	for label := range g.labels {
+		for _, word := range strings.Fields(labelKey(label)) {
+			if !labelStopWords[word] {
+				result[word] = true
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,93 @@
+package classifier
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// labelingClassifier answers with the category configured for each content, failing
+// for contents without one
+type labelingClassifier struct {
+	categories map[string]string
+}
+
+func (c *labelingClassifier) Classify(content string) (*Classification, error) {
+	return c.ClassifyWithOptions(content, ClassificationOptions{})
+}
+
+func (c *labelingClassifier) ClassifyWithOptions(content string, options ClassificationOptions) (*Classification, error) {
+	if len(options.Categories) > 0 {
+		return nil, errors.New("discovery must not offer categories")
+	}
+	category, ok := c.categories[content]
+	if !ok {
+		return nil, errors.New("provider unavailable")
+	}
+	return &Classification{Category: category}, nil
+}
+
+func (c *labelingClassifier) Configure(config ModelConfig) error { return nil }
+
+func TestDiscoverCategories(t *testing.T) {
+	c := &labelingClassifier{categories: map[string]string{
+		"doc1": "Invoice",
+		"doc2": "invoices",
+		"doc3": "Sales  Invoice",
+		"doc4": "Employment Contract",
+		"doc5": "Contract",
+		"doc6": "Meeting Notes",
+	}}
+	d := NewCategoryDiscoverer(c)
+	d.Options.Categories = []string{"ignored"}
+	contents := []string{"doc1", "doc2", "doc3", "doc4", "doc5", "doc6", "unlabeled"}
+
+	// Case and plural variants always merge; word overlap then folds related labels
+	got, err := d.DiscoverCategories(contents, 3)
+	if err != nil {
+		t.Fatalf("DiscoverCategories: %v", err)
+	}
+	if want := []string{"Invoice", "Contract", "Meeting Notes"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DiscoverCategories(3) = %q, want %q", got, want)
+	}
+
+	// Unrelated labels are dropped rather than merged
+	got, err = d.DiscoverCategories(contents, 2)
+	if err != nil {
+		t.Fatalf("DiscoverCategories: %v", err)
+	}
+	if want := []string{"Invoice", "Contract"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DiscoverCategories(2) = %q, want %q", got, want)
+	}
+
+	if _, err := d.DiscoverCategories([]string{"unlabeled"}, 3); err == nil {
+		t.Error("DiscoverCategories with no classifiable document succeeded")
+	}
+	if _, err := d.DiscoverCategories(contents, 0); err == nil {
+		t.Error("DiscoverCategories with a target of 0 succeeded")
+	}
+}
+
+func TestDiscoverCategoriesWithEmbeddings(t *testing.T) {
+	c := &labelingClassifier{categories: map[string]string{
+		"doc1": "Invoice",
+		"doc2": "Bill",
+		"doc3": "Bill",
+		"doc4": "Memo",
+	}}
+	d := NewCategoryDiscoverer(c)
+	// Invoice and Bill share no words but have close embeddings
+	d.Embedder = &fakeEmbedder{vectors: map[string][]float64{
+		"Bill":    {1, 0.1},
+		"Invoice": {1, 0},
+		"Memo":    {0, 1},
+	}}
+
+	got, err := d.DiscoverCategories([]string{"doc1", "doc2", "doc3", "doc4"}, 2)
+	if err != nil {
+		t.Fatalf("DiscoverCategories: %v", err)
+	}
+	if want := []string{"Bill", "Memo"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DiscoverCategories = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,307 @@
+package classifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxEmbeddingTokens bounds the document text sent for embedding; embedding models
+// accept about 8K tokens
+const maxEmbeddingTokens = 8000
+
+// Embedder turns texts into embedding vectors, returned in input order
+type Embedder interface {
+	Embed(texts []string) ([][]float64, error)
+}
+
+// OpenAIEmbedder calls the OpenAI embeddings API
+type OpenAIEmbedder struct {
+	apiKey   string
+	model    string
+	endpoint string
+	headers  map[string]string
+	retry    retryOverrides
+}
+
+// NewOpenAIEmbedder creates an embedder using the model, key, endpoint and headers of config
+func NewOpenAIEmbedder(config ModelConfig) *OpenAIEmbedder {
+	apiKey := config.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = providerEndpoint(OpenAI, "/embeddings", "https://api.openai.com/v1/embeddings")
+	}
+	model := config.Model
+	if model == "" {
+		model = string(TextEmbedding3Small)
+	}
+	return &OpenAIEmbedder{
+		apiKey:   apiKey,
+		model:    model,
+		endpoint: endpoint,
+		headers:  config.Headers,
+		retry:    retryOverridesFor(config),
+	}
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Embed returns one embedding per text
+func (e *OpenAIEmbedder) Embed(texts []string) ([][]float64, error) {
+	logger := log.WithFields(log.Fields{
+		"function":   "Embed",
+		"model":      e.model,
+		"text_count": len(texts),
+	})
+
+	if e.apiKey == "" {
+		return nil, fmt.Errorf("OpenAI API key is required")
+	}
+
+	jsonBody, err := json.Marshal(embeddingRequest{Model: e.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %w", err)
+	}
+	req, err := http.NewRequest("POST", e.endpoint, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	setCustomHeaders(req, e.headers, logger)
+
+	resp, _, err := sendRequest(req, e.retry, logger)
+	if err != nil {
+		logger.WithError(err).Error("API request failed")
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	var embeddingResp embeddingResponse
+	if err := json.Unmarshal(respBody, &embeddingResp); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		logger.WithField("status_code", resp.StatusCode).Error("API request failed")
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, embeddingResp.Error.Message)
+	}
+
+	vectors := make([][]float64, len(texts))
+	for _, d := range embeddingResp.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if v == nil {
+			return nil, fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+// EmbeddingClassifier routes content to the category whose description embedding is
+// most similar to the content's embedding. It is far cheaper than generative
+// classification but produces no summary; keywords are extracted locally.
+type EmbeddingClassifier struct {
+	embedder Embedder
+	model    string
+	// descriptions maps category names to the text embedded for them. Categories
+	// without a description here or in the taxonomy are embedded by name.
+	descriptions map[string]string
+
+	mu    sync.Mutex
+	cache map[string][]float64 // category text to embedding
+}
+
+// NewEmbeddingClassifier creates a classifier backed by embedder. model is reported in
+// the classification metadata.
+func NewEmbeddingClassifier(embedder Embedder, model string, descriptions map[string]string) *EmbeddingClassifier {
+	return &EmbeddingClassifier{
+		embedder:     embedder,
+		model:        model,
+		descriptions: descriptions,
+		cache:        make(map[string][]float64),
+	}
+}
+
+// Configure replaces the OpenAI embedder with one built from config
+func (c *EmbeddingClassifier) Configure(config ModelConfig) error {
+	if _, ok := c.embedder.(*OpenAIEmbedder); ok {
+		embedder := NewOpenAIEmbedder(config)
+		c.embedder = embedder
+		c.model = embedder.model
+	}
+	return nil
+}
+
+// modelName returns the embedding model
+func (c *EmbeddingClassifier) modelName() string { return c.model }
+
+// Classify takes text content and returns classification details
+func (c *EmbeddingClassifier) Classify(content string) (*Classification, error) {
+	return c.ClassifyWithOptions(content, ClassificationOptions{})
+}
+
+// ClassifyWithOptions picks the category nearest to content. Categories come from the
+// options, the taxonomy, or the configured descriptions, in that order. Confidence is
+// the cosine similarity of the winner, clamped to [0,1].
+func (c *EmbeddingClassifier) ClassifyWithOptions(content string, options ClassificationOptions) (*Classification, error) {
+	logger := log.WithFields(log.Fields{
+		"function":       "ClassifyWithOptions",
+		"model":          c.model,
+		"content_length": len(content),
+	})
+	start := time.Now()
+
+	categories := promptCategories(options)
+	if len(categories) == 0 {
+		for name := range c.descriptions {
+			categories = append(categories, name)
+		}
+		sort.Strings(categories)
+		categories = withoutExcluded(categories, options.ExcludeCategories)
+	}
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("embedding classification requires categories")
+	}
+
+	text, _ := TruncateToTokens(content, maxEmbeddingTokens)
+	vectors, err := c.categoryVectors(categories, options.CategorySet)
+	if err != nil {
+		logger.WithError(err).Error("Failed to embed categories")
+		return nil, err
+	}
+	embedded, err := c.embedder.Embed([]string{text})
+	if err != nil {
+		logger.WithError(err).Error("Failed to embed content")
+		return nil, fmt.Errorf("error embedding content: %w", err)
+	}
+	if len(embedded) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for the content", len(embedded))
+	}
+
+	scores := make(map[string]float64, len(categories))
+	best := -1
+	bestScore := math.Inf(-1)
+	for i, category := range categories {
+		score := cosineSimilarity(embedded[0], vectors[i])
+		scores[category] = math.Max(score, 0)
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	classification := Classification{
+		Category:   categories[best],
+		Confidence: bestScore,
+	}
+	if wantsScores(options) {
+		classification.Scores = scores
+	}
+	postprocess(&classification, content, options)
+	if err := validateCategory(&classification, options, logger); err != nil {
+		return nil, err
+	}
+	classification.Metadata = newMetadata(start, 0, Embedding, c.model)
+
+	logger.WithFields(log.Fields{
+		"category":   classification.Category,
+		"confidence": classification.Confidence,
+	}).Debug("Classification completed successfully")
+	return &classification, nil
+}
+
+// categoryVectors returns the embedding of each category's description, embedding
+// only those not cached yet
+func (c *EmbeddingClassifier) categoryVectors(categories []string, set *CategorySet) ([][]float64, error) {
+	texts := make([]string, len(categories))
+	for i, category := range categories {
+		texts[i] = category
+		if description := c.descriptions[category]; description != "" {
+			texts[i] = category + ": " + description
+		} else if description := set.Description(category); description != "" {
+			texts[i] = category + ": " + description
+		}
+	}
+
+	c.mu.Lock()
+	var missing []string
+	for _, text := range texts {
+		if _, ok := c.cache[text]; !ok {
+			missing = append(missing, text)
+		}
+	}
+	c.mu.Unlock()
+
+	if len(missing) > 0 {
+		embedded, err := c.embedder.Embed(missing)
+		if err != nil {
+			return nil, fmt.Errorf("error embedding categories: %w", err)
+		}
+		if len(embedded) != len(missing) {
+			return nil, fmt.Errorf("embedder returned %d vectors for %d categories", len(embedded), len(missing))
+		}
+		c.mu.Lock()
+		for i, text := range missing {
+			c.cache[text] = embedded[i]
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = c.cache[text]
+	}
+	return vectors, nil
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, or 0 when either
+// is empty or their lengths differ
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,121 @@
+package classifier
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeEmbedder embeds texts from a fixed table and records every batch it is asked for
+type fakeEmbedder struct {
+	vectors map[string][]float64
+	batches [][]string
+}
+
+func (e *fakeEmbedder) Embed(texts []string) ([][]float64, error) {
+	e.batches = append(e.batches, texts)
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.vectors[text]
+	}
+	return vectors, nil
+}
+
+func TestEmbeddingClassifierPicksNearestCategory(t *testing.T) {
+	embedder := &fakeEmbedder{vectors: map[string][]float64{
+		"Invoice: a request for payment": {1, 0},
+		"Contract":                       {0, 1},
+		"Pay $40 by Friday":              {0.8, 0.6},
+	}}
+	c := NewEmbeddingClassifier(embedder, "text-embedding-3-small", map[string]string{
+		"Invoice": "a request for payment",
+	})
+	options := ClassificationOptions{Categories: []string{"Invoice", "Contract"}, ScoreAllCategories: true}
+
+	classification, err := c.ClassifyWithOptions("Pay $40 by Friday", options)
+	if err != nil {
+		t.Fatalf("ClassifyWithOptions: %v", err)
+	}
+	if classification.Category != "Invoice" || math.Abs(classification.Confidence-0.8) > 1e-9 {
+		t.Errorf("classification = %s %.2f, want Invoice 0.80", classification.Category, classification.Confidence)
+	}
+	if math.Abs(classification.Scores["Contract"]-0.6) > 1e-9 {
+		t.Errorf("scores = %v, want Contract scored by its similarity", classification.Scores)
+	}
+	if classification.Metadata == nil || classification.Metadata.Provider != Embedding {
+		t.Errorf("metadata = %+v, want the embedding provider", classification.Metadata)
+	}
+
+	// Category embeddings are cached, so a second document embeds only its own text
+	if _, err := c.ClassifyWithOptions("Pay $40 by Friday", options); err != nil {
+		t.Fatalf("ClassifyWithOptions: %v", err)
+	}
+	want := [][]string{
+		{"Invoice: a request for payment", "Contract"},
+		{"Pay $40 by Friday"},
+		{"Pay $40 by Friday"},
+	}
+	if !reflect.DeepEqual(embedder.batches, want) {
+		t.Errorf("embedded batches = %q, want %q", embedder.batches, want)
+	}
+
+	if _, err := NewEmbeddingClassifier(embedder, "m", nil).Classify("text"); err == nil {
+		t.Error("Classify without categories succeeded")
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	for _, tc := range []struct {
+		a, b []float64
+		want float64
+	}{
+		{[]float64{1, 0}, []float64{2, 0}, 1},
+		{[]float64{1, 0}, []float64{0, 3}, 0},
+		{[]float64{1, 1}, []float64{-1, -1}, -1},
+		{[]float64{1, 0}, []float64{1, 0, 0}, 0},
+		{[]float64{0, 0}, []float64{1, 0}, 0},
+	} {
+		if got := cosineSimilarity(tc.a, tc.b); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("cosineSimilarity(%v, %v) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestEmbeddingProvider(t *testing.T) {
+	var requests []embeddingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req embeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req)
+		// Answer out of order; the embedder reassembles vectors by index
+		var data []map[string]interface{}
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			vector := []float64{0, 1}
+			if strings.HasPrefix(req.Input[i], "Receipt") || strings.Contains(req.Input[i], "paid") {
+				vector = []float64{1, 0}
+			}
+			data = append(data, map[string]interface{}{"index": i, "embedding": vector})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+
+	c, err := NewClassifier(Embedding, ModelConfig{Endpoint: server.URL, APIKey: "key", MaxRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	classification, err := c.ClassifyWithOptions("Thanks, your order is paid", ClassificationOptions{Categories: []string{"Memo", "Receipt"}})
+	if err != nil {
+		t.Fatalf("ClassifyWithOptions: %v", err)
+	}
+	if classification.Category != "Receipt" {
+		t.Errorf("category = %q, want Receipt", classification.Category)
+	}
+	if len(requests) != 2 || requests[0].Model != string(TextEmbedding3Small) {
+		t.Errorf("requests = %+v, want the categories and the content embedded with the default model", requests)
+	}
+}
@@ -0,0 +1,78 @@
+package classifier
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrExcludedCategory is returned when the model keeps choosing a category listed in
+// ExcludeCategories
+var ErrExcludedCategory = errors.New("classifier returned an excluded category")
+
+// maxExclusionRetries is the number of times a classification is repeated after the
+// model returned an excluded category
+const maxExclusionRetries = 2
+
+// excludingClassifier repeats classifications whose category is in ExcludeCategories
+type excludingClassifier struct {
+	Classifier
+}
+
+// ClassifyWithOptions classifies content, retrying up to maxExclusionRetries times
+// while the model returns an excluded category
+func (c excludingClassifier) ClassifyWithOptions(content string, options ClassificationOptions) (*Classification, error) {
+	classification, err := c.Classifier.ClassifyWithOptions(content, options)
+	for attempt := 1; attempt <= maxExclusionRetries && errors.Is(err, ErrExcludedCategory); attempt++ {
+		log.WithFields(log.Fields{
+			"function": "ClassifyWithOptions",
+			"attempt":  attempt,
+			"excluded": options.ExcludeCategories,
+		}).WithError(err).Warn("Retrying classification")
+		classification, err = c.Classifier.ClassifyWithOptions(content, options)
+	}
+	return classification, err
+}
+
+// Classify takes text content and returns classification details
+func (c excludingClassifier) Classify(content string) (*Classification, error) {
+	return c.ClassifyWithOptions(content, ClassificationOptions{})
+}
+
+// isExcluded reports whether category is one of the excluded categories, ignoring case
+func isExcluded(category string, excluded []string) bool {
+	for _, e := range excluded {
+		if strings.EqualFold(strings.TrimSpace(category), strings.TrimSpace(e)) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutExcluded drops the excluded entries from categories
+func withoutExcluded(categories, excluded []string) []string {
+	if len(excluded) == 0 {
+		return categories
+	}
+	var kept []string
+	for _, category := range categories {
+		if !isExcluded(category, excluded) {
+			kept = append(kept, category)
+		}
+	}
+	return kept
+}
+
+// checkExcluded rejects a classification whose category is excluded
+func checkExcluded(classification *Classification, options ClassificationOptions, logger *log.Entry) error {
+	if !isExcluded(classification.Category, options.ExcludeCategories) {
+		return nil
+	}
+	logger.WithFields(log.Fields{
+		"received_category": classification.Category,
+		"excluded":          options.ExcludeCategories,
+	}).Warn("Classification returned excluded category")
+	return fmt.Errorf("%w: %s", ErrExcludedCategory, classification.Category)
+}
@@ -0,0 +1,71 @@
+package classifier
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveCategories starts a GPT-compatible stub answering with each category in turn,
+// repeating the last one, and returns its URL along with the prompts it received
+func serveCategories(t *testing.T, categories ...string) (string, *[]string) {
+	t.Helper()
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		prompts = append(prompts, body.Messages[len(body.Messages)-1].Content)
+
+		category := categories[min(len(prompts), len(categories))-1]
+		content, _ := json.Marshal(`{"category":"` + category + `","confidence":0.8,"summary":"s","keywords":[]}`)
+		w.Write([]byte(`{"choices":[{"message":{"content":` + string(content) + `},"finish_reason":"stop"}]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, &prompts
+}
+
+func TestExcludeCategories(t *testing.T) {
+	options := ClassificationOptions{
+		Categories:        []string{"Invoice", "Receipt", "Spam"},
+		ExcludeCategories: []string{"spam"},
+	}
+
+	url, prompts := serveCategories(t, "Spam", "Invoice")
+	c, err := NewClassifier(OpenAI, ModelConfig{Endpoint: url, APIKey: "key", MaxRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	classification, err := c.ClassifyWithOptions("Amount due: $40", options)
+	if err != nil {
+		t.Fatalf("ClassifyWithOptions: %v", err)
+	}
+	if classification.Category != "Invoice" || len(*prompts) != 2 {
+		t.Errorf("got %q after %d requests, want Invoice after a retry", classification.Category, len(*prompts))
+	}
+	prompt := (*prompts)[0]
+	if !strings.Contains(prompt, "Do not use any of these categories: spam.") {
+		t.Errorf("prompt does not forbid the excluded category:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "Invoice, Receipt") || strings.Contains(prompt, "Receipt, Spam") {
+		t.Errorf("prompt still offers the excluded category:\n%s", prompt)
+	}
+
+	// A model that never complies fails after the retries
+	url, prompts = serveCategories(t, "SPAM")
+	c, _ = NewClassifier(OpenAI, ModelConfig{Endpoint: url, APIKey: "key", MaxRetries: -1})
+	if _, err := c.ClassifyWithOptions("Win a prize", options); !errors.Is(err, ErrExcludedCategory) {
+		t.Errorf("error = %v, want ErrExcludedCategory", err)
+	}
+	if len(*prompts) != 1+maxExclusionRetries {
+		t.Errorf("provider called %d times, want %d", len(*prompts), 1+maxExclusionRetries)
+	}
+}
@@ -0,0 +1,287 @@
+package classifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultGeminiEndpoint is the generateContent API; {model} is replaced by the model name
+const defaultGeminiEndpoint = "https://generativelanguage.googleapis.com/v1beta/models/{model}:generateContent"
+
+// GeminiClassifier handles content classification using Google's Gemini models
+type GeminiClassifier struct {
+	apiKey     string
+	model      string
+	endpoint   string
+	seed       *int
+	headers    map[string]string
+	parameters map[string]interface{}
+	retry      retryOverrides
+}
+
+// NewGeminiClassifier creates a new Gemini classifier. The endpoint may contain a
+// {model} placeholder, which is replaced by the configured model.
+func NewGeminiClassifier(config ModelConfig) *GeminiClassifier {
+	apiKey := config.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("GEMINI_API_KEY")
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = providerEndpoint(Gemini, "/models/{model}:generateContent", defaultGeminiEndpoint)
+	}
+
+	model := config.Model
+	if model == "" {
+		model = string(Gemini15Flash)
+	}
+
+	return &GeminiClassifier{
+		apiKey:     apiKey,
+		model:      model,
+		endpoint:   endpoint,
+		seed:       config.Seed,
+		headers:    config.Headers,
+		parameters: config.Parameters,
+		retry:      retryOverridesFor(config),
+	}
+}
+
+// Configure updates the classifier configuration
+func (c *GeminiClassifier) Configure(config ModelConfig) error {
+	if config.APIKey != "" {
+		c.apiKey = config.APIKey
+	}
+	if config.Endpoint != "" {
+		c.endpoint = config.Endpoint
+	}
+	if config.Model != "" {
+		c.model = config.Model
+	}
+	if config.Seed != nil {
+		c.seed = config.Seed
+	}
+	if config.Headers != nil {
+		c.headers = config.Headers
+	}
+	if config.Parameters != nil {
+		c.parameters = config.Parameters
+	}
+	c.retry.update(config)
+	return nil
+}
+
+// modelName returns the model classification requests are sent to
+func (c *GeminiClassifier) modelName() string { return c.model }
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent        `json:"contents"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      float64  `json:"temperature"`
+	MaxOutputTokens  int      `json:"maxOutputTokens,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	ResponseMimeType string   `json:"responseMimeType,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata *struct {
+		PromptTokenCount        int `json:"promptTokenCount"`
+		CandidatesTokenCount    int `json:"candidatesTokenCount"`
+		TotalTokenCount         int `json:"totalTokenCount"`
+		CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// text joins the text parts of the first candidate
+func (r *geminiResponse) text() string {
+	var parts []string
+	for _, part := range r.Candidates[0].Content.Parts {
+		parts = append(parts, part.Text)
+	}
+	return strings.Join(parts, "")
+}
+
+// usage converts Gemini's usage metadata, or returns nil when the response has none
+func (r *geminiResponse) usage() *TokenUsage {
+	if r.UsageMetadata == nil {
+		return nil
+	}
+	usage := &TokenUsage{
+		PromptTokens:       r.UsageMetadata.PromptTokenCount,
+		CompletionTokens:   r.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:        r.UsageMetadata.TotalTokenCount,
+		CachedPromptTokens: r.UsageMetadata.CachedContentTokenCount,
+	}
+	return usage.finish()
+}
+
+// Classify takes text content and returns classification details
+func (c *GeminiClassifier) Classify(content string) (*Classification, error) {
+	return c.ClassifyWithOptions(content, ClassificationOptions{})
+}
+
+// ClassifyWithOptions takes text content and classification options and returns classification details
+func (c *GeminiClassifier) ClassifyWithOptions(content string, options ClassificationOptions) (*Classification, error) {
+	logger := log.WithFields(log.Fields{
+		"function":       "ClassifyWithOptions",
+		"model":          c.model,
+		"content_length": len(content),
+		"has_categories": len(options.Categories) > 0,
+	})
+	logger.Debug("Starting content classification")
+	start := time.Now()
+
+	if c.apiKey == "" {
+		logger.Error("Gemini API key is required")
+		return nil, fmt.Errorf("Gemini API key is required")
+	}
+
+	temperature := 0.3
+	if temp, ok := c.parameters["temperature"].(float64); ok {
+		temperature = temp
+	}
+
+	reqBody := geminiRequest{
+		SystemInstruction: &geminiContent{
+			Parts: []geminiPart{{Text: systemMessage(options)}},
+		},
+		Contents: []geminiContent{
+			{
+				Role:  "user",
+				Parts: []geminiPart{{Text: buildPrompt(content, options)}},
+			},
+		},
+		GenerationConfig: geminiGenerationConfig{
+			Temperature:      temperature,
+			MaxOutputTokens:  IntParameter(c.parameters, "max_tokens", defaultMaxTokens),
+			Seed:             c.seed,
+			StopSequences:    stringsParameter(c.parameters, "stop"),
+			ResponseMimeType: "application/json",
+		},
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		logger.WithError(err).Error("Failed to marshal request")
+		return nil, fmt.Errorf("error marshaling request: %w", err)
+	}
+
+	logger.WithFields(log.Fields{
+		"request_body":          loggableBody(jsonBody),
+		"model":                 c.model,
+		"temperature":           temperature,
+		"predefined_categories": options.Categories,
+	}).Debug("Request payload prepared")
+
+	endpoint := strings.ReplaceAll(c.endpoint, "{model}", url.PathEscape(c.model))
+	req, err := http.NewRequestWithContext(requestContext(options), "POST", endpoint, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		logger.WithError(err).Error("Failed to create request")
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", c.apiKey)
+	setCustomHeaders(req, c.headers, logger)
+
+	resp, retries, err := sendRequest(req, c.retry, logger)
+	if err != nil {
+		logger.WithError(err).Error("API request failed")
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.WithError(err).Error("Failed to read response body")
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		logger.WithFields(log.Fields{
+			"status_code":   resp.StatusCode,
+			"response_body": loggableBody(respBody),
+		}).Error("API request failed")
+		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+	}
+
+	var geminiResp geminiResponse
+	if err := json.Unmarshal(respBody, &geminiResp); err != nil {
+		logger.WithError(err).Error("Failed to decode response")
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	// A blocked prompt returns no candidates, only the reason it was blocked
+	if reason := geminiResp.PromptFeedback.BlockReason; reason != "" {
+		return nil, checkCompletion("", "prompt blocked: "+reason, logger)
+	}
+	if len(geminiResp.Candidates) == 0 {
+		logger.Error("No classification result received")
+		return nil, fmt.Errorf("no classification result received")
+	}
+	if err := checkCompletion(geminiResp.Candidates[0].FinishReason, "", logger); err != nil {
+		return nil, err
+	}
+
+	text := geminiResp.text()
+	var classification Classification
+	if err := json.Unmarshal([]byte(extractJSON(text)), &classification); err != nil {
+		logger.WithFields(log.Fields{
+			"raw_content": text,
+		}).WithError(err).Error("Failed to parse classification")
+		return nil, fmt.Errorf("error parsing classification: %w", err)
+	}
+	if options.DebugIncludeRaw {
+		classification.RawResponse = text
+	}
+
+	postprocess(&classification, content, options)
+
+	// Validate category if predefined categories or a taxonomy were provided
+	if err := validateCategory(&classification, options, logger); err != nil {
+		return nil, err
+	}
+	classification.Metadata = newMetadata(start, retries, Gemini, c.model)
+	classification.setUsage(geminiResp.usage(), c.model)
+
+	logger.WithFields(log.Fields{
+		"category":                   classification.Category,
+		"confidence":                 classification.Confidence,
+		"keywords_count":             len(classification.Keywords),
+		"summary_length":             len(classification.Summary),
+		"used_predefined_categories": len(options.Categories) > 0,
+	}).Debug("Classification completed successfully")
+
+	return &classification, nil
+}
@@ -0,0 +1,133 @@
+package classifier
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// geminiReply is a successful generateContent response carrying a classification split
+// over two text parts
+var geminiReply = `{"candidates":[{"content":{"role":"model","parts":[{"text":"{\"category\":\"Invoice\","},{"text":"\"confidence\":0.8,\"summary\":\"s\",\"keywords\":[\"k\"]}"}]},"finishReason":"STOP"}],` +
+	`"usageMetadata":{"promptTokenCount":120,"candidatesTokenCount":25,"totalTokenCount":145,"cachedContentTokenCount":20}}`
+
+// geminiRequestLog records what the mocked Gemini endpoint received
+type geminiRequestLog struct {
+	path   string
+	apiKey string
+	body   geminiRequest
+}
+
+// serveGemini starts a mocked Gemini endpoint answering with reply, whose endpoint has the
+// {model} placeholder of the real API
+func serveGemini(t *testing.T, reply string, received *geminiRequestLog) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.path = r.URL.Path
+		received.apiKey = r.Header.Get("x-goog-api-key")
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &received.body); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		w.Write([]byte(reply))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/v1beta/models/{model}:generateContent"
+}
+
+func TestGeminiClassify(t *testing.T) {
+	var got geminiRequestLog
+	endpoint := serveGemini(t, geminiReply, &got)
+
+	c := NewGeminiClassifier(ModelConfig{
+		Endpoint:   endpoint,
+		APIKey:     "gemini-key",
+		Model:      string(Gemini15Pro),
+		Parameters: map[string]interface{}{"max_tokens": 300.0, "temperature": 0.0},
+	})
+	classification, err := c.Classify("Invoice 42: please pay $40 by Friday.")
+	if err != nil {
+		t.Fatalf("Classify: %v", err)
+	}
+
+	if got.path != "/v1beta/models/gemini-1.5-pro:generateContent" {
+		t.Errorf("path = %q, want the model substituted", got.path)
+	}
+	if got.apiKey != "gemini-key" {
+		t.Errorf("x-goog-api-key = %q, want gemini-key", got.apiKey)
+	}
+	config := got.body.GenerationConfig
+	if config.MaxOutputTokens != 300 || config.Temperature != 0 || config.ResponseMimeType != "application/json" {
+		t.Errorf("generation config = %+v, want 300 tokens at temperature 0 as JSON", config)
+	}
+	if got.body.SystemInstruction == nil || len(got.body.Contents) != 1 || got.body.Contents[0].Role != "user" {
+		t.Errorf("request = %+v, want a system instruction and one user message", got.body)
+	}
+
+	if classification.Category != "Invoice" || classification.Confidence != 0.8 {
+		t.Errorf("classification = %s at %v, want Invoice at 0.8", classification.Category, classification.Confidence)
+	}
+	if m := classification.Metadata; m.Provider != Gemini || m.Model != string(Gemini15Pro) {
+		t.Errorf("metadata = %+v, want provider gemini and model gemini-1.5-pro", m)
+	}
+}
+
+func TestGeminiUsageMetadata(t *testing.T) {
+	var got geminiRequestLog
+	c := NewGeminiClassifier(ModelConfig{
+		Endpoint: serveGemini(t, geminiReply, &got),
+		APIKey:   "key",
+		Model:    string(Gemini15Flash),
+	})
+	classification, err := c.Classify("some text")
+	if err != nil {
+		t.Fatalf("Classify: %v", err)
+	}
+
+	want := TokenUsage{PromptTokens: 120, CompletionTokens: 25, TotalTokens: 145, CachedPromptTokens: 20}
+	if usage := classification.Usage; usage == nil || *usage != want {
+		t.Errorf("usage = %+v, want %+v", usage, want)
+	}
+	if wantCost := EstimateCost(Gemini15Flash, 120, 25); classification.EstimatedCost != wantCost {
+		t.Errorf("estimated cost = %v, want %v", classification.EstimatedCost, wantCost)
+	}
+}
+
+func TestGeminiBlocked(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+	}{
+		{name: "prompt blocked", reply: `{"promptFeedback":{"blockReason":"SAFETY"}}`},
+		{name: "candidate filtered", reply: `{"candidates":[{"content":{"parts":[]},"finishReason":"SAFETY"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got geminiRequestLog
+			c := NewGeminiClassifier(ModelConfig{Endpoint: serveGemini(t, tt.reply, &got), APIKey: "key"})
+			if _, err := c.Classify("some text"); !errors.Is(err, ErrRefused) {
+				t.Errorf("error = %v, want ErrRefused", err)
+			}
+		})
+	}
+}
+
+func TestGeminiTruncated(t *testing.T) {
+	var got geminiRequestLog
+	reply := `{"candidates":[{"content":{"parts":[{"text":"{\"category\":"}]},"finishReason":"MAX_TOKENS"}]}`
+	c := NewGeminiClassifier(ModelConfig{Endpoint: serveGemini(t, reply, &got), APIKey: "key"})
+	if _, err := c.Classify("some text"); !errors.Is(err, ErrResponseTruncated) {
+		t.Errorf("error = %v, want ErrResponseTruncated", err)
+	}
+}
+
+func TestGeminiBaseURL(t *testing.T) {
+	t.Setenv("GEMINI_BASE_URL", "https://gateway.internal/gemini/")
+	c := NewGeminiClassifier(ModelConfig{APIKey: "key"})
+	if want := "https://gateway.internal/gemini/models/{model}:generateContent"; c.endpoint != want {
+		t.Errorf("endpoint = %q, want %q", c.endpoint, want)
+	}
+}
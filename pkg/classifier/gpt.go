@@ -2,22 +2,30 @@ package classifier
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
 // GPTClassifier handles content classification using OpenAI's GPT models
 type GPTClassifier struct {
-	apiKey     string
-	model      string
-	endpoint   string
-	parameters map[string]interface{}
+	apiKey       string
+	model        string
+	visionModel  string
+	endpoint     string
+	organization string
+	project      string
+	seed         *int
+	headers      map[string]string
+	parameters   map[string]interface{}
+	retry        retryOverrides
 }
 
 // NewGPTClassifier creates a new GPT classifier
@@ -37,7 +45,7 @@ func NewGPTClassifier(config ModelConfig) *GPTClassifier {
 	endpoint := config.Endpoint
 	if endpoint == "" {
 		logger.Debug("Using default OpenAI endpoint")
-		endpoint = "https://api.openai.com/v1/chat/completions"
+		endpoint = providerEndpoint(OpenAI, "/chat/completions", "https://api.openai.com/v1/chat/completions")
 	}
 
 	model := config.Model
@@ -46,18 +54,41 @@ func NewGPTClassifier(config ModelConfig) *GPTClassifier {
 		model = "gpt-3.5-turbo"
 	}
 
+	visionModel := config.VisionModel
+	if visionModel == "" {
+		visionModel = "gpt-4o"
+	}
+
+	organization := config.Organization
+	if organization == "" {
+		organization = os.Getenv("OPENAI_ORG_ID")
+	}
+
+	project := config.Project
+	if project == "" {
+		project = os.Getenv("OPENAI_PROJECT")
+	}
+
 	logger.WithFields(log.Fields{
-		"endpoint":     endpoint,
-		"has_api_key":  apiKey != "",
-		"model":        model,
-		"params_count": len(config.Parameters),
+		"endpoint":         endpoint,
+		"has_api_key":      apiKey != "",
+		"model":            model,
+		"has_organization": organization != "",
+		"has_project":      project != "",
+		"params_count":     len(config.Parameters),
 	}).Debug("GPT classifier initialized")
 
 	return &GPTClassifier{
-		apiKey:     apiKey,
-		model:      model,
-		endpoint:   endpoint,
-		parameters: config.Parameters,
+		apiKey:       apiKey,
+		model:        model,
+		visionModel:  visionModel,
+		endpoint:     endpoint,
+		organization: organization,
+		project:      project,
+		seed:         config.Seed,
+		headers:      config.Headers,
+		parameters:   config.Parameters,
+		retry:        retryOverridesFor(config),
 	}
 }
 
@@ -82,32 +113,93 @@ func (c *GPTClassifier) Configure(config ModelConfig) error {
 		logger.WithField("new_model", config.Model).Debug("Updating model")
 		c.model = config.Model
 	}
+	if config.VisionModel != "" {
+		logger.WithField("new_vision_model", config.VisionModel).Debug("Updating vision model")
+		c.visionModel = config.VisionModel
+	}
+	if config.Organization != "" {
+		logger.Debug("Updating organization")
+		c.organization = config.Organization
+	}
+	if config.Project != "" {
+		logger.Debug("Updating project")
+		c.project = config.Project
+	}
+	if config.Seed != nil {
+		logger.WithField("new_seed", *config.Seed).Debug("Updating seed")
+		c.seed = config.Seed
+	}
+	if config.Headers != nil {
+		logger.WithField("headers_count", len(config.Headers)).Debug("Updating headers")
+		c.headers = config.Headers
+	}
 	if config.Parameters != nil {
 		logger.WithField("params_count", len(config.Parameters)).Debug("Updating parameters")
 		c.parameters = config.Parameters
 	}
+	c.retry.update(config)
 
 	logger.Debug("Configuration updated successfully")
 	return nil
 }
 
+// modelName returns the model classification requests are sent to
+func (c *GPTClassifier) modelName() string { return c.model }
+
 type gptRequest struct {
 	Model       string       `json:"model"`
 	Messages    []gptMessage `json:"messages"`
-	Temperature float64      `json:"temperature,omitempty"`
+	Temperature float64      `json:"temperature"`
 	MaxTokens   int          `json:"max_tokens,omitempty"`
+	Seed        *int         `json:"seed,omitempty"`
+	// Stop lists sequences that end the completion
+	Stop []string `json:"stop,omitempty"`
+	// LogitBias maps token IDs to a bias between -100 and 100 added to their likelihood
+	LogitBias map[string]float64 `json:"logit_bias,omitempty"`
 }
 
 type gptMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role string `json:"role"`
+	// Content is either a plain string or a list of gptContentPart
+	Content interface{} `json:"content"`
+}
+
+type gptContentPart struct {
+	Type     string       `json:"type"`
+	Text     string       `json:"text,omitempty"`
+	ImageURL *gptImageURL `json:"image_url,omitempty"`
+}
+
+type gptImageURL struct {
+	URL string `json:"url"`
+}
+
+// buildGPTUserContent returns the user message content, attaching the image as a
+// base64 data URL content part when vision classification is requested
+func buildGPTUserContent(content string, options ClassificationOptions) (interface{}, bool) {
+	if !options.Vision || options.Document == nil || !strings.HasPrefix(options.Document.MediaType, "image/") {
+		return buildPrompt(content, options), false
+	}
+	dataURL := "data:" + options.Document.MediaType + ";base64," + base64.StdEncoding.EncodeToString(options.Document.Data)
+	return []gptContentPart{
+		{
+			Type: "text",
+			Text: buildPrompt(attachedImageText, options),
+		},
+		{
+			Type:     "image_url",
+			ImageURL: &gptImageURL{URL: dataURL},
+		},
+	}, true
 }
 
 type gptResponse struct {
 	Choices []struct {
 		Message struct {
 			Content string `json:"content"`
+			Refusal string `json:"refusal"`
 		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
 	Error struct {
 		Message string `json:"message"`
@@ -131,63 +223,45 @@ func (c *GPTClassifier) ClassifyWithOptions(content string, options Classificati
 		"has_categories": len(options.Categories) > 0,
 	})
 	logger.Debug("Starting content classification")
+	start := time.Now()
 
 	if c.apiKey == "" {
 		logger.Error("Missing API key")
 		return nil, fmt.Errorf("OpenAI API key is required")
 	}
 
-	var prompt string
-	if len(options.Categories) > 0 {
-		categoriesStr := strings.Join(options.Categories, ", ")
-		prompt = fmt.Sprintf(`Analyze the following text and classify it into one of these categories: %s
-
-Provide a JSON response with these fields:
-	- category: One of the categories listed above that best matches the content
-	- confidence: A confidence score between 0 and 1 indicating how well the content matches the chosen category
-	- summary: A brief summary of the content (max 100 words)
-	- keywords: Up to 5 key terms or phrases from the content
-
-Text to analyze:
-%s`, categoriesStr, content)
-	} else {
-		prompt = fmt.Sprintf(`Analyze the following text and provide a JSON response with these fields:
-	- category: The main category/topic of the content
-	- confidence: A confidence score between 0 and 1
-	- summary: A brief summary of the content (max 100 words)
-	- keywords: Up to 5 key terms or phrases from the content
-
-Text to analyze:
-%s`, content)
+	userContent, vision := buildGPTUserContent(content, options)
+	model := c.model
+	if vision {
+		model = c.visionModel
+		logger = logger.WithField("vision_model", model)
 	}
 
 	// Extract parameters from the config
 	temperature := 0.3 // default temperature
-	maxTokens := 2000  // default max tokens
-	if c.parameters != nil {
-		if temp, ok := c.parameters["temperature"].(float64); ok {
-			temperature = temp
-		}
-		if tokens, ok := c.parameters["max_tokens"].(int); ok {
-			maxTokens = tokens
-		}
+	maxTokens := IntParameter(c.parameters, "max_tokens", defaultMaxTokens)
+	if temp, ok := c.parameters["temperature"].(float64); ok {
+		temperature = temp
 	}
 
 	logger.Debug("Preparing API request")
 	reqBody := gptRequest{
-		Model: c.model,
+		Model: model,
 		Messages: []gptMessage{
 			{
 				Role:    "system",
-				Content: "You are a content classification expert. Always respond in valid JSON format.",
+				Content: systemMessage(options),
 			},
 			{
 				Role:    "user",
-				Content: prompt,
+				Content: userContent,
 			},
 		},
 		Temperature: temperature,
 		MaxTokens:   maxTokens,
+		Seed:        c.seed,
+		Stop:        stringsParameter(c.parameters, "stop"),
+		LogitBias:   floatMapParameter(c.parameters, "logit_bias"),
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -197,14 +271,15 @@ Text to analyze:
 	}
 
 	logger.WithFields(log.Fields{
-		"request_body":          string(jsonBody),
-		"model":                 c.model,
+		"request_body":          loggableBody(jsonBody),
+		"model":                 model,
 		"temperature":           temperature,
 		"max_tokens":            maxTokens,
+		"seed":                  c.seed,
 		"predefined_categories": options.Categories,
 	}).Debug("Request payload prepared")
 
-	req, err := http.NewRequest("POST", c.endpoint, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(requestContext(options), "POST", c.endpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		logger.WithError(err).Error("Failed to create request")
 		return nil, fmt.Errorf("error creating request: %w", err)
@@ -212,10 +287,16 @@ Text to analyze:
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if c.organization != "" {
+		req.Header.Set("OpenAI-Organization", c.organization)
+	}
+	if c.project != "" {
+		req.Header.Set("OpenAI-Project", c.project)
+	}
+	setCustomHeaders(req, c.headers, logger)
 
 	logger.Debug("Sending request to OpenAI API")
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, retries, err := sendRequest(req, c.retry, logger)
 	if err != nil {
 		logger.WithError(err).Error("API request failed")
 		return nil, fmt.Errorf("error making request: %w", err)
@@ -246,7 +327,7 @@ Text to analyze:
 			"error_code":    gptResp.Error.Code,
 			"error_message": errorMsg,
 			"request_id":    resp.Header.Get("X-Request-Id"),
-			"request_body":  string(jsonBody),
+			"request_body":  loggableBody(jsonBody),
 		}).Error("API request failed")
 
 		return nil, fmt.Errorf("API request failed: %s (type: %s, code: %s)",
@@ -257,34 +338,30 @@ Text to analyze:
 		logger.Error("No classification result received")
 		return nil, fmt.Errorf("no classification result received")
 	}
+	if err := checkCompletion(gptResp.Choices[0].FinishReason, gptResp.Choices[0].Message.Refusal, logger); err != nil {
+		return nil, err
+	}
 
 	logger.Debug("Parsing classification result")
 	var classification Classification
-	if err := json.Unmarshal([]byte(gptResp.Choices[0].Message.Content), &classification); err != nil {
+	if err := json.Unmarshal([]byte(extractJSON(gptResp.Choices[0].Message.Content)), &classification); err != nil {
 		logger.WithFields(log.Fields{
 			"raw_content": gptResp.Choices[0].Message.Content,
 		}).WithError(err).Error("Failed to parse classification")
 		return nil, fmt.Errorf("error parsing classification: %w", err)
 	}
+	if options.DebugIncludeRaw {
+		classification.RawResponse = gptResp.Choices[0].Message.Content
+	}
 
-	// Validate category if predefined categories were provided
-	if len(options.Categories) > 0 {
-		categoryValid := false
-		for _, validCategory := range options.Categories {
-			if strings.EqualFold(classification.Category, validCategory) {
-				classification.Category = validCategory // Use exact case from predefined list
-				categoryValid = true
-				break
-			}
-		}
-		if !categoryValid {
-			logger.WithFields(log.Fields{
-				"received_category": classification.Category,
-				"valid_categories":  options.Categories,
-			}).Error("Classification returned invalid category")
-			return nil, fmt.Errorf("classifier returned invalid category: %s", classification.Category)
-		}
+	postprocess(&classification, content, options)
+
+	// Validate category if predefined categories or a taxonomy were provided
+	if err := validateCategory(&classification, options, logger); err != nil {
+		return nil, err
 	}
+	classification.Metadata = newMetadata(start, retries, OpenAI, model)
+	classification.setUsage(parseUsage(respBody), model)
 
 	logger.WithFields(log.Fields{
 		"category":                   classification.Category,
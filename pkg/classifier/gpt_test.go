@@ -0,0 +1,124 @@
+package classifier
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// gptReply is a minimal successful chat completion carrying a classification
+const gptReply = `{"choices":[{"message":{"content":"{\"category\":\"Invoice\",\"confidence\":0.9,\"summary\":\"s\",\"keywords\":[\"k\"]}"},"finish_reason":"stop"}],"usage":{"prompt_tokens":100,"completion_tokens":20,"total_tokens":120}}`
+
+func TestGPTOrganizationAndProjectHeaders(t *testing.T) {
+	tests := []struct {
+		name         string
+		organization string
+		project      string
+	}{
+		{name: "configured", organization: "org-123", project: "proj-456"},
+		{name: "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPENAI_ORG_ID", "")
+			t.Setenv("OPENAI_PROJECT", "")
+
+			var header http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Clone()
+				w.Write([]byte(gptReply))
+			}))
+			defer server.Close()
+
+			c := NewGPTClassifier(ModelConfig{
+				Endpoint:     server.URL,
+				APIKey:       "key",
+				Organization: tt.organization,
+				Project:      tt.project,
+			})
+			if _, err := c.Classify("some text"); err != nil {
+				t.Fatalf("Classify: %v", err)
+			}
+
+			for name, want := range map[string]string{
+				"OpenAI-Organization": tt.organization,
+				"OpenAI-Project":      tt.project,
+			} {
+				values, sent := header[name]
+				if want == "" && sent {
+					t.Errorf("%s sent as %q, want it left out", name, values)
+				}
+				if want != "" && header.Get(name) != want {
+					t.Errorf("%s = %q, want %q", name, header.Get(name), want)
+				}
+			}
+		})
+	}
+}
+
+func TestGPTOrganizationAndProjectFromEnvironment(t *testing.T) {
+	t.Setenv("OPENAI_ORG_ID", "org-env")
+	t.Setenv("OPENAI_PROJECT", "proj-env")
+
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.Write([]byte(gptReply))
+	}))
+	defer server.Close()
+
+	c := NewGPTClassifier(ModelConfig{Endpoint: server.URL, APIKey: "key"})
+	if _, err := c.Classify("some text"); err != nil {
+		t.Fatalf("Classify: %v", err)
+	}
+	if got := header.Get("OpenAI-Organization"); got != "org-env" {
+		t.Errorf("OpenAI-Organization = %q, want org-env", got)
+	}
+	if got := header.Get("OpenAI-Project"); got != "proj-env" {
+		t.Errorf("OpenAI-Project = %q, want proj-env", got)
+	}
+}
+
+func TestGPTVisionUsesImagePartAndVisionModel(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nimage")
+	tests := []struct {
+		name      string
+		options   ClassificationOptions
+		wantModel string
+		wantImage bool
+	}{
+		{
+			name:      "vision",
+			options:   ClassificationOptions{Vision: true, Document: &Document{MediaType: "image/png", Data: png}},
+			wantModel: "gpt-4o-mini",
+			wantImage: true,
+		},
+		{name: "vision not requested", options: ClassificationOptions{Document: &Document{MediaType: "image/png", Data: png}}, wantModel: "gpt-4"},
+		{name: "not an image", options: ClassificationOptions{Vision: true, Document: &Document{MediaType: "application/pdf", Data: png}}, wantModel: "gpt-4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]interface{}
+			server := captureBody(t, gptReply, &body)
+			c := NewGPTClassifier(ModelConfig{Endpoint: server.URL, APIKey: "key", Model: "gpt-4", VisionModel: "gpt-4o-mini"})
+			if _, err := c.ClassifyWithOptions("ocr text", tt.options); err != nil {
+				t.Fatalf("ClassifyWithOptions: %v", err)
+			}
+			if body["model"] != tt.wantModel {
+				t.Errorf("model = %v, want %s", body["model"], tt.wantModel)
+			}
+			messages, _ := body["messages"].([]interface{})
+			encoded, _ := json.Marshal(messages[len(messages)-1])
+			wantURL := `"url":"data:image/png;base64,` + base64.StdEncoding.EncodeToString(png) + `"`
+			if got := strings.Contains(string(encoded), wantURL); got != tt.wantImage {
+				t.Errorf("image data URL sent = %v, want %v: %s", got, tt.wantImage, encoded)
+			}
+			if got := strings.Contains(string(encoded), "ocr text"); got == tt.wantImage {
+				t.Errorf("extracted text sent = %v, want %v", got, !tt.wantImage)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package classifier
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TransportConfig tunes the HTTP transport shared by all classifiers
+type TransportConfig struct {
+	// Maximum idle connections kept across all providers
+	MaxIdleConns int
+	// Maximum idle connections kept per provider host
+	MaxIdleConnsPerHost int
+	// How long an idle connection stays in the pool
+	IdleConnTimeout time.Duration
+	// Timeout for establishing a TCP connection
+	DialTimeout time.Duration
+	// Timeout for the TLS handshake
+	TLSHandshakeTimeout time.Duration
+}
+
+// DefaultTransportConfig returns transport settings suited to sustained traffic against a few provider hosts
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 32,
+		IdleConnTimeout:     90 * time.Second,
+		DialTimeout:         10 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+var (
+	httpClientMu sync.RWMutex
+	httpClient   = newHTTPClient(DefaultTransportConfig())
+)
+
+// newHTTPClient builds a client with a keep-alive, HTTP/2-enabled transport
+func newHTTPClient(config TransportConfig) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   config.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          config.MaxIdleConns,
+			MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+			IdleConnTimeout:       config.IdleConnTimeout,
+			TLSHandshakeTimeout:   config.TLSHandshakeTimeout,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}
+}
+
+// ConfigureTransport replaces the shared HTTP client used for all provider requests
+func ConfigureTransport(config TransportConfig) {
+	client := newHTTPClient(config)
+
+	httpClientMu.Lock()
+	defer httpClientMu.Unlock()
+	httpClient.CloseIdleConnections()
+	httpClient = client
+}
+
+// sharedHTTPClient returns the HTTP client shared by all classifiers
+func sharedHTTPClient() *http.Client {
+	httpClientMu.RLock()
+	defer httpClientMu.RUnlock()
+	return httpClient
+}
+
+// maxRetryAfter caps how long we are willing to wait on a provider's Retry-After hint
+const maxRetryAfter = 60 * time.Second
+
+// parseRetryAfter parses a Retry-After header value, which may be either a number of
+// seconds or an HTTP-date (RFC 1123). The returned delay is capped at maxRetryAfter.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = date.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+	} else {
+		return 0, false
+	}
+
+	if delay > maxRetryAfter {
+		delay = maxRetryAfter
+	}
+	return delay, true
+}
+
+// BaseURLEnvVars name the environment variables that override a provider's endpoint
+// when ModelConfig.Endpoint is empty, e.g. to route every provider through a gateway.
+// OpenAI, Anthropic and Gemini take a base URL to which the API path is appended,
+// following their SDKs; Azure and custom providers take the full endpoint URL.
+var BaseURLEnvVars = map[Provider]string{
+	OpenAI:    "OPENAI_BASE_URL",
+	Anthropic: "ANTHROPIC_BASE_URL",
+	Gemini:    "GEMINI_BASE_URL",
+	Azure:     "AZURE_OPENAI_BASE_URL",
+	Custom:    "CUSTOM_BASE_URL",
+}
+
+// providerEndpoint returns the endpoint of provider for a config without one: the base
+// URL from the provider's environment variable followed by path, or fallback when the
+// variable is unset
+func providerEndpoint(provider Provider, path, fallback string) string {
+	if base := strings.TrimRight(os.Getenv(BaseURLEnvVars[provider]), "/"); base != "" {
+		return base + path
+	}
+	return fallback
+}
+
+// protectedHeaders are the authentication headers that ModelConfig.Headers cannot override
+var protectedHeaders = map[string]bool{
+	"Authorization":  true,
+	"Api-Key":        true,
+	"X-Api-Key":      true,
+	"X-Goog-Api-Key": true,
+}
+
+// setCustomHeaders adds the configured extra headers to req, skipping authentication headers
+func setCustomHeaders(req *http.Request, headers map[string]string, logger *log.Entry) {
+	for name, value := range headers {
+		if protectedHeaders[http.CanonicalHeaderKey(name)] {
+			logger.WithField("header", name).Warn("Ignoring custom header that would override authentication")
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+}
+
+// rewindableBody makes sure every attempt of req can send the full body. Requests built
+// from a bytes.Buffer, bytes.Reader or strings.Reader already know how to recreate their
+// body; any other body is read into memory once and replayed from there.
+func rewindableBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return fmt.Errorf("error buffering request body: %w", err)
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.Body, _ = req.GetBody()
+	req.ContentLength = int64(len(data))
+	return nil
+}
+
+// requestContext returns the context provider requests for options are made with
+func requestContext(options ClassificationOptions) context.Context {
+	if options.Context != nil {
+		return options.Context
+	}
+	return context.Background()
+}
+
+// sleepContext waits for d on clock, returning early with the context's error when ctx
+// is done first. Only the system clock can be interrupted; other clocks sleep in full.
+func sleepContext(ctx context.Context, clock Clock, d time.Duration) error {
+	if _, ok := clock.(systemClock); !ok {
+		clock.Sleep(d)
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryableStatus are the response codes of transient provider failures
+var retryableStatus = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+}
+
+// sendRequest sends the request and, when the provider answers 429, 500, 502 or 503 or
+// the request fails with a network error, waits and tries again up to the configured
+// number of retries. The wait is the provider's Retry-After hint when it sends one,
+// otherwise an exponential backoff with jitter. Other errors are returned at once. Once
+// the retries are used up the last response or error is returned, together with the
+// number of retries made. Cancelling the request's context ends the request and any wait
+// between attempts.
+func sendRequest(req *http.Request, overrides retryOverrides, logger *log.Entry) (*http.Response, int, error) {
+	client := sharedHTTPClient()
+	policy := currentRetryPolicy()
+	maxRetries, baseDelay := overrides.apply(policy)
+	if maxRetries > 0 {
+		if err := rewindableBody(req); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	attempt := req
+	for retries := 0; ; retries++ {
+		resp, err := client.Do(attempt)
+		retryable := err == nil && retryableStatus[resp.StatusCode] || err != nil && req.Context().Err() == nil
+		if !retryable || retries >= maxRetries {
+			return resp, retries, err
+		}
+
+		delay, ok := time.Duration(0), false
+		fields := log.Fields{"attempt": retries + 1}
+		if err == nil {
+			delay, ok = parseRetryAfter(resp.Header.Get("Retry-After"), policy.Clock.Now())
+			fields["status_code"] = resp.StatusCode
+			resp.Body.Close()
+		}
+		if !ok {
+			delay = policy.delay(baseDelay, retries+1)
+		}
+		fields["retry_after"] = delay.String()
+		logger.WithFields(fields).WithError(err).Warn("Transient provider failure, retrying after delay")
+		if err := sleepContext(req.Context(), policy.Clock, delay); err != nil {
+			return nil, retries + 1, err
+		}
+
+		attempt = req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, retries + 1, fmt.Errorf("error resetting request body: %w", err)
+			}
+			attempt.Body = body
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package classifier
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// fakeClock is a Clock standing still at now that records the waits instead of sleeping
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sleeps = append(c.sleeps, d)
+}
+
+// useRetries installs config with a fake clock for the rest of the test
+func useRetries(t *testing.T, config RetryConfig) *fakeClock {
+	t.Helper()
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	config.Clock = clock
+	ConfigureRetries(config)
+	t.Cleanup(func() { ConfigureRetries(DefaultRetryConfig()) })
+	return clock
+}
+
+// testLogger is passed to the helpers under test
+var testLogger = log.WithField("test", true)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{value: "2", want: 2 * time.Second, ok: true},
+		{value: " 0 ", want: 0, ok: true},
+		{value: now.Add(5 * time.Second).Format(http.TimeFormat), want: 5 * time.Second, ok: true},
+		{value: now.Add(-time.Minute).Format(http.TimeFormat), want: 0, ok: true},
+		{value: "120", want: maxRetryAfter, ok: true},
+		{value: now.Add(time.Hour).Format(http.TimeFormat), want: maxRetryAfter, ok: true},
+		{value: ""},
+		{value: "-1"},
+		{value: "soon"},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSendRequestHonorsRetryAfter(t *testing.T) {
+	// useRetries starts its clock at this time
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		retryAfter string
+		want       time.Duration
+	}{
+		{name: "seconds", retryAfter: "2", want: 2 * time.Second},
+		{name: "HTTP-date", retryAfter: now.Add(7 * time.Second).Format(http.TimeFormat), want: 7 * time.Second},
+		{name: "capped", retryAfter: "3600", want: maxRetryAfter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := useRetries(t, RetryConfig{MaxRetries: 1, BaseDelay: time.Millisecond})
+
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if attempts == 1 {
+					w.Header().Set("Retry-After", tt.retryAfter)
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.Write([]byte("ok"))
+			}))
+			defer server.Close()
+
+			req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("{}"))
+			resp, retries, err := sendRequest(req, retryOverrides{}, testLogger)
+			if err != nil {
+				t.Fatalf("sendRequest: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || retries != 1 {
+				t.Fatalf("status %d after %d retries, want 200 after 1", resp.StatusCode, retries)
+			}
+			if len(clock.sleeps) != 1 || clock.sleeps[0] != tt.want {
+				t.Errorf("slept %v, want [%v]", clock.sleeps, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendRequestRetriesTransientFailures(t *testing.T) {
+	clock := useRetries(t, RetryConfig{MaxRetries: 0, BaseDelay: time.Second})
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(gptReply))
+	}))
+	defer server.Close()
+
+	// The classifier's own settings override the shared ones, which disable retries
+	c := NewGPTClassifier(ModelConfig{
+		Endpoint:       server.URL,
+		APIKey:         "key",
+		MaxRetries:     3,
+		RetryBaseDelay: 100 * time.Millisecond,
+	})
+	classification, err := c.Classify("some text")
+	if err != nil {
+		t.Fatalf("Classify: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+	if classification.Category != "Invoice" || classification.Metadata.Retries != 2 {
+		t.Errorf("category %q after %d retries, want Invoice after 2", classification.Category, classification.Metadata.Retries)
+	}
+	if len(clock.sleeps) != 2 {
+		t.Fatalf("slept %v, want 2 backoffs", clock.sleeps)
+	}
+	// Backoff doubles with jitter between half and all of the delay
+	for i, limit := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond} {
+		if clock.sleeps[i] < limit/2 || clock.sleeps[i] > limit {
+			t.Errorf("backoff %d = %v, want between %v and %v", i+1, clock.sleeps[i], limit/2, limit)
+		}
+	}
+}
+
+func TestRetryDelayJitterIsReproducible(t *testing.T) {
+	delays := func(seed int64) []time.Duration {
+		p := newRetryPolicy(RetryConfig{BaseDelay: time.Second, MaxDelay: 5 * time.Second, Source: rand.NewSource(seed)})
+		var got []time.Duration
+		for attempt := 1; attempt <= 5; attempt++ {
+			got = append(got, p.delay(p.BaseDelay, attempt))
+		}
+		return got
+	}
+
+	first, again, other := delays(7), delays(7), delays(8)
+	if !slices.Equal(first, again) {
+		t.Errorf("delays with the same seed = %v and %v, want them equal", first, again)
+	}
+	if slices.Equal(first, other) {
+		t.Errorf("delays with different seeds are both %v, want them to differ", first)
+	}
+	// 1s, 2s, 4s, then capped at 5s, each with jitter between half and all of it
+	for i, limit := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if first[i] < limit/2 || first[i] > limit {
+			t.Errorf("delay %d = %v, want between %v and %v", i+1, first[i], limit/2, limit)
+		}
+	}
+	if d := newRetryPolicy(RetryConfig{}).delay(0, 1); d != 0 {
+		t.Errorf("delay without a base delay = %v, want 0", d)
+	}
+}
+
+func TestSendRequestGivesUpAfterMaxRetries(t *testing.T) {
+	useRetries(t, RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond})
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("{}"))
+	resp, retries, err := sendRequest(req, retryOverrides{}, testLogger)
+	if err != nil {
+		t.Fatalf("sendRequest: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || retries != 2 || attempts != 3 {
+		t.Errorf("status %d after %d retries and %d attempts, want 502 after 2 and 3", resp.StatusCode, retries, attempts)
+	}
+}
+
+func TestSendRequestDoesNotRetryClientErrors(t *testing.T) {
+	useRetries(t, RetryConfig{MaxRetries: 3, BaseDelay: time.Millisecond})
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("{}"))
+	resp, retries, err := sendRequest(req, retryOverrides{}, testLogger)
+	if err != nil {
+		t.Fatalf("sendRequest: %v", err)
+	}
+	resp.Body.Close()
+	if retries != 0 || attempts != 1 {
+		t.Errorf("%d retries and %d attempts, want none and 1", retries, attempts)
+	}
+}
+
+func TestSendRequestResendsBodyWithoutGetBody(t *testing.T) {
+	useRetries(t, RetryConfig{MaxRetries: 1, BaseDelay: time.Millisecond})
+
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	payload := `{"model":"gpt-4","messages":[{"role":"user","content":"classify me"}]}`
+	// A reader of unknown type leaves GetBody nil, unlike bytes.Buffer or strings.Reader
+	req, _ := http.NewRequest(http.MethodPost, server.URL, io.MultiReader(strings.NewReader(payload)))
+	if req.GetBody != nil {
+		t.Fatal("GetBody is set, the test needs a request without it")
+	}
+	resp, retries, err := sendRequest(req, retryOverrides{}, testLogger)
+	if err != nil {
+		t.Fatalf("sendRequest: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || retries != 1 {
+		t.Fatalf("status %d after %d retries, want 200 after 1", resp.StatusCode, retries)
+	}
+	if len(bodies) != 2 || bodies[0] != payload || bodies[1] != payload {
+		t.Errorf("bodies = %q, want the full payload twice", bodies)
+	}
+}
+
+func TestConfigureTransportReusesConnections(t *testing.T) {
+	httpClientMu.RLock()
+	previous := httpClient
+	httpClientMu.RUnlock()
+	t.Cleanup(func() {
+		httpClientMu.Lock()
+		httpClient = previous
+		httpClientMu.Unlock()
+	})
+
+	config := DefaultTransportConfig()
+	config.MaxIdleConnsPerHost = 4
+	config.IdleConnTimeout = 5 * time.Second
+	ConfigureTransport(config)
+
+	transport, ok := sharedHTTPClient().Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("shared client transport is %T, want *http.Transport", sharedHTTPClient().Transport)
+	}
+	if transport.MaxIdleConnsPerHost != 4 || transport.IdleConnTimeout != 5*time.Second || !transport.ForceAttemptHTTP2 {
+		t.Errorf("transport = idle per host %d, idle timeout %v, HTTP/2 %v; want 4, 5s, true",
+			transport.MaxIdleConnsPerHost, transport.IdleConnTimeout, transport.ForceAttemptHTTP2)
+	}
+
+	var mu sync.Mutex
+	connections := 0
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(gptReply))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			connections++
+			mu.Unlock()
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	c := NewGPTClassifier(ModelConfig{Endpoint: server.URL, APIKey: "key", MaxRetries: -1})
+	for i := 0; i < 3; i++ {
+		if _, err := c.Classify("some text"); err != nil {
+			t.Fatalf("Classify: %v", err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if connections != 1 {
+		t.Errorf("%d connections opened for sequential requests, want 1 kept alive", connections)
+	}
+}
+
+func TestProviderBaseURLEnvVars(t *testing.T) {
+	t.Setenv("OPENAI_BASE_URL", "https://gateway.internal/openai/v1/")
+	t.Setenv("ANTHROPIC_BASE_URL", "https://gateway.internal/anthropic")
+	t.Setenv("AZURE_OPENAI_BASE_URL", "https://example.openai.azure.com/openai/deployments/gpt-4o/chat/completions")
+	t.Setenv("CUSTOM_BASE_URL", "")
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"openai", NewGPTClassifier(ModelConfig{}).endpoint, "https://gateway.internal/openai/v1/chat/completions"},
+		{"openai embeddings", NewOpenAIEmbedder(ModelConfig{}).endpoint, "https://gateway.internal/openai/v1/embeddings"},
+		{"anthropic", NewAnthropicClassifier(ModelConfig{}).endpoint, "https://gateway.internal/anthropic/v1/messages"},
+		{"azure", NewAzureClassifier(ModelConfig{}).endpoint, "https://example.openai.azure.com/openai/deployments/gpt-4o/chat/completions"},
+		{"custom unset", NewCustomClassifier(ModelConfig{}).endpoint, ""},
+		{"configured endpoint wins", NewGPTClassifier(ModelConfig{Endpoint: "https://llm.internal/v1/chat"}).endpoint, "https://llm.internal/v1/chat"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s endpoint = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	t.Setenv("OPENAI_BASE_URL", "")
+	if got := NewGPTClassifier(ModelConfig{}).endpoint; got != "https://api.openai.com/v1/chat/completions" {
+		t.Errorf("endpoint without a base URL = %q, want the OpenAI default", got)
+	}
+}
+
+func TestCustomHeadersSentWithoutOverridingAuthentication(t *testing.T) {
+	headers := map[string]string{
+		"X-Tenant":      "acme",
+		"traceparent":   "00-abc-def-01",
+		"Authorization": "Bearer stolen",
+		"x-api-key":     "stolen",
+	}
+	tests := []struct {
+		name     string
+		reply    string
+		classify func(endpoint string) (*Classification, error)
+		authName string
+	}{
+		{
+			name:  "openai",
+			reply: gptReply,
+			classify: func(endpoint string) (*Classification, error) {
+				return NewGPTClassifier(ModelConfig{Endpoint: endpoint, APIKey: "key", Headers: headers}).Classify("some text")
+			},
+			authName: "Authorization",
+		},
+		{
+			name:  "anthropic",
+			reply: anthropicReply,
+			classify: func(endpoint string) (*Classification, error) {
+				return NewAnthropicClassifier(ModelConfig{Endpoint: endpoint, APIKey: "key", Headers: headers}).Classify("some text")
+			},
+			authName: "X-Api-Key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Clone()
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tt.reply))
+			}))
+			defer server.Close()
+
+			if _, err := tt.classify(server.URL); err != nil {
+				t.Fatalf("Classify: %v", err)
+			}
+			if got := header.Get("X-Tenant"); got != "acme" {
+				t.Errorf("X-Tenant = %q, want acme", got)
+			}
+			if got := header.Get("Traceparent"); got != "00-abc-def-01" {
+				t.Errorf("traceparent = %q, want 00-abc-def-01", got)
+			}
+			if got := header.Get(tt.authName); strings.Contains(got, "stolen") {
+				t.Errorf("%s = %q, custom headers must not override authentication", tt.authName, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package classifier
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// IncrementalClassifier re-classifies growing documents (such as log files) only
+// once enough new content has been appended since the last classification
+type IncrementalClassifier struct {
+	classifier Classifier
+	// Threshold is the number of appended bytes required before re-classifying
+	Threshold int
+
+	mu        sync.Mutex
+	documents map[string]*incrementalState
+}
+
+type incrementalState struct {
+	hash           [sha256.Size]byte
+	length         int
+	classification *Classification
+}
+
+// NewIncrementalClassifier wraps classifier so documents are only re-classified
+// after at least threshold bytes have been appended
+func NewIncrementalClassifier(classifier Classifier, threshold int) *IncrementalClassifier {
+	return &IncrementalClassifier{
+		classifier: classifier,
+		Threshold:  threshold,
+		documents:  make(map[string]*incrementalState),
+	}
+}
+
+// ClassifyDocument classifies the current content of the document identified by id.
+// The cached classification is returned unchanged while the content only grew by less
+// than Threshold bytes; any edit to previously classified text forces a re-run. The
+// boolean result reports whether the underlying classifier was called.
+func (c *IncrementalClassifier) ClassifyDocument(id, content string, options ClassificationOptions) (*Classification, bool, error) {
+	logger := log.WithFields(log.Fields{
+		"function":    "ClassifyDocument",
+		"document_id": id,
+		"length":      len(content),
+	})
+
+	c.mu.Lock()
+	state, ok := c.documents[id]
+	c.mu.Unlock()
+
+	if ok && len(content) >= state.length && sha256.Sum256([]byte(content[:state.length])) == state.hash {
+		appended := len(content) - state.length
+		if appended < c.Threshold {
+			logger.WithField("appended", appended).Debug("Below re-classification threshold, using cached result")
+			return state.classification, false, nil
+		}
+	}
+
+	classification, err := c.classifier.ClassifyWithOptions(content, options)
+	if err != nil {
+		return nil, false, err
+	}
+
+	c.mu.Lock()
+	c.documents[id] = &incrementalState{
+		hash:           sha256.Sum256([]byte(content)),
+		length:         len(content),
+		classification: classification,
+	}
+	c.mu.Unlock()
+
+	logger.Debug("Document re-classified")
+	return classification, true, nil
+}
+
+// Forget drops the cached state for the document identified by id
+func (c *IncrementalClassifier) Forget(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.documents, id)
+}
@@ -0,0 +1,243 @@
+package classifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/adaptive-scale/superclass/pkg/textutil"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrClassificationTimeout is returned when the provider did not answer within
+// ClassificationOptions.Timeout and no local fallback was requested
+var ErrClassificationTimeout = errors.New("classification timed out")
+
+// MaxHeuristicConfidence caps the confidence of local classifications, so callers
+// filtering on confidence treat them as low-confidence
+const MaxHeuristicConfidence = 0.5
+
+// UncategorizedCategory is returned by the local classifier when no category is
+// requested and no rule matches
+const UncategorizedCategory = "Uncategorized"
+
+// LocalFallbackClassifier classifies content without a model by counting the
+// occurrences of each category's name, aliases and rule keywords. Results are flagged
+// as heuristic in their metadata.
+type LocalFallbackClassifier struct {
+	// Rules maps category names to additional keywords that indicate them. When no
+	// categories are requested, the rule categories are the candidates.
+	Rules map[string][]string
+}
+
+// NewLocalFallbackClassifier creates a local classifier using rules
+func NewLocalFallbackClassifier(rules map[string][]string) *LocalFallbackClassifier {
+	return &LocalFallbackClassifier{Rules: rules}
+}
+
+// Configure is a no-op; the local classifier has no provider settings
+func (c *LocalFallbackClassifier) Configure(config ModelConfig) error {
+	return nil
+}
+
+// Classify takes text content and returns classification details
+func (c *LocalFallbackClassifier) Classify(content string) (*Classification, error) {
+	return c.ClassifyWithOptions(content, ClassificationOptions{})
+}
+
+// ClassifyWithOptions picks the candidate category whose terms occur most often in
+// content. Confidence is the winner's share of all matches, scaled by
+// MaxHeuristicConfidence. Without any match it returns the first requested category,
+// or UncategorizedCategory, with zero confidence.
+func (c *LocalFallbackClassifier) ClassifyWithOptions(content string, options ClassificationOptions) (*Classification, error) {
+	logger := log.WithFields(log.Fields{
+		"function":       "ClassifyWithOptions",
+		"provider":       Local,
+		"content_length": len(content),
+	})
+	start := time.Now()
+
+	categories := promptCategories(options)
+	if len(categories) == 0 {
+		for name := range c.Rules {
+			categories = append(categories, name)
+		}
+		sort.Strings(categories)
+		categories = withoutExcluded(categories, options.ExcludeCategories)
+	}
+
+	counts := wordCounts(content)
+	scores := make(map[string]float64, len(categories))
+	total := 0.0
+	best, bestHits := -1, 0.0
+	for i, category := range categories {
+		hits := 0.0
+		for _, term := range c.terms(category, options.CategorySet) {
+			hits += float64(termCount(counts, term))
+		}
+		scores[category] = hits
+		total += hits
+		if hits > bestHits {
+			best, bestHits = i, hits
+		}
+	}
+
+	classification := Classification{Category: UncategorizedCategory}
+	switch {
+	case best >= 0:
+		classification.Category = categories[best]
+		classification.Confidence = bestHits / total * MaxHeuristicConfidence
+	case len(categories) > 0 && (len(options.Categories) > 0 || options.CategorySet != nil):
+		classification.Category = categories[0]
+	}
+	if wantsScores(options) {
+		for category, hits := range scores {
+			if total > 0 {
+				scores[category] = hits / total * MaxHeuristicConfidence
+			}
+		}
+		classification.Scores = scores
+	}
+	classification.Keywords = textutil.ExtractKeywordsLocal(content, 5)
+
+	postprocess(&classification, content, options)
+	if classification.Category != UncategorizedCategory {
+		if err := validateCategory(&classification, options, logger); err != nil {
+			return nil, err
+		}
+	}
+	classification.Metadata = newMetadata(start, 0, Local, "")
+	classification.Metadata.Heuristic = true
+
+	logger.WithFields(log.Fields{
+		"category":   classification.Category,
+		"confidence": classification.Confidence,
+	}).Debug("Local classification completed")
+	return &classification, nil
+}
+
+// terms returns the lowercased words and phrases that indicate category: its name,
+// its taxonomy aliases and its rule keywords
+func (c *LocalFallbackClassifier) terms(category string, set *CategorySet) []string {
+	terms := []string{category}
+	if set != nil {
+		terms = append(terms, set.byName[category].Aliases...)
+	}
+	for name, keywords := range c.Rules {
+		if strings.EqualFold(name, category) {
+			terms = append(terms, keywords...)
+		}
+	}
+	for i, term := range terms {
+		terms[i] = strings.Join(words(term), " ")
+	}
+	return terms
+}
+
+// words splits text into lowercased letter and digit runs
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// wordCounts counts the words and two- and three-word phrases of text
+func wordCounts(text string) map[string]int {
+	tokens := words(text)
+	counts := make(map[string]int)
+	for i := range tokens {
+		for n := 1; n <= 3 && i+n <= len(tokens); n++ {
+			counts[strings.Join(tokens[i:i+n], " ")]++
+		}
+	}
+	return counts
+}
+
+// termCount returns the occurrences of term, also counting a trailing "s" so that
+// "invoice" matches "invoices". Terms longer than three words never match.
+func termCount(counts map[string]int, term string) int {
+	if term == "" {
+		return 0
+	}
+	n := counts[term]
+	if strings.HasSuffix(term, "s") {
+		return n + counts[strings.TrimSuffix(term, "s")]
+	}
+	return n + counts[term+"s"]
+}
+
+// fallbackClassifier bounds classifications by ClassificationOptions.Timeout and
+// replaces failed or timed-out ones with a local classification when
+// ClassificationOptions.LocalFallback is set
+type fallbackClassifier struct {
+	Classifier
+}
+
+// ClassifyWithOptions classifies content with the wrapped classifier, falling back to
+// LocalFallbackClassifier as configured by options
+func (c fallbackClassifier) ClassifyWithOptions(content string, options ClassificationOptions) (*Classification, error) {
+	if options.Timeout <= 0 && !options.LocalFallback {
+		return c.Classifier.ClassifyWithOptions(content, options)
+	}
+
+	classification, err := c.classifyWithTimeout(content, options)
+	if err == nil || !options.LocalFallback {
+		return classification, err
+	}
+
+	log.WithFields(log.Fields{
+		"function": "ClassifyWithOptions",
+	}).WithError(err).Warn("Classification failed, falling back to local classifier")
+	local, localErr := NewLocalFallbackClassifier(options.LocalRules).ClassifyWithOptions(content, options)
+	if localErr != nil {
+		return nil, fmt.Errorf("%w (local fallback: %v)", err, localErr)
+	}
+	local.Metadata.FallbackReason = err.Error()
+	return local, nil
+}
+
+// Classify takes text content and returns classification details
+func (c fallbackClassifier) Classify(content string) (*Classification, error) {
+	return c.ClassifyWithOptions(content, ClassificationOptions{})
+}
+
+// classifyWithTimeout runs the wrapped classifier, giving up after options.Timeout and
+// cancelling the provider request through options.Context
+func (c fallbackClassifier) classifyWithTimeout(content string, options ClassificationOptions) (*Classification, error) {
+	if options.Timeout <= 0 {
+		return c.Classifier.ClassifyWithOptions(content, options)
+	}
+
+	ctx, cancel := context.WithTimeout(requestContext(options), options.Timeout)
+	defer cancel()
+	options.Context = ctx
+
+	type outcome struct {
+		classification *Classification
+		err            error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		classification, err := c.Classifier.ClassifyWithOptions(content, options)
+		done <- outcome{classification, err}
+	}()
+
+	// Classifiers that cannot be cancelled, such as the embedding classifier, are abandoned
+	// and finish in the background
+	select {
+	case result := <-done:
+		if result.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			break
+		}
+		return result.classification, result.err
+	case <-ctx.Done():
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, fmt.Errorf("%w after %s", ErrClassificationTimeout, options.Timeout)
+}
@@ -0,0 +1,71 @@
+package classifier
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutCancelsProviderRequest(t *testing.T) {
+	cancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices a closed connection once the body has been read
+		io.ReadAll(r.Body)
+		select {
+		case <-r.Context().Done():
+			close(cancelled)
+		case <-time.After(5 * time.Second):
+			w.Write([]byte(gptReply))
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewClassifier(OpenAI, ModelConfig{Endpoint: server.URL, APIKey: "key", MaxRetries: -1})
+	if err != nil {
+		t.Fatalf("NewClassifier: %v", err)
+	}
+	_, err = c.ClassifyWithOptions("some text", ClassificationOptions{Timeout: 50 * time.Millisecond})
+	if !errors.Is(err, ErrClassificationTimeout) {
+		t.Fatalf("error = %v, want ErrClassificationTimeout", err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("provider request was not cancelled after the timeout")
+	}
+}
+
+func TestLocalFallbackOnFailingPrimary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"bad request"}}`, http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	c, err := NewClassifier(OpenAI, ModelConfig{Endpoint: server.URL, APIKey: "key", MaxRetries: -1})
+	if err != nil {
+		t.Fatalf("NewClassifier: %v", err)
+	}
+	classification, err := c.ClassifyWithOptions("Please pay the attached invoice by Friday. Invoice total: $40.", ClassificationOptions{
+		Categories:    []string{"Invoice", "Contract"},
+		LocalFallback: true,
+	})
+	if err != nil {
+		t.Fatalf("ClassifyWithOptions: %v", err)
+	}
+	if classification.Category != "Invoice" {
+		t.Errorf("category = %q, want Invoice", classification.Category)
+	}
+	if classification.Metadata == nil || !classification.Metadata.Heuristic {
+		t.Errorf("metadata = %+v, want a heuristic result", classification.Metadata)
+	}
+	if classification.Metadata != nil && classification.Metadata.FallbackReason == "" {
+		t.Error("fallback reason is empty")
+	}
+	if classification.Confidence > MaxHeuristicConfidence {
+		t.Errorf("confidence = %v, want at most %v", classification.Confidence, MaxHeuristicConfidence)
+	}
+}
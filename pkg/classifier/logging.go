@@ -0,0 +1,26 @@
+package classifier
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// MaxLoggedBodyChars limits how much of a request body is written to debug logs.
+// A value of 0 disables request body logging entirely.
+var MaxLoggedBodyChars = 2048
+
+// loggableBody returns the request body trimmed to MaxLoggedBodyChars bytes for logging,
+// cut at a rune boundary so multi-byte characters are not split
+func loggableBody(body []byte) string {
+	if MaxLoggedBodyChars <= 0 {
+		return "[omitted]"
+	}
+	if len(body) <= MaxLoggedBodyChars {
+		return string(body)
+	}
+	cut := MaxLoggedBodyChars
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...[truncated, %d bytes total]", body[:cut], len(body))
+}
@@ -2,6 +2,9 @@ package classifier
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
 )
 
 // ModelType represents a specific model from a provider
@@ -21,9 +24,17 @@ const (
 	Claude3Haiku  ModelType = "claude-3-haiku-20240229"
 	Claude2       ModelType = "claude-2.1"
 
+	// Google Gemini Models
+	Gemini15Pro   ModelType = "gemini-1.5-pro"
+	Gemini15Flash ModelType = "gemini-1.5-flash"
+
 	// Azure OpenAI Models (base names, deployment names are configured separately)
 	AzureGPT4       ModelType = "gpt-4"
 	AzureGPT35Turbo ModelType = "gpt-35-turbo"
+
+	// OpenAI embedding models, used by the embedding provider
+	TextEmbedding3Small ModelType = "text-embedding-3-small"
+	TextEmbedding3Large ModelType = "text-embedding-3-large"
 )
 
 // ModelCapability represents what a model is good at
@@ -106,6 +117,18 @@ var ModelCosts = map[ModelType]CostInfo{
 		BatchProcessingSupport:  true,
 		ConcurrentRequests:      5000,
 	},
+	Gemini15Pro: {
+		InputPerThousandTokens:  0.00125,
+		OutputPerThousandTokens: 0.005,
+		BatchProcessingSupport:  true,
+		ConcurrentRequests:      1000,
+	},
+	Gemini15Flash: {
+		InputPerThousandTokens:  0.000075,
+		OutputPerThousandTokens: 0.0003,
+		BatchProcessingSupport:  true,
+		ConcurrentRequests:      2000,
+	},
 }
 
 // DefaultModelParams returns recommended parameters for each model
@@ -134,6 +157,14 @@ var DefaultModelParams = map[ModelType]map[string]interface{}{
 		"top_k":       10,
 		"top_p":       0.8,
 	},
+	Gemini15Pro: {
+		"temperature": 0.7,
+		"max_tokens":  2000,
+	},
+	Gemini15Flash: {
+		"temperature": 0.7,
+		"max_tokens":  1000,
+	},
 }
 
 // ModelRegistry contains information about available models
@@ -201,6 +232,39 @@ var ModelRegistry = map[ModelType]ModelInfo{
 		Cost:         ModelCosts[Claude3Sonnet],
 		AvgLatencyMs: 1000,
 	},
+
+	// Google Gemini Models
+	Gemini15Pro: {
+		Type:     Gemini15Pro,
+		Provider: Gemini,
+		Capabilities: []ModelCapability{
+			HighAccuracy,
+			LongContext,
+			CodeAnalysis,
+			MultilingualSupport,
+			StructuredOutput,
+		},
+		MaxTokens:    2097152,
+		Description:  "Most capable Gemini model with a very long context window",
+		Parameters:   DefaultModelParams[Gemini15Pro],
+		Cost:         ModelCosts[Gemini15Pro],
+		AvgLatencyMs: 1800,
+	},
+	Gemini15Flash: {
+		Type:     Gemini15Flash,
+		Provider: Gemini,
+		Capabilities: []ModelCapability{
+			GeneralPurpose,
+			FastResponse,
+			LongContext,
+			MultilingualSupport,
+		},
+		MaxTokens:    1048576,
+		Description:  "Fast, low-cost Gemini model, good for high-volume classification",
+		Parameters:   DefaultModelParams[Gemini15Flash],
+		Cost:         ModelCosts[Gemini15Flash],
+		AvgLatencyMs: 600,
+	},
 }
 
 // EstimateCost calculates the estimated cost for processing text with a specific model
@@ -215,6 +279,39 @@ func EstimateCost(model ModelType, inputTokens, outputTokens int) float64 {
 	return inputCost + outputCost
 }
 
+// pricedModel returns the registry model that a provider-reported model name is priced
+// as: the name itself, or else the longest registry name it extends with a version
+// suffix, e.g. gpt-4 for gpt-4-0613. Azure's gpt-35 spelling is read as gpt-3.5. Names
+// matching nothing are returned unchanged and price at 0.
+func pricedModel(name string) ModelType {
+	name = strings.Replace(strings.ToLower(name), "gpt-35", "gpt-3.5", 1)
+	if _, ok := ModelRegistry[ModelType(name)]; ok {
+		return ModelType(name)
+	}
+	var best ModelType
+	for model := range ModelRegistry {
+		if strings.HasPrefix(name, string(model)+"-") && len(model) > len(best) {
+			best = model
+		}
+	}
+	if best == "" {
+		return ModelType(name)
+	}
+	return best
+}
+
+// EstimateClassificationCost prices the provider-reported token usage of a classification
+// of text, or approximates it from the text length and the size of the returned
+// classification when none was reported
+func EstimateClassificationCost(model ModelType, text string, classification *Classification) float64 {
+	if usage := classification.Usage; usage != nil {
+		return EstimateCost(pricedModel(string(model)), usage.PromptTokens, usage.CompletionTokens)
+	}
+	inputTokens := EstimateTokens(text) + PromptOverheadTokens
+	outputTokens := EstimateTokens(classification.Category + classification.Summary + strings.Join(classification.Keywords, " "))
+	return EstimateCost(pricedModel(string(model)), inputTokens, outputTokens)
+}
+
 // CompareModels compares two models and returns their differences
 type ModelComparison struct {
 	CostDiff           float64           // Difference in cost per 1K tokens
@@ -277,6 +374,7 @@ type ModelConstraints struct {
 	MinTokenLimit            int               // Minimum token limit required
 }
 
+// RecommendModel returns the models that satisfy the constraints, cheapest and then fastest first
 func RecommendModel(contentType ContentType, constraints ModelConstraints) []ModelType {
 	var recommendations []ModelType
 
@@ -337,9 +435,56 @@ func RecommendModel(contentType ContentType, constraints ModelConstraints) []Mod
 		}
 	}
 
+	// Order cheapest first, then fastest, so the first entry is the best fit
+	sort.Slice(recommendations, func(i, j int) bool {
+		a, b := ModelRegistry[recommendations[i]], ModelRegistry[recommendations[j]]
+		if a.Cost.InputPerThousandTokens != b.Cost.InputPerThousandTokens {
+			return a.Cost.InputPerThousandTokens < b.Cost.InputPerThousandTokens
+		}
+		if a.AvgLatencyMs != b.AvgLatencyMs {
+			return a.AvgLatencyMs < b.AvgLatencyMs
+		}
+		return recommendations[i] < recommendations[j]
+	})
+
 	return recommendations
 }
 
+// CharsPerToken is the average number of characters per token used for estimates
+const CharsPerToken = 4
+
+// PromptOverheadTokens approximates the instructions wrapped around the document
+const PromptOverheadTokens = 150
+
+// EstimateTokens approximates the number of tokens in text
+func EstimateTokens(text string) int {
+	return (len(text) + CharsPerToken - 1) / CharsPerToken
+}
+
+// TruncateToTokens cuts text to roughly maxTokens tokens, backing up to the last
+// whitespace so no word is split. The boolean reports whether text was cut.
+func TruncateToTokens(text string, maxTokens int) (string, bool) {
+	limit := maxTokens * CharsPerToken
+	if limit < 0 {
+		limit = 0
+	}
+	if len(text) <= limit {
+		return text, false
+	}
+	cut := text[:limit]
+	if i := strings.LastIndexAny(cut, " \t\n"); i > limit/2 {
+		cut = cut[:i]
+	}
+	// Drop a trailing partial UTF-8 sequence
+	for i := 0; i < utf8.UTFMax-1; i++ {
+		if r, size := utf8.DecodeLastRuneInString(cut); r != utf8.RuneError || size != 1 {
+			break
+		}
+		cut = cut[:len(cut)-1]
+	}
+	return cut, true
+}
+
 // GetModelInfo returns information about a specific model
 func GetModelInfo(modelType ModelType) (ModelInfo, bool) {
 	info, exists := ModelRegistry[modelType]
@@ -371,6 +516,21 @@ func GetModelsByProvider(provider Provider) []ModelInfo {
 	return models
 }
 
+// DefaultModels is the model used for each provider when none is configured
+var DefaultModels = map[Provider]ModelType{
+	OpenAI:    GPT4,
+	Anthropic: Claude3Opus,
+	Gemini:    Gemini15Flash,
+	Azure:     AzureGPT4,
+	Embedding: TextEmbedding3Small,
+}
+
+// DefaultModelForProvider returns the default model for the provider, or an empty
+// ModelType when the provider has no default (e.g. custom providers)
+func DefaultModelForProvider(provider Provider) ModelType {
+	return DefaultModels[provider]
+}
+
 // NewModelConfig creates a ModelConfig with default parameters for the specified model
 func NewModelConfig(modelType ModelType, apiKey string) ModelConfig {
 	info, exists := ModelRegistry[modelType]
@@ -0,0 +1,250 @@
+package classifier
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/adaptive-scale/superclass/pkg/textutil"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultLocalKeywords is the number of local keywords produced when MaxKeywords is unset
+const defaultLocalKeywords = 5
+
+// postprocess applies the options that are enforced locally after the model response is parsed
+func postprocess(classification *Classification, content string, options ClassificationOptions) {
+	classification.Normalize()
+	applyLocalKeywords(classification, content, options)
+	applyOutputLimits(classification, options)
+	if wantsScores(options) {
+		normalizeScores(classification, promptCategories(options))
+		applyCategoryFloors(classification, options.CategoryFloors)
+		applyCategoryPriors(classification, options)
+	}
+	if len(options.SummaryLengths) > 0 {
+		normalizeSummaries(classification, options.SummaryLengths)
+	}
+}
+
+// wantsScores reports whether the model is asked to score every category
+func wantsScores(options ClassificationOptions) bool {
+	return options.ScoreAllCategories || len(options.CategoryFloors) > 0 || len(options.CategoryPriors) > 0
+}
+
+// categoryValue returns the floor or prior configured for category, matched case-insensitively
+func categoryValue(values map[string]float64, category string) float64 {
+	for name, value := range values {
+		if strings.EqualFold(name, category) {
+			return value
+		}
+	}
+	return 0
+}
+
+// applyCategoryFloors demotes a chosen category whose confidence is below its floor to
+// the highest-scoring other category that meets its own floor. The category is kept
+// when no alternative qualifies.
+func applyCategoryFloors(classification *Classification, floors map[string]float64) {
+	floor := categoryValue(floors, classification.Category)
+	if classification.Confidence >= floor {
+		return
+	}
+
+	var best string
+	bestScore := -1.0
+	for name, score := range classification.Scores {
+		if strings.EqualFold(name, classification.Category) || score < categoryValue(floors, name) {
+			continue
+		}
+		if score > bestScore || (score == bestScore && name < best) {
+			best, bestScore = name, score
+		}
+	}
+	if best == "" {
+		log.WithFields(log.Fields{
+			"category":   classification.Category,
+			"confidence": classification.Confidence,
+			"floor":      floor,
+		}).Debug("Category below its floor but no alternative qualifies")
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"category":   classification.Category,
+		"confidence": classification.Confidence,
+		"floor":      floor,
+		"runner_up":  best,
+		"score":      bestScore,
+	}).Debug("Category below its floor, using runner-up")
+	classification.Category = best
+	classification.Confidence = bestScore
+}
+
+// defaultPriorTieMargin is the confidence difference treated as a tie when
+// PriorTieMargin is unset
+const defaultPriorTieMargin = 0.05
+
+// applyCategoryPriors switches to the best-scoring alternative when it is within the tie
+// margin of the chosen category and has a higher prior. Alternatives below their floor
+// are not considered.
+func applyCategoryPriors(classification *Classification, options ClassificationOptions) {
+	if len(options.CategoryPriors) == 0 {
+		return
+	}
+	margin := options.PriorTieMargin
+	if margin <= 0 {
+		margin = defaultPriorTieMargin
+	}
+
+	var runnerUp string
+	runnerUpScore := -1.0
+	for name, score := range classification.Scores {
+		if strings.EqualFold(name, classification.Category) || score < categoryValue(options.CategoryFloors, name) {
+			continue
+		}
+		if score > runnerUpScore || (score == runnerUpScore && name < runnerUp) {
+			runnerUp, runnerUpScore = name, score
+		}
+	}
+	if runnerUp == "" || math.Abs(classification.Confidence-runnerUpScore) > margin {
+		return
+	}
+	prior := categoryValue(options.CategoryPriors, classification.Category)
+	runnerUpPrior := categoryValue(options.CategoryPriors, runnerUp)
+	if runnerUpPrior <= prior {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"category":        classification.Category,
+		"confidence":      classification.Confidence,
+		"prior":           prior,
+		"runner_up":       runnerUp,
+		"score":           runnerUpScore,
+		"runner_up_prior": runnerUpPrior,
+	}).Debug("Near tie, using the category with the higher prior")
+	classification.Category = runnerUp
+	classification.Confidence = runnerUpScore
+}
+
+// normalizeSummaries keys the returned summaries exactly as requested, matching the
+// model's keys case-insensitively and dropping lengths that were not asked for
+func normalizeSummaries(classification *Classification, lengths []string) {
+	summaries := make(map[string]string, len(lengths))
+	for _, length := range lengths {
+		for key, summary := range classification.Summaries {
+			if strings.EqualFold(key, length) {
+				summaries[length] = summary
+				break
+			}
+		}
+	}
+	classification.Summaries = summaries
+}
+
+// applyLocalKeywords fills the keywords with a local extractor when the model omitted
+// them or when options.LocalKeywords asks to replace them
+func applyLocalKeywords(classification *Classification, content string, options ClassificationOptions) {
+	if !options.LocalKeywords && len(classification.Keywords) > 0 {
+		return
+	}
+	n := defaultLocalKeywords
+	if options.MaxKeywords > 0 {
+		n = options.MaxKeywords
+	}
+	classification.Keywords = textutil.ExtractKeywordsLocal(content, n)
+}
+
+// applyOutputLimits trims the parsed classification to the keyword, key phrase length
+// and summary limits in options. Models do not always respect the limits in the prompt.
+func applyOutputLimits(classification *Classification, options ClassificationOptions) {
+	if options.KeywordNgramMax > 0 {
+		keywords := classification.Keywords[:0]
+		for _, keyword := range classification.Keywords {
+			if len(strings.Fields(keyword)) <= options.KeywordNgramMax {
+				keywords = append(keywords, keyword)
+			}
+		}
+		classification.Keywords = keywords
+	}
+	if options.MaxKeywords > 0 && len(classification.Keywords) > options.MaxKeywords {
+		classification.Keywords = classification.Keywords[:options.MaxKeywords]
+	}
+
+	if options.MaxSummaryWords > 0 {
+		words := strings.Fields(classification.Summary)
+		if len(words) > options.MaxSummaryWords {
+			classification.Summary = strings.Join(words[:options.MaxSummaryWords], " ")
+		}
+	}
+}
+
+// normalizeScores keeps a score in [0,1] for every requested category, matching the
+// model's keys case-insensitively and scoring categories it skipped as 0
+func normalizeScores(classification *Classification, categories []string) {
+	scores := make(map[string]float64, len(categories))
+	for _, category := range categories {
+		var score float64
+		for name, value := range classification.Scores {
+			if strings.EqualFold(name, category) {
+				score = value
+				break
+			}
+		}
+		scores[category] = math.Max(0, math.Min(1, score))
+	}
+	classification.Scores = scores
+}
+
+// promptCategories returns the categories offered to the model: the explicit list,
+// or the active entries of the taxonomy when no list is given
+func promptCategories(options ClassificationOptions) []string {
+	if len(options.Categories) == 0 && options.CategorySet != nil {
+		return withoutExcluded(options.CategorySet.Active(), options.ExcludeCategories)
+	}
+	return withoutExcluded(options.Categories, options.ExcludeCategories)
+}
+
+// validateCategory checks the returned category against the requested categories or the
+// taxonomy, rewrites it to its canonical name and rejects excluded categories
+func validateCategory(classification *Classification, options ClassificationOptions, logger *log.Entry) error {
+	if err := matchCategory(classification, options, logger); err != nil {
+		return err
+	}
+	return checkExcluded(classification, options, logger)
+}
+
+// matchCategory rewrites the returned category to the matching requested category or
+// taxonomy entry, failing when there is none
+func matchCategory(classification *Classification, options ClassificationOptions, logger *log.Entry) error {
+	if len(options.Categories) == 0 && options.CategorySet != nil {
+		canonical, ok := options.CategorySet.Normalize(classification.Category)
+		if !ok {
+			logger.WithFields(log.Fields{
+				"received_category": classification.Category,
+				"valid_categories":  options.CategorySet.Active(),
+			}).Error("Classification returned invalid category")
+			return fmt.Errorf("classifier returned invalid category: %s", classification.Category)
+		}
+		classification.Category = canonical
+		return nil
+	}
+
+	if len(options.Categories) == 0 {
+		return nil
+	}
+	for _, validCategory := range options.Categories {
+		if strings.EqualFold(classification.Category, validCategory) {
+			if !options.PreserveModelCasing {
+				classification.Category = validCategory // Use exact case from predefined list
+			}
+			return nil
+		}
+	}
+	logger.WithFields(log.Fields{
+		"received_category": classification.Category,
+		"valid_categories":  options.Categories,
+	}).Error("Classification returned invalid category")
+	return fmt.Errorf("classifier returned invalid category: %s", classification.Category)
+}
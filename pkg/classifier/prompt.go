@@ -0,0 +1,158 @@
+package classifier
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/adaptive-scale/superclass/pkg/textutil"
+)
+
+// systemPrompt is the instruction sent as the system message to every provider
+const systemPrompt = "You are a content classification expert. Always respond in valid JSON format."
+
+// attachedDocumentText replaces the document text in the prompt when the file is sent natively
+const attachedDocumentText = "(the attached document)"
+
+// attachedImageText replaces the document text in the prompt for vision classification
+const attachedImageText = "(the attached image)"
+
+// Limits requested in the prompt when MaxSummaryWords or MaxKeywords are unset
+const (
+	defaultPromptSummaryWords = 100
+	defaultPromptKeywords     = 5
+)
+
+// promptLimits returns the summary word limit and keyword count requested from the model
+func promptLimits(options ClassificationOptions) (int, int) {
+	summaryWords, keywords := defaultPromptSummaryWords, defaultPromptKeywords
+	if options.MaxSummaryWords > 0 {
+		summaryWords = options.MaxSummaryWords
+	}
+	if options.MaxKeywords > 0 {
+		keywords = options.MaxKeywords
+	}
+	return summaryWords, keywords
+}
+
+// buildPrompt renders the classification prompt for the given content and options
+func buildPrompt(content string, options ClassificationOptions) string {
+	if content != attachedDocumentText && content != attachedImageText {
+		if options.OutputLanguage == "" && options.AutoOutputLanguage {
+			if code := textutil.DetectLanguage(content); code != "" && code != "en" {
+				options.OutputLanguage = textutil.LanguageNames[code]
+			}
+		}
+		content = guardContent(content, options)
+	}
+	summaryWords, keywords := promptLimits(options)
+	if categories := promptCategories(options); len(categories) > 0 {
+		categoriesStr := strings.Join(categories, ", ")
+		extra := extraFields(options)
+		if wantsScores(options) {
+			extra += "\n\t- scores: An object mapping every category listed above to a score between 0 and 1 for how well the content fits it, scored independently"
+		}
+		return fmt.Sprintf(`Analyze the following text and classify it into one of these categories: %s
+
+Provide a JSON response with these fields:
+	- category: One of the categories listed above that best matches the content
+	- confidence: A confidence score between 0 and 1 indicating how well the content matches the chosen category
+	- summary: A brief summary of the content (max %d words)
+	- keywords: %s%s
+%s
+Text to analyze:
+%s`, categoriesStr, summaryWords, keywordField(keywords, options), extra, exclusions(options)+documentMetadata(options)+outputLanguage(options, true), content)
+	}
+
+	var hints string
+	if len(options.CategoryHints) > 0 {
+		hints = fmt.Sprintf("\nDocuments of this type often fall into categories such as: %s. Use one of these if it fits, otherwise choose a better category.\n",
+			strings.Join(options.CategoryHints, ", "))
+	}
+
+	return fmt.Sprintf(`Analyze the following text and provide a JSON response with these fields:
+	- category: The main category/topic of the content
+	- confidence: A confidence score between 0 and 1
+	- summary: A brief summary of the content (max %d words)
+	- keywords: %s%s
+%s%s
+Text to analyze:
+%s`, summaryWords, keywordField(keywords, options), extraFields(options), hints, exclusions(options)+documentMetadata(options)+outputLanguage(options, false), content)
+}
+
+// keywordField describes the requested keywords, asking for multi-word key phrases when
+// KeywordNgramMax allows them
+func keywordField(keywords int, options ClassificationOptions) string {
+	switch {
+	case options.KeywordNgramMax == 1:
+		return fmt.Sprintf("Up to %d single-word key terms from the content", keywords)
+	case options.KeywordNgramMax > 1:
+		return fmt.Sprintf(`Up to %d key phrases from the content, each of 1 to %d words. Keep multi-word concepts such as "machine learning" together as one phrase instead of splitting them into separate words`,
+			keywords, options.KeywordNgramMax)
+	}
+	return fmt.Sprintf("Up to %d key terms or phrases from the content", keywords)
+}
+
+// documentMetadata returns the prompt lines describing the document's filename and context
+func documentMetadata(options ClassificationOptions) string {
+	var lines []string
+	if options.Filename != "" {
+		lines = append(lines, "\t- Filename: "+filepath.Base(options.Filename))
+	}
+	if options.ContextHint != "" {
+		lines = append(lines, "\t- Context: "+options.ContextHint)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\nDocument metadata (use it as a hint alongside the text):\n" + strings.Join(lines, "\n") + "\n"
+}
+
+// outputLanguage returns the prompt line asking for the summary and keywords in
+// OutputLanguage. Categories offered to the model must keep their given names.
+func outputLanguage(options ClassificationOptions, fixedCategories bool) string {
+	language := options.OutputLanguage
+	if name, ok := textutil.LanguageNames[strings.ToLower(language)]; ok {
+		language = name
+	}
+	if language == "" {
+		return ""
+	}
+	if fixedCategories {
+		return fmt.Sprintf("\nWrite the summary and keywords in %s, but keep the category exactly as listed above.\n", language)
+	}
+	return fmt.Sprintf("\nWrite the summary and keywords in %s.\n", language)
+}
+
+// exclusions returns the prompt line forbidding ExcludeCategories
+func exclusions(options ClassificationOptions) string {
+	if len(options.ExcludeCategories) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\nDo not use any of these categories: %s. Choose the closest other category instead.\n",
+		strings.Join(options.ExcludeCategories, ", "))
+}
+
+// summaryLengthDescriptions describes the well-known summary lengths to the model.
+// Other requested lengths are passed through as written.
+var summaryLengthDescriptions = map[string]string{
+	"short":  "a single sentence",
+	"medium": "one paragraph of 3-5 sentences",
+	"long":   "several paragraphs, up to 250 words",
+}
+
+// extraFields returns the optional response fields requested through options
+func extraFields(options ClassificationOptions) string {
+	if len(options.SummaryLengths) == 0 {
+		return ""
+	}
+	var lengths []string
+	for _, length := range options.SummaryLengths {
+		if description, ok := summaryLengthDescriptions[strings.ToLower(length)]; ok {
+			lengths = append(lengths, fmt.Sprintf("%q (%s)", length, description))
+		} else {
+			lengths = append(lengths, fmt.Sprintf("%q", length))
+		}
+	}
+	return "\n\t- summaries: An object with a summary of the content for each of these keys: " + strings.Join(lengths, ", ")
+}
@@ -0,0 +1,69 @@
+package classifier
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrResponseTruncated is returned when the model stopped because it hit its output token limit
+var ErrResponseTruncated = errors.New("model response was truncated")
+
+// ErrRefused is returned when the model declined to classify the content
+var ErrRefused = errors.New("model refused to classify the content")
+
+// truncatedFinishReasons are the finish/stop reasons providers report when output was cut off
+var truncatedFinishReasons = map[string]bool{
+	"length":     true, // OpenAI, Azure
+	"max_tokens": true, // Anthropic
+	"MAX_TOKENS": true, // Gemini
+}
+
+// refusedFinishReasons are the finish/stop reasons providers report for refusals and filtered output
+var refusedFinishReasons = map[string]bool{
+	"content_filter":     true, // OpenAI, Azure
+	"refusal":            true, // Anthropic
+	"SAFETY":             true, // Gemini
+	"RECITATION":         true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+}
+
+// checkCompletion turns a refusal message or a truncated/refused finish reason into
+// a descriptive error, so a partial or refused response is never parsed as a classification
+func checkCompletion(finishReason, refusal string, logger *log.Entry) error {
+	switch {
+	case refusal != "":
+		logger.WithField("refusal", refusal).Error("Model refused the request")
+		return fmt.Errorf("%w: %s", ErrRefused, refusal)
+	case refusedFinishReasons[finishReason]:
+		logger.WithField("finish_reason", finishReason).Error("Model refused the request")
+		return fmt.Errorf("%w (finish reason: %s)", ErrRefused, finishReason)
+	case truncatedFinishReasons[finishReason]:
+		logger.WithField("finish_reason", finishReason).Error("Model response was truncated")
+		return fmt.Errorf("%w (finish reason: %s); increase max_tokens", ErrResponseTruncated, finishReason)
+	}
+	return nil
+}
+
+// extractJSON returns the model's message content without the markdown code fence
+// (```json or ```) models often wrap their JSON in. Content without a fence is only
+// trimmed of surrounding whitespace.
+func extractJSON(raw string) string {
+	content := strings.TrimSpace(raw)
+	if !strings.HasPrefix(content, "```") {
+		return content
+	}
+	// Drop the opening fence line, including any language tag
+	if i := strings.IndexByte(content, '\n'); i >= 0 {
+		content = content[i+1:]
+	} else {
+		content = strings.TrimLeft(strings.TrimPrefix(content, "```"), "jsonJSON")
+	}
+	content = strings.TrimSpace(content)
+	content = strings.TrimSuffix(content, "```")
+	return strings.TrimSpace(content)
+}
@@ -0,0 +1,77 @@
+package classifier
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRefusedAndTruncatedResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider Provider
+		reply    string
+		want     error
+	}{
+		{
+			name:     "openai refusal message",
+			provider: OpenAI,
+			reply:    `{"choices":[{"message":{"content":"","refusal":"I can't help with that."},"finish_reason":"stop"}]}`,
+			want:     ErrRefused,
+		},
+		{
+			name:     "openai content filter",
+			provider: OpenAI,
+			reply:    `{"choices":[{"message":{"content":""},"finish_reason":"content_filter"}]}`,
+			want:     ErrRefused,
+		},
+		{
+			name:     "openai length limit",
+			provider: OpenAI,
+			reply:    `{"choices":[{"message":{"content":"{\"category\":\"Inv"},"finish_reason":"length"}]}`,
+			want:     ErrResponseTruncated,
+		},
+		{
+			name:     "anthropic max tokens",
+			provider: Anthropic,
+			reply:    `{"content":[{"type":"text","text":"{\"category\":\"Inv"}],"stop_reason":"max_tokens"}`,
+			want:     ErrResponseTruncated,
+		},
+		{
+			name:     "anthropic refusal",
+			provider: Anthropic,
+			reply:    `{"content":[],"stop_reason":"refusal"}`,
+			want:     ErrRefused,
+		},
+		{
+			name:     "anthropic end of turn",
+			provider: Anthropic,
+			reply:    anthropicReply,
+		},
+		{
+			name:     "openai stop",
+			provider: OpenAI,
+			reply:    gptReply,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := serveJSON(t, tt.reply)
+			config := ModelConfig{Endpoint: server.URL, APIKey: "key", MaxRetries: -1}
+			var c Classifier = NewGPTClassifier(config)
+			if tt.provider == Anthropic {
+				c = NewAnthropicClassifier(config)
+			}
+
+			classification, err := c.Classify("some text")
+			if tt.want == nil {
+				if err != nil || classification.Category == "" {
+					t.Errorf("Classify() = %+v, %v, want a classification", classification, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Classify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package classifier
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits; RetryConfig uses it so tests can control delays
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// systemClock is the real clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// RetryConfig controls how provider requests are retried
+type RetryConfig struct {
+	// MaxRetries is the number of retries after the first attempt (0 disables retries)
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles with every retry
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff of a single retry
+	MaxDelay time.Duration
+	// Source supplies the backoff jitter (default: seeded from the current time).
+	// Inject a fixed source for reproducible delays.
+	Source rand.Source
+	// Clock supplies the current time and waits between attempts (default: the system clock)
+	Clock Clock
+}
+
+// DefaultRetryConfig returns the retry settings used unless ConfigureRetries is called
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries: 1,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   maxRetryAfter,
+	}
+}
+
+var (
+	retryMu     sync.RWMutex
+	retryConfig = newRetryPolicy(DefaultRetryConfig())
+)
+
+// retryPolicy is a RetryConfig with its defaults filled in. math/rand sources are not
+// safe for concurrent use, so the random generator is guarded by a mutex.
+type retryPolicy struct {
+	RetryConfig
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func newRetryPolicy(config RetryConfig) *retryPolicy {
+	if config.Source == nil {
+		config.Source = rand.NewSource(time.Now().UnixNano())
+	}
+	if config.Clock == nil {
+		config.Clock = systemClock{}
+	}
+	return &retryPolicy{RetryConfig: config, rnd: rand.New(config.Source)}
+}
+
+// ConfigureRetries replaces the retry settings used for all provider requests
+func ConfigureRetries(config RetryConfig) {
+	policy := newRetryPolicy(config)
+
+	retryMu.Lock()
+	defer retryMu.Unlock()
+	retryConfig = policy
+}
+
+// currentRetryPolicy returns the retry settings shared by all classifiers
+func currentRetryPolicy() *retryPolicy {
+	retryMu.RLock()
+	defer retryMu.RUnlock()
+	return retryConfig
+}
+
+// delay returns the backoff before retry number attempt (starting at 1): a random
+// duration between half and all of base doubled attempt-1 times, capped at MaxDelay.
+// The same Source yields the same sequence of delays.
+func (p *retryPolicy) delay(base time.Duration, attempt int) time.Duration {
+	if attempt < 1 || base <= 0 {
+		return 0
+	}
+	delay := base
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	p.mu.Lock()
+	jitter := p.rnd.Int63n(int64(delay/2) + 1)
+	p.mu.Unlock()
+	return delay/2 + time.Duration(jitter)
+}
+
+// retryOverrides are a classifier's own retry settings from ModelConfig. Zero values
+// use the shared RetryConfig.
+type retryOverrides struct {
+	maxRetries int
+	baseDelay  time.Duration
+}
+
+// retryOverridesFor returns the retry settings configured in config
+func retryOverridesFor(config ModelConfig) retryOverrides {
+	return retryOverrides{maxRetries: config.MaxRetries, baseDelay: config.RetryBaseDelay}
+}
+
+// update replaces the settings that config sets
+func (o *retryOverrides) update(config ModelConfig) {
+	if config.MaxRetries != 0 {
+		o.maxRetries = config.MaxRetries
+	}
+	if config.RetryBaseDelay != 0 {
+		o.baseDelay = config.RetryBaseDelay
+	}
+}
+
+// apply returns the number of retries and the base backoff delay to use with policy.
+// A negative maxRetries disables retries.
+func (o retryOverrides) apply(policy *retryPolicy) (int, time.Duration) {
+	maxRetries, baseDelay := policy.MaxRetries, policy.BaseDelay
+	if o.maxRetries != 0 {
+		maxRetries = max(o.maxRetries, 0)
+	}
+	if o.baseDelay > 0 {
+		baseDelay = o.baseDelay
+	}
+	return maxRetries, baseDelay
+}
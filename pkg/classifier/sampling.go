@@ -0,0 +1,135 @@
+package classifier
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BatchEstimate extrapolates the outcome of classifying a whole batch from a random sample
+type BatchEstimate struct {
+	// TotalDocuments is the size of the whole batch
+	TotalDocuments int `json:"total_documents"`
+	// SampleSize is the number of documents classified
+	SampleSize int `json:"sample_size"`
+	// Failed is the number of sampled documents that could not be classified
+	Failed int `json:"failed"`
+	// CategoryProportions is the share of each category among the classified samples
+	CategoryProportions map[string]float64 `json:"category_proportions"`
+	// ProjectedCounts is the expected number of documents per category in the whole batch
+	ProjectedCounts map[string]int `json:"projected_counts"`
+	// SampleCost is the estimated cost of classifying the sample, in USD
+	SampleCost float64 `json:"sample_cost"`
+	// EstimatedTotalCost is the estimated cost of classifying every document, in USD
+	EstimatedTotalCost float64 `json:"estimated_total_cost"`
+}
+
+// BatchSampler estimates category distribution and cost of a large batch by classifying
+// a random sample of it
+type BatchSampler struct {
+	classifier Classifier
+	// Model prices the classifications; when empty the model reported in each result's
+	// metadata is used
+	Model ModelType
+	// Options are used for every sampled classification
+	Options ClassificationOptions
+	// Concurrency is the number of documents classified at once
+	Concurrency int
+	// Source picks the sample (default: seeded from the current time). Inject a fixed
+	// source for a reproducible sample.
+	Source rand.Source
+}
+
+// NewBatchSampler creates a sampler that classifies with classifier
+func NewBatchSampler(classifier Classifier) *BatchSampler {
+	return &BatchSampler{classifier: classifier, Concurrency: 4}
+}
+
+// SampleClassify classifies sampleSize randomly chosen documents of contents and
+// extrapolates the category proportions and total cost to all of them. The cost of each
+// sampled document comes from EstimateClassificationCost; the mean cost of the sample
+// is scaled to the whole batch, so documents that failed to classify are not counted.
+// A sampleSize of at least len(contents) classifies every document.
+func (s *BatchSampler) SampleClassify(contents []string, sampleSize int) (*BatchEstimate, error) {
+	logger := log.WithFields(log.Fields{
+		"function":    "SampleClassify",
+		"documents":   len(contents),
+		"sample_size": sampleSize,
+	})
+	if sampleSize <= 0 {
+		return nil, fmt.Errorf("sample size must be positive, got %d", sampleSize)
+	}
+	if len(contents) == 0 {
+		return nil, fmt.Errorf("no documents to sample")
+	}
+	sampleSize = min(sampleSize, len(contents))
+
+	source := s.Source
+	if source == nil {
+		source = rand.NewSource(time.Now().UnixNano())
+	}
+	sample := rand.New(source).Perm(len(contents))[:sampleSize]
+
+	classifications := make([]*Classification, sampleSize)
+	errs := make([]error, sampleSize)
+	slots := make(chan struct{}, max(s.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i, doc := range sample {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			classifications[i], errs[i] = s.classifier.ClassifyWithOptions(contents[doc], s.Options)
+		}()
+	}
+	wg.Wait()
+
+	estimate := &BatchEstimate{
+		TotalDocuments:      len(contents),
+		SampleSize:          sampleSize,
+		CategoryProportions: make(map[string]float64),
+		ProjectedCounts:     make(map[string]int),
+	}
+	counts := make(map[string]int)
+	var lastErr error
+	for i, classification := range classifications {
+		if errs[i] != nil {
+			logger.WithError(errs[i]).WithField("document", sample[i]).Warn("Failed to classify sampled document")
+			estimate.Failed++
+			lastErr = errs[i]
+			continue
+		}
+		counts[classification.Category]++
+		estimate.SampleCost += EstimateClassificationCost(s.model(classification), contents[sample[i]], classification)
+	}
+
+	classified := sampleSize - estimate.Failed
+	if classified == 0 {
+		return nil, fmt.Errorf("no sampled document could be classified: %w", lastErr)
+	}
+	for category, count := range counts {
+		proportion := float64(count) / float64(classified)
+		estimate.CategoryProportions[category] = proportion
+		estimate.ProjectedCounts[category] = int(proportion*float64(len(contents)) + 0.5)
+	}
+	estimate.EstimatedTotalCost = estimate.SampleCost / float64(classified) * float64(len(contents))
+
+	logger.WithFields(log.Fields{
+		"classified":           classified,
+		"categories":           len(counts),
+		"estimated_total_cost": estimate.EstimatedTotalCost,
+	}).Info("Estimated batch from sample")
+	return estimate, nil
+}
+
+// model returns the model the classification is priced with
+func (s *BatchSampler) model(classification *Classification) ModelType {
+	if s.Model == "" && classification.Metadata != nil {
+		return ModelType(classification.Metadata.Model)
+	}
+	return s.Model
+}
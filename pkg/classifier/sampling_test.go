@@ -0,0 +1,77 @@
+package classifier
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSampleClassify(t *testing.T) {
+	categories := map[string]string{}
+	var contents []string
+	for i, category := range []string{"Invoice", "Invoice", "Invoice", "Invoice", "Invoice", "Invoice", "Contract", "Contract", "Contract", ""} {
+		content := string(rune('a'+i)) + "-document"
+		if category != "" {
+			categories[content] = category
+		}
+		contents = append(contents, content)
+	}
+	s := NewBatchSampler(&labelingClassifier{categories: categories})
+	s.Model = GPT4
+
+	// A sample larger than the batch classifies every document; the unlabeled one fails
+	estimate, err := s.SampleClassify(contents, 20)
+	if err != nil {
+		t.Fatalf("SampleClassify: %v", err)
+	}
+	if estimate.TotalDocuments != 10 || estimate.SampleSize != 10 || estimate.Failed != 1 {
+		t.Errorf("estimate = %+v, want 10 documents sampled with 1 failure", estimate)
+	}
+	if want := map[string]int{"Invoice": 7, "Contract": 3}; !reflect.DeepEqual(estimate.ProjectedCounts, want) {
+		t.Errorf("projected counts = %v, want %v", estimate.ProjectedCounts, want)
+	}
+	if got := estimate.CategoryProportions["Contract"]; math.Abs(got-1.0/3) > 1e-9 {
+		t.Errorf("Contract proportion = %v, want 1/3 of the classified documents", got)
+	}
+	// Every document is priced the same, so the mean of the nine classified ones covers all ten
+	perDocument := EstimateClassificationCost(GPT4, contents[0], &Classification{Category: "Invoice"})
+	if math.Abs(estimate.SampleCost-9*perDocument) > 1e-9 || math.Abs(estimate.EstimatedTotalCost-10*perDocument) > 1e-9 {
+		t.Errorf("costs = %v sample, %v total, want %v and %v", estimate.SampleCost, estimate.EstimatedTotalCost, 9*perDocument, 10*perDocument)
+	}
+
+	// A fixed source draws the same sample every time
+	s.Source = rand.NewSource(42)
+	first, err := s.SampleClassify(contents, 4)
+	if err != nil {
+		t.Fatalf("SampleClassify: %v", err)
+	}
+	s.Source = rand.NewSource(42)
+	second, _ := s.SampleClassify(contents, 4)
+	if first.SampleSize != 4 || !reflect.DeepEqual(first, second) {
+		t.Errorf("estimates with the same source = %+v and %+v, want equal samples of 4", first, second)
+	}
+
+	if _, err := s.SampleClassify(contents, 0); err == nil {
+		t.Error("SampleClassify with a sample size of 0 succeeded")
+	}
+	if _, err := s.SampleClassify(nil, 5); err == nil {
+		t.Error("SampleClassify without documents succeeded")
+	}
+	if _, err := s.SampleClassify([]string{"unlabeled"}, 1); err == nil {
+		t.Error("SampleClassify with no classifiable document succeeded")
+	}
+}
+
+func TestSampleClassifyPricesReportedUsage(t *testing.T) {
+	c := NewGPTClassifier(ModelConfig{Endpoint: serveJSON(t, gptReply).URL, APIKey: "key", Model: string(GPT4), MaxRetries: -1})
+	estimate, err := NewBatchSampler(c).SampleClassify([]string{"first", "second", "third", "fourth"}, 2)
+	if err != nil {
+		t.Fatalf("SampleClassify: %v", err)
+	}
+	// gptReply reports 100 prompt and 20 completion tokens; the model comes from the metadata
+	perDocument := EstimateCost(GPT4, 100, 20)
+	if math.Abs(estimate.SampleCost-2*perDocument) > 1e-9 || math.Abs(estimate.EstimatedTotalCost-4*perDocument) > 1e-9 {
+		t.Errorf("costs = %v sample, %v total, want %v and %v", estimate.SampleCost, estimate.EstimatedTotalCost, 2*perDocument, 4*perDocument)
+	}
+}
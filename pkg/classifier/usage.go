@@ -0,0 +1,128 @@
+package classifier
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// TokenUsage is the number of tokens a provider billed for a classification
+type TokenUsage struct {
+	// PromptTokens counts every prompt token, including tokens read from or written to a prompt cache
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	// CachedPromptTokens is the part of PromptTokens served from a prompt cache
+	CachedPromptTokens int `json:"cached_prompt_tokens,omitempty"`
+}
+
+// usageBlock is a provider usage object. OpenAI and Azure report prompt/completion
+// tokens; Anthropic reports input/output tokens with cache tokens counted separately.
+type usageBlock struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+}
+
+// usageEnvelope locates the usage object of a response body or stream event. Anthropic's
+// message_start event nests it under message; every other shape carries it at the top level.
+type usageEnvelope struct {
+	Usage   *usageBlock `json:"usage"`
+	Message *struct {
+		Usage *usageBlock `json:"usage"`
+	} `json:"message"`
+}
+
+// parseUsage reads the token usage from a provider response. body is either a JSON
+// response or a server-sent event stream; in a stream the usage arrives in the final
+// chunk (OpenAI with stream_options.include_usage) or is split between the
+// message_start and message_delta events (Anthropic). Both modes produce the same
+// TokenUsage. It returns nil when the response carries no usage.
+func parseUsage(body []byte) *TokenUsage {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '{' {
+		var envelope usageEnvelope
+		if err := json.Unmarshal(body, &envelope); err != nil || envelope.Usage == nil {
+			return nil
+		}
+		var usage TokenUsage
+		usage.merge(envelope.Usage)
+		return usage.finish()
+	}
+
+	var usage TokenUsage
+	found := false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4<<20)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		var envelope usageEnvelope
+		if err := json.Unmarshal([]byte(data), &envelope); err != nil {
+			continue
+		}
+		if envelope.Message != nil && envelope.Message.Usage != nil {
+			usage.merge(envelope.Message.Usage)
+			found = true
+		}
+		if envelope.Usage != nil {
+			usage.merge(envelope.Usage)
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return usage.finish()
+}
+
+// merge folds a usage object into u. Counts that are zero in block keep their
+// previous value, since stream events only repeat the counts they update.
+func (u *TokenUsage) merge(block *usageBlock) {
+	input := block.PromptTokens
+	cached := block.PromptTokensDetails.CachedTokens
+	if anthropicInput := block.InputTokens + block.CacheReadInputTokens + block.CacheCreationInputTokens; anthropicInput > 0 {
+		input = anthropicInput
+		cached = block.CacheReadInputTokens
+	}
+	output := block.CompletionTokens
+	if block.OutputTokens > 0 {
+		output = block.OutputTokens
+	}
+
+	if input > 0 {
+		u.PromptTokens = input
+	}
+	if cached > 0 {
+		u.CachedPromptTokens = cached
+	}
+	if output > 0 {
+		u.CompletionTokens = output
+	}
+	if block.TotalTokens > 0 {
+		u.TotalTokens = block.TotalTokens
+	}
+}
+
+// finish fills in the total when the provider did not report one
+func (u *TokenUsage) finish() *TokenUsage {
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	}
+	return u
+}
@@ -0,0 +1,160 @@
+package classifier
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// contentJSON returns classificationJSON encoded as a JSON string
+func contentJSON() string {
+	encoded, _ := json.Marshal(classificationJSON)
+	return string(encoded)
+}
+
+func TestClassificationUsagePerProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider Provider
+		model    string
+		reply    string
+		want     *TokenUsage
+		pricedAs ModelType
+	}{
+		{
+			name:     "openai",
+			provider: OpenAI,
+			model:    string(GPT4),
+			reply:    `{"choices":[{"message":{"content":` + contentJSON() + `},"finish_reason":"stop"}],"usage":{"prompt_tokens":100,"completion_tokens":20,"total_tokens":120}}`,
+			want:     &TokenUsage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+			pricedAs: GPT4,
+		},
+		{
+			// The deployment name says nothing about the model, the response does
+			name:     "azure",
+			provider: Azure,
+			model:    "my-deployment",
+			reply:    `{"model":"gpt-35-turbo-0125","choices":[{"message":{"content":` + contentJSON() + `},"finish_reason":"stop"}],"usage":{"prompt_tokens":300,"completion_tokens":50,"total_tokens":350}}`,
+			want:     &TokenUsage{PromptTokens: 300, CompletionTokens: 50, TotalTokens: 350},
+			pricedAs: GPT35Turbo,
+		},
+		{
+			name:     "anthropic",
+			provider: Anthropic,
+			model:    string(Claude3Sonnet),
+			reply:    `{"content":[{"type":"text","text":` + contentJSON() + `}],"stop_reason":"end_turn","usage":{"input_tokens":200,"output_tokens":40}}`,
+			want:     &TokenUsage{PromptTokens: 200, CompletionTokens: 40, TotalTokens: 240},
+			pricedAs: Claude3Sonnet,
+		},
+		{
+			name:     "gemini",
+			provider: Gemini,
+			model:    string(Gemini15Pro),
+			reply:    `{"candidates":[{"content":{"parts":[{"text":` + contentJSON() + `}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":150,"candidatesTokenCount":30,"totalTokenCount":180}}`,
+			want:     &TokenUsage{PromptTokens: 150, CompletionTokens: 30, TotalTokens: 180},
+			pricedAs: Gemini15Pro,
+		},
+		{
+			// Custom endpoints report no usage the classifier can rely on
+			name:     "custom",
+			provider: Custom,
+			model:    "in-house",
+			reply:    `{"content":` + contentJSON() + `,"usage":{"prompt_tokens":10,"completion_tokens":5}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := serveJSON(t, tt.reply)
+			c, err := NewClassifier(tt.provider, ModelConfig{
+				Endpoint:   server.URL,
+				APIKey:     "key",
+				Model:      tt.model,
+				MaxRetries: -1,
+			})
+			if err != nil {
+				t.Fatalf("NewClassifier: %v", err)
+			}
+			classification, err := c.Classify("some text")
+			if err != nil {
+				t.Fatalf("Classify: %v", err)
+			}
+
+			if tt.want == nil {
+				if classification.Usage != nil || classification.EstimatedCost != 0 {
+					t.Fatalf("usage = %+v, cost = %v, want none", classification.Usage, classification.EstimatedCost)
+				}
+				return
+			}
+			if classification.Usage == nil || *classification.Usage != *tt.want {
+				t.Fatalf("usage = %+v, want %+v", classification.Usage, tt.want)
+			}
+			wantCost := EstimateCost(tt.pricedAs, tt.want.PromptTokens, tt.want.CompletionTokens)
+			if wantCost == 0 || classification.EstimatedCost != wantCost {
+				t.Errorf("estimated cost = %v, want %v", classification.EstimatedCost, wantCost)
+			}
+		})
+	}
+}
+
+func TestPricedModel(t *testing.T) {
+	tests := map[string]ModelType{
+		"gpt-4":            GPT4,
+		"gpt-4-0613":       GPT4,
+		"gpt-35-turbo":     GPT35Turbo,
+		"gpt-35-turbo-16k": GPT35Turbo,
+		"unknown-model":    "unknown-model",
+	}
+	for name, want := range tests {
+		if got := pricedModel(name); got != want {
+			t.Errorf("pricedModel(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestParseUsageStreamingMatchesNonStreaming(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		streamed string
+		want     TokenUsage
+	}{
+		{
+			name: "openai",
+			body: `{"choices":[],"usage":{"prompt_tokens":120,"completion_tokens":30,"total_tokens":150,"prompt_tokens_details":{"cached_tokens":64}}}`,
+			streamed: "data: {\"choices\":[{\"delta\":{\"content\":\"{\"}}]}\n\n" +
+				"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":120,\"completion_tokens\":30,\"total_tokens\":150,\"prompt_tokens_details\":{\"cached_tokens\":64}}}\n\n" +
+				"data: [DONE]\n",
+			want: TokenUsage{PromptTokens: 120, CompletionTokens: 30, TotalTokens: 150, CachedPromptTokens: 64},
+		},
+		{
+			// Anthropic counts cache reads and writes apart from input_tokens
+			name: "anthropic",
+			body: `{"content":[],"usage":{"input_tokens":20,"cache_read_input_tokens":80,"cache_creation_input_tokens":10,"output_tokens":40}}`,
+			streamed: "event: message_start\n" +
+				"data: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":20,\"cache_read_input_tokens\":80,\"cache_creation_input_tokens\":10,\"output_tokens\":1}}}\n\n" +
+				"event: content_block_delta\n" +
+				"data: {\"type\":\"content_block_delta\",\"delta\":{\"text\":\"{\"}}\n\n" +
+				"event: message_delta\n" +
+				"data: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":40}}\n\n",
+			want: TokenUsage{PromptTokens: 110, CompletionTokens: 40, TotalTokens: 150, CachedPromptTokens: 80},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, streamed := parseUsage([]byte(tt.body)), parseUsage([]byte(tt.streamed))
+			if body == nil || *body != tt.want {
+				t.Errorf("response usage = %+v, want %+v", body, tt.want)
+			}
+			if streamed == nil || *streamed != tt.want {
+				t.Errorf("streamed usage = %+v, want %+v", streamed, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUsageWithoutUsage(t *testing.T) {
+	for _, body := range []string{`{"choices":[]}`, "data: {\"choices\":[]}\n\ndata: [DONE]\n", "", "not json"} {
+		if usage := parseUsage([]byte(body)); usage != nil {
+			t.Errorf("parseUsage(%q) = %+v, want nil", body, usage)
+		}
+	}
+}
@@ -13,11 +13,123 @@ func ProviderFromString(provider string) Provider {
 		return Anthropic
 	case "azure":
 		return Azure
+	case "custom":
+		return Custom
+	case "gemini":
+		return Gemini
+	case "embedding":
+		return Embedding
 	default:
 		return OpenAI
 	}
 }
 
+// ContentTypeFromString converts a string such as "legal_document" to a ContentType.
+// Unknown values map to GeneralText.
+func ContentTypeFromString(contentType string) ContentType {
+	switch strings.ToLower(strings.ReplaceAll(contentType, "-", "_")) {
+	case "technical_doc", "technical":
+		return TechnicalDoc
+	case "creative_writing", "creative":
+		return CreativeWriting
+	case "code_snippet", "code":
+		return CodeSnippet
+	case "legal_document", "legal":
+		return LegalDocument
+	case "academic_paper", "academic":
+		return AcademicPaper
+	case "business_report", "business":
+		return BusinessReport
+	case "social_media_content", "social_media":
+		return SocialMediaContent
+	default:
+		return GeneralText
+	}
+}
+
+// defaultMaxTokens is the response token limit used when the parameters set none
+const defaultMaxTokens = 2000
+
+// IntParameter returns the named model parameter as an int, or fallback when it is
+// missing or not a number. Parameters decoded from JSON arrive as float64.
+func IntParameter(parameters map[string]interface{}, name string, fallback int) int {
+	switch v := parameters[name].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	default:
+		return fallback
+	}
+}
+
+// stringsParameter returns the named model parameter as a list of strings. A single
+// string is a one-element list; anything else, or a missing parameter, yields nil.
+func stringsParameter(parameters map[string]interface{}, name string) []string {
+	switch v := parameters[name].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// withoutParameters returns a copy of parameters without the named ones, for parameters
+// sent as their own request fields. It returns nil when nothing is left.
+func withoutParameters(parameters map[string]interface{}, names ...string) map[string]interface{} {
+	rest := make(map[string]interface{}, len(parameters))
+	for name, value := range parameters {
+		rest[name] = value
+	}
+	for _, name := range names {
+		delete(rest, name)
+	}
+	if len(rest) == 0 {
+		return nil
+	}
+	return rest
+}
+
+// floatMapParameter returns the named model parameter as a map of numbers, skipping
+// entries that are not numbers
+func floatMapParameter(parameters map[string]interface{}, name string) map[string]float64 {
+	switch v := parameters[name].(type) {
+	case map[string]float64:
+		return v
+	case map[string]int:
+		values := make(map[string]float64, len(v))
+		for key, n := range v {
+			values[key] = float64(n)
+		}
+		return values
+	case map[string]interface{}:
+		values := make(map[string]float64, len(v))
+		for key, item := range v {
+			switch n := item.(type) {
+			case float64:
+				values[key] = n
+			case int:
+				values[key] = float64(n)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
 // CompareClassifications compares two classifications and returns similarity metrics
 func CompareClassifications(a, b *Classification) *ComparisonResult {
 	result := &ComparisonResult{
@@ -0,0 +1,53 @@
+package classifier
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestIntParameter(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]interface{}
+		want       int
+	}{
+		{name: "nil parameters", parameters: nil, want: 2000},
+		{name: "missing", parameters: map[string]interface{}{"temperature": 0.1}, want: 2000},
+		{name: "int", parameters: map[string]interface{}{"max_tokens": 500}, want: 500},
+		{name: "int64", parameters: map[string]interface{}{"max_tokens": int64(600)}, want: 600},
+		{name: "float64 from JSON", parameters: map[string]interface{}{"max_tokens": 700.0}, want: 700},
+		{name: "not a number", parameters: map[string]interface{}{"max_tokens": "800"}, want: 2000},
+	}
+	for _, tt := range tests {
+		if got := IntParameter(tt.parameters, "max_tokens", 2000); got != tt.want {
+			t.Errorf("%s: IntParameter = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoggableBody(t *testing.T) {
+	previous := MaxLoggedBodyChars
+	t.Cleanup(func() { MaxLoggedBodyChars = previous })
+
+	// "é" is two bytes, so a 9-byte limit falls inside the second one
+	body := []byte(`{"t":"éééé"}`)
+	tests := []struct {
+		limit int
+		want  string
+	}{
+		{limit: 0, want: "[omitted]"},
+		{limit: 64, want: string(body)},
+		{limit: 8, want: `{"t":"é...[truncated, 16 bytes total]`},
+		{limit: 9, want: `{"t":"é...[truncated, 16 bytes total]`},
+	}
+	for _, tt := range tests {
+		MaxLoggedBodyChars = tt.limit
+		got := loggableBody(body)
+		if got != tt.want {
+			t.Errorf("limit %d: loggableBody = %q, want %q", tt.limit, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("limit %d: %q is not valid UTF-8", tt.limit, got)
+		}
+	}
+}
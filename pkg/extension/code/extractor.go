@@ -0,0 +1,219 @@
+package code
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultMaxBytes is the default cap on the source read from a file
+const DefaultMaxBytes = 256 << 10
+
+// Language describes a programming language's name and comment syntax
+type Language struct {
+	Name string
+	// LineComments start comments that run to the end of the line
+	LineComments []string
+	// BlockStart and BlockEnd delimit block comments; empty when the language has none
+	BlockStart, BlockEnd string
+	// Quotes are the string delimiters, inside which comment markers are ignored. Only
+	// backtick (raw) strings may span lines.
+	Quotes string
+}
+
+var (
+	cStyle        = Language{LineComments: []string{"//"}, BlockStart: "/*", BlockEnd: "*/", Quotes: `"'`}
+	backtickStyle = Language{LineComments: []string{"//"}, BlockStart: "/*", BlockEnd: "*/", Quotes: "\"'`"}
+	hashStyle     = Language{LineComments: []string{"#"}, Quotes: `"'`}
+)
+
+// named returns a copy of the comment style with the given language name
+func (l Language) named(name string) Language {
+	l.Name = name
+	return l
+}
+
+// Languages maps source file extensions to their language
+var Languages = map[string]Language{
+	".go":    backtickStyle.named("Go"),
+	".js":    backtickStyle.named("JavaScript"),
+	".jsx":   backtickStyle.named("JavaScript"),
+	".mjs":   backtickStyle.named("JavaScript"),
+	".ts":    backtickStyle.named("TypeScript"),
+	".tsx":   backtickStyle.named("TypeScript"),
+	".java":  cStyle.named("Java"),
+	".kt":    cStyle.named("Kotlin"),
+	".scala": cStyle.named("Scala"),
+	".c":     cStyle.named("C"),
+	".h":     cStyle.named("C"),
+	".cpp":   cStyle.named("C++"),
+	".cc":    cStyle.named("C++"),
+	".hpp":   cStyle.named("C++"),
+	".cs":    cStyle.named("C#"),
+	".rs":    {Name: "Rust", LineComments: []string{"//"}, BlockStart: "/*", BlockEnd: "*/", Quotes: `"`}, // ' also marks lifetimes
+	".swift": cStyle.named("Swift"),
+	".php":   {Name: "PHP", LineComments: []string{"//", "#"}, BlockStart: "/*", BlockEnd: "*/", Quotes: `"'`},
+	".py":    hashStyle.named("Python"),
+	".rb":    hashStyle.named("Ruby"),
+	".sh":    hashStyle.named("Shell"),
+	".bash":  hashStyle.named("Shell"),
+	".r":     hashStyle.named("R"),
+	".pl":    hashStyle.named("Perl"),
+	".sql":   {Name: "SQL", LineComments: []string{"--"}, BlockStart: "/*", BlockEnd: "*/", Quotes: `'"`},
+	".lua":   {Name: "Lua", LineComments: []string{"--"}, Quotes: `"'`},
+}
+
+// Extractor reads source code files, prefixing the content with a language hint
+type Extractor struct {
+	// StripComments removes line and block comments before classification
+	StripComments bool
+	// MaxBytes caps the source read from each file (0 uses DefaultMaxBytes)
+	MaxBytes int
+}
+
+func NewExtractor() *Extractor {
+	return &Extractor{MaxBytes: DefaultMaxBytes}
+}
+
+func (e *Extractor) Extract(path string) (string, error) {
+	return e.ExtractWithOptions(path, extension.Options{})
+}
+
+// ExtractWithOptions reads the source, warning when it was truncated to MaxBytes
+func (e *Extractor) ExtractWithOptions(path string, opts extension.Options) (string, error) {
+	logger := log.WithFields(log.Fields{
+		"function": "Extract",
+		"path":     path,
+	})
+
+	ext := strings.ToLower(filepath.Ext(path))
+	lang, ok := Languages[ext]
+	if !ok {
+		return "", fmt.Errorf("unsupported source file extension: %s", ext)
+	}
+
+	maxBytes := e.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, int64(maxBytes)+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read source file: %w", err)
+	}
+	if len(data) > maxBytes {
+		logger.WithField("max_bytes", maxBytes).Debug("Source file truncated")
+		opts.Warnf("source truncated to the first %d bytes", maxBytes)
+		data = data[:maxBytes]
+		// Drop a rune split by the cap
+		for i := 0; i < utf8.UTFMax-1 && len(data) > 0; i++ {
+			if r, size := utf8.DecodeLastRune(data); r != utf8.RuneError || size > 1 {
+				break
+			}
+			data = data[:len(data)-1]
+		}
+	}
+
+	source := string(data)
+	if e.StripComments {
+		source = stripComments(source, lang)
+	}
+
+	logger.WithFields(log.Fields{
+		"language":         lang.Name,
+		"extracted_length": len(source),
+	}).Debug("Source code extraction completed")
+	return fmt.Sprintf("Language: %s\n\n%s", lang.Name, source), nil
+}
+
+// stripComments removes comments outside string literals and collapses the blank
+// lines they leave behind
+func stripComments(source string, lang Language) string {
+	var out strings.Builder
+	var quote byte
+	for i := 0; i < len(source); i++ {
+		c := source[i]
+		if quote != 0 {
+			out.WriteByte(c)
+			if c == '\\' && quote != '`' && i+1 < len(source) {
+				i++
+				out.WriteByte(source[i])
+			} else if c == quote || (c == '\n' && quote != '`') {
+				quote = 0
+			}
+			continue
+		}
+		if strings.IndexByte(lang.Quotes, c) >= 0 {
+			quote = c
+			out.WriteByte(c)
+			continue
+		}
+		if lang.BlockStart != "" && strings.HasPrefix(source[i:], lang.BlockStart) {
+			end := strings.Index(source[i+len(lang.BlockStart):], lang.BlockEnd)
+			if end < 0 {
+				break
+			}
+			i += len(lang.BlockStart) + end + len(lang.BlockEnd) - 1
+			continue
+		}
+		if isLineComment(source[i:], lang.LineComments) {
+			newline := strings.IndexByte(source[i:], '\n')
+			if newline < 0 {
+				break
+			}
+			i += newline - 1
+			continue
+		}
+		out.WriteByte(c)
+	}
+
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(out.String(), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func isLineComment(s string, markers []string) bool {
+	for _, marker := range markers {
+		if strings.HasPrefix(s, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Extractor) SupportedExtensions() []string {
+	extensions := make([]string, 0, len(Languages))
+	for ext := range Languages {
+		extensions = append(extensions, ext)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// FormatName names the format in the /formats listing
+func (e *Extractor) FormatName() string {
+	return "source code"
+}
@@ -0,0 +1,97 @@
+package code
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+// writeSource writes source to a file called name and returns its path
+func writeSource(t *testing.T, name, source string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStripComments(t *testing.T) {
+	for _, tc := range []struct {
+		name, source, want string
+	}{
+		{
+			name:   "Go",
+			source: "// Package main\npackage main\n\n/* block\n   comment */\nvar url = \"http://example.com\" // trailing\nvar raw = `/* kept */`\n",
+			want:   "package main\n\nvar url = \"http://example.com\"\nvar raw = `/* kept */`",
+		},
+		{
+			name:   "Python",
+			source: "# setup\nimport os\n\n\n\nprint('# not a comment')  # comment\n",
+			want:   "import os\n\nprint('# not a comment')",
+		},
+		{
+			name:   "SQL",
+			source: "-- report\nSELECT '--' FROM t; /* done */\n",
+			want:   "SELECT '--' FROM t;",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var lang Language
+			for _, l := range Languages {
+				if l.Name == tc.name {
+					lang = l
+				}
+			}
+			if got := stripComments(tc.source, lang); got != tc.want {
+				t.Errorf("stripComments() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	path := writeSource(t, "main.rs", "// entry point\nfn main() {}\n")
+
+	text, err := NewExtractor().Extract(path)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if text != "Language: Rust\n\n// entry point\nfn main() {}\n" {
+		t.Errorf("text = %q, want the source behind a language hint", text)
+	}
+
+	text, err = (&Extractor{StripComments: true}).Extract(path)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if text != "Language: Rust\n\nfn main() {}" {
+		t.Errorf("text with StripComments = %q", text)
+	}
+
+	if _, err := NewExtractor().Extract(writeSource(t, "notes.txt", "plain")); err == nil {
+		t.Error("Extract() of an unsupported extension succeeded")
+	}
+}
+
+func TestExtractTruncatesToMaxBytes(t *testing.T) {
+	// The cap falls inside the two-byte "é", which is dropped rather than split
+	path := writeSource(t, "name.py", "name = 'José'\n")
+
+	var warnings []string
+	text, err := (&Extractor{MaxBytes: 12}).ExtractWithOptions(path, extension.Options{
+		Warn: func(message string) { warnings = append(warnings, message) },
+	})
+	if err != nil {
+		t.Fatalf("ExtractWithOptions() error = %v", err)
+	}
+	if !strings.HasSuffix(text, "\n\nname = 'Jos") {
+		t.Errorf("text = %q, want the source cut before the split character", text)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "12 bytes") {
+		t.Errorf("warnings = %q, want one truncation warning", warnings)
+	}
+}
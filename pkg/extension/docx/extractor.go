@@ -1,32 +1,288 @@
 package docx
 
 import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
 	"github.com/unidoc/unioffice/document"
 )
 
-type Extractor struct{}
+type Extractor struct {
+	// IncludeComments appends reviewer comments and tracked insertions/deletions to the body text
+	IncludeComments bool
+}
 
 func NewExtractor() *Extractor {
 	return &Extractor{}
 }
 
 func (e *Extractor) Extract(path string) (string, error) {
+	if err := extension.CheckOOXMLEncryption(path); err != nil {
+		return "", err
+	}
+
 	doc, err := document.Open(path)
 	if err != nil {
 		return "", err
 	}
 	defer doc.Close()
 
+	numbering, err := readNumbering(path)
+	if err != nil {
+		return "", err
+	}
+
 	var text string
 	for _, para := range doc.Paragraphs() {
+		// List paragraphs are prefixed with their indented bullet or number
+		if ppr := para.X().PPr; ppr != nil && ppr.NumPr != nil && ppr.NumPr.NumId != nil && ppr.NumPr.NumId.ValAttr != 0 {
+			level := 0
+			if ppr.NumPr.Ilvl != nil {
+				level = int(ppr.NumPr.Ilvl.ValAttr)
+			}
+			text += numbering.label(int(ppr.NumPr.NumId.ValAttr), level)
+		}
 		for _, run := range para.Runs() {
 			text += run.Text()
 		}
 		text += "\n"
 	}
+
+	if e.IncludeComments {
+		review, err := extractReviewMarkup(path)
+		if err != nil {
+			return "", err
+		}
+		text += review
+	}
 	return text, nil
 }
 
+// extractReviewMarkup reads comments and tracked changes straight from the package
+// parts, since they are not exposed through the paragraph runs
+func extractReviewMarkup(path string) (string, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	var result strings.Builder
+	for _, file := range reader.File {
+		switch file.Name {
+		case "word/document.xml":
+			if err := readPart(file, func(r io.Reader) error { return writeRevisions(r, &result) }); err != nil {
+				return "", err
+			}
+		case "word/comments.xml":
+			if err := readPart(file, func(r io.Reader) error { return writeComments(r, &result) }); err != nil {
+				return "", err
+			}
+		}
+	}
+	return result.String(), nil
+}
+
+func readPart(file *zip.File, fn func(io.Reader) error) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return fn(rc)
+}
+
+// writeRevisions emits the text of every tracked insertion (w:ins) and deletion (w:del)
+func writeRevisions(r io.Reader, out *strings.Builder) error {
+	decoder := xml.NewDecoder(r)
+	var label string
+	var text strings.Builder
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "ins":
+				label = "[Tracked insertion"
+			case "del":
+				label = "[Tracked deletion"
+			default:
+				continue
+			}
+			if author := attr(t, "author"); author != "" {
+				label += " by " + author
+			}
+			label += "] "
+			text.Reset()
+		case xml.CharData:
+			if label != "" {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if (t.Name.Local == "ins" || t.Name.Local == "del") && label != "" {
+				if s := strings.TrimSpace(text.String()); s != "" {
+					out.WriteString(label + s + "\n")
+				}
+				label = ""
+			}
+		}
+	}
+}
+
+// writeComments emits every comment in word/comments.xml, labeled with its author
+func writeComments(r io.Reader, out *strings.Builder) error {
+	decoder := xml.NewDecoder(r)
+	var label string
+	var text strings.Builder
+	inText := false
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "comment":
+				label = "[Comment"
+				if author := attr(t, "author"); author != "" {
+					label += " by " + author
+				}
+				label += "] "
+				text.Reset()
+			case "t":
+				inText = true
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text.WriteString(" ")
+			case "comment":
+				if s := strings.TrimSpace(text.String()); s != "" {
+					out.WriteString(label + s + "\n")
+				}
+			}
+		}
+	}
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Headings returns the paragraphs styled as Heading 1-9, in document order. Documents
+// without heading styles return no headings.
+func (e *Extractor) Headings(path string) ([]extension.Heading, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var headings []extension.Heading
+	for _, file := range reader.File {
+		if file.Name != "word/document.xml" {
+			continue
+		}
+		err := readPart(file, func(r io.Reader) error {
+			headings, err = readHeadings(r)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return headings, nil
+}
+
+// readHeadings scans paragraphs for a w:pStyle of the form "Heading1" or "heading 1"
+func readHeadings(r io.Reader) ([]extension.Heading, error) {
+	decoder := xml.NewDecoder(r)
+	var headings []extension.Heading
+	var level int
+	var text strings.Builder
+	inText := false
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return headings, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				level = 0
+				text.Reset()
+			case "pStyle":
+				level = headingLevel(attr(t, "val"))
+			case "t":
+				inText = true
+			}
+		case xml.CharData:
+			if inText && level > 0 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if s := strings.TrimSpace(text.String()); level > 0 && s != "" {
+					headings = append(headings, extension.Heading{Level: level, Text: s})
+				}
+			}
+		}
+	}
+}
+
+// headingLevel returns the level of a heading style ID, or 0 for other styles
+func headingLevel(style string) int {
+	style = strings.ToLower(strings.ReplaceAll(style, " ", ""))
+	if !strings.HasPrefix(style, "heading") {
+		return 0
+	}
+	level, err := strconv.Atoi(strings.TrimPrefix(style, "heading"))
+	if err != nil || level < 1 || level > 9 {
+		return 0
+	}
+	return level
+}
+
+// CheckDependencies reports whether UniOffice is licensed
+func (e *Extractor) CheckDependencies() error {
+	return extension.CheckOfficeLicense()
+}
+
 func (e *Extractor) SupportedExtensions() []string {
 	return []string{".docx"}
 }
@@ -0,0 +1,183 @@
+package docx
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+// wordNamespace declares the WordprocessingML namespace on a part's root element
+const wordNamespace = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+
+// writeDocx writes a Word package holding the given document body and extra parts.
+// Only the parts read without UniOffice are needed.
+func writeDocx(t *testing.T, body string, extra map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "document.docx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	parts := map[string]string{
+		"word/document.xml": `<w:document ` + wordNamespace + `><w:body>` + body + `</w:body></w:document>`,
+	}
+	for name, content := range extra {
+		parts[name] = content
+	}
+	archive := zip.NewWriter(f)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractReviewMarkup(t *testing.T) {
+	path := writeDocx(t, `<w:p><w:r><w:t>The fee is </w:t></w:r>`+
+		`<w:del w:id="1" w:author="Dana"><w:r><w:delText>$100</w:delText></w:r></w:del>`+
+		`<w:ins w:id="2" w:author="Lee"><w:r><w:t>$120</w:t></w:r></w:ins></w:p>`,
+		map[string]string{"word/comments.xml": `<w:comments ` + wordNamespace + `>` +
+			`<w:comment w:id="0" w:author="Sam"><w:p><w:r><w:t>Check with</w:t></w:r></w:p><w:p><w:r><w:t>finance</w:t></w:r></w:p></w:comment>` +
+			`<w:comment w:id="1"><w:p><w:r><w:t>Unsigned note</w:t></w:r></w:p></w:comment></w:comments>`})
+
+	text, err := extractReviewMarkup(path)
+	if err != nil {
+		t.Fatalf("extractReviewMarkup: %v", err)
+	}
+	for _, want := range []string{
+		"[Tracked deletion by Dana] $100\n",
+		"[Tracked insertion by Lee] $120\n",
+		"[Comment by Sam] Check with finance\n",
+		"[Comment] Unsigned note\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("review markup lacks %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "The fee is") {
+		t.Errorf("review markup repeats the body text:\n%s", text)
+	}
+}
+
+func TestHeadings(t *testing.T) {
+	heading := func(style, text string) string {
+		return `<w:p><w:pPr><w:pStyle w:val="` + style + `"/></w:pPr><w:r><w:t>` + text + `</w:t></w:r></w:p>`
+	}
+	path := writeDocx(t, heading("Title", "Annual Report")+
+		heading("Heading1", "Results")+
+		`<w:p><w:r><w:t>Revenue grew.</w:t></w:r></w:p>`+
+		heading("heading 2", "By region")+
+		heading("Heading2", "  ")+
+		heading("Heading10", "Too deep")+
+		heading("Heading1", "Outlook"), nil)
+
+	headings, err := NewExtractor().Headings(path)
+	if err != nil {
+		t.Fatalf("Headings: %v", err)
+	}
+	want := []extension.Heading{{Level: 1, Text: "Results"}, {Level: 2, Text: "By region"}, {Level: 1, Text: "Outlook"}}
+	if !reflect.DeepEqual(headings, want) {
+		t.Errorf("headings = %+v, want %+v", headings, want)
+	}
+}
+
+func TestListNumbering(t *testing.T) {
+	level := func(ilvl int, format, text string) string {
+		return `<w:lvl w:ilvl="` + strconv.Itoa(ilvl) + `"><w:start w:val="1"/><w:numFmt w:val="` + format + `"/><w:lvlText w:val="` + text + `"/></w:lvl>`
+	}
+	path := writeDocx(t, "", map[string]string{"word/numbering.xml": `<w:numbering ` + wordNamespace + `>` +
+		`<w:abstractNum w:abstractNumId="0">` + level(0, "decimal", "%1.") + level(1, "lowerLetter", "%1.%2)") + `</w:abstractNum>` +
+		`<w:abstractNum w:abstractNumId="1">` + level(0, "bullet", "•") + `</w:abstractNum>` +
+		`<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>` +
+		`<w:num w:numId="2"><w:abstractNumId w:val="1"/></w:num>` +
+		`</w:numbering>`})
+
+	numbering, err := readNumbering(path)
+	if err != nil {
+		t.Fatalf("readNumbering: %v", err)
+	}
+	var labels []string
+	for _, item := range []struct{ numID, level int }{
+		{1, 0}, {1, 1}, {1, 1}, {2, 0}, {1, 0}, {1, 1},
+	} {
+		labels = append(labels, numbering.label(item.numID, item.level))
+	}
+	// Each list keeps its own counters, and a new item restarts the levels below it
+	want := []string{"1. ", "  1.a) ", "  1.b) ", "- ", "2. ", "  2.a) "}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("labels = %q, want %q", labels, want)
+	}
+
+	// Without a numbering part lists fall back to decimal numbers
+	numbering, err = readNumbering(writeDocx(t, "", nil))
+	if err != nil {
+		t.Fatalf("readNumbering: %v", err)
+	}
+	if got := numbering.label(5, 0) + numbering.label(5, 0); got != "1. 2. " {
+		t.Errorf("default labels = %q, want %q", got, "1. 2. ")
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	for _, tt := range []struct {
+		n      int
+		format string
+		want   string
+	}{
+		{4, "decimal", "4"},
+		{3, "lowerLetter", "c"},
+		{28, "upperLetter", "BB"},
+		{14, "lowerRoman", "xiv"},
+		{1994, "upperRoman", "MCMXCIV"},
+		{7, "unknown", "7"},
+	} {
+		if got := formatNumber(tt.n, tt.format); got != tt.want {
+			t.Errorf("formatNumber(%d, %q) = %q, want %q", tt.n, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestTables(t *testing.T) {
+	cell := func(paragraphs ...string) string {
+		var b strings.Builder
+		for _, p := range paragraphs {
+			b.WriteString(`<w:p><w:r><w:t>` + p + `</w:t></w:r></w:p>`)
+		}
+		return `<w:tc>` + b.String() + `</w:tc>`
+	}
+	row := func(cells ...string) string { return `<w:tr>` + strings.Join(cells, "") + `</w:tr>` }
+	nested := `<w:tbl>` + row(cell("inner")) + `</w:tbl>`
+
+	path := writeDocx(t, `<w:p><w:r><w:t>Quarterly figures</w:t></w:r></w:p>`+
+		`<w:tbl>`+row(cell("Region"), cell("Revenue"))+
+		row(cell("EMEA"), cell("120", "(estimate)"))+
+		row(cell("APAC"), `<w:tc><w:p><w:r><w:t>95</w:t></w:r></w:p>`+nested+`</w:tc>`)+`</w:tbl>`+
+		`<w:tbl>`+row(cell(""), cell(" "))+`</w:tbl>`+
+		`<w:tbl>`+row(cell("Name"))+row(cell("Ada"))+`</w:tbl>`, nil)
+
+	tables, err := NewExtractor().Tables(path)
+	if err != nil {
+		t.Fatalf("Tables: %v", err)
+	}
+	want := []extension.Table{
+		{Headers: []string{"Region", "Revenue"}, Rows: [][]string{{"EMEA", "120 (estimate)"}, {"APAC", "95 inner"}}},
+		{Headers: []string{"Name"}, Rows: [][]string{{"Ada"}}},
+	}
+	if !reflect.DeepEqual(tables, want) {
+		t.Errorf("Tables = %+v, want %+v", tables, want)
+	}
+}
@@ -0,0 +1,168 @@
+package docx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// listLevel is one level of a numbering definition in word/numbering.xml
+type listLevel struct {
+	Level int `xml:"ilvl,attr"`
+	Start struct {
+		Val int `xml:"val,attr"`
+	} `xml:"start"`
+	Format struct {
+		Val string `xml:"val,attr"`
+	} `xml:"numFmt"`
+	Text struct {
+		Val string `xml:"val,attr"`
+	} `xml:"lvlText"`
+}
+
+// numberingPart is the subset of word/numbering.xml needed to label list paragraphs
+type numberingPart struct {
+	Abstract []struct {
+		ID     int         `xml:"abstractNumId,attr"`
+		Levels []listLevel `xml:"lvl"`
+	} `xml:"abstractNum"`
+	Num []struct {
+		ID       int `xml:"numId,attr"`
+		Abstract struct {
+			Val int `xml:"val,attr"`
+		} `xml:"abstractNumId"`
+	} `xml:"num"`
+}
+
+// maxListLevel is the deepest list level Word supports (levels are 0-8)
+const maxListLevel = 8
+
+// listNumbering labels list paragraphs with their bullet or number, keeping a counter
+// per list and level
+type listNumbering struct {
+	levels   map[int]map[int]listLevel // numId to level definitions
+	counters map[int]*[maxListLevel + 1]int
+}
+
+// readNumbering loads the list definitions of the document at path. Documents without
+// a numbering part get numbered with decimal defaults.
+func readNumbering(path string) (*listNumbering, error) {
+	numbering := &listNumbering{
+		levels:   make(map[int]map[int]listLevel),
+		counters: make(map[int]*[maxListLevel + 1]int),
+	}
+
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.Name != "word/numbering.xml" {
+			continue
+		}
+		var part numberingPart
+		err := readPart(file, func(r io.Reader) error { return xml.NewDecoder(r).Decode(&part) })
+		if err != nil {
+			return nil, err
+		}
+		abstract := make(map[int]map[int]listLevel)
+		for _, a := range part.Abstract {
+			levels := make(map[int]listLevel)
+			for _, level := range a.Levels {
+				levels[level.Level] = level
+			}
+			abstract[a.ID] = levels
+		}
+		for _, num := range part.Num {
+			numbering.levels[num.ID] = abstract[num.Abstract.Val]
+		}
+	}
+	return numbering, nil
+}
+
+// label advances the counter of the list numID at level and returns the paragraph's
+// prefix: its indentation followed by the bullet or number
+func (n *listNumbering) label(numID, level int) string {
+	if level < 0 {
+		level = 0
+	}
+	if level > maxListLevel {
+		level = maxListLevel
+	}
+	counters, ok := n.counters[numID]
+	if !ok {
+		counters = &[maxListLevel + 1]int{}
+		n.counters[numID] = counters
+	}
+	definition := n.levels[numID][level]
+	if counters[level] == 0 {
+		counters[level] = max(definition.Start.Val, 1)
+	} else {
+		counters[level]++
+	}
+	// A new item restarts the numbering of the levels below it
+	for deeper := level + 1; deeper <= maxListLevel; deeper++ {
+		counters[deeper] = 0
+	}
+
+	indent := strings.Repeat("  ", level)
+	if definition.Format.Val == "bullet" {
+		return indent + "- "
+	}
+	text := definition.Text.Val
+	if text == "" {
+		text = "%" + strconv.Itoa(level+1) + "."
+	}
+	for l := level; l >= 0; l-- {
+		format := n.levels[numID][l].Format.Val
+		if l != level && format == "" {
+			format = "decimal"
+		}
+		text = strings.ReplaceAll(text, "%"+strconv.Itoa(l+1), formatNumber(max(counters[l], 1), format))
+	}
+	return indent + text + " "
+}
+
+// formatNumber renders n in a Word number format, falling back to decimal
+func formatNumber(n int, format string) string {
+	switch format {
+	case "lowerLetter":
+		return strings.ToLower(letters(n))
+	case "upperLetter":
+		return letters(n)
+	case "lowerRoman":
+		return strings.ToLower(roman(n))
+	case "upperRoman":
+		return roman(n)
+	}
+	return strconv.Itoa(n)
+}
+
+// letters renders n as Word does: A-Z, then AA-ZZ and so on
+func letters(n int) string {
+	letter := string(rune('A' + (n-1)%26))
+	return strings.Repeat(letter, (n-1)/26+1)
+}
+
+// roman renders n in upper-case Roman numerals
+func roman(n int) string {
+	numerals := []struct {
+		value  int
+		symbol string
+	}{
+		{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+		{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+	}
+	var result strings.Builder
+	for _, numeral := range numerals {
+		for n >= numeral.value {
+			result.WriteString(numeral.symbol)
+			n -= numeral.value
+		}
+	}
+	return result.String()
+}
@@ -0,0 +1,109 @@
+package docx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+// Tables returns the top-level tables (w:tbl) of the document body in order. Tables
+// nested in a cell are part of that cell's text.
+func (e *Extractor) Tables(path string) ([]extension.Table, error) {
+	if err := extension.CheckOOXMLEncryption(path); err != nil {
+		return nil, err
+	}
+
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var tables []extension.Table
+	for _, file := range reader.File {
+		if file.Name != "word/document.xml" {
+			continue
+		}
+		err := readPart(file, func(r io.Reader) error {
+			tables, err = readTables(r)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tables, nil
+}
+
+// readTables collects the rows (w:tr) and cells (w:tc) of each outermost w:tbl,
+// separating the paragraphs of a cell with a space
+func readTables(r io.Reader) ([]extension.Table, error) {
+	decoder := xml.NewDecoder(r)
+	var tables []extension.Table
+	var rows [][]string
+	var row []string
+	var cell strings.Builder
+	depth := 0 // w:tbl nesting level
+	inText := false
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return tables, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "tbl":
+				depth++
+				if depth == 1 {
+					rows = nil
+				}
+			case "tr":
+				if depth == 1 {
+					row = nil
+				}
+			case "tc":
+				if depth == 1 {
+					cell.Reset()
+				}
+			case "t":
+				inText = true
+			}
+		case xml.CharData:
+			if inText && depth > 0 {
+				cell.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if depth > 0 {
+					cell.WriteString(" ")
+				}
+			case "tc":
+				if depth == 1 {
+					row = append(row, cell.String())
+				}
+			case "tr":
+				if depth == 1 {
+					rows = append(rows, row)
+				}
+			case "tbl":
+				if depth == 1 {
+					if table := extension.NewTable(rows); len(table.Headers) > 0 {
+						tables = append(tables, table)
+					}
+				}
+				depth--
+			}
+		}
+	}
+}
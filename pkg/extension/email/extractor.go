@@ -0,0 +1,259 @@
+package email
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Extractor reads RFC 822 email messages, preferring the plain-text part of multipart
+// messages and falling back to the text of the HTML part
+type Extractor struct {
+	// StripQuotes removes quoted reply history: lines starting with ">", everything after
+	// an "On ... wrote:" or "-----Original Message-----" header, and HTML blockquotes
+	StripQuotes bool
+	// StripSignature removes the signature below the "-- " delimiter line
+	StripSignature bool
+}
+
+func NewExtractor() *Extractor {
+	return &Extractor{StripQuotes: true, StripSignature: true}
+}
+
+func (e *Extractor) Extract(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	msg, err := mail.ReadMessage(bufio.NewReader(f))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse email: %w", err)
+	}
+
+	body, err := e.messageText(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return "", err
+	}
+	if e.StripQuotes {
+		body = stripQuotedReply(body)
+	}
+	if e.StripSignature {
+		body = stripSignature(body)
+	}
+
+	var result strings.Builder
+	decoder := new(mime.WordDecoder)
+	for _, name := range []string{"Subject", "From", "To", "Date"} {
+		value := msg.Header.Get(name)
+		if decoded, err := decoder.DecodeHeader(value); err == nil {
+			value = decoded
+		}
+		if value != "" {
+			result.WriteString(name + ": " + value + "\n")
+		}
+	}
+	if result.Len() > 0 {
+		result.WriteString("\n")
+	}
+	result.WriteString(strings.TrimSpace(body))
+	return result.String(), nil
+}
+
+// messageText returns the text of a message part, choosing text/plain over text/html
+// among multipart alternatives and joining the text parts of mixed messages
+func (e *Extractor) messageText(contentType, encoding string, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	body = decodeTransfer(body, encoding)
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		reader := multipart.NewReader(body, params["boundary"])
+		var plain, htmlText []string
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", fmt.Errorf("failed to read email part: %w", err)
+			}
+			if strings.HasPrefix(part.Header.Get("Content-Disposition"), "attachment") {
+				continue
+			}
+			partType := part.Header.Get("Content-Type")
+			text, err := e.messageText(partType, part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				return "", err
+			}
+			if strings.HasPrefix(strings.ToLower(partType), "text/html") {
+				htmlText = append(htmlText, text)
+			} else if text != "" {
+				plain = append(plain, text)
+			}
+		}
+		if mediaType == "multipart/alternative" && len(plain) > 0 {
+			return plain[0], nil
+		}
+		if len(plain) > 0 {
+			return strings.Join(plain, "\n\n"), nil
+		}
+		return strings.Join(htmlText, "\n\n"), nil
+	case mediaType == "text/html":
+		return e.htmlText(body)
+	case strings.HasPrefix(mediaType, "text/"):
+		data, err := io.ReadAll(body)
+		return string(data), err
+	}
+	return "", nil
+}
+
+// decodeTransfer undoes the base64 or quoted-printable content transfer encoding
+func decodeTransfer(body io.Reader, encoding string) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, newlineStripper{body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// newlineStripper drops the line breaks of base64 bodies, which the decoder rejects
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (s newlineStripper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	kept := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' {
+			p[kept] = b
+			kept++
+		}
+	}
+	return kept, err
+}
+
+// htmlText returns the text of an HTML body with one line per block element. Quoted
+// replies in blockquotes and Gmail quote containers are skipped when StripQuotes is set.
+func (e *Extractor) htmlText(body io.Reader) (string, error) {
+	doc, err := html.Parse(body)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "head":
+				return
+			case "blockquote":
+				if e.StripQuotes {
+					return
+				}
+			case "div":
+				if e.StripQuotes && strings.Contains(attr(n, "class"), "gmail_quote") {
+					return
+				}
+			}
+		}
+		if n.Type == html.TextNode {
+			if text := strings.Join(strings.Fields(n.Data), " "); text != "" {
+				result.WriteString(text + " ")
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "p", "div", "br", "li", "tr", "h1", "h2", "h3", "h4", "h5", "h6":
+				result.WriteString("\n")
+			}
+		}
+	}
+	walk(doc)
+
+	var lines []string
+	for _, line := range strings.Split(result.String(), "\n") {
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+var (
+	// replyHeader matches the line introducing quoted history, e.g. "On Mon, 3 Jun 2024,
+	// Ann <ann@example.com> wrote:" or Outlook's "-----Original Message-----"
+	replyHeader    = regexp.MustCompile(`(?i)^(?:on\b.*\bwrote:|-{2,}\s*original message\s*-{2,}|-{2,}\s*forwarded message\s*-{2,})$`)
+	replyHeadStart = regexp.MustCompile(`(?i)^on\b`)
+	// outlookHeader matches the "From:" line of a quoted Outlook header block
+	outlookHeader = regexp.MustCompile(`(?i)^from:\s+\S`)
+	outlookField  = regexp.MustCompile(`(?i)^(?:sent|date|to|subject):\s`)
+)
+
+// stripQuotedReply drops lines quoted with ">" and everything from the first reply
+// header on. Mail clients wrap long "On ... wrote:" headers, so a header split over two
+// lines is recognized too.
+func stripQuotedReply(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var kept []string
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if replyHeader.MatchString(line) {
+			break
+		}
+		if i+1 < len(lines) && replyHeadStart.MatchString(line) && replyHeader.MatchString(line+" "+strings.TrimSpace(lines[i+1])) {
+			break
+		}
+		if i+1 < len(lines) && outlookHeader.MatchString(line) && outlookField.MatchString(strings.TrimSpace(lines[i+1])) {
+			break
+		}
+		if strings.HasPrefix(line, ">") {
+			continue
+		}
+		kept = append(kept, lines[i])
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// stripSignature drops everything from the "-- " signature delimiter line on
+func stripSignature(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.TrimRight(line, "\r") == "-- " || strings.TrimSpace(line) == "--" {
+			return strings.TrimSpace(strings.Join(lines[:i], "\n"))
+		}
+	}
+	return text
+}
+
+func (e *Extractor) SupportedExtensions() []string {
+	return []string{".eml"}
+}
@@ -0,0 +1,135 @@
+package email
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeEmail writes an RFC 822 message with CRLF line endings and returns its path
+func writeEmail(t *testing.T, message string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "message.eml")
+	if err := os.WriteFile(path, []byte(strings.ReplaceAll(message, "\n", "\r\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStripQuotedReply(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{
+			name: "quote markers",
+			text: "Sounds good.\n> Can we meet Friday?\n>> Earlier thread\nSee you then.",
+			want: "Sounds good.\nSee you then.",
+		},
+		{
+			name: "wrapped reply header",
+			text: "Approved.\n\nOn Mon, 3 Jun 2024 at 09:12, Ann Lee <ann@example.com>\nwrote:\nPlease approve the invoice.",
+			want: "Approved.",
+		},
+		{
+			name: "outlook original message",
+			text: "Paid today.\n-----Original Message-----\nFrom: Billing\nPlease pay.",
+			want: "Paid today.",
+		},
+		{
+			name: "outlook header block",
+			text: "Forwarding this.\n\nFrom: Ann Lee\nSent: Monday\nSubject: Invoice",
+			want: "Forwarding this.",
+		},
+		{
+			name: "from line in the body",
+			text: "From: the finance team, with thanks.\nRegards",
+			want: "From: the finance team, with thanks.\nRegards",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripQuotedReply(tt.text); got != tt.want {
+				t.Errorf("stripQuotedReply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripSignature(t *testing.T) {
+	if got := stripSignature("Thanks,\nDana\n-- \nDana Smith\nAcme Corp"); got != "Thanks,\nDana" {
+		t.Errorf("stripSignature() = %q", got)
+	}
+	if text := "Totals -- see attached"; stripSignature(text) != text {
+		t.Errorf("stripSignature() changed text without a delimiter line")
+	}
+}
+
+func TestExtractMultipartEmail(t *testing.T) {
+	path := writeEmail(t, `From: Ann Lee <ann@example.com>
+To: billing@example.com
+Subject: =?UTF-8?Q?Invoice_=E2=84=96_42?=
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Please find the invoice attached. Payment is due in 30 d=
+ays.
+
+On Fri, 1 Mar 2024, Bob wrote:
+> Can you resend the invoice?
+--=20
+Ann Lee
+--inner
+Content-Type: text/html
+
+<p>HTML version</p>
+--inner--
+--outer
+Content-Type: text/plain
+Content-Disposition: attachment; filename="notes.txt"
+
+Attachment text
+--outer--
+`)
+
+	text, err := NewExtractor().Extract(path)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	want := "Subject: Invoice № 42\nFrom: Ann Lee <ann@example.com>\nTo: billing@example.com\n\n" +
+		"Please find the invoice attached. Payment is due in 30 days."
+	if text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+
+	// With stripping disabled the reply history and signature are kept
+	text, err = (&Extractor{}).Extract(path)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if !strings.Contains(text, "> Can you resend the invoice?") || !strings.HasSuffix(text, "Ann Lee") {
+		t.Errorf("text = %q, want the quoted reply and signature kept", text)
+	}
+}
+
+func TestExtractHTMLEmail(t *testing.T) {
+	path := writeEmail(t, `Subject: Receipt
+Content-Type: text/html; charset=utf-8
+Content-Transfer-Encoding: base64
+
+`+"PGRpdj5UaGFua3MgZm9yIHlvdXIgb3JkZXIuPC9kaXY+PGRpdiBjbGFzcz0iZ21haWxfcXVvdGUiPk9s\nZCB0aHJlYWQ8L2Rpdj48YmxvY2txdW90ZT5RdW90ZWQ8L2Jsb2NrcXVvdGU+\n")
+
+	text, err := NewExtractor().Extract(path)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if text != "Subject: Receipt\n\nThanks for your order." {
+		t.Errorf("text = %q, want the HTML text without quoted history", text)
+	}
+}
@@ -1,9 +1,12 @@
 package excel
 
 import (
+	"fmt"
 	"strings"
 
+	"github.com/adaptive-scale/superclass/pkg/extension"
 	"github.com/unidoc/unioffice/spreadsheet"
+	"github.com/unidoc/unioffice/spreadsheet/reference"
 )
 
 type Extractor struct{}
@@ -13,16 +16,40 @@ func NewExtractor() *Extractor {
 }
 
 func (e *Extractor) Extract(path string) (string, error) {
+	return e.ExtractWithOptions(path, extension.Options{})
+}
+
+// ExtractWithOptions extracts the text of the sheets selected by opts.Sheets, failing
+// with ErrUnmatchedSelection when a selected sheet does not exist
+func (e *Extractor) ExtractWithOptions(path string, opts extension.Options) (string, error) {
+	if err := extension.CheckOOXMLEncryption(path); err != nil {
+		return "", err
+	}
+
 	wb, err := spreadsheet.Open(path)
 	if err != nil {
 		return "", err
 	}
 	defer wb.Close()
 
+	sheets := wb.Sheets()
+	for _, name := range opts.Sheets {
+		found := false
+		for _, sheet := range sheets {
+			found = found || strings.EqualFold(strings.TrimSpace(sheet.Name()), strings.TrimSpace(name))
+		}
+		if !found {
+			return "", fmt.Errorf("%w: no sheet named %q", extension.ErrUnmatchedSelection, name)
+		}
+	}
+
 	var result strings.Builder
 
-	// Process each sheet
-	for _, sheet := range wb.Sheets() {
+	// Process each selected sheet
+	for _, sheet := range sheets {
+		if !opts.SheetSelected(sheet.Name()) {
+			continue
+		}
 		result.WriteString("Sheet: " + sheet.Name() + "\n")
 
 		// Process each row
@@ -50,6 +77,48 @@ func (e *Extractor) Extract(path string) (string, error) {
 	return result.String(), nil
 }
 
+// Tables returns one table per sheet, with the first non-empty row as the header.
+// Cells keep their column position, so blank cells inside a row stay empty strings.
+func (e *Extractor) Tables(path string) ([]extension.Table, error) {
+	if err := extension.CheckOOXMLEncryption(path); err != nil {
+		return nil, err
+	}
+
+	wb, err := spreadsheet.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer wb.Close()
+
+	var tables []extension.Table
+	for _, sheet := range wb.Sheets() {
+		var rows [][]string
+		for _, row := range sheet.Rows() {
+			var cells []string
+			for i, cell := range row.Cells() {
+				column := i
+				if ref, err := reference.ParseCellReference(cell.Reference()); err == nil {
+					column = int(ref.ColumnIdx)
+				}
+				for len(cells) <= column {
+					cells = append(cells, "")
+				}
+				cells[column] = cell.GetString()
+			}
+			rows = append(rows, cells)
+		}
+		if table := extension.NewTable(rows); len(table.Headers) > 0 {
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+// CheckDependencies reports whether UniOffice is licensed
+func (e *Extractor) CheckDependencies() error {
+	return extension.CheckOfficeLicense()
+}
+
 func (e *Extractor) SupportedExtensions() []string {
 	return []string{".xlsx", ".xlsm"}
 }
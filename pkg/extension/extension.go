@@ -0,0 +1,202 @@
+package extension
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ProgressFunc is called by extractors as they work through a document, e.g. once per
+// PDF page or presentation slide, with the number of units done out of total
+type ProgressFunc func(done, total int)
+
+// Options carries optional per-call settings for extractors that support them
+type Options struct {
+	// Progress, when set, receives progress updates during extraction
+	Progress ProgressFunc
+	// MaxPages stops extraction after this many pages or slides (0 extracts everything)
+	MaxPages int
+	// Pages selects the pages or slides to extract (empty extracts every page)
+	Pages []PageRange
+	// Sheets selects spreadsheet sheets by name, ignoring case (empty extracts every sheet)
+	Sheets []string
+	// Warn, when set, receives non-fatal problems such as a skipped slide or low OCR
+	// confidence; the text extracted around them is still returned
+	Warn func(message string)
+}
+
+// PageLimit returns how many of total pages to extract under MaxPages
+func (o Options) PageLimit(total int) int {
+	if o.MaxPages > 0 && o.MaxPages < total {
+		return o.MaxPages
+	}
+	return total
+}
+
+// SelectPages returns the 1-based numbers of the pages to extract out of total under
+// Pages and MaxPages. It fails with ErrUnmatchedSelection when a range of Pages lies
+// beyond the document.
+func (o Options) SelectPages(total int) ([]int, error) {
+	for _, r := range o.Pages {
+		if r.First > total {
+			return nil, fmt.Errorf("%w: page %s of %d", ErrUnmatchedSelection, r, total)
+		}
+	}
+	var pages []int
+	for n := 1; n <= total && (o.MaxPages <= 0 || len(pages) < o.MaxPages); n++ {
+		if o.PageSelected(n) {
+			pages = append(pages, n)
+		}
+	}
+	return pages, nil
+}
+
+// PageSelected reports whether the 1-based page n is selected by Pages
+func (o Options) PageSelected(n int) bool {
+	if len(o.Pages) == 0 {
+		return true
+	}
+	for _, r := range o.Pages {
+		if n >= r.First && n <= r.Last {
+			return true
+		}
+	}
+	return false
+}
+
+// SheetSelected reports whether the sheet named name is selected by Sheets
+func (o Options) SheetSelected(name string) bool {
+	if len(o.Sheets) == 0 {
+		return true
+	}
+	for _, sheet := range o.Sheets {
+		if strings.EqualFold(strings.TrimSpace(sheet), strings.TrimSpace(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrUnmatchedSelection is returned when a page, slide or sheet selection names
+// something the document does not have
+var ErrUnmatchedSelection = errors.New("selection does not match the document")
+
+// PageRange is an inclusive range of 1-based page or slide numbers
+type PageRange struct {
+	First, Last int
+}
+
+func (r PageRange) String() string {
+	if r.First == r.Last {
+		return strconv.Itoa(r.First)
+	}
+	return fmt.Sprintf("%d-%d", r.First, r.Last)
+}
+
+// ParsePageRanges parses a comma-separated list of page numbers and ranges such as "1-3,7"
+func ParsePageRanges(s string) ([]PageRange, error) {
+	var ranges []PageRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		if !isRange {
+			last = first
+		}
+		r := PageRange{}
+		var err1, err2 error
+		r.First, err1 = strconv.Atoi(strings.TrimSpace(first))
+		r.Last, err2 = strconv.Atoi(strings.TrimSpace(last))
+		if err1 != nil || err2 != nil || r.First < 1 || r.Last < r.First {
+			return nil, fmt.Errorf("invalid page range %q", part)
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no pages selected in %q", s)
+	}
+	return ranges, nil
+}
+
+// Report calls the progress callback when one is configured
+func (o Options) Report(done, total int) {
+	if o.Progress != nil {
+		o.Progress(done, total)
+	}
+}
+
+// Warnf reports a non-fatal extraction problem when a warning callback is configured
+func (o Options) Warnf(format string, args ...interface{}) {
+	if o.Warn != nil {
+		o.Warn(fmt.Sprintf(format, args...))
+	}
+}
+
+// Heading is a document heading with its outline level (1 for top-level headings)
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+}
+
+// Table is a table read from a document, with the cell text of its header row and of
+// each following row
+type Table struct {
+	Headers []string   `json:"headers"`
+	Rows    [][]string `json:"rows"`
+}
+
+// NewTable builds a Table from rows of cell text, taking the first row as the header.
+// Cells are trimmed, and trailing empty cells and empty rows are dropped, so the same
+// table read from different formats compares equal.
+func NewTable(rows [][]string) Table {
+	var cleaned [][]string
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.Join(strings.Fields(cell), " ")
+		}
+		for len(cells) > 0 && cells[len(cells)-1] == "" {
+			cells = cells[:len(cells)-1]
+		}
+		if len(cells) > 0 {
+			cleaned = append(cleaned, cells)
+		}
+	}
+	if len(cleaned) == 0 {
+		return Table{}
+	}
+	return Table{Headers: cleaned[0], Rows: cleaned[1:]}
+}
+
+// ErrEncryptedDocument is returned when a document is password-protected and cannot be read
+var ErrEncryptedDocument = errors.New("document is encrypted or password-protected")
+
+// oleSignature is the magic header of OLE compound files. Password-protected
+// OOXML documents (docx, pptx, xlsx) are wrapped in this container instead of zip.
+var oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// CheckOOXMLEncryption returns ErrEncryptedDocument when the OOXML file at path
+// is stored in an encrypted OLE container rather than a plain zip package
+func CheckOOXMLEncryption(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(oleSignature))
+	if _, err := io.ReadFull(f, header); err != nil {
+		// Too short to be encrypted; let the extractor report the real problem
+		return nil
+	}
+	if bytes.Equal(header, oleSignature) {
+		return fmt.Errorf("%w: %s", ErrEncryptedDocument, path)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package extension
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePageRanges(t *testing.T) {
+	got, err := ParsePageRanges(" 1-3, 7 ,,9-9")
+	if err != nil {
+		t.Fatalf("ParsePageRanges: %v", err)
+	}
+	if want := []PageRange{{1, 3}, {7, 7}, {9, 9}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsePageRanges = %v, want %v", got, want)
+	}
+
+	for _, s := range []string{"", " , ", "0", "3-1", "a-2", "2-"} {
+		if _, err := ParsePageRanges(s); err == nil {
+			t.Errorf("ParsePageRanges(%q) succeeded", s)
+		}
+	}
+}
+
+func TestSelectPages(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		want    []int
+	}{
+		{name: "everything", want: []int{1, 2, 3, 4, 5}},
+		{name: "max pages", options: Options{MaxPages: 2}, want: []int{1, 2}},
+		{name: "ranges", options: Options{Pages: []PageRange{{4, 9}, {1, 1}}}, want: []int{1, 4, 5}},
+		{name: "ranges and max pages", options: Options{Pages: []PageRange{{2, 5}}, MaxPages: 2}, want: []int{2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.options.SelectPages(5)
+			if err != nil {
+				t.Fatalf("SelectPages: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectPages(5) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := (Options{Pages: []PageRange{{6, 8}}}).SelectPages(5); !errors.Is(err, ErrUnmatchedSelection) {
+		t.Errorf("range beyond the document: error = %v, want ErrUnmatchedSelection", err)
+	}
+}
+
+func TestSheetSelected(t *testing.T) {
+	if !(Options{}).SheetSelected("Summary") {
+		t.Error("no selection should select every sheet")
+	}
+	o := Options{Sheets: []string{" summary "}}
+	if !o.SheetSelected("Summary") || o.SheetSelected("Data") {
+		t.Errorf("Sheets %q matched the wrong sheets", o.Sheets)
+	}
+}
+
+func TestNewTable(t *testing.T) {
+	got := NewTable([][]string{
+		{" ", ""},
+		{" Region ", "Revenue\n (USD)", ""},
+		{"EMEA", "120"},
+		{"", "", ""},
+		{"Total", ""},
+	})
+	want := Table{Headers: []string{"Region", "Revenue (USD)"}, Rows: [][]string{{"EMEA", "120"}, {"Total"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewTable = %+v, want %+v", got, want)
+	}
+
+	if got := NewTable([][]string{{"", " "}}); !reflect.DeepEqual(got, Table{}) {
+		t.Errorf("NewTable of blank rows = %+v, want an empty table", got)
+	}
+}
@@ -2,30 +2,52 @@ package html
 
 import (
 	"bytes"
+	"io"
 	"io/ioutil"
+	"os"
 	"strings"
 
+	"github.com/adaptive-scale/superclass/pkg/extension"
 	"golang.org/x/net/html"
 )
 
-type Extractor struct{}
+type Extractor struct {
+	// IncludeOutline prepends the outline of the h1-h6 headings to the extracted text
+	IncludeOutline bool
+}
 
 func NewExtractor() *Extractor {
-	return &Extractor{}
+	return &Extractor{IncludeOutline: true}
 }
 
 func (e *Extractor) Extract(path string) (string, error) {
-	content, err := ioutil.ReadFile(path)
+	f, err := os.Open(path)
 	if err != nil {
 		return "", err
 	}
+	defer f.Close()
+	return e.ExtractReader(f)
+}
 
-	doc, err := html.Parse(bytes.NewReader(content))
+// ExtractReader extracts the text of an HTML document read from r, for callers that
+// find HTML inside other containers such as web archives
+func (e *Extractor) ExtractReader(r io.Reader) (string, error) {
+	doc, err := html.Parse(r)
 	if err != nil {
 		return "", err
 	}
 
 	var result strings.Builder
+	if e.IncludeOutline {
+		if headings := readHeadings(doc); len(headings) > 0 {
+			result.WriteString("Outline:\n")
+			for _, heading := range headings {
+				result.WriteString(strings.Repeat("  ", heading.Level-1) + "- " + heading.Text + "\n")
+			}
+			result.WriteString("\n")
+		}
+	}
+
 	var extractText func(*html.Node)
 	extractText = func(n *html.Node) {
 		if n.Type == html.TextNode {
@@ -44,6 +66,116 @@ func (e *Extractor) Extract(path string) (string, error) {
 	return strings.TrimSpace(result.String()), nil
 }
 
+// Headings returns the h1-h6 elements of the page in document order. Headings without
+// text, such as icon-only ones, are skipped.
+func (e *Extractor) Headings(path string) ([]extension.Heading, error) {
+	doc, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+	return readHeadings(doc), nil
+}
+
+func parse(path string) (*html.Node, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return html.Parse(bytes.NewReader(content))
+}
+
+// headingLevels maps heading tags to their outline levels
+var headingLevels = map[string]int{"h1": 1, "h2": 2, "h3": 3, "h4": 4, "h5": 5, "h6": 6}
+
+func readHeadings(doc *html.Node) []extension.Heading {
+	var headings []extension.Heading
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if level, ok := headingLevels[n.Data]; ok {
+				if text := nodeText(n); text != "" {
+					headings = append(headings, extension.Heading{Level: level, Text: text})
+				}
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return headings
+}
+
+// Tables returns the table elements of the page in document order, one row per tr.
+// Tables nested in a cell are part of that cell's text.
+func (e *Extractor) Tables(path string) ([]extension.Table, error) {
+	doc, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []extension.Table
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "table" {
+			if table := readTable(n); len(table.Headers) > 0 || len(table.Rows) > 0 {
+				tables = append(tables, table)
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return tables, nil
+}
+
+// readTable collects the th and td cells of the rows directly under table, including
+// those in thead, tbody and tfoot
+func readTable(table *html.Node) extension.Table {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "thead", "tbody", "tfoot":
+				walk(c)
+			case "tr":
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "th" || cell.Data == "td") {
+						row = append(row, nodeText(cell))
+					}
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	walk(table)
+	return extension.NewTable(rows)
+}
+
+// nodeText returns the text inside n with whitespace collapsed
+func nodeText(n *html.Node) string {
+	var parts []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			parts = append(parts, strings.Fields(n.Data)...)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(parts, " ")
+}
+
 func (e *Extractor) SupportedExtensions() []string {
 	return []string{".html", ".htm"}
 }
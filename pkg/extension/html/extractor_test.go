@@ -0,0 +1,96 @@
+package html
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+const page = `<html><body>
+<h1>Annual   Report</h1>
+<p>Revenue grew.</p>
+<h2><i class="icon"></i></h2>
+<h2>Results by <em>region</em></h2>
+<h4>EMEA</h4>
+<p>Strong quarter.</p>
+</body></html>`
+
+func TestHeadings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.html")
+	if err := os.WriteFile(path, []byte(page), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewExtractor().Headings(path)
+	if err != nil {
+		t.Fatalf("Headings: %v", err)
+	}
+	want := []extension.Heading{
+		{Level: 1, Text: "Annual Report"},
+		{Level: 2, Text: "Results by region"},
+		{Level: 4, Text: "EMEA"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Headings = %+v, want %+v", got, want)
+	}
+}
+
+func TestExtractPrependsOutline(t *testing.T) {
+	e := NewExtractor()
+	text, err := e.ExtractReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("ExtractReader: %v", err)
+	}
+	outline := "Outline:\n- Annual Report\n  - Results by region\n      - EMEA\n\n"
+	if !strings.HasPrefix(text, outline) {
+		t.Errorf("text = %q, want it to start with %q", text, outline)
+	}
+	if !strings.HasSuffix(text, "EMEA Strong quarter.") {
+		t.Errorf("text = %q, want the page text after the outline", text)
+	}
+
+	e.IncludeOutline = false
+	text, _ = e.ExtractReader(strings.NewReader(page))
+	if strings.Contains(text, "Outline:") || !strings.HasPrefix(text, "Annual   Report") {
+		t.Errorf("text = %q, want only the page text with the outline disabled", text)
+	}
+
+	text, _ = NewExtractor().ExtractReader(strings.NewReader("<p>No headings here.</p>"))
+	if text != "No headings here." {
+		t.Errorf("text = %q, want no outline for a page without headings", text)
+	}
+}
+
+func TestTables(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.html")
+	err := os.WriteFile(path, []byte(`<html><body>
+<table>
+  <thead><tr><th>Region</th><th>Revenue <small>(USD)</small></th></tr></thead>
+  <tbody>
+    <tr><td>EMEA</td><td>120</td></tr>
+    <tr><td>APAC</td><td><table><tr><td>95</td></tr></table></td></tr>
+  </tbody>
+  <tfoot><tr><td>Total</td><td>215</td></tr></tfoot>
+</table>
+<table><tr><td> </td></tr></table>
+</body></html>`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := NewExtractor().Tables(path)
+	if err != nil {
+		t.Fatalf("Tables: %v", err)
+	}
+	want := []extension.Table{{
+		Headers: []string{"Region", "Revenue (USD)"},
+		Rows:    [][]string{{"EMEA", "120"}, {"APAC", "95"}, {"Total", "215"}},
+	}}
+	if !reflect.DeepEqual(tables, want) {
+		t.Errorf("Tables = %+v, want %+v", tables, want)
+	}
+}
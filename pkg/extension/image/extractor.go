@@ -1,30 +1,168 @@
 package image
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
 	gosseract "github.com/otiai10/gosseract/v2"
+	log "github.com/sirupsen/logrus"
 )
 
-type Extractor struct{}
+type Extractor struct {
+	// Languages passed to Tesseract, combined as e.g. "eng+ara". Defaults to English.
+	Languages []string
+	// FallbackLanguages is a broader language set retried when confidence is low
+	FallbackLanguages []string
+	// MinConfidence (0-100) below which the fallback languages are tried
+	MinConfidence float64
+	// Preprocess cleans up the image before it is passed to Tesseract
+	Preprocess Preprocessing
+	// IncludeMetadata prepends the image's EXIF/XMP title, description, artist, device,
+	// software and date to the OCR text
+	IncludeMetadata bool
+	// IncludeGPS also prepends the EXIF GPS coordinates when IncludeMetadata is set
+	IncludeGPS bool
+}
 
 func NewExtractor() *Extractor {
-	return &Extractor{}
+	return &Extractor{
+		Languages:     []string{"eng"},
+		MinConfidence: 60,
+	}
 }
 
 func (e *Extractor) Extract(path string) (string, error) {
+	return e.ExtractWithOptions(path, extension.Options{})
+}
+
+// ExtractWithOptions runs OCR, warning when the best result stays below MinConfidence.
+// With IncludeMetadata the image's EXIF/XMP fields precede the text.
+func (e *Extractor) ExtractWithOptions(path string, opts extension.Options) (string, error) {
+	text, confidence, err := e.extract(path)
+	if err != nil {
+		return "", err
+	}
+	if confidence < e.MinConfidence {
+		opts.Warnf("low OCR confidence (%.0f, below %.0f)", confidence, e.MinConfidence)
+	}
+	if e.IncludeMetadata {
+		metadata, err := ReadMetadata(path)
+		if err != nil {
+			opts.Warnf("image metadata could not be read: %v", err)
+		} else {
+			text = metadata.Text(e.IncludeGPS) + text
+		}
+	}
+	return text, nil
+}
+
+// extract runs OCR with the configured languages, retrying with the fallback languages
+// when confidence is low, and returns the better result with its confidence
+func (e *Extractor) extract(path string) (string, float64, error) {
+	path, cleanup, err := preprocessImage(path, e.Preprocess)
+	if err != nil {
+		return "", 0, err
+	}
+	defer cleanup()
+
+	text, confidence, err := ocr(path, e.Languages, e.Preprocess.DPI)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if len(e.FallbackLanguages) == 0 || confidence >= e.MinConfidence {
+		return text, confidence, nil
+	}
+
+	logger := log.WithFields(log.Fields{
+		"function":           "Extract",
+		"path":               path,
+		"confidence":         confidence,
+		"languages":          strings.Join(e.Languages, "+"),
+		"fallback_languages": strings.Join(e.FallbackLanguages, "+"),
+	})
+	logger.Debug("Low OCR confidence, retrying with fallback languages")
+
+	fallbackText, fallbackConfidence, err := ocr(path, e.FallbackLanguages, e.Preprocess.DPI)
+	if err != nil {
+		logger.WithError(err).Warn("Fallback OCR failed, keeping initial result")
+		return text, confidence, nil
+	}
+	if fallbackConfidence > confidence {
+		logger.WithField("fallback_confidence", fallbackConfidence).Debug("Using fallback OCR result")
+		return fallbackText, fallbackConfidence, nil
+	}
+	return text, confidence, nil
+}
+
+// ocr recognizes the text of an image; it is a variable so tests can stand in for Tesseract
+var ocr = tesseractOCR
+
+// tesseractOCR runs Tesseract with the given languages and returns the text along with
+// the mean word confidence (0-100). A non-zero dpi overrides the resolution Tesseract assumes.
+func tesseractOCR(path string, languages []string, dpi int) (string, float64, error) {
 	client := gosseract.NewClient()
 	defer client.Close()
 
 	// Set the image path
 	if err := client.SetImage(path); err != nil {
-		return "", err
+		return "", 0, err
 	}
 
 	// Set additional OCR configurations for better accuracy
-	client.SetLanguage("eng")                         // Use English language
+	if len(languages) == 0 {
+		languages = []string{"eng"}
+	}
+	client.SetLanguage(languages...)                  // Joined by Tesseract as e.g. "eng+ara"
 	client.SetConfigFile("preserve_interword_spaces") // Preserve spacing between words
+	if dpi > 0 {
+		client.SetVariable("user_defined_dpi", strconv.Itoa(dpi))
+	}
 
 	// Perform OCR
-	return client.Text()
+	text, err := client.Text()
+	if err != nil {
+		return "", 0, err
+	}
+
+	boxes, err := client.GetBoundingBoxes(gosseract.RIL_WORD)
+	if err != nil || len(boxes) == 0 {
+		return text, 0, nil
+	}
+	var total float64
+	for _, box := range boxes {
+		total += box.Confidence
+	}
+	return text, total / float64(len(boxes)), nil
+}
+
+// availableLanguages lists the installed Tesseract language data
+var availableLanguages = gosseract.GetAvailableLanguages
+
+// CheckDependencies reports whether Tesseract language data is installed for every
+// configured language
+func (e *Extractor) CheckDependencies() error {
+	installed, err := availableLanguages()
+	if err != nil {
+		return fmt.Errorf("tesseract language data not found: %w", err)
+	}
+	if len(installed) == 0 {
+		return fmt.Errorf("tesseract language data not found; is tesseract installed?")
+	}
+	have := make(map[string]bool, len(installed))
+	for _, lang := range installed {
+		have[lang] = true
+	}
+	for _, lang := range append(append([]string{}, e.Languages...), e.FallbackLanguages...) {
+		for _, l := range strings.Split(lang, "+") {
+			if l != "" && !have[l] {
+				return fmt.Errorf("tesseract language data for %q is not installed", l)
+			}
+		}
+	}
+	return nil
 }
 
 func (e *Extractor) SupportedExtensions() []string {
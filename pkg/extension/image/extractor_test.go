@@ -0,0 +1,102 @@
+package image
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+// fakeOCR stands in for Tesseract, answering with the result configured for the
+// requested language set and recording the sets tried
+type fakeOCR struct {
+	results map[string]ocrResult
+	tried   []string
+}
+
+type ocrResult struct {
+	text       string
+	confidence float64
+}
+
+func (f *fakeOCR) install(t *testing.T) {
+	t.Helper()
+	previous := ocr
+	ocr = func(path string, languages []string, dpi int) (string, float64, error) {
+		key := strings.Join(languages, "+")
+		f.tried = append(f.tried, key)
+		r := f.results[key]
+		return r.text, r.confidence, nil
+	}
+	t.Cleanup(func() { ocr = previous })
+}
+
+func TestOCRLanguageFallback(t *testing.T) {
+	tests := []struct {
+		name        string
+		results     map[string]ocrResult
+		wantText    string
+		wantTried   []string
+		wantWarning bool
+	}{
+		{
+			name:      "confident first pass",
+			results:   map[string]ocrResult{"eng": {"invoice", 85}},
+			wantText:  "invoice",
+			wantTried: []string{"eng"},
+		},
+		{
+			name:      "fallback improves confidence",
+			results:   map[string]ocrResult{"eng": {"f?ct?ra", 40}, "eng+ara+spa": {"factura", 75}},
+			wantText:  "factura",
+			wantTried: []string{"eng", "eng+ara+spa"},
+		},
+		{
+			name:        "fallback is worse",
+			results:     map[string]ocrResult{"eng": {"receipt", 50}, "eng+ara+spa": {"r3c3ipt", 30}},
+			wantText:    "receipt",
+			wantTried:   []string{"eng", "eng+ara+spa"},
+			wantWarning: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeOCR{results: tt.results}
+			fake.install(t)
+
+			e := NewExtractor()
+			e.Languages = []string{"eng"}
+			e.FallbackLanguages = []string{"eng", "ara", "spa"}
+			var warnings []string
+			text, err := e.ExtractWithOptions("scan.png", extension.Options{Warn: func(m string) { warnings = append(warnings, m) }})
+			if err != nil {
+				t.Fatalf("ExtractWithOptions: %v", err)
+			}
+			if text != tt.wantText {
+				t.Errorf("text = %q, want %q", text, tt.wantText)
+			}
+			if strings.Join(fake.tried, ",") != strings.Join(tt.wantTried, ",") {
+				t.Errorf("languages tried = %q, want %q", fake.tried, tt.wantTried)
+			}
+			if warned := len(warnings) > 0; warned != tt.wantWarning {
+				t.Errorf("warnings = %q, want a low-confidence warning: %v", warnings, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestCheckDependenciesRequiresEveryLanguage(t *testing.T) {
+	previous := availableLanguages
+	availableLanguages = func() ([]string, error) { return []string{"eng", "ara"}, nil }
+	t.Cleanup(func() { availableLanguages = previous })
+
+	e := NewExtractor()
+	e.Languages = []string{"eng+ara"}
+	if err := e.CheckDependencies(); err != nil {
+		t.Errorf("CheckDependencies with installed languages: %v", err)
+	}
+	e.FallbackLanguages = []string{"eng", "fra"}
+	if err := e.CheckDependencies(); err == nil || !strings.Contains(err.Error(), `"fra"`) {
+		t.Errorf("CheckDependencies = %v, want an error naming fra", err)
+	}
+}
@@ -0,0 +1,328 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// maxMetadataBytes bounds how much of an image is scanned for EXIF and XMP. Both are
+// stored near the start of JPEG, PNG and WebP files.
+const maxMetadataBytes = 1 << 20
+
+// Metadata holds the descriptive EXIF and XMP fields of an image
+type Metadata struct {
+	Title       string
+	Description string
+	Artist      string
+	Copyright   string
+	// Device is the camera or scanner make and model
+	Device   string
+	Software string
+	// DateTime is when the image was taken or, failing that, last modified
+	DateTime string
+	// Latitude and Longitude are decimal degrees; HasGPS reports whether they were present
+	Latitude, Longitude float64
+	HasGPS              bool
+}
+
+// Text renders the populated fields as lines to prepend to the OCR text. GPS
+// coordinates are included only when includeGPS is set.
+func (m Metadata) Text(includeGPS bool) string {
+	var lines []string
+	add := func(name, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			lines = append(lines, fmt.Sprintf("- %s: %s", name, value))
+		}
+	}
+	add("Title", m.Title)
+	add("Description", m.Description)
+	add("Artist", m.Artist)
+	add("Copyright", m.Copyright)
+	add("Device", m.Device)
+	add("Software", m.Software)
+	add("Date", m.DateTime)
+	if includeGPS && m.HasGPS {
+		add("Location", fmt.Sprintf("%.6f, %.6f", m.Latitude, m.Longitude))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "Image metadata:\n" + strings.Join(lines, "\n") + "\n\n"
+}
+
+// ReadMetadata reads the EXIF and XMP metadata of the image at path. EXIF is read from
+// JPEG APP1 segments, PNG eXIf chunks, WebP EXIF chunks and TIFF headers; XMP packets
+// are found by scanning the file. XMP values take precedence over EXIF ones.
+func ReadMetadata(path string) (Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxMetadataBytes))
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var m Metadata
+	if tiff := findEXIF(data); tiff != nil {
+		readEXIF(tiff, &m)
+	}
+	readXMP(data, &m)
+	return m, nil
+}
+
+// findEXIF returns the TIFF-structured EXIF block of a JPEG, PNG, WebP or TIFF file
+func findEXIF(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")):
+		return data
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		// JPEG: walk the segments up to the image data
+		for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+			marker := data[i+1]
+			if marker == 0xDA || marker == 0xD9 {
+				break
+			}
+			size := int(binary.BigEndian.Uint16(data[i+2:]))
+			end := i + 2 + size
+			if size < 2 || end > len(data) {
+				break
+			}
+			if segment := data[i+4 : end]; marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+				return segment[6:]
+			}
+			i = end
+		}
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		for i := 8; i+8 <= len(data); {
+			size := int(binary.BigEndian.Uint32(data[i:]))
+			end := i + 8 + size
+			if size < 0 || end > len(data) {
+				break
+			}
+			if string(data[i+4:i+8]) == "eXIf" {
+				return data[i+8 : end]
+			}
+			i = end + 4 // skip the CRC
+		}
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		for i := 12; i+8 <= len(data); {
+			size := int(binary.LittleEndian.Uint32(data[i+4:]))
+			end := i + 8 + size
+			if size < 0 || end > len(data) {
+				break
+			}
+			if string(data[i:i+4]) == "EXIF" {
+				return bytes.TrimPrefix(data[i+8:end], []byte("Exif\x00\x00"))
+			}
+			i = end + size%2 // chunks are padded to an even size
+		}
+	}
+	return nil
+}
+
+// EXIF tags read from the image, Exif and GPS directories
+const (
+	tagImageDescription = 0x010E
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagSoftware         = 0x0131
+	tagDateTime         = 0x0132
+	tagArtist           = 0x013B
+	tagCopyright        = 0x8298
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+	tagGPSLatitudeRef   = 0x0001
+	tagGPSLatitude      = 0x0002
+	tagGPSLongitudeRef  = 0x0003
+	tagGPSLongitude     = 0x0004
+)
+
+// tiffReader reads values from a TIFF structure, returning zero values for offsets
+// outside the data
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func (r tiffReader) uint16(offset int) int {
+	if offset < 0 || offset+2 > len(r.data) {
+		return 0
+	}
+	return int(r.order.Uint16(r.data[offset:]))
+}
+
+func (r tiffReader) uint32(offset int) int {
+	if offset < 0 || offset+4 > len(r.data) {
+		return 0
+	}
+	return int(r.order.Uint32(r.data[offset:]))
+}
+
+// tiffEntry is a directory entry; valueOffset points at the value, which is stored in
+// the entry itself when it fits in four bytes
+type tiffEntry struct {
+	typ, count, valueOffset int
+}
+
+// directory reads the entries of the directory at offset, keyed by tag
+func (r tiffReader) directory(offset int) map[int]tiffEntry {
+	entries := make(map[int]tiffEntry)
+	n := r.uint16(offset)
+	for i := 0; i < n; i++ {
+		at := offset + 2 + i*12
+		if at+12 > len(r.data) {
+			break
+		}
+		entry := tiffEntry{typ: r.uint16(at + 2), count: r.uint32(at + 4), valueOffset: at + 8}
+		if size := entry.count * tiffTypeSize(entry.typ); size > 4 || size < 0 {
+			entry.valueOffset = r.uint32(at + 8)
+		}
+		entries[r.uint16(at)] = entry
+	}
+	return entries
+}
+
+// tiffTypeSize returns the byte size of one value of a TIFF field type
+func tiffTypeSize(typ int) int {
+	switch typ {
+	case 1, 2, 6, 7: // BYTE, ASCII, SBYTE, UNDEFINED
+		return 1
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	}
+	return 0
+}
+
+// ascii returns the string value of an ASCII entry
+func (r tiffReader) ascii(entries map[int]tiffEntry, tag int) string {
+	entry, ok := entries[tag]
+	if !ok || entry.typ != 2 || entry.valueOffset < 0 || entry.valueOffset+entry.count > len(r.data) {
+		return ""
+	}
+	value := r.data[entry.valueOffset : entry.valueOffset+entry.count]
+	if i := bytes.IndexByte(value, 0); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(string(value))
+}
+
+// degrees converts a GPS coordinate stored as three rationals to decimal degrees
+func (r tiffReader) degrees(entries map[int]tiffEntry, tag int) (float64, bool) {
+	entry, ok := entries[tag]
+	if !ok || entry.typ != 5 || entry.count != 3 {
+		return 0, false
+	}
+	var value float64
+	for i, scale := range []float64{1, 60, 3600} {
+		numerator := r.uint32(entry.valueOffset + i*8)
+		denominator := r.uint32(entry.valueOffset + i*8 + 4)
+		if denominator == 0 {
+			return 0, false
+		}
+		value += float64(numerator) / float64(denominator) / scale
+	}
+	return value, true
+}
+
+// readEXIF fills m from a TIFF-structured EXIF block
+func readEXIF(tiff []byte, m *Metadata) {
+	r := tiffReader{data: tiff}
+	switch {
+	case bytes.HasPrefix(tiff, []byte("II")):
+		r.order = binary.LittleEndian
+	case bytes.HasPrefix(tiff, []byte("MM")):
+		r.order = binary.BigEndian
+	default:
+		return
+	}
+	if r.uint16(2) != 42 {
+		return
+	}
+
+	ifd0 := r.directory(r.uint32(4))
+	m.Description = r.ascii(ifd0, tagImageDescription)
+	m.Artist = r.ascii(ifd0, tagArtist)
+	m.Copyright = r.ascii(ifd0, tagCopyright)
+	m.Device = strings.TrimSpace(r.ascii(ifd0, tagMake) + " " + r.ascii(ifd0, tagModel))
+	m.Software = r.ascii(ifd0, tagSoftware)
+	m.DateTime = r.ascii(ifd0, tagDateTime)
+
+	if entry, ok := ifd0[tagExifIFD]; ok {
+		exif := r.directory(r.uint32(entry.valueOffset))
+		if original := r.ascii(exif, tagDateTimeOriginal); original != "" {
+			m.DateTime = original
+		}
+	}
+	if entry, ok := ifd0[tagGPSIFD]; ok {
+		gps := r.directory(r.uint32(entry.valueOffset))
+		lat, latOK := r.degrees(gps, tagGPSLatitude)
+		lon, lonOK := r.degrees(gps, tagGPSLongitude)
+		if latOK && lonOK {
+			if r.ascii(gps, tagGPSLatitudeRef) == "S" {
+				lat = -lat
+			}
+			if r.ascii(gps, tagGPSLongitudeRef) == "W" {
+				lon = -lon
+			}
+			m.Latitude, m.Longitude, m.HasGPS = lat, lon, true
+		}
+	}
+}
+
+var (
+	xmpPacket = regexp.MustCompile(`(?s)<x:xmpmeta.*?</x:xmpmeta>`)
+	// xmpAltText matches dc:title and dc:description, whose values are language alternatives
+	xmpAltText = regexp.MustCompile(`(?s)<dc:(title|description)>.*?<rdf:li[^>]*>(.*?)</rdf:li>`)
+	// xmpProperty matches simple properties written as elements or attributes
+	xmpProperty = regexp.MustCompile(`(?s)(xmp:CreatorTool|xmp:CreateDate|photoshop:DateCreated)(?:="([^"]*)"|>([^<]*)<)`)
+	xmpCreator  = regexp.MustCompile(`(?s)<dc:creator>.*?<rdf:li[^>]*>(.*?)</rdf:li>`)
+)
+
+// readXMP fills m from the first XMP packet in data
+func readXMP(data []byte, m *Metadata) {
+	packet := xmpPacket.Find(data)
+	if packet == nil {
+		return
+	}
+	for _, match := range xmpAltText.FindAllSubmatch(packet, -1) {
+		value := html.UnescapeString(strings.TrimSpace(string(match[2])))
+		if value == "" {
+			continue
+		}
+		if string(match[1]) == "title" {
+			m.Title = value
+		} else {
+			m.Description = value
+		}
+	}
+	if match := xmpCreator.FindSubmatch(packet); match != nil {
+		if value := html.UnescapeString(strings.TrimSpace(string(match[1]))); value != "" {
+			m.Artist = value
+		}
+	}
+	for _, match := range xmpProperty.FindAllSubmatch(packet, -1) {
+		value := html.UnescapeString(strings.TrimSpace(string(match[2]) + string(match[3])))
+		if value == "" {
+			continue
+		}
+		switch string(match[1]) {
+		case "xmp:CreatorTool":
+			m.Software = value
+		case "xmp:CreateDate", "photoshop:DateCreated":
+			m.DateTime = value
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package image
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+// tiffField is a directory entry written by tiffDirectory
+type tiffField struct {
+	tag, typ int
+	value    []byte
+}
+
+func asciiField(tag int, s string) tiffField {
+	return tiffField{tag: tag, typ: 2, value: append([]byte(s), 0)}
+}
+
+func longField(tag, v int) tiffField {
+	return tiffField{tag: tag, typ: 4, value: binary.LittleEndian.AppendUint32(nil, uint32(v))}
+}
+
+// rationalField stores degrees, minutes and seconds as three rationals over 1
+func rationalField(tag int, dms ...int) tiffField {
+	var value []byte
+	for _, v := range dms {
+		value = binary.LittleEndian.AppendUint32(value, uint32(v))
+		value = binary.LittleEndian.AppendUint32(value, 1)
+	}
+	return tiffField{tag: tag, typ: 5, value: value}
+}
+
+// tiffDirectory encodes a little-endian directory that starts at offset, followed by
+// the values too large to store in their entries
+func tiffDirectory(offset int, fields ...tiffField) []byte {
+	var dir, values []byte
+	dir = binary.LittleEndian.AppendUint16(dir, uint16(len(fields)))
+	valuesAt := offset + 2 + 12*len(fields) + 4
+	for _, f := range fields {
+		count := len(f.value) / tiffTypeSize(f.typ)
+		dir = binary.LittleEndian.AppendUint16(dir, uint16(f.tag))
+		dir = binary.LittleEndian.AppendUint16(dir, uint16(f.typ))
+		dir = binary.LittleEndian.AppendUint32(dir, uint32(count))
+		if len(f.value) <= 4 {
+			dir = append(dir, make([]byte, 4)...)
+			copy(dir[len(dir)-4:], f.value)
+		} else {
+			dir = binary.LittleEndian.AppendUint32(dir, uint32(valuesAt+len(values)))
+			values = append(values, f.value...)
+		}
+	}
+	dir = binary.LittleEndian.AppendUint32(dir, 0) // no next directory
+	return append(dir, values...)
+}
+
+// exifBlock returns a TIFF-structured EXIF block describing a photo taken in London
+func exifBlock() []byte {
+	ifd0 := func(gpsAt int) []byte {
+		return tiffDirectory(8,
+			asciiField(tagImageDescription, "Receipt scan"),
+			asciiField(tagMake, "Canon"),
+			asciiField(tagModel, "EOS R5"),
+			asciiField(tagArtist, "Dana"),
+			longField(tagGPSIFD, gpsAt),
+		)
+	}
+	gpsAt := 8 + len(ifd0(0))
+	gps := tiffDirectory(gpsAt,
+		asciiField(tagGPSLatitudeRef, "N"),
+		rationalField(tagGPSLatitude, 51, 30, 0),
+		asciiField(tagGPSLongitudeRef, "W"),
+		rationalField(tagGPSLongitude, 0, 7, 30),
+	)
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	tiff = append(tiff, ifd0(gpsAt)...)
+	return append(tiff, gps...)
+}
+
+// writeJPEG writes a JPEG header holding exif in an APP1 segment, followed by extra
+// bytes, and returns its path
+func writeJPEG(t *testing.T, exif, extra []byte) string {
+	t.Helper()
+	var b bytes.Buffer
+	b.Write([]byte{0xFF, 0xD8, 0xFF, 0xE1})
+	binary.Write(&b, binary.BigEndian, uint16(2+6+len(exif)))
+	b.WriteString("Exif\x00\x00")
+	b.Write(exif)
+	b.Write(extra)
+	b.Write([]byte{0xFF, 0xD9})
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadMetadataFromEXIF(t *testing.T) {
+	m, err := ReadMetadata(writeJPEG(t, exifBlock(), nil))
+	if err != nil {
+		t.Fatalf("ReadMetadata: %v", err)
+	}
+	if m.Description != "Receipt scan" || m.Device != "Canon EOS R5" || m.Artist != "Dana" {
+		t.Errorf("metadata = %+v, want the EXIF description, device and artist", m)
+	}
+	if !m.HasGPS || math.Abs(m.Latitude-51.5) > 1e-9 || math.Abs(m.Longitude+0.125) > 1e-9 {
+		t.Errorf("GPS = %v %f, %f, want 51.5, -0.125", m.HasGPS, m.Latitude, m.Longitude)
+	}
+}
+
+func TestXMPOverridesEXIF(t *testing.T) {
+	xmp := []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF><rdf:Description xmp:CreatorTool="Scanner App 2.1">` +
+		`<dc:title><rdf:Alt><rdf:li xml:lang="x-default">Lunch &amp; taxi</rdf:li></rdf:Alt></dc:title>` +
+		`<dc:description><rdf:Alt><rdf:li xml:lang="x-default">Expense receipts</rdf:li></rdf:Alt></dc:description>` +
+		`</rdf:Description></rdf:RDF></x:xmpmeta>`)
+
+	m, err := ReadMetadata(writeJPEG(t, exifBlock(), xmp))
+	if err != nil {
+		t.Fatalf("ReadMetadata: %v", err)
+	}
+	if m.Title != "Lunch & taxi" || m.Description != "Expense receipts" || m.Software != "Scanner App 2.1" {
+		t.Errorf("metadata = %+v, want the XMP title, description and creator tool", m)
+	}
+	if m.Artist != "Dana" {
+		t.Errorf("artist = %q, want the EXIF value kept where XMP has none", m.Artist)
+	}
+}
+
+func TestExtractIncludesMetadata(t *testing.T) {
+	fake := &fakeOCR{results: map[string]ocrResult{"eng": {"TOTAL 12.50", 90}}}
+	fake.install(t)
+	path := writeJPEG(t, exifBlock(), nil)
+
+	e := NewExtractor()
+	e.Languages = []string{"eng"}
+	text, err := e.ExtractWithOptions(path, extension.Options{})
+	if err != nil {
+		t.Fatalf("ExtractWithOptions: %v", err)
+	}
+	if text != "TOTAL 12.50" {
+		t.Errorf("text = %q, want only the OCR text by default", text)
+	}
+
+	e.IncludeMetadata = true
+	text, _ = e.ExtractWithOptions(path, extension.Options{})
+	want := "Image metadata:\n- Description: Receipt scan\n- Artist: Dana\n- Device: Canon EOS R5\n\nTOTAL 12.50"
+	if text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+
+	e.IncludeGPS = true
+	text, _ = e.ExtractWithOptions(path, extension.Options{})
+	if !strings.Contains(text, "- Location: 51.500000, -0.125000\n") {
+		t.Errorf("text = %q, want the GPS location", text)
+	}
+}
@@ -0,0 +1,253 @@
+package image
+
+import (
+	"fmt"
+	goimage "image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"math"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Preprocessing configures the image clean-up applied before OCR. Deskew and
+// Binarize work on a grayscale copy, so they imply Grayscale.
+type Preprocessing struct {
+	// Grayscale converts the image to 8-bit luminance
+	Grayscale bool
+	// Binarize thresholds the image to black and white using Otsu's method
+	Binarize bool
+	// Deskew straightens text rotated by up to MaxSkewDegrees
+	Deskew bool
+	// Scale upscales the image by this factor before OCR (values <= 1 leave the size unchanged)
+	Scale float64
+	// DPI is passed to Tesseract as the source resolution when the image lacks one (0 lets Tesseract guess)
+	DPI int
+}
+
+// MaxSkewDegrees bounds the rotation searched for when deskewing
+const MaxSkewDegrees = 5.0
+
+// skewStepDegrees is the angle resolution of the deskew search
+const skewStepDegrees = 0.5
+
+// transforms reports whether any pixel-level preprocessing is requested
+func (p Preprocessing) transforms() bool {
+	return p.Grayscale || p.Binarize || p.Deskew || p.Scale > 1
+}
+
+// preprocessImage applies p to the image at path and writes the result to a temporary
+// PNG. It returns the path to OCR and a cleanup function. Images in formats the
+// standard library cannot decode are passed through unchanged.
+func preprocessImage(path string, p Preprocessing) (string, func(), error) {
+	noop := func() {}
+	if !p.transforms() {
+		return path, noop, nil
+	}
+
+	logger := log.WithFields(log.Fields{
+		"function": "preprocessImage",
+		"path":     path,
+	})
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to open image: %w", err)
+	}
+	src, format, err := goimage.Decode(f)
+	f.Close()
+	if err != nil {
+		logger.WithError(err).Debug("Image format not supported for preprocessing, using original")
+		return path, noop, nil
+	}
+
+	gray := toGray(src)
+	if p.Scale > 1 {
+		gray = upscale(gray, p.Scale)
+	}
+	if p.Deskew {
+		if angle := detectSkew(gray); angle != 0 {
+			logger.WithField("angle", angle).Debug("Deskewing image")
+			gray = rotate(gray, -angle)
+		}
+	}
+	if p.Binarize {
+		binarize(gray, otsuThreshold(gray))
+	}
+
+	out, err := os.CreateTemp("", "ocr-preprocessed-*.png")
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to create preprocessed image: %w", err)
+	}
+	cleanup := func() { os.Remove(out.Name()) }
+	if err := png.Encode(out, gray); err != nil {
+		out.Close()
+		cleanup()
+		return "", noop, fmt.Errorf("failed to encode preprocessed image: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		cleanup()
+		return "", noop, err
+	}
+
+	logger.WithFields(log.Fields{
+		"format":   format,
+		"width":    gray.Bounds().Dx(),
+		"height":   gray.Bounds().Dy(),
+		"binarize": p.Binarize,
+		"deskew":   p.Deskew,
+		"scale":    p.Scale,
+	}).Debug("Image preprocessed for OCR")
+	return out.Name(), cleanup, nil
+}
+
+// toGray converts img to grayscale with its origin at (0, 0)
+func toGray(img goimage.Image) *goimage.Gray {
+	b := img.Bounds()
+	gray := goimage.NewGray(goimage.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			gray.Set(x, y, color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)))
+		}
+	}
+	return gray
+}
+
+// upscale resizes gray by factor using bilinear interpolation
+func upscale(gray *goimage.Gray, factor float64) *goimage.Gray {
+	b := gray.Bounds()
+	w, h := int(float64(b.Dx())*factor), int(float64(b.Dy())*factor)
+	out := goimage.NewGray(goimage.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		sy := math.Min(float64(y)/factor, float64(b.Dy()-1))
+		y0 := int(sy)
+		y1 := min(y0+1, b.Dy()-1)
+		fy := sy - float64(y0)
+		for x := 0; x < w; x++ {
+			sx := math.Min(float64(x)/factor, float64(b.Dx()-1))
+			x0 := int(sx)
+			x1 := min(x0+1, b.Dx()-1)
+			fx := sx - float64(x0)
+			top := float64(gray.GrayAt(x0, y0).Y)*(1-fx) + float64(gray.GrayAt(x1, y0).Y)*fx
+			bottom := float64(gray.GrayAt(x0, y1).Y)*(1-fx) + float64(gray.GrayAt(x1, y1).Y)*fx
+			out.SetGray(x, y, color.Gray{Y: uint8(top*(1-fy) + bottom*fy + 0.5)})
+		}
+	}
+	return out
+}
+
+// otsuThreshold picks the gray level that best separates foreground from background
+func otsuThreshold(gray *goimage.Gray) uint8 {
+	var histogram [256]int
+	for _, v := range gray.Pix {
+		histogram[v]++
+	}
+	total := len(gray.Pix)
+	var sum float64
+	for i, count := range histogram {
+		sum += float64(i * count)
+	}
+
+	var sumBackground, bestVariance float64
+	var weightBackground int
+	var threshold uint8
+	for i, count := range histogram {
+		weightBackground += count
+		if weightBackground == 0 {
+			continue
+		}
+		weightForeground := total - weightBackground
+		if weightForeground == 0 {
+			break
+		}
+		sumBackground += float64(i * count)
+		meanBackground := sumBackground / float64(weightBackground)
+		meanForeground := (sum - sumBackground) / float64(weightForeground)
+		variance := float64(weightBackground) * float64(weightForeground) * (meanBackground - meanForeground) * (meanBackground - meanForeground)
+		if variance > bestVariance {
+			bestVariance = variance
+			threshold = uint8(i)
+		}
+	}
+	return threshold
+}
+
+// binarize sets every pixel above threshold to white and the rest to black
+func binarize(gray *goimage.Gray, threshold uint8) {
+	for i, v := range gray.Pix {
+		if v > threshold {
+			gray.Pix[i] = 255
+		} else {
+			gray.Pix[i] = 0
+		}
+	}
+}
+
+// detectSkew estimates the text rotation in degrees by finding the angle whose
+// horizontal projection of dark pixels has the sharpest row-to-row contrast
+func detectSkew(gray *goimage.Gray) float64 {
+	threshold := otsuThreshold(gray)
+	b := gray.Bounds()
+	// Sample a bounded number of pixels so large scans stay fast
+	step := max(1, max(b.Dx(), b.Dy())/800)
+
+	var dark []goimage.Point
+	for y := 0; y < b.Dy(); y += step {
+		for x := 0; x < b.Dx(); x += step {
+			if gray.GrayAt(x, y).Y <= threshold {
+				dark = append(dark, goimage.Point{X: x, Y: y})
+			}
+		}
+	}
+	if len(dark) == 0 {
+		return 0
+	}
+
+	cx, cy := float64(b.Dx())/2, float64(b.Dy())/2
+	var bestAngle, bestScore float64
+	for angle := -MaxSkewDegrees; angle <= MaxSkewDegrees; angle += skewStepDegrees {
+		rad := angle * math.Pi / 180
+		sin, cos := math.Sin(rad), math.Cos(rad)
+		rows := make(map[int]int)
+		for _, p := range dark {
+			// Row of the point once the image is rotated by -angle
+			y := -float64(p.X-int(cx))*sin + (float64(p.Y)-cy)*cos
+			rows[int(math.Round(y/float64(step)))]++
+		}
+		var score float64
+		for row, count := range rows {
+			diff := float64(count - rows[row-1])
+			score += diff * diff
+		}
+		if score > bestScore {
+			bestScore = score
+			bestAngle = angle
+		}
+	}
+	return bestAngle
+}
+
+// rotate turns gray by angle degrees around its centre, filling uncovered areas with white
+func rotate(gray *goimage.Gray, angle float64) *goimage.Gray {
+	b := gray.Bounds()
+	out := goimage.NewGray(b)
+	rad := angle * math.Pi / 180
+	sin, cos := math.Sin(rad), math.Cos(rad)
+	cx, cy := float64(b.Dx())/2, float64(b.Dy())/2
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			sx := int(math.Round(dx*cos + dy*sin + cx))
+			sy := int(math.Round(-dx*sin + dy*cos + cy))
+			if sx < 0 || sy < 0 || sx >= b.Dx() || sy >= b.Dy() {
+				out.Pix[y*out.Stride+x] = 255
+				continue
+			}
+			out.Pix[y*out.Stride+x] = gray.Pix[sy*gray.Stride+sx]
+		}
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package image
+
+import (
+	goimage "image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// textLines draws dark horizontal bars, standing in for lines of text, on a white page
+func textLines(width, height int) *goimage.Gray {
+	page := goimage.NewGray(goimage.Rect(0, 0, width, height))
+	for i := range page.Pix {
+		page.Pix[i] = 235
+	}
+	for y := 40; y+8 < height-40; y += 30 {
+		for dy := 0; dy < 8; dy++ {
+			for x := 40; x < width-40; x++ {
+				page.SetGray(x, y+dy, color.Gray{Y: 25})
+			}
+		}
+	}
+	return page
+}
+
+func TestBinarizeSeparatesInkFromPaper(t *testing.T) {
+	page := textLines(200, 120)
+	binarize(page, otsuThreshold(page))
+	if page.GrayAt(0, 0).Y != 255 || page.GrayAt(50, 42).Y != 0 {
+		t.Errorf("paper = %d, ink = %d, want 255 and 0", page.GrayAt(0, 0).Y, page.GrayAt(50, 42).Y)
+	}
+	for _, v := range page.Pix {
+		if v != 0 && v != 255 {
+			t.Fatalf("binarized image holds gray level %d", v)
+		}
+	}
+}
+
+func TestDetectSkew(t *testing.T) {
+	page := textLines(600, 400)
+	if angle := detectSkew(page); angle != 0 {
+		t.Errorf("detectSkew(straight page) = %v, want 0", angle)
+	}
+
+	skewed := rotate(page, 3)
+	angle := detectSkew(skewed)
+	if math.Abs(angle-3) > skewStepDegrees {
+		t.Errorf("detectSkew(page rotated by 3) = %v, want 3", angle)
+	}
+	if straightened := detectSkew(rotate(skewed, -angle)); math.Abs(straightened) > skewStepDegrees {
+		t.Errorf("skew after straightening = %v, want about 0", straightened)
+	}
+}
+
+func TestPreprocessedImageReachesOCR(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, textLines(100, 80)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	var ocrPath string
+	var ocrDPI int
+	var ocrSize goimage.Point
+	previous := ocr
+	ocr = func(path string, languages []string, dpi int) (string, float64, error) {
+		ocrPath, ocrDPI = path, dpi
+		if f, err := os.Open(path); err == nil {
+			if config, err := png.DecodeConfig(f); err == nil {
+				ocrSize = goimage.Pt(config.Width, config.Height)
+			}
+			f.Close()
+		}
+		return "text", 90, nil
+	}
+	t.Cleanup(func() { ocr = previous })
+
+	e := NewExtractor()
+	e.Preprocess = Preprocessing{Binarize: true, Scale: 2, DPI: 300}
+	if _, err := e.Extract(path); err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if ocrPath == path || ocrDPI != 300 || ocrSize != goimage.Pt(200, 160) {
+		t.Errorf("OCR read %s (%v) at %d DPI, want a 200x160 preprocessed copy at 300 DPI", ocrPath, ocrSize, ocrDPI)
+	}
+	if _, err := os.Stat(ocrPath); !os.IsNotExist(err) {
+		t.Errorf("preprocessed image %s left behind", ocrPath)
+	}
+
+	// Without transforms the original image is read as is
+	e.Preprocess = Preprocessing{DPI: 150}
+	if _, err := e.Extract(path); err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if ocrPath != path || ocrDPI != 150 {
+		t.Errorf("OCR read %s at %d DPI, want the original at 150 DPI", ocrPath, ocrDPI)
+	}
+}
@@ -0,0 +1,151 @@
+package iwork
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/adaptive-scale/superclass/pkg/extension/pdf"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrUnsupportedVersion is returned when a package holds neither IWA archives nor a preview PDF
+var ErrUnsupportedVersion = errors.New("unsupported iWork document version")
+
+// previewPDFNames are the locations of the QuickLook preview in iWork packages
+var previewPDFNames = []string{"QuickLook/Preview.pdf", "preview.pdf"}
+
+// Extractor reads Apple Pages, Keynote and Numbers documents. Text is recovered from
+// the IWA archives of current iWork versions, falling back to the QuickLook preview
+// PDF that older versions embed.
+type Extractor struct{}
+
+func NewExtractor() *Extractor {
+	return &Extractor{}
+}
+
+func (e *Extractor) Extract(filePath string) (string, error) {
+	logger := log.WithFields(log.Fields{
+		"function": "Extract",
+		"path":     filePath,
+	})
+
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open iWork package: %w", err)
+	}
+	defer reader.Close()
+
+	files := reader.File
+	// Some versions nest the archives in Index.zip
+	if index := findFile(files, "Index.zip"); index != nil {
+		nested, err := openNestedZip(index)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to open Index.zip")
+		} else {
+			files = append(files, nested.File...)
+		}
+	}
+
+	text, err := extractIWA(files)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to read IWA archives, trying preview PDF")
+	}
+	if strings.TrimSpace(text) != "" {
+		return text, nil
+	}
+
+	for _, name := range previewPDFNames {
+		if preview := findFile(reader.File, name); preview != nil {
+			logger.WithField("preview", name).Debug("Extracting text from preview PDF")
+			return extractPreviewPDF(preview)
+		}
+	}
+	return "", fmt.Errorf("%w: no IWA text or preview PDF found", ErrUnsupportedVersion)
+}
+
+// extractIWA concatenates the text stored in every .iwa archive of the package.
+// Archives are read in name order so Document.iwa precedes its data tiles.
+func extractIWA(files []*zip.File) (string, error) {
+	var archives []*zip.File
+	for _, f := range files {
+		if strings.HasSuffix(f.Name, ".iwa") {
+			archives = append(archives, f)
+		}
+	}
+	sort.Slice(archives, func(i, j int) bool { return archives[i].Name < archives[j].Name })
+
+	var builder strings.Builder
+	var errs []error
+	for _, f := range archives {
+		data, err := readZipFile(f)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.Name, err))
+			continue
+		}
+		if err := readIWAText(data, &builder); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.Name, err))
+		}
+	}
+	return builder.String(), errors.Join(errs...)
+}
+
+// extractPreviewPDF writes the embedded preview to a temporary file and extracts its text
+func extractPreviewPDF(f *zip.File) (string, error) {
+	data, err := readZipFile(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to read preview PDF: %w", err)
+	}
+	tmp, err := os.CreateTemp("", "iwork-preview-*.pdf")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return pdf.ExtractTextFromPDF(tmp.Name())
+}
+
+// findFile returns the zip entry with the given name, ignoring case and a leading bundle directory
+func findFile(files []*zip.File, name string) *zip.File {
+	for _, f := range files {
+		if strings.EqualFold(f.Name, name) {
+			return f
+		}
+		if _, rest, ok := strings.Cut(f.Name, "/"); ok && strings.EqualFold(rest, name) {
+			return f
+		}
+	}
+	return nil
+}
+
+func openNestedZip(f *zip.File) (*zip.Reader, error) {
+	data, err := readZipFile(f)
+	if err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(data), int64(len(data)))
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func (e *Extractor) SupportedExtensions() []string {
+	return []string{".pages", ".key", ".numbers"}
+}
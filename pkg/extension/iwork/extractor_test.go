@@ -0,0 +1,120 @@
+package iwork
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// protoBytes encodes a length-delimited protobuf field
+func protoBytes(number int, data []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(number)<<3|2)
+	out = binary.AppendUvarint(out, uint64(len(data)))
+	return append(out, data...)
+}
+
+// protoVarint encodes a varint protobuf field
+func protoVarint(number int, value uint64) []byte {
+	out := binary.AppendUvarint(nil, uint64(number)<<3)
+	return binary.AppendUvarint(out, value)
+}
+
+// iwaMessage encodes an ArchiveInfo describing one message of messageType, followed by payload
+func iwaMessage(messageType uint64, payload []byte) []byte {
+	info := protoBytes(2, append(protoVarint(1, messageType), protoVarint(3, uint64(len(payload)))...))
+	out := binary.AppendUvarint(nil, uint64(len(info)))
+	out = append(out, info...)
+	return append(out, payload...)
+}
+
+// iwaFile frames stream as a single IWA chunk holding one Snappy literal
+func iwaFile(stream []byte) []byte {
+	block := binary.AppendUvarint(nil, uint64(len(stream)))
+	if n := len(stream) - 1; n < 60 {
+		block = append(block, byte(n<<2))
+	} else {
+		block = append(block, 61<<2, byte(n), byte(n>>8))
+	}
+	block = append(block, stream...)
+	return append([]byte{0, byte(len(block)), byte(len(block) >> 8), byte(len(block) >> 16)}, block...)
+}
+
+// writePackage zips the given files into an iWork package and returns its path
+func writePackage(t *testing.T, name string, files map[string][]byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	archive := zip.NewWriter(f)
+	for name, data := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractIWAText(t *testing.T) {
+	body := iwaMessage(storageArchiveType, protoBytes(3, []byte("Quarterly plan\u2029Hire two engineers\ufffc")))
+	note := iwaMessage(storageArchiveAltType, protoBytes(3, []byte("Speaker note")))
+	cells := iwaMessage(tableDataListType, append(
+		protoBytes(3, protoBytes(3, []byte("Region"))),
+		protoBytes(3, protoBytes(3, []byte("EMEA")))...))
+	ignored := iwaMessage(1, protoBytes(3, []byte("style name")))
+
+	path := writePackage(t, "plan.key", map[string][]byte{
+		"Index/Document.iwa": iwaFile(append(append(body, ignored...), note...)),
+		"Index/Tables/1.iwa": iwaFile(cells),
+	})
+	text, err := NewExtractor().Extract(path)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if want := "Quarterly plan\nHire two engineers\nSpeaker note\nRegion\nEMEA\n"; text != want {
+		t.Errorf("text = %q, want %q", text, want)
+	}
+}
+
+func TestExtractWithoutTextOrPreview(t *testing.T) {
+	path := writePackage(t, "empty.pages", map[string][]byte{"Metadata/Properties.plist": []byte("<plist/>")})
+	if _, err := NewExtractor().Extract(path); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Extract() error = %v, want ErrUnsupportedVersion", err)
+	}
+}
+
+func TestSnappyDecode(t *testing.T) {
+	// "abcd" as a literal, then a copy of 4 bytes at offset 4 and a 1-byte-offset copy of 5 overlapping bytes
+	block := []byte{13, 3 << 2, 'a', 'b', 'c', 'd', 0x01, 4, 1<<2 | 0x01, 4}
+	got, err := snappyDecode(block)
+	if err != nil {
+		t.Fatalf("snappyDecode: %v", err)
+	}
+	if string(got) != "abcdabcdabcda" {
+		t.Errorf("snappyDecode = %q, want %q", got, "abcdabcdabcda")
+	}
+
+	for name, bad := range map[string][]byte{
+		"offset before start": {4, 0x01, 4},
+		"truncated literal":   {4, 3 << 2, 'a'},
+		"oversized":           binary.AppendUvarint(nil, maxDecodedChunk+1),
+	} {
+		if _, err := snappyDecode(bad); err == nil {
+			t.Errorf("snappyDecode(%s) succeeded", name)
+		}
+	}
+	if _, err := decompressIWA([]byte{1, 0, 0, 0}); err == nil || !strings.Contains(err.Error(), "chunk header") {
+		t.Errorf("decompressIWA without the zero marker = %v, want a chunk header error", err)
+	}
+}
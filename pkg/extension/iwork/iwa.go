@@ -0,0 +1,269 @@
+package iwork
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// IWA (iWork Archive) files are Snappy-compressed chunks without checksums. Once
+// decompressed they hold a sequence of length-prefixed ArchiveInfo protobuf messages,
+// each followed by the payloads of the messages it describes.
+
+// Message types whose payloads carry document text
+const (
+	// storageArchiveType is TSWP.StorageArchive; field 3 holds the text of a text storage
+	storageArchiveType = 2001
+	// storageArchiveAltType is a second TSWP.StorageArchive type used for headers, notes and shapes
+	storageArchiveAltType = 2005
+	// tableDataListType is TST.TableDataList; field 3 entries hold shared table cell strings in field 3
+	tableDataListType = 6005
+)
+
+var errTruncated = errors.New("truncated IWA data")
+
+// maxDecodedChunk bounds the decoded size of a chunk; IWA chunks are at most 64 KiB
+const maxDecodedChunk = 1 << 20
+
+// readIWAText decompresses an IWA file and appends the text of its storage and
+// table string messages to builder
+func readIWAText(data []byte, builder *strings.Builder) error {
+	stream, err := decompressIWA(data)
+	if err != nil {
+		return err
+	}
+
+	for len(stream) > 0 {
+		infoLen, n := binary.Uvarint(stream)
+		if n <= 0 || uint64(len(stream)-n) < infoLen {
+			return errTruncated
+		}
+		info := stream[n : n+int(infoLen)]
+		stream = stream[n+int(infoLen):]
+
+		infoFields, err := parseProto(info)
+		if err != nil {
+			return err
+		}
+		for _, field := range infoFields {
+			if field.number != 2 || field.bytes == nil {
+				continue // not a MessageInfo
+			}
+			messageType, length, err := messageInfo(field.bytes)
+			if err != nil {
+				return err
+			}
+			if uint64(len(stream)) < length {
+				return errTruncated
+			}
+			payload := stream[:length]
+			stream = stream[length:]
+
+			switch messageType {
+			case storageArchiveType, storageArchiveAltType:
+				appendStrings(payload, 3, builder)
+			case tableDataListType:
+				appendTableStrings(payload, builder)
+			}
+		}
+	}
+	return nil
+}
+
+// messageInfo reads the type (field 1) and payload length (field 3) of a MessageInfo
+func messageInfo(data []byte) (uint64, uint64, error) {
+	fields, err := parseProto(data)
+	if err != nil {
+		return 0, 0, err
+	}
+	var messageType, length uint64
+	for _, f := range fields {
+		switch f.number {
+		case 1:
+			messageType = f.varint
+		case 3:
+			length = f.varint
+		}
+	}
+	return messageType, length, nil
+}
+
+// appendStrings writes every string in the given field of a message, one per line
+func appendStrings(message []byte, number int, builder *strings.Builder) {
+	fields, err := parseProto(message)
+	if err != nil {
+		return
+	}
+	for _, f := range fields {
+		if f.number != number || f.bytes == nil || !utf8.Valid(f.bytes) {
+			continue
+		}
+		if text := cleanText(string(f.bytes)); text != "" {
+			builder.WriteString(text)
+			builder.WriteString("\n")
+		}
+	}
+}
+
+// appendTableStrings writes the strings of a TableDataList's entries
+func appendTableStrings(message []byte, builder *strings.Builder) {
+	fields, err := parseProto(message)
+	if err != nil {
+		return
+	}
+	for _, f := range fields {
+		if f.number == 3 && f.bytes != nil {
+			appendStrings(f.bytes, 3, builder)
+		}
+	}
+}
+
+// cleanText converts iWork paragraph and line separators to newlines and drops
+// the placeholders marking attachments such as images
+func cleanText(text string) string {
+	text = strings.NewReplacer("\u2029", "\n", "\u2028", "\n", "\ufffc", "").Replace(text)
+	return strings.TrimSpace(text)
+}
+
+// decompressIWA undoes the IWA chunk framing: each chunk starts with a zero byte and
+// a 3-byte little-endian length, followed by a raw Snappy block
+func decompressIWA(data []byte) ([]byte, error) {
+	var out []byte
+	for len(data) > 0 {
+		if len(data) < 4 || data[0] != 0 {
+			return nil, errors.New("invalid IWA chunk header")
+		}
+		length := int(data[1]) | int(data[2])<<8 | int(data[3])<<16
+		data = data[4:]
+		if len(data) < length {
+			return nil, errTruncated
+		}
+		chunk, err := snappyDecode(data[:length])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk...)
+		data = data[length:]
+	}
+	return out, nil
+}
+
+// snappyDecode decodes a raw (unframed) Snappy block
+func snappyDecode(src []byte) ([]byte, error) {
+	size, n := binary.Uvarint(src)
+	if n <= 0 || size > maxDecodedChunk {
+		return nil, errors.New("invalid snappy header")
+	}
+	src = src[n:]
+	dst := make([]byte, 0, size)
+
+	for len(src) > 0 {
+		tag := src[0]
+		var length, offset int
+		switch tag & 3 {
+		case 0: // literal
+			length = int(tag >> 2)
+			src = src[1:]
+			if length >= 60 {
+				extra := length - 59
+				if len(src) < extra {
+					return nil, errTruncated
+				}
+				length = 0
+				for i := 0; i < extra; i++ {
+					length |= int(src[i]) << (8 * i)
+				}
+				src = src[extra:]
+			}
+			length++
+			if len(src) < length {
+				return nil, errTruncated
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+		case 1: // copy with 1-byte offset
+			if len(src) < 2 {
+				return nil, errTruncated
+			}
+			length = 4 + int(tag>>2&7)
+			offset = int(tag&0xe0)<<3 | int(src[1])
+			src = src[2:]
+		case 2: // copy with 2-byte offset
+			if len(src) < 3 {
+				return nil, errTruncated
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:3]))
+			src = src[3:]
+		case 3: // copy with 4-byte offset
+			if len(src) < 5 {
+				return nil, errTruncated
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:5]))
+			src = src[5:]
+		}
+		if offset <= 0 || offset > len(dst) {
+			return nil, errors.New("invalid snappy copy offset")
+		}
+		for i := 0; i < length; i++ {
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+	return dst, nil
+}
+
+// protoField is a decoded protobuf field. Length-delimited fields set bytes;
+// varint and fixed-width fields set varint.
+type protoField struct {
+	number int
+	varint uint64
+	bytes  []byte
+}
+
+// parseProto decodes the top-level fields of a protobuf message without a schema
+func parseProto(data []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		data = data[n:]
+		field := protoField{number: int(key >> 3)}
+		switch key & 7 {
+		case 0: // varint
+			field.varint, n = binary.Uvarint(data)
+			if n <= 0 {
+				return nil, errTruncated
+			}
+			data = data[n:]
+		case 1: // 64-bit
+			if len(data) < 8 {
+				return nil, errTruncated
+			}
+			field.varint = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case 2: // length-delimited
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return nil, errTruncated
+			}
+			field.bytes = data[n : n+int(length)]
+			data = data[n+int(length):]
+		case 5: // 32-bit
+			if len(data) < 4 {
+				return nil, errTruncated
+			}
+			field.varint = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type %d", key&7)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
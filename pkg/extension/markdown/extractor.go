@@ -18,7 +18,9 @@ func (e *Extractor) Extract(path string) (string, error) {
 		return "", err
 	}
 
-	text := string(content)
+	// Front-matter is metadata, not body text
+	_, body := ParseFrontMatter(content)
+	text := string(body)
 
 	// Remove code blocks
 	text = regexp.MustCompile("```[\\s\\S]*?```").ReplaceAllString(text, "")
@@ -49,6 +51,16 @@ func (e *Extractor) Extract(path string) (string, error) {
 	return text, nil
 }
 
+// FrontMatter returns the fields of the document's YAML or TOML front-matter, or nil if it has none
+func (e *Extractor) FrontMatter(path string) (map[string]interface{}, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fields, _ := ParseFrontMatter(content)
+	return fields, nil
+}
+
 func (e *Extractor) SupportedExtensions() []string {
 	return []string{".md", ".markdown"}
 }
@@ -0,0 +1,128 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+)
+
+// frontMatterFences maps opening fences to their syntax: --- for YAML, +++ for TOML
+var frontMatterFences = map[string]string{
+	"---": "yaml",
+	"+++": "toml",
+}
+
+// ParseFrontMatter splits a leading YAML (---) or TOML (+++) front-matter block from
+// the document and returns its fields along with the remaining body. Only flat
+// key/value pairs and lists of strings are understood; nested tables are skipped.
+// Documents without front-matter are returned unchanged with a nil map.
+func ParseFrontMatter(content []byte) (map[string]interface{}, []byte) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	firstLine, rest, _ := bytes.Cut(content, []byte("\n"))
+	fence := strings.TrimSpace(string(firstLine))
+	syntax, ok := frontMatterFences[fence]
+	if !ok {
+		return nil, content
+	}
+
+	var block []string
+	var body []byte
+	closed := false
+	for len(rest) > 0 {
+		line, next, found := bytes.Cut(rest, []byte("\n"))
+		if strings.TrimSpace(string(line)) == fence {
+			closed = true
+			body = next
+			break
+		}
+		block = append(block, strings.TrimRight(string(line), "\r"))
+		if !found {
+			break
+		}
+		rest = next
+	}
+	if !closed {
+		return nil, content
+	}
+
+	if syntax == "toml" {
+		return parseTOMLFields(block), body
+	}
+	return parseYAMLFields(block), body
+}
+
+// parseYAMLFields reads top-level "key: value" pairs, inline [a, b] lists and
+// "- item" block lists
+func parseYAMLFields(lines []string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	var listKey string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if item, ok := strings.CutPrefix(trimmed, "- "); ok && listKey != "" {
+			list, _ := fields[listKey].([]string)
+			fields[listKey] = append(list, unquote(item))
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue // nested mapping
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		listKey = ""
+		if value == "" {
+			listKey = key
+			continue
+		}
+		fields[key] = parseValue(value)
+	}
+	return fields
+}
+
+// parseTOMLFields reads top-level "key = value" pairs up to the first table header
+func parseTOMLFields(lines []string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") {
+			break // fields after a [table] header are not top-level
+		}
+		key, value, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			continue
+		}
+		fields[unquote(strings.TrimSpace(key))] = parseValue(strings.TrimSpace(value))
+	}
+	return fields
+}
+
+// parseValue converts an inline [a, b] list to []string and unquotes scalars
+func parseValue(value string) interface{} {
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		var list []string
+		for _, item := range strings.Split(value[1:len(value)-1], ",") {
+			if item = unquote(strings.TrimSpace(item)); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list
+	}
+	return unquote(value)
+}
+
+// unquote strips matching single or double quotes
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
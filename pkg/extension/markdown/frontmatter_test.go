@@ -0,0 +1,61 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantFields map[string]interface{}
+		wantBody   string
+	}{
+		{
+			name: "YAML",
+			content: "---\ntitle: \"Release notes\"\ncategory: Changelog\ntags: [release, 'v2']\n" +
+				"authors:\n  - Dana\n  - Lee\nlinks:\n  home: https://example.com\n# comment\n---\n# Notes\n",
+			wantFields: map[string]interface{}{
+				"title":    "Release notes",
+				"category": "Changelog",
+				"tags":     []string{"release", "v2"},
+				"authors":  []string{"Dana", "Lee"},
+			},
+			wantBody: "# Notes\n",
+		},
+		{
+			name:       "TOML",
+			content:    "+++\ntitle = \"Guide\"\ncategories = [\"Docs\", \"Howto\"]\n[params]\nhidden = true\n+++\nBody",
+			wantFields: map[string]interface{}{"title": "Guide", "categories": []string{"Docs", "Howto"}},
+			wantBody:   "Body",
+		},
+		{
+			name:       "byte order mark and CRLF",
+			content:    "\xef\xbb\xbf---\r\ntags: [a]\r\n---\r\nBody",
+			wantFields: map[string]interface{}{"tags": []string{"a"}},
+			wantBody:   "Body",
+		},
+		{
+			name:     "unclosed block",
+			content:  "---\ntitle: Draft\nNo closing fence",
+			wantBody: "---\ntitle: Draft\nNo closing fence",
+		},
+		{
+			name:     "no front-matter",
+			content:  "# Title\n\n---\n",
+			wantBody: "# Title\n\n---\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, body := ParseFrontMatter([]byte(tt.content))
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("fields = %#v, want %#v", fields, tt.wantFields)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package markdown
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+// delimiterRow matches the line below a pipe table's header, e.g. "|---|:--:|"
+var delimiterRow = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+
+// Tables returns the GitHub-flavored pipe tables of the document in order. Tables
+// inside fenced code blocks are ignored.
+func (e *Extractor) Tables(path string) ([]extension.Table, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	_, body := ParseFrontMatter(content)
+	lines := strings.Split(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n")
+
+	var tables []extension.Table
+	inFence := false
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || !strings.Contains(line, "|") || i+1 >= len(lines) {
+			continue
+		}
+		if !delimiterRow.MatchString(strings.TrimSpace(lines[i+1])) {
+			continue
+		}
+
+		rows := [][]string{splitRow(line)}
+		i += 2
+		for ; i < len(lines); i++ {
+			row := strings.TrimSpace(lines[i])
+			if row == "" || !strings.Contains(row, "|") {
+				break
+			}
+			rows = append(rows, splitRow(row))
+		}
+		tables = append(tables, extension.NewTable(rows))
+	}
+	return tables, nil
+}
+
+// splitRow splits a pipe table row into its cells, honoring escaped pipes
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = strings.TrimSuffix(line, "|")
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, cell.String())
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, cell.String())
+}
@@ -0,0 +1,35 @@
+package markdown
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+func TestTables(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.md")
+	content := "---\ntitle: Notes\n---\n" +
+		"# Prices\n\n" +
+		"| Plan | Price | Notes |\n|:-----|------:|:-----:|\n| Basic | $5 | a \\| b |\n| Pro | $20 |  |\n\n" +
+		"```\n| Not | A table |\n|---|---|\n| x | y |\n```\n\n" +
+		"A | B\n--- | ---\n1 | 2\nAfter the table.\n\n" +
+		"Plain text | with a pipe\nbut no delimiter row\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := NewExtractor().Tables(path)
+	if err != nil {
+		t.Fatalf("Tables: %v", err)
+	}
+	want := []extension.Table{
+		{Headers: []string{"Plan", "Price", "Notes"}, Rows: [][]string{{"Basic", "$5", "a | b"}, {"Pro", "$20"}}},
+		{Headers: []string{"A", "B"}, Rows: [][]string{{"1", "2"}}},
+	}
+	if !reflect.DeepEqual(tables, want) {
+		t.Errorf("Tables = %+v, want %+v", tables, want)
+	}
+}
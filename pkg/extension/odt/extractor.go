@@ -5,7 +5,10 @@ import (
 	"bytes"
 	"encoding/xml"
 	"io"
+	"strconv"
 	"strings"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
 )
 
 type Extractor struct{}
@@ -75,6 +78,71 @@ func (e *Extractor) Extract(path string) (string, error) {
 	return result.String(), nil
 }
 
+// Headings returns the text:h elements of the document with their outline levels,
+// in document order. Documents without headings return no headings.
+func (e *Extractor) Headings(path string) ([]extension.Heading, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.Name != "content.xml" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return readHeadings(rc)
+	}
+	return nil, nil
+}
+
+func readHeadings(r io.Reader) ([]extension.Heading, error) {
+	decoder := xml.NewDecoder(r)
+	var headings []extension.Heading
+	var level int
+	var text strings.Builder
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return headings, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "h" {
+				level = 1
+				for _, a := range t.Attr {
+					if a.Name.Local == "outline-level" {
+						if l, err := strconv.Atoi(a.Value); err == nil && l > 0 {
+							level = l
+						}
+					}
+				}
+				text.Reset()
+			}
+		case xml.CharData:
+			if level > 0 {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "h" && level > 0 {
+				if s := strings.TrimSpace(text.String()); s != "" {
+					headings = append(headings, extension.Heading{Level: level, Text: s})
+				}
+				level = 0
+			}
+		}
+	}
+}
+
 func (e *Extractor) SupportedExtensions() []string {
 	return []string{".odt"}
 }
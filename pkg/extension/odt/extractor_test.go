@@ -0,0 +1,52 @@
+package odt
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+// writeODT writes an OpenDocument text package whose office:text element holds body
+func writeODT(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "document.odt")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	archive := zip.NewWriter(f)
+	w, err := archive.Create("content.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(`<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" ` +
+		`xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"><office:body><office:text>` +
+		body + `</office:text></office:body></office:document-content>`))
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHeadings(t *testing.T) {
+	path := writeODT(t, `<text:h text:outline-level="1">Results</text:h>`+
+		`<text:p>Revenue grew.</text:p>`+
+		`<text:h text:outline-level="2">By <text:span>region</text:span></text:h>`+
+		`<text:h>Untitled level</text:h>`+
+		`<text:h text:outline-level="2"> </text:h>`)
+
+	headings, err := NewExtractor().Headings(path)
+	if err != nil {
+		t.Fatalf("Headings: %v", err)
+	}
+	want := []extension.Heading{{Level: 1, Text: "Results"}, {Level: 2, Text: "By region"}, {Level: 1, Text: "Untitled level"}}
+	if !reflect.DeepEqual(headings, want) {
+		t.Errorf("headings = %+v, want %+v", headings, want)
+	}
+}
@@ -0,0 +1,19 @@
+package extension
+
+import (
+	"errors"
+
+	"github.com/unidoc/unioffice/common/license"
+)
+
+// ErrOfficeUnlicensed is reported by the DOCX, PPTX and XLSX extractors when no
+// UniOffice license has been loaded, since the library may refuse to open documents
+var ErrOfficeUnlicensed = errors.New("no UniOffice license is loaded")
+
+// CheckOfficeLicense returns ErrOfficeUnlicensed unless a valid UniOffice license key is loaded
+func CheckOfficeLicense() error {
+	if key := license.GetLicenseKey(); key == nil || !key.IsLicensed() {
+		return ErrOfficeUnlicensed
+	}
+	return nil
+}
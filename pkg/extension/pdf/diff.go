@@ -0,0 +1,61 @@
+package pdf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// DiffPDF compares two versions of a PDF page by page and returns the 1-based numbers
+// of the pages of newPath whose text differs from the same page of oldPath. Pages
+// added after the end of the old version count as changed. Whitespace differences are
+// ignored, since re-rendering a document often reflows its text.
+func DiffPDF(oldPath, newPath string) ([]int, error) {
+	e := NewExtractor()
+	oldPages, err := e.pageTexts(oldPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read previous version: %w", err)
+	}
+	newPages, err := e.pageTexts(newPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []int
+	for i, text := range newPages {
+		if i >= len(oldPages) || oldPages[i] != text {
+			changed = append(changed, i+1)
+		}
+	}
+	return changed, nil
+}
+
+// pageTexts returns the whitespace-normalized text of every page of the PDF at path
+func (e *Extractor) pageTexts(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	r, err := pdf.NewReaderEncrypted(f, fi.Size(), e.passwords())
+	if err != nil {
+		return nil, err
+	}
+
+	texts := make([]string, r.NumPage())
+	for n := 1; n <= r.NumPage(); n++ {
+		content, err := r.Page(n).GetPlainText(nil)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", n, err)
+		}
+		texts[n-1] = strings.Join(strings.Fields(content), " ")
+	}
+	return texts, nil
+}
@@ -1,33 +1,90 @@
 package pdf
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"strings"
 
+	"github.com/adaptive-scale/superclass/pkg/extension"
 	"github.com/ledongthuc/pdf"
 )
 
-type Extractor struct{}
+type Extractor struct {
+	// Password used to decrypt password-protected PDFs
+	Password string
+}
 
 func NewExtractor() *Extractor {
 	return &Extractor{}
 }
 
 func (e *Extractor) Extract(path string) (string, error) {
-	f, r, err := pdf.Open(path)
+	return e.ExtractWithOptions(path, extension.Options{})
+}
+
+// ExtractWithOptions extracts the text of the selected pages, reporting progress once
+// per page and a warning for pages whose text could not be fully read
+func (e *Extractor) ExtractWithOptions(path string, opts extension.Options) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
 	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	r, err := pdf.NewReaderEncrypted(f, fi.Size(), e.passwords())
+	if err != nil {
+		if isEncryptionError(err) {
+			return "", fmt.Errorf("%w: %v", extension.ErrEncryptedDocument, err)
+		}
+		return "", err
+	}
+
+	pages, err := opts.SelectPages(r.NumPage())
 	if err != nil {
 		return "", err
 	}
 	var textBuilder strings.Builder
-	rNumPages := r.NumPage()
-	for i := 1; i <= rNumPages; i++ {
-		page := r.Page(i)
-		content, _ := page.GetPlainText(nil)
+	for i, n := range pages {
+		page := r.Page(n)
+		content, err := page.GetPlainText(nil)
+		if err != nil {
+			opts.Warnf("page %d: %v", n, err)
+		}
 		textBuilder.WriteString(content)
+		opts.Report(i+1, len(pages))
 	}
+	// Fillable forms keep their data in AcroForm fields rather than page text
+	writeFormFields(&textBuilder, formFields(r))
 	return textBuilder.String(), nil
 }
 
+// passwords returns the password callback for the pdf reader, offering the
+// configured password once
+func (e *Extractor) passwords() func() string {
+	if e.Password == "" {
+		return nil
+	}
+	tried := false
+	return func() string {
+		if tried {
+			return ""
+		}
+		tried = true
+		return e.Password
+	}
+}
+
+// isEncryptionError reports whether err came from the pdf reader refusing an encrypted file
+func isEncryptionError(err error) bool {
+	return errors.Is(err, pdf.ErrInvalidPassword) || strings.Contains(err.Error(), "encryption")
+}
+
 func (e *Extractor) SupportedExtensions() []string {
 	return []string{".pdf"}
 }
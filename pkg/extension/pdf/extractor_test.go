@@ -0,0 +1,211 @@
+package pdf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+// textAt returns a content stream operation drawing s at (x, y)
+func textAt(x, y int, s string) string {
+	return fmt.Sprintf("BT /F1 12 Tf %d %d Td (%s) Tj ET\n", x, y, s)
+}
+
+// writePDF writes an unencrypted PDF with one page per content stream and returns its
+// path. acroForm, when set, is the body of the document's AcroForm dictionary; objects
+// referenced from it are appended after the pages from extra.
+func writePDF(t *testing.T, pages []string, acroForm string, extra ...string) string {
+	t.Helper()
+
+	// Objects: 1 catalog, 2 page tree, 3 font, then a page and its content per page
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	catalog := "<< /Type /Catalog /Pages 2 0 R"
+	if acroForm != "" {
+		catalog += " /AcroForm << " + acroForm + " >>"
+	}
+	objects = append(objects,
+		catalog+" >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	for i, content := range pages {
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+	objects = append(objects, extra...)
+
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	path := filepath.Join(t.TempDir(), "document.pdf")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// threePages returns a document whose pages read "Page one", "Page two" and "Page three"
+func threePages(t *testing.T) string {
+	return writePDF(t, []string{
+		textAt(72, 712, "Page one"),
+		textAt(72, 712, "Page two"),
+		textAt(72, 712, "Page three"),
+	}, "")
+}
+
+func TestExtractReportsProgressPerPage(t *testing.T) {
+	path := threePages(t)
+
+	var calls [][2]int
+	text, err := NewExtractor().ExtractWithOptions(path, extension.Options{
+		Progress: func(done, total int) { calls = append(calls, [2]int{done, total}) },
+	})
+	if err != nil {
+		t.Fatalf("ExtractWithOptions() error = %v", err)
+	}
+	if !strings.Contains(text, "Page one") || !strings.Contains(text, "Page three") {
+		t.Errorf("text = %q, want every page", text)
+	}
+	if want := [][2]int{{1, 3}, {2, 3}, {3, 3}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("progress = %v, want %v", calls, want)
+	}
+
+	// Progress counts the pages actually extracted
+	calls = nil
+	if _, err := NewExtractor().ExtractWithOptions(path, extension.Options{
+		MaxPages: 2,
+		Progress: func(done, total int) { calls = append(calls, [2]int{done, total}) },
+	}); err != nil {
+		t.Fatalf("ExtractWithOptions() error = %v", err)
+	}
+	if want := [][2]int{{1, 2}, {2, 2}}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("progress with MaxPages = %v, want %v", calls, want)
+	}
+}
+
+func TestExtractFormFields(t *testing.T) {
+	// One page takes objects 4 and 5, so the fields start at 6
+	path := writePDF(t, []string{textAt(72, 712, "Application")},
+		"/Fields [6 0 R 7 0 R 9 0 R 10 0 R]",
+		"<< /FT /Tx /T (Name) /V (Ada Lovelace) >>",
+		"<< /T (address) /Kids [8 0 R] >>",
+		"<< /FT /Tx /Parent 7 0 R /T (city) /V (London) >>",
+		"<< /FT /Btn /T (subscribe) /V /Off >>",
+		"<< /FT /Btn /T (agree) /V /Yes >>",
+	)
+
+	text, err := NewExtractor().Extract(path)
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	want := "Form fields:\nName: Ada Lovelace\naddress.city: London\nagree: Yes\n"
+	if !strings.Contains(text, "Application") || !strings.HasSuffix(text, want) {
+		t.Errorf("text = %q, want the page text followed by %q", text, want)
+	}
+
+	text, err = NewExtractor().Extract(threePages(t))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if strings.Contains(text, "Form fields") {
+		t.Errorf("text = %q, want no form section without an AcroForm", text)
+	}
+}
+
+func TestExtractSelectedPages(t *testing.T) {
+	path := threePages(t)
+
+	text, err := NewExtractor().ExtractWithOptions(path, extension.Options{Pages: []extension.PageRange{{First: 3, Last: 3}, {First: 1, Last: 1}}})
+	if err != nil {
+		t.Fatalf("ExtractWithOptions() error = %v", err)
+	}
+	if !strings.Contains(text, "Page one") || strings.Contains(text, "Page two") || !strings.Contains(text, "Page three") {
+		t.Errorf("text = %q, want pages one and three", text)
+	}
+
+	if _, err := NewExtractor().ExtractWithOptions(path, extension.Options{Pages: []extension.PageRange{{First: 4, Last: 6}}}); !errors.Is(err, extension.ErrUnmatchedSelection) {
+		t.Errorf("selecting past the last page: error = %v, want ErrUnmatchedSelection", err)
+	}
+}
+
+func TestDiffPDF(t *testing.T) {
+	previous := threePages(t)
+	// Page one only reflows, page two is edited and page four is new
+	current := writePDF(t, []string{
+		textAt(72, 712, "Page") + textAt(110, 712, "one"),
+		textAt(72, 712, "Page two, revised"),
+		textAt(72, 712, "Page three"),
+		textAt(72, 712, "Page four"),
+	}, "")
+
+	changed, err := DiffPDF(previous, current)
+	if err != nil {
+		t.Fatalf("DiffPDF() error = %v", err)
+	}
+	if want := []int{2, 4}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed pages = %v, want %v", changed, want)
+	}
+
+	if changed, err := DiffPDF(current, current); err != nil || len(changed) != 0 {
+		t.Errorf("DiffPDF of identical versions = %v, %v; want no changes", changed, err)
+	}
+	if _, err := DiffPDF(filepath.Join(t.TempDir(), "missing.pdf"), current); err == nil || !strings.Contains(err.Error(), "previous version") {
+		t.Errorf("missing previous version: error = %v", err)
+	}
+}
+
+// textMatrixAt draws s at (x, y) by setting the text matrix, which is how the reader
+// learns the positions it uses to line up table columns
+func textMatrixAt(x, y int, s string) string {
+	return fmt.Sprintf("BT /F1 12 Tf 1 0 0 1 %d %d Tm (%s) Tj ET\n", x, y, s)
+}
+
+func TestTables(t *testing.T) {
+	path := writePDF(t, []string{
+		textMatrixAt(72, 712, "Quarterly report") +
+			textMatrixAt(72, 680, "Region") + textMatrixAt(200, 680, "Revenue") + textMatrixAt(320, 680, "Growth") +
+			textMatrixAt(72, 664, "EMEA") + textMatrixAt(201, 664, "120") + textMatrixAt(320, 664, "4%") +
+			textMatrixAt(72, 648, "APAC") + textMatrixAt(200, 648, "95") + textMatrixAt(319, 648, "7%") +
+			textMatrixAt(72, 616, "Revenue rose") + textMatrixAt(260, 616, "in both regions."),
+		// Text position moves within a line must not add empty cells
+		"BT /F1 12 Tf 1 0 0 1 72 712 Tm 0 0 Td (Name) Tj ET\n" + textMatrixAt(200, 712, "Role") +
+			textMatrixAt(72, 696, "Ada") + textMatrixAt(200, 696, "Engineer"),
+		// Only one run per line: prose, not a table
+		textMatrixAt(72, 712, "First line") + textMatrixAt(72, 696, "Second line"),
+	}, "")
+
+	tables, err := NewExtractor().Tables(path)
+	if err != nil {
+		t.Fatalf("Tables: %v", err)
+	}
+	want := []extension.Table{
+		{Headers: []string{"Region", "Revenue", "Growth"}, Rows: [][]string{{"EMEA", "120", "4%"}, {"APAC", "95", "7%"}}},
+		{Headers: []string{"Name", "Role"}, Rows: [][]string{{"Ada", "Engineer"}}},
+	}
+	if !reflect.DeepEqual(tables, want) {
+		t.Errorf("Tables = %+v, want %+v", tables, want)
+	}
+}
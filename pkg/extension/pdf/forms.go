@@ -0,0 +1,95 @@
+package pdf
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// maxFieldDepth bounds the field hierarchy walk so malformed, cyclic forms terminate
+const maxFieldDepth = 32
+
+// formField is a filled AcroForm field with its fully qualified name
+type formField struct {
+	Name  string
+	Value string
+}
+
+// formFields returns the AcroForm fields that have a value, in document order.
+// Documents without a form yield no fields.
+func formFields(r *pdf.Reader) []formField {
+	var fields []formField
+	root := r.Trailer().Key("Root").Key("AcroForm").Key("Fields")
+	for i := 0; i < root.Len(); i++ {
+		collectFields(root.Index(i), "", 0, &fields)
+	}
+	return fields
+}
+
+// collectFields walks a field and its kids, joining partial names with dots
+func collectFields(field pdf.Value, parent string, depth int, fields *[]formField) {
+	if depth > maxFieldDepth || field.Kind() != pdf.Dict {
+		return
+	}
+
+	name := parent
+	if partial := field.Key("T").Text(); partial != "" {
+		if name != "" {
+			name += "."
+		}
+		name += partial
+	}
+
+	if value := fieldValue(field.Key("V")); value != "" && name != "" {
+		*fields = append(*fields, formField{Name: name, Value: value})
+	}
+
+	kids := field.Key("Kids")
+	for i := 0; i < kids.Len(); i++ {
+		collectFields(kids.Index(i), name, depth+1, fields)
+	}
+}
+
+// fieldValue renders text, choice and button values. Unchecked buttons ("Off") are empty.
+func fieldValue(v pdf.Value) string {
+	switch v.Kind() {
+	case pdf.String:
+		return strings.TrimSpace(v.Text())
+	case pdf.Name:
+		if v.Name() == "Off" {
+			return ""
+		}
+		return v.Name()
+	case pdf.Integer:
+		return fmt.Sprint(v.Int64())
+	case pdf.Real:
+		return fmt.Sprint(v.Float64())
+	case pdf.Array:
+		var values []string
+		for i := 0; i < v.Len(); i++ {
+			if value := fieldValue(v.Index(i)); value != "" {
+				values = append(values, value)
+			}
+		}
+		return strings.Join(values, ", ")
+	}
+	return ""
+}
+
+// writeFormFields appends the fields as "name: value" lines after the page text
+func writeFormFields(builder *strings.Builder, fields []formField) {
+	if len(fields) == 0 {
+		return
+	}
+	if builder.Len() > 0 {
+		builder.WriteString("\n\n")
+	}
+	builder.WriteString("Form fields:\n")
+	for _, field := range fields {
+		builder.WriteString(field.Name)
+		builder.WriteString(": ")
+		builder.WriteString(field.Value)
+		builder.WriteString("\n")
+	}
+}
@@ -0,0 +1,108 @@
+package pdf
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+	"github.com/ledongthuc/pdf"
+)
+
+// columnTolerance is how far, in points, text in the same table column may start apart
+const columnTolerance = 3.0
+
+// Tables returns the tables found on the pages of the PDF in order. PDFs carry no table
+// structure, so tables are detected heuristically: two or more consecutive lines that
+// each consist of several text runs starting at the same horizontal positions. Tables
+// spanning a page break are returned as one table per page.
+func (e *Extractor) Tables(path string) ([]extension.Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	r, err := pdf.NewReaderEncrypted(f, fi.Size(), e.passwords())
+	if err != nil {
+		if isEncryptionError(err) {
+			return nil, fmt.Errorf("%w: %v", extension.ErrEncryptedDocument, err)
+		}
+		return nil, err
+	}
+
+	var tables []extension.Table
+	for n := 1; n <= r.NumPage(); n++ {
+		rows, err := r.Page(n).GetTextByRow()
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", n, err)
+		}
+		tables = append(tables, pageTables(rows)...)
+	}
+	return tables, nil
+}
+
+// pageTables groups consecutive rows whose text runs line up into tables
+func pageTables(rows pdf.Rows) []extension.Table {
+	// Rows are positioned bottom to top
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Position > rows[j].Position })
+
+	var tables []extension.Table
+	var current [][]string
+	var columns []float64
+	flush := func() {
+		if len(current) >= 2 {
+			tables = append(tables, extension.NewTable(current))
+		}
+		current, columns = nil, nil
+	}
+	for _, row := range rows {
+		// The reader reports a blank run for every text position move; those are not cells
+		var texts []pdf.Text
+		for _, text := range row.Content {
+			if strings.TrimSpace(text.S) != "" {
+				texts = append(texts, text)
+			}
+		}
+		sort.SliceStable(texts, func(i, j int) bool { return texts[i].X < texts[j].X })
+		if len(texts) < 2 {
+			flush()
+			continue
+		}
+
+		xs := make([]float64, len(texts))
+		cells := make([]string, len(texts))
+		for i, text := range texts {
+			xs[i] = text.X
+			cells[i] = text.S
+		}
+		if columns != nil && !alignedColumns(columns, xs) {
+			flush()
+		}
+		if columns == nil {
+			columns = xs
+		}
+		current = append(current, cells)
+	}
+	flush()
+	return tables
+}
+
+// alignedColumns reports whether two rows have text runs starting at the same positions
+func alignedColumns(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > columnTolerance {
+			return false
+		}
+	}
+	return true
+}
@@ -2,7 +2,9 @@ package pptx
 
 import (
 	"bytes"
+	"fmt"
 
+	"github.com/adaptive-scale/superclass/pkg/extension"
 	"github.com/unidoc/unioffice/presentation"
 )
 
@@ -13,44 +15,85 @@ func NewExtractor() *Extractor {
 }
 
 func (e *Extractor) Extract(path string) (string, error) {
+	return e.ExtractWithOptions(path, extension.Options{})
+}
+
+// ExtractWithOptions extracts the text of the selected slides, reporting progress once
+// per slide. Slides that cannot be read are skipped with a warning.
+func (e *Extractor) ExtractWithOptions(path string, opts extension.Options) (string, error) {
+	if err := extension.CheckOOXMLEncryption(path); err != nil {
+		return "", err
+	}
+
 	ppt, err := presentation.Open(path)
 	if err != nil {
 		return "", err
 	}
 	defer ppt.Close()
 
+	slides := ppt.Slides()
+	selected, err := opts.SelectPages(len(slides))
+	if err != nil {
+		return "", err
+	}
 	var buffer bytes.Buffer
-	for _, slide := range ppt.Slides() {
-		// Extract text from text boxes
-		for _, textBox := range slide.GetTextBoxes() {
-			for _, para := range textBox.X().TxBody.P {
-				for _, run := range para.EG_TextRun {
-					if run.R != nil && run.R.T != "" {
-						buffer.WriteString(run.R.T)
-						buffer.WriteString(" ")
-					}
+	for i, n := range selected {
+		text, err := slideText(slides[n-1])
+		if err != nil {
+			opts.Warnf("slide %d skipped: %v", n, err)
+		} else {
+			buffer.WriteString(text)
+		}
+		opts.Report(i+1, len(selected))
+	}
+
+	return buffer.String(), nil
+}
+
+// slideText returns the text of a slide's text boxes and placeholders. A malformed
+// slide makes the underlying library panic; the panic is returned as an error so the
+// remaining slides are still extracted.
+func slideText(slide presentation.Slide) (text string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed slide: %v", r)
+		}
+	}()
+
+	var buffer bytes.Buffer
+	// Extract text from text boxes
+	for _, textBox := range slide.GetTextBoxes() {
+		for _, para := range textBox.X().TxBody.P {
+			for _, run := range para.EG_TextRun {
+				if run.R != nil && run.R.T != "" {
+					buffer.WriteString(run.R.T)
+					buffer.WriteString(" ")
 				}
 			}
-			buffer.WriteString("\n")
 		}
+		buffer.WriteString("\n")
+	}
 
-		// Extract text from placeholders
-		for _, ph := range slide.PlaceHolders() {
-			for _, para := range ph.Paragraphs() {
-				for _, run := range para.X().EG_TextRun {
-					if run.R != nil && run.R.T != "" {
-						buffer.WriteString(run.R.T)
-						buffer.WriteString(" ")
-					}
+	// Extract text from placeholders
+	for _, ph := range slide.PlaceHolders() {
+		for _, para := range ph.Paragraphs() {
+			for _, run := range para.X().EG_TextRun {
+				if run.R != nil && run.R.T != "" {
+					buffer.WriteString(run.R.T)
+					buffer.WriteString(" ")
 				}
 			}
-			buffer.WriteString("\n")
 		}
+		buffer.WriteString("\n")
 	}
-
 	return buffer.String(), nil
 }
 
+// CheckDependencies reports whether UniOffice is licensed
+func (e *Extractor) CheckDependencies() error {
+	return extension.CheckOfficeLicense()
+}
+
 func (e *Extractor) SupportedExtensions() []string {
 	return []string{".pptx"}
 }
@@ -1,11 +1,16 @@
 package svg
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
 	"strings"
 
+	"github.com/adaptive-scale/superclass/pkg/extension/image"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -26,7 +31,29 @@ type SVGTextElement struct {
 	Tspan   []string `xml:"tspan"`
 }
 
-type Extractor struct{}
+// ImageExtractor extracts text from a raster image file, typically through OCR
+type ImageExtractor interface {
+	Extract(path string) (string, error)
+}
+
+type Extractor struct {
+	// OCREmbeddedImages decodes base64 raster images embedded in the SVG and appends
+	// their OCR text, along with any aria-label alt text, after the vector text
+	OCREmbeddedImages bool
+	// ImageExtractor runs OCR on embedded images; defaults to the image extractor
+	ImageExtractor ImageExtractor
+}
+
+// embeddedImageExtensions maps data URL media types to file extensions Tesseract understands
+var embeddedImageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/jpg":  ".jpg",
+	"image/gif":  ".gif",
+	"image/bmp":  ".bmp",
+	"image/tiff": ".tiff",
+	"image/webp": ".webp",
+}
 
 func NewExtractor() *Extractor {
 	return &Extractor{}
@@ -73,6 +100,10 @@ func (e *Extractor) Extract(path string) (string, error) {
 	// Extract text from all elements recursively
 	extractText(&svg, &textBuilder)
 
+	if e.OCREmbeddedImages {
+		e.extractEmbeddedImages(content, &textBuilder, logger)
+	}
+
 	result := textBuilder.String()
 	logger.WithField("extracted_length", len(result)).Debug("SVG text extraction completed")
 
@@ -115,6 +146,94 @@ func extractText(element *SVGElement, builder *strings.Builder) {
 	}
 }
 
+// extractEmbeddedImages appends the alt text and OCR text of every <image> element
+// carrying a base64 data URL. Images that cannot be decoded or read are skipped.
+func (e *Extractor) extractEmbeddedImages(content []byte, builder *strings.Builder, logger *log.Entry) {
+	ocr := e.ImageExtractor
+	if ocr == nil {
+		ocr = image.NewExtractor()
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			logger.WithError(err).Warn("Failed to scan SVG for embedded images")
+			return
+		}
+
+		el, ok := tok.(xml.StartElement)
+		if !ok || el.Name.Local != "image" {
+			continue
+		}
+
+		var href, alt string
+		for _, a := range el.Attr {
+			switch a.Name.Local {
+			case "href":
+				href = a.Value
+			case "aria-label":
+				alt = a.Value
+			}
+		}
+		if alt = strings.TrimSpace(alt); alt != "" {
+			builder.WriteString("[Image] ")
+			builder.WriteString(alt)
+			builder.WriteString("\n")
+		}
+
+		text, err := ocrDataURL(href, ocr)
+		if err != nil {
+			logger.WithError(err).Warn("Skipping embedded image")
+			continue
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			builder.WriteString("[Embedded image text] ")
+			builder.WriteString(text)
+			builder.WriteString("\n")
+		}
+	}
+}
+
+// ocrDataURL decodes a base64 image data URL into a temporary file and runs OCR on it.
+// Links to external files are ignored.
+func ocrDataURL(href string, ocr ImageExtractor) (string, error) {
+	header, data, ok := strings.Cut(strings.TrimPrefix(href, "data:"), ",")
+	if !ok || !strings.HasPrefix(href, "data:") {
+		return "", nil
+	}
+	mediaType, encoding, _ := strings.Cut(header, ";")
+	if encoding != "base64" {
+		return "", fmt.Errorf("unsupported data URL encoding %q", encoding)
+	}
+	ext, ok := embeddedImageExtensions[strings.ToLower(mediaType)]
+	if !ok {
+		return "", fmt.Errorf("unsupported embedded image type %q", mediaType)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode embedded image: %w", err)
+	}
+
+	tmp, err := os.CreateTemp("", "svg-embedded-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(decoded); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return ocr.Extract(tmp.Name())
+}
+
 func (e *Extractor) SupportedExtensions() []string {
 	return []string{".svg"}
 }
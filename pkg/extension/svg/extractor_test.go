@@ -0,0 +1,77 @@
+package svg
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeOCR records the images it is asked to read and returns fixed text
+type fakeOCR struct {
+	text  string
+	paths []string
+	data  [][]byte
+}
+
+func (f *fakeOCR) Extract(path string) (string, error) {
+	f.paths = append(f.paths, path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	f.data = append(f.data, data)
+	if f.text == "" {
+		return "", errors.New("no text")
+	}
+	return f.text, nil
+}
+
+// writeSVG writes an SVG document and returns its path
+func writeSVG(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "drawing.svg")
+	content := `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">` + body + `</svg>`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractEmbeddedImages(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nscanned label")
+	path := writeSVG(t, `<text>Floor plan</text>`+
+		`<image aria-label="Exit sign" href="data:image/png;base64,`+base64.StdEncoding.EncodeToString(png)+`"/>`+
+		`<image xlink:href="https://example.com/logo.png"/>`+
+		`<image href="data:image/x-icon;base64,AAAA"/>`)
+
+	ocr := &fakeOCR{text: "EMERGENCY EXIT"}
+	text, err := (&Extractor{OCREmbeddedImages: true, ImageExtractor: ocr}).Extract(path)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	for _, want := range []string{"Floor plan\n", "[Image] Exit sign\n", "[Embedded image text] EMERGENCY EXIT\n"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text lacks %q:\n%s", want, text)
+		}
+	}
+	// Only the decodable data URL reaches OCR, as a temporary file with its extension
+	if len(ocr.paths) != 1 || filepath.Ext(ocr.paths[0]) != ".png" || string(ocr.data[0]) != string(png) {
+		t.Fatalf("OCR ran on %v, want the one embedded PNG", ocr.paths)
+	}
+	if _, err := os.Stat(ocr.paths[0]); !os.IsNotExist(err) {
+		t.Errorf("temporary image %s left behind", ocr.paths[0])
+	}
+
+	// Embedded images are ignored unless enabled
+	ocr = &fakeOCR{text: "EMERGENCY EXIT"}
+	text, err = (&Extractor{ImageExtractor: ocr}).Extract(path)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if len(ocr.paths) != 0 || strings.Contains(text, "[Image]") {
+		t.Errorf("embedded images read while disabled: %q", text)
+	}
+}
@@ -0,0 +1,200 @@
+package webarchive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/adaptive-scale/superclass/pkg/extension/html"
+)
+
+// Extractor reads saved web pages: MHTML files, whose main text/html part is extracted,
+// and WARC files, whose first HTML response record is extracted. The HTML is converted
+// to text by the html extractor.
+type Extractor struct {
+	html *html.Extractor
+}
+
+func NewExtractor() *Extractor {
+	return &Extractor{html: html.NewExtractor()}
+}
+
+func (e *Extractor) Extract(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var page []byte
+	if strings.EqualFold(filepath.Ext(path), ".warc") {
+		page, err = warcPage(f)
+	} else {
+		page, err = mhtmlPage(f)
+	}
+	if err != nil {
+		return "", err
+	}
+	return e.html.ExtractReader(bytes.NewReader(page))
+}
+
+// mhtmlPage returns the HTML of the root part of an MHTML archive: the part named by
+// the start parameter, or else the first text/html part
+func mhtmlPage(r io.Reader) ([]byte, error) {
+	msg, err := mail.ReadMessage(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse MHTML: %w", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse MHTML content type: %w", err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		if mediaType != "text/html" {
+			return nil, fmt.Errorf("MHTML archive has no HTML page (content type %s)", mediaType)
+		}
+		return io.ReadAll(decodeTransfer(msg.Body, msg.Header.Get("Content-Transfer-Encoding")))
+	}
+
+	start := strings.Trim(params["start"], "<>")
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	var first []byte
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read MHTML part: %w", err)
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		isStart := start != "" && strings.Trim(part.Header.Get("Content-ID"), "<>") == start
+		if partType != "text/html" && !isStart {
+			continue
+		}
+
+		page, err := io.ReadAll(decodeTransfer(part, part.Header.Get("Content-Transfer-Encoding")))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode MHTML part: %w", err)
+		}
+		if isStart || start == "" {
+			return page, nil
+		}
+		if first == nil {
+			first = page
+		}
+	}
+	if first == nil {
+		return nil, fmt.Errorf("MHTML archive has no HTML page")
+	}
+	return first, nil
+}
+
+// decodeTransfer undoes the base64 or quoted-printable content transfer encoding
+func decodeTransfer(body io.Reader, encoding string) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// warcPage returns the body of the first response record of a WARC file whose HTTP
+// payload is HTML. Gzip-compressed archives (.warc.gz renamed to .warc) are accepted.
+func warcPage(r io.Reader) ([]byte, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress WARC: %w", err)
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	for {
+		header, block, err := readWARCRecord(br)
+		if err == io.EOF {
+			return nil, fmt.Errorf("WARC archive has no HTML response record")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(header.Get("WARC-Type"), "response") {
+			continue
+		}
+
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+		if err != nil {
+			continue // not an HTTP response, e.g. a DNS record
+		}
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+			resp.Body.Close()
+			continue
+		}
+		body := io.Reader(resp.Body)
+		if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+			if gz, err := gzip.NewReader(resp.Body); err == nil {
+				body = gz
+			}
+		}
+		page, err := io.ReadAll(body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WARC response: %w", err)
+		}
+		return page, nil
+	}
+}
+
+// readWARCRecord reads the next record: a "WARC/1.x" version line, named fields, and
+// a content block of Content-Length bytes followed by two line breaks
+func readWARCRecord(br *bufio.Reader) (textproto.MIMEHeader, []byte, error) {
+	tp := textproto.NewReader(br)
+	var version string
+	for version == "" {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return nil, nil, err
+		}
+		version = strings.TrimSpace(line)
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, nil, fmt.Errorf("invalid WARC record: expected version line, got %q", version)
+	}
+
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid WARC record header: %w", err)
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, nil, fmt.Errorf("invalid WARC record length %q", header.Get("Content-Length"))
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(br, block); err != nil {
+		return nil, nil, fmt.Errorf("truncated WARC record: %w", err)
+	}
+	return header, block, nil
+}
+
+func (e *Extractor) SupportedExtensions() []string {
+	return []string{".mhtml", ".mht", ".warc"}
+}
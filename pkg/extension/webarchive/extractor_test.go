@@ -0,0 +1,115 @@
+package webarchive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeArchive writes data under name and returns its path
+func writeArchive(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// warcRecord encodes a WARC record of the given type with block as its content
+func warcRecord(recordType, block string) string {
+	return fmt.Sprintf("WARC/1.1\r\nWARC-Type: %s\r\nContent-Length: %d\r\n\r\n%s\r\n\r\n", recordType, len(block), block)
+}
+
+// httpResponse encodes an HTTP response carrying body with the given content type
+func httpResponse(contentType, body string) string {
+	return fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s", contentType, len(body), body)
+}
+
+func TestExtractMHTML(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive string
+		want    string
+	}{
+		{
+			name: "start part",
+			archive: "MIME-Version: 1.0\r\n" +
+				"Content-Type: multipart/related; boundary=\"b\"; start=\"<page@saved>\"\r\n\r\n" +
+				"--b\r\nContent-Type: text/html\r\nContent-ID: <frame@saved>\r\n\r\n<p>Advertising frame</p>\r\n" +
+				"--b\r\nContent-Type: text/html; charset=utf-8\r\nContent-ID: <page@saved>\r\n" +
+				"Content-Transfer-Encoding: quoted-printable\r\n\r\n<p>Caf=C3=A9 menu: soup =3D $4</p>\r\n" +
+				"--b--\r\n",
+			want: "Café menu: soup = $4",
+		},
+		{
+			name: "first HTML part",
+			archive: "MIME-Version: 1.0\r\nContent-Type: multipart/related; boundary=\"b\"\r\n\r\n" +
+				"--b\r\nContent-Type: image/png\r\nContent-Transfer-Encoding: base64\r\n\r\niVBORw0KGgo=\r\n" +
+				"--b\r\nContent-Type: text/html\r\nContent-Transfer-Encoding: base64\r\n\r\nPHA+UmVsZWFzZSBub3RlczwvcD4=\r\n" +
+				"--b\r\nContent-Type: text/html\r\n\r\n<p>Second page</p>\r\n" +
+				"--b--\r\n",
+			want: "Release notes",
+		},
+		{
+			name:    "single HTML page",
+			archive: "MIME-Version: 1.0\r\nContent-Type: text/html\r\n\r\n<p>Just a page</p>",
+			want:    "Just a page",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := NewExtractor().Extract(writeArchive(t, "page.mhtml", []byte(tt.archive)))
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			if text != tt.want {
+				t.Errorf("text = %q, want %q", text, tt.want)
+			}
+		})
+	}
+
+	noPage := "MIME-Version: 1.0\r\nContent-Type: multipart/related; boundary=\"b\"\r\n\r\n" +
+		"--b\r\nContent-Type: image/png\r\n\r\npng\r\n--b--\r\n"
+	if _, err := NewExtractor().Extract(writeArchive(t, "image.mht", []byte(noPage))); err == nil {
+		t.Error("Extract of an archive without HTML succeeded")
+	}
+}
+
+func TestExtractWARC(t *testing.T) {
+	archive := warcRecord("warcinfo", "software: crawler\r\n") +
+		warcRecord("request", "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n") +
+		warcRecord("response", httpResponse("image/png", "png")) +
+		warcRecord("response", httpResponse("text/html; charset=utf-8", "<html><body><p>Opening hours</p></body></html>")) +
+		warcRecord("response", httpResponse("text/html", "<p>Later page</p>"))
+
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(archive))
+	gz.Close()
+
+	for name, data := range map[string][]byte{"plain": []byte(archive), "gzip": compressed.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			text, err := NewExtractor().Extract(writeArchive(t, "crawl.warc", data))
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			if text != "Opening hours" {
+				t.Errorf("text = %q, want the first HTML response", text)
+			}
+		})
+	}
+
+	_, err := NewExtractor().Extract(writeArchive(t, "images.warc", []byte(warcRecord("response", httpResponse("image/png", "png")))))
+	if err == nil || !strings.Contains(err.Error(), "no HTML response") {
+		t.Errorf("error = %v, want no HTML response record", err)
+	}
+	_, err = NewExtractor().Extract(writeArchive(t, "broken.warc", []byte("WARC/1.1\r\nWARC-Type: response\r\nContent-Length: 500\r\n\r\nshort")))
+	if err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("error = %v, want a truncated record", err)
+	}
+}
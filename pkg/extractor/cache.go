@@ -0,0 +1,140 @@
+package extractor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+// ExtractorVersion is mixed into extraction cache keys. Bump it whenever an extractor
+// changes its output so stale cache entries are no longer used.
+const ExtractorVersion = "3"
+
+// ExtractionCache stores extracted text keyed by a hash of the file contents
+type ExtractionCache interface {
+	// Get returns the cached text for key
+	Get(key string) (string, bool)
+	// Put stores the text for key
+	Put(key, text string) error
+}
+
+// DefaultCache is consulted by ExtractText when set; nil disables caching
+var DefaultCache ExtractionCache
+
+// cacheKey hashes the file contents together with the extension, the extractor type and
+// settings, the page limit and selection and ExtractorVersion, so a changed file or
+// extractor configuration yields a different key
+func cacheKey(path, ext string, extractor TextExtractor, opts extension.Options) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%T\x00%s\x00%d\x00%v\x00%q\x00", ExtractorVersion, ext, extractor, extractorSettings(extractor), opts.MaxPages, opts.Pages, opts.Sheets)
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// extractorSettings describes the exported configuration of extractor, such as OCR
+// languages or comment stripping. JSON is used rather than %+v because nested pointers
+// and interfaces would otherwise be formatted as addresses that change between runs.
+func extractorSettings(extractor TextExtractor) string {
+	data, err := json.Marshal(extractor)
+	if err != nil {
+		return fmt.Sprintf("%+v", extractor)
+	}
+	return string(data)
+}
+
+// MemoryExtractionCache keeps extracted text in memory
+type MemoryExtractionCache struct {
+	mu         sync.RWMutex
+	maxEntries int
+	entries    map[string]string
+	order      []string
+}
+
+// NewMemoryExtractionCache creates an in-memory cache holding at most maxEntries texts
+// (0 means unbounded), evicting the oldest entry first
+func NewMemoryExtractionCache(maxEntries int) *MemoryExtractionCache {
+	return &MemoryExtractionCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]string),
+	}
+}
+
+// Get returns the cached text for key
+func (c *MemoryExtractionCache) Get(key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	text, ok := c.entries[key]
+	return text, ok
+}
+
+// Put stores the text for key
+func (c *MemoryExtractionCache) Put(key, text string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; !exists {
+		if c.maxEntries > 0 && len(c.order) >= c.maxEntries {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = text
+	return nil
+}
+
+// DiskExtractionCache stores extracted text as one file per key in a directory
+type DiskExtractionCache struct {
+	Dir string
+}
+
+// NewDiskExtractionCache creates a cache in dir, creating the directory if needed
+func NewDiskExtractionCache(dir string) (*DiskExtractionCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &DiskExtractionCache{Dir: dir}, nil
+}
+
+// Get returns the cached text for key
+func (c *DiskExtractionCache) Get(key string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(c.Dir, key+".txt"))
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// Put stores the text for key. The file is written under a temporary name and renamed
+// so concurrent readers never see a partial entry.
+func (c *DiskExtractionCache) Put(key, text string) error {
+	tmp, err := os.CreateTemp(c.Dir, key+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(text); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(c.Dir, key+".txt"))
+}
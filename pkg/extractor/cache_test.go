@@ -0,0 +1,73 @@
+package extractor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+// settingsExtractor returns text that depends on its configuration
+type settingsExtractor struct {
+	Upper bool
+}
+
+func (e *settingsExtractor) Extract(path string) (string, error) {
+	if e.Upper {
+		return "TEXT", nil
+	}
+	return "text", nil
+}
+
+func (e *settingsExtractor) SupportedExtensions() []string { return []string{".cfg"} }
+
+func TestCacheKeyIncludesExtractorSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.cfg")
+	if err := os.WriteFile(path, []byte("contents"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	key := func(e TextExtractor) string {
+		t.Helper()
+		k, err := cacheKey(path, ".cfg", e, extension.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	plain := key(&settingsExtractor{})
+	if again := key(&settingsExtractor{}); again != plain {
+		t.Errorf("equal configurations hashed differently: %s vs %s", plain, again)
+	}
+	if upper := key(&settingsExtractor{Upper: true}); upper == plain {
+		t.Error("changing an extractor setting kept the same cache key")
+	}
+}
+
+func TestExtractTextCacheMissAfterReconfiguring(t *testing.T) {
+	previous := DefaultCache
+	DefaultCache = NewMemoryExtractionCache(0)
+	t.Cleanup(func() { DefaultCache = previous })
+
+	registry := DefaultRegistry
+	DefaultRegistry = NewRegistry()
+	t.Cleanup(func() { DefaultRegistry = registry })
+	e := &settingsExtractor{}
+	if err := DefaultRegistry.Register(e); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "doc.cfg")
+	if err := os.WriteFile(path, []byte("contents"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if text, err := ExtractText(path); err != nil || text != "text" {
+		t.Fatalf("first extraction = %q, %v", text, err)
+	}
+	e.Upper = true
+	if text, err := ExtractText(path); err != nil || text != "TEXT" {
+		t.Errorf("extraction after reconfiguring = %q, %v; want the new settings to bypass the cached text", text, err)
+	}
+}
@@ -0,0 +1,95 @@
+package extractor
+
+import (
+	"os"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+)
+
+// fileConfig collects the settings assembled by ClassifyFile options
+type fileConfig struct {
+	provider classifier.Provider
+	model    string
+	apiKey   string
+	options  classifier.ClassificationOptions
+}
+
+// Option customizes a ClassifyFile call
+type Option func(*fileConfig)
+
+// WithProvider selects the model provider (default: MODEL_PROVIDER, or OpenAI)
+func WithProvider(provider classifier.Provider) Option {
+	return func(c *fileConfig) {
+		c.provider = provider
+	}
+}
+
+// WithModel selects the model (default: MODEL_TYPE, or the provider's default model)
+func WithModel(model string) Option {
+	return func(c *fileConfig) {
+		c.model = model
+	}
+}
+
+// WithAPIKey sets the API key (default: the provider's API key environment variable)
+func WithAPIKey(apiKey string) Option {
+	return func(c *fileConfig) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithCategories restricts classification to the given categories
+func WithCategories(categories ...string) Option {
+	return func(c *fileConfig) {
+		c.options.Categories = categories
+	}
+}
+
+// WithClassificationOptions sets the full classification options. Categories set
+// through WithCategories are kept when they come later in the option list.
+func WithClassificationOptions(options classifier.ClassificationOptions) Option {
+	return func(c *fileConfig) {
+		c.options = options
+	}
+}
+
+// apiKeyEnv maps each provider to the environment variable holding its API key
+var apiKeyEnv = map[classifier.Provider]string{
+	classifier.OpenAI:    "OPENAI_API_KEY",
+	classifier.Anthropic: "ANTHROPIC_API_KEY",
+	classifier.Azure:     "AZURE_OPENAI_API_KEY",
+	classifier.Custom:    "CUSTOM_API_KEY",
+	classifier.Gemini:    "GEMINI_API_KEY",
+	classifier.Embedding: "OPENAI_API_KEY",
+}
+
+// ClassifyFile extracts and classifies the file at path in one call. Settings not
+// given as options are read from the same environment variables as the server
+// (MODEL_PROVIDER, MODEL_TYPE and the provider's API key variable).
+func ClassifyFile(path string, opts ...Option) (*ExtractResult, error) {
+	cfg := fileConfig{
+		provider: classifier.ProviderFromString(os.Getenv("MODEL_PROVIDER")),
+		model:    os.Getenv("MODEL_TYPE"),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.model == "" {
+		cfg.model = string(classifier.DefaultModelForProvider(cfg.provider))
+	}
+	if cfg.apiKey == "" {
+		cfg.apiKey = os.Getenv(apiKeyEnv[cfg.provider])
+	}
+
+	config := classifier.ModelConfig{
+		Model:    cfg.model,
+		APIKey:   cfg.apiKey,
+		Endpoint: os.Getenv("MODEL_ENDPOINT"),
+		Parameters: map[string]interface{}{
+			"max_tokens":  2000,
+			"temperature": 0.3,
+		},
+	}
+	return ExtractAndClassifyWithOptions(path, cfg.provider, config, cfg.options)
+}
@@ -0,0 +1,91 @@
+package extractor
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+)
+
+// providerRequest is what the OpenAI stub of TestClassifyFile received
+type providerRequest struct {
+	model         string
+	authorization string
+	prompt        string
+}
+
+func TestClassifyFile(t *testing.T) {
+	var got providerRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		got = providerRequest{model: body.Model, authorization: r.Header.Get("Authorization")}
+		if len(body.Messages) > 0 {
+			got.prompt = body.Messages[len(body.Messages)-1].Content
+		}
+		content, _ := json.Marshal(`{"category":"Lease","confidence":0.9,"summary":"s","keywords":["rent","tenant","deposit"]}`)
+		w.Write([]byte(`{"choices":[{"message":{"content":` + string(content) + `},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("MODEL_PROVIDER", "openai")
+	t.Setenv("MODEL_TYPE", "")
+	t.Setenv("MODEL_ENDPOINT", server.URL)
+	t.Setenv("OPENAI_API_KEY", "env-key")
+	path := writeFile(t, "lease.txt", "The tenant agrees to pay rent monthly.")
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		want     providerRequest
+		keywords int
+	}{
+		{
+			name:     "environment defaults",
+			want:     providerRequest{model: "gpt-4", authorization: "Bearer env-key"},
+			keywords: 3,
+		},
+		{
+			name:     "explicit options",
+			opts:     []Option{WithModel("gpt-4o-mini"), WithAPIKey("explicit-key"), WithCategories("Lease", "Invoice")},
+			want:     providerRequest{model: "gpt-4o-mini", authorization: "Bearer explicit-key", prompt: "Lease, Invoice"},
+			keywords: 3,
+		},
+		{
+			name: "categories after full options",
+			opts: []Option{
+				WithClassificationOptions(classifier.ClassificationOptions{MaxKeywords: 1}),
+				WithCategories("Lease", "Deed"),
+			},
+			want:     providerRequest{model: "gpt-4", authorization: "Bearer env-key", prompt: "Lease, Deed"},
+			keywords: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ClassifyFile(path, tt.opts...)
+			if err != nil {
+				t.Fatalf("ClassifyFile: %v", err)
+			}
+			if result.Classification.Category != "Lease" || len(result.Classification.Keywords) != tt.keywords {
+				t.Errorf("classification = %+v, want Lease with %d keywords", result.Classification, tt.keywords)
+			}
+			if got.model != tt.want.model || got.authorization != tt.want.authorization {
+				t.Errorf("request used model %q with %q, want %q with %q", got.model, got.authorization, tt.want.model, tt.want.authorization)
+			}
+			if !strings.Contains(got.prompt, tt.want.prompt) {
+				t.Errorf("prompt does not offer %q:\n%s", tt.want.prompt, got.prompt)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package extractor
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExtractTextEncryptedPDF(t *testing.T) {
+	// testdata/encrypted.pdf uses the standard security handler with a user password
+	text, err := ExtractText("testdata/encrypted.pdf")
+	if !errors.Is(err, ErrEncryptedDocument) {
+		t.Fatalf("error = %v, want ErrEncryptedDocument", err)
+	}
+	if text != "" {
+		t.Errorf("text = %q, want none", text)
+	}
+
+	// testdata/password.pdf opens with the user password "secret"
+	if _, err := ExtractText("testdata/password.pdf"); !errors.Is(err, ErrEncryptedDocument) {
+		t.Errorf("error without a password = %v, want ErrEncryptedDocument", err)
+	}
+}
@@ -1,51 +1,178 @@
 package extractor
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/adaptive-scale/superclass/pkg/classifier"
+	"github.com/adaptive-scale/superclass/pkg/extension"
+	"github.com/adaptive-scale/superclass/pkg/extension/code"
 	"github.com/adaptive-scale/superclass/pkg/extension/docx"
+	"github.com/adaptive-scale/superclass/pkg/extension/email"
 	"github.com/adaptive-scale/superclass/pkg/extension/epub"
 	"github.com/adaptive-scale/superclass/pkg/extension/excel"
 	"github.com/adaptive-scale/superclass/pkg/extension/html"
 	"github.com/adaptive-scale/superclass/pkg/extension/image"
+	"github.com/adaptive-scale/superclass/pkg/extension/iwork"
 	"github.com/adaptive-scale/superclass/pkg/extension/markdown"
 	"github.com/adaptive-scale/superclass/pkg/extension/odt"
 	"github.com/adaptive-scale/superclass/pkg/extension/pdf"
 	"github.com/adaptive-scale/superclass/pkg/extension/pptx"
 	"github.com/adaptive-scale/superclass/pkg/extension/rtf"
 	"github.com/adaptive-scale/superclass/pkg/extension/svg"
+	"github.com/adaptive-scale/superclass/pkg/extension/webarchive"
+	"github.com/adaptive-scale/superclass/pkg/textutil"
 	log "github.com/sirupsen/logrus"
 )
 
+// ErrMalformedDocument is returned when an extractor panics while parsing a file
+var ErrMalformedDocument = errors.New("malformed document")
+
+// ErrClassificationFailed is returned when text was extracted but the classifier failed
+var ErrClassificationFailed = errors.New("classification failed")
+
+// ErrLowQualityText is returned when the extracted text is mostly non-printable,
+// typically because a binary file was mis-detected
+var ErrLowQualityText = errors.New("extracted text is mostly non-printable")
+
+// ErrInsufficientText is returned when the extracted text is shorter than
+// ClassificationOptions.MinTextLength
+var ErrInsufficientText = errors.New("extracted text is too short to classify")
+
+// MinPrintableRatio is the minimum fraction of printable characters extracted text must
+// have before it is classified (0 disables the check)
+var MinPrintableRatio = 0.0
+
+// RejectLowQualityText makes low-quality text fail with ErrLowQualityText; otherwise
+// a warning is logged and classification proceeds
+var RejectLowQualityText = true
+
+// ErrEncryptedDocument is returned when a document is password-protected and cannot be read
+var ErrEncryptedDocument = extension.ErrEncryptedDocument
+
+// ErrUnmatchedSelection is returned when the requested pages, slides or sheets are
+// invalid or missing from the document
+var ErrUnmatchedSelection = extension.ErrUnmatchedSelection
+
+// ErrNoChangedPages is returned when ClassificationOptions.PreviousVersion is set and no
+// selected page changed since that version
+var ErrNoChangedPages = errors.New("no pages changed since the previous version")
+
 // ExtractResult contains both the extracted text and its classification
 type ExtractResult struct {
 	Text           string
 	Classification *classifier.Classification
+	// FrontMatter holds the document's front-matter fields for formats that support it
+	FrontMatter map[string]interface{}
+	// Preview is set when only the leading portion of the document was classified
+	Preview bool
+	// Warnings lists non-fatal extraction problems, such as skipped slides or low OCR confidence
+	Warnings []string
+	// TypePath is the file format followed by the category path, e.g. [pdf Finance Invoice].
+	// The category path comes from the taxonomy when one is used.
+	TypePath []string
+	// ChangedPages lists the pages that differ from ClassificationOptions.PreviousVersion
+	// and were classified
+	ChangedPages []int
+}
+
+// nativeMediaTypes maps extensions of files that can be sent to providers as-is
+// when ClassificationOptions.NativeDocument is set
+var nativeMediaTypes = map[string]string{
+	".pdf": "application/pdf",
+}
+
+// visionMediaTypes maps image extensions accepted by vision models when
+// ClassificationOptions.Vision is set
+var visionMediaTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// FormatCategoryHints maps file extensions to categories commonly seen for that format.
+// They are suggested to the model when the caller supplies no categories and
+// ClassificationOptions.UseFormatHints is set.
+var FormatCategoryHints = map[string][]string{
+	".xlsx": {"Financial Report", "Budget", "Invoice", "Data Export"},
+	".xlsm": {"Financial Report", "Budget", "Invoice", "Data Export"},
+	".pptx": {"Presentation", "Sales Pitch", "Training Material"},
+	".svg":  {"Diagram", "Logo", "Illustration", "Chart"},
+	".epub": {"Book", "Fiction", "Non-Fiction"},
+	".md":   {"Technical Documentation", "README", "Notes"},
 }
 
 func init() {
 	log.Debug("Initializing default registry with built-in extractors")
 	// Register all built-in extractors
-	DefaultRegistry.Register(pdf.NewExtractor())
-	DefaultRegistry.Register(image.NewExtractor())
-	DefaultRegistry.Register(docx.NewExtractor())
-	DefaultRegistry.Register(pptx.NewExtractor())
-	DefaultRegistry.Register(rtf.NewExtractor())
-	DefaultRegistry.Register(odt.NewExtractor())
-	DefaultRegistry.Register(html.NewExtractor())
-	DefaultRegistry.Register(markdown.NewExtractor())
-	DefaultRegistry.Register(epub.NewExtractor())
-	DefaultRegistry.Register(excel.NewExtractor())
-	DefaultRegistry.Register(svg.NewExtractor())
-	log.Debug("All built-in extractors registered successfully")
+	for _, e := range []TextExtractor{
+		pdf.NewExtractor(),
+		image.NewExtractor(),
+		docx.NewExtractor(),
+		pptx.NewExtractor(),
+		rtf.NewExtractor(),
+		odt.NewExtractor(),
+		html.NewExtractor(),
+		markdown.NewExtractor(),
+		epub.NewExtractor(),
+		excel.NewExtractor(),
+		svg.NewExtractor(),
+		iwork.NewExtractor(),
+		code.NewExtractor(),
+		email.NewExtractor(),
+		webarchive.NewExtractor(),
+	} {
+		if err := DefaultRegistry.Register(e); err != nil {
+			log.WithError(err).Errorf("Failed to register built-in extractor %T", e)
+		}
+	}
+	log.Debug("All built-in extractors registered")
 }
 
 // ExtractText extracts text from a file using the appropriate registered extractor
 func ExtractText(path string) (string, error) {
+	return ExtractTextWithOptions(path, extension.Options{})
+}
+
+// ExtractWithWarnings extracts text like ExtractText and also returns the non-fatal
+// problems reported by the extractor
+func ExtractWithWarnings(path string) (string, []string, error) {
+	return extractWithWarnings(path, extension.Options{})
+}
+
+// extractWithWarnings runs ExtractTextWithOptions, collecting the warnings reported
+// through opts in addition to passing them to any callback already set
+func extractWithWarnings(path string, opts extension.Options) (string, []string, error) {
+	var mu sync.Mutex
+	var warnings []string
+	next := opts.Warn
+	opts.Warn = func(message string) {
+		mu.Lock()
+		warnings = append(warnings, message)
+		mu.Unlock()
+		if next != nil {
+			next(message)
+		}
+	}
+	text, err := ExtractTextWithOptions(path, opts)
+	return text, warnings, err
+}
+
+// ExtractTextWithOptions extracts text like ExtractText, passing opts to extractors that
+// support them. The pdf and pptx extractors report progress and honor MaxPages and Pages
+// per page and slide; the excel extractor honors Sheets.
+// Text extracted with warnings is not cached, so a later attempt can do better.
+func ExtractTextWithOptions(path string, opts extension.Options) (string, error) {
 	logger := log.WithFields(log.Fields{
 		"function": "ExtractText",
 		"path":     path,
@@ -55,10 +182,13 @@ func ExtractText(path string) (string, error) {
 	ext := strings.ToLower(filepath.Ext(path))
 	logger.WithField("extension", ext).Debug("Detected file extension")
 
-	// Special case for plain text files
+	// Special case for plain text files. They skip the extraction cache, since reading
+	// the file costs no more than hashing it, but are still counted in the metrics.
 	if ext == ".txt" {
 		logger.Debug("Processing plain text file")
+		started := time.Now()
 		bytes, err := ioutil.ReadFile(path)
+		recordExtraction(ext, path, time.Since(started), string(bytes), err)
 		if err != nil {
 			logger.WithError(err).Error("Failed to read text file")
 			return "", err
@@ -75,13 +205,40 @@ func ExtractText(path string) (string, error) {
 		return "", fmt.Errorf("unsupported file type: %s", ext)
 	}
 
+	var key string
+	if DefaultCache != nil {
+		if key, err = cacheKey(path, ext, extractor, opts); err != nil {
+			logger.WithError(err).Warn("Failed to hash file for extraction cache")
+		} else if text, ok := DefaultCache.Get(key); ok {
+			logger.WithField("cache_key", key).Debug("Extraction cache hit")
+			return text, nil
+		}
+	}
+
 	logger.Debug("Starting extraction with appropriate extractor")
-	text, err := extractor.Extract(path)
+	warned := false
+	warn := opts.Warn
+	opts.Warn = func(message string) {
+		warned = true
+		logger.WithField("warning", message).Warn("Extraction warning")
+		if warn != nil {
+			warn(message)
+		}
+	}
+	started := time.Now()
+	text, err := safeExtract(extractor, path, opts)
+	recordExtraction(ext, path, time.Since(started), text, err)
 	if err != nil {
 		logger.WithError(err).Error("Extraction failed")
 		return "", err
 	}
 
+	if DefaultCache != nil && key != "" && !warned {
+		if err := DefaultCache.Put(key, text); err != nil {
+			logger.WithError(err).Warn("Failed to store extraction cache entry")
+		}
+	}
+
 	logger.WithFields(log.Fields{
 		"chars_extracted": len(text),
 		"lines_extracted": len(strings.Split(text, "\n")),
@@ -89,12 +246,92 @@ func ExtractText(path string) (string, error) {
 	return text, nil
 }
 
+// recordExtraction adds an extractor call to DefaultMetrics, with the file size as
+// its input bytes
+func recordExtraction(ext, path string, duration time.Duration, text string, err error) {
+	if DefaultMetrics == nil {
+		return
+	}
+	var inputBytes int64
+	if info, statErr := os.Stat(path); statErr == nil {
+		inputBytes = info.Size()
+	}
+	DefaultMetrics.Observe(ext, duration, inputBytes, int64(len(text)), err)
+}
+
+// safeExtract runs the extractor and converts any panic raised by the underlying
+// parsing library into an ErrMalformedDocument error
+func safeExtract(extractor TextExtractor, path string, opts extension.Options) (text string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.WithFields(log.Fields{
+				"function": "safeExtract",
+				"path":     path,
+				"panic":    r,
+			}).Error("Extractor panicked")
+			text = ""
+			err = fmt.Errorf("%w: %v", ErrMalformedDocument, r)
+		}
+	}()
+	if e, ok := extractor.(OptionsExtractor); ok {
+		return e.ExtractWithOptions(path, opts)
+	}
+	return extractor.Extract(path)
+}
+
+// checkTextQuality guards against classifying garbage by comparing the printable
+// character ratio with MinPrintableRatio
+func checkTextQuality(text string, logger *log.Entry) error {
+	if MinPrintableRatio <= 0 {
+		return nil
+	}
+	ratio := textutil.PrintableRatio(text)
+	if ratio >= MinPrintableRatio {
+		return nil
+	}
+
+	logger = logger.WithFields(log.Fields{
+		"printable_ratio":     ratio,
+		"min_printable_ratio": MinPrintableRatio,
+	})
+	if RejectLowQualityText {
+		logger.Error("Extracted text is mostly non-printable, skipping classification")
+		return fmt.Errorf("%w: %.0f%% printable", ErrLowQualityText, ratio*100)
+	}
+	logger.Warn("Extracted text is mostly non-printable")
+	return nil
+}
+
+// checkTextLength fails with ErrInsufficientText when text has fewer than minLength
+// non-whitespace characters
+func checkTextLength(text string, minLength int, logger *log.Entry) error {
+	if minLength <= 0 {
+		return nil
+	}
+	length := 0
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			length++
+		}
+	}
+	if length >= minLength {
+		return nil
+	}
+	logger.WithFields(log.Fields{
+		"text_length":     length,
+		"min_text_length": minLength,
+	}).Warn("Extracted text is too short, skipping classification")
+	return fmt.Errorf("%w: %d characters, at least %d required", ErrInsufficientText, length, minLength)
+}
+
 // ExtractAndClassify extracts text from a file and classifies it using the specified model
 func ExtractAndClassify(path string, provider classifier.Provider, config classifier.ModelConfig) (*ExtractResult, error) {
 	return ExtractAndClassifyWithOptions(path, provider, config, classifier.ClassificationOptions{})
 }
 
-// ExtractAndClassifyWithOptions extracts text from a file and classifies it using the specified model and options
+// ExtractAndClassifyWithOptions extracts text from a file and classifies it using the specified model and options.
+// When extraction succeeds but classification fails, the returned error wraps
+// ErrClassificationFailed and the result still carries the extracted text.
 func ExtractAndClassifyWithOptions(path string, provider classifier.Provider, config classifier.ModelConfig, options classifier.ClassificationOptions) (*ExtractResult, error) {
 	logger := log.WithFields(log.Fields{
 		"function":       "ExtractAndClassifyWithOptions",
@@ -105,14 +342,78 @@ func ExtractAndClassifyWithOptions(path string, provider classifier.Provider, co
 	})
 	logger.Debug("Starting extraction and classification")
 
-	// First extract the text
-	logger.Debug("Extracting text from file")
-	text, err := ExtractText(path)
-	if err != nil {
-		logger.WithError(err).Error("Text extraction failed")
-		return nil, fmt.Errorf("text extraction failed: %w", err)
+	var text string
+	var warnings []string
+	var changedPages []int
+	var err error
+	if mediaType, ok := visionMediaTypes[strings.ToLower(filepath.Ext(path))]; ok && options.Vision && provider == classifier.OpenAI {
+		// Vision models read the image directly, so OCR is skipped
+		logger.Debug("Attaching image for vision classification")
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			logger.WithError(err).Error("Failed to read image")
+			return nil, fmt.Errorf("failed to read image: %w", err)
+		}
+		options.Document = &classifier.Document{MediaType: mediaType, Data: data}
+	} else {
+		// First extract the text
+		logger.Debug("Extracting text from file")
+		opts := extension.Options{MaxPages: options.PreviewPages, Sheets: options.Sheets}
+		if options.Pages != "" {
+			if opts.Pages, err = extension.ParsePageRanges(options.Pages); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrUnmatchedSelection, err)
+			}
+		}
+		if options.PreviousVersion != "" {
+			if opts.Pages, changedPages, err = changedPageRanges(path, options.PreviousVersion, opts.Pages); err != nil {
+				return nil, err
+			}
+			logger.WithField("changed_pages", changedPages).Debug("Restricted extraction to changed pages")
+		}
+		text, warnings, err = extractWithWarnings(path, opts)
+		if err != nil {
+			logger.WithError(err).Error("Text extraction failed")
+			return nil, fmt.Errorf("text extraction failed: %w", err)
+		}
+		text = previewText(text, options.PreviewChars)
+		logger.WithField("text_length", len(text)).Debug("Text extraction completed")
+
+		if err := checkTextQuality(text, logger); err != nil {
+			return nil, err
+		}
+		if err := checkTextLength(text, options.MinTextLength, logger); err != nil {
+			return nil, err
+		}
+	}
+
+	frontMatter := ExtractFrontMatter(path)
+	if options.UseFrontMatterHints && len(options.Categories) == 0 && len(options.CategoryHints) == 0 {
+		options.CategoryHints = frontMatterHints(frontMatter)
+		if len(options.CategoryHints) > 0 {
+			logger.WithField("category_hints", options.CategoryHints).Debug("Applied front-matter category hints")
+		}
+	}
+
+	if options.UseFormatHints && len(options.Categories) == 0 && len(options.CategoryHints) == 0 {
+		ext := strings.ToLower(filepath.Ext(path))
+		options.CategoryHints = FormatCategoryHints[ext]
+		logger.WithFields(log.Fields{
+			"extension":      ext,
+			"category_hints": options.CategoryHints,
+		}).Debug("Applied format category hints")
+	}
+
+	if options.NativeDocument && options.Document == nil {
+		if mediaType, ok := nativeMediaTypes[strings.ToLower(filepath.Ext(path))]; ok {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				logger.WithError(err).Warn("Failed to read document for native classification, using extracted text")
+			} else {
+				options.Document = &classifier.Document{MediaType: mediaType, Data: data}
+				logger.WithField("media_type", mediaType).Debug("Attached original document")
+			}
+		}
 	}
-	logger.WithField("text_length", len(text)).Debug("Text extraction completed")
 
 	// Create classifier for the specified provider
 	logger.Debug("Creating classifier instance")
@@ -127,7 +428,7 @@ func ExtractAndClassifyWithOptions(path string, provider classifier.Provider, co
 	classification, err := clf.ClassifyWithOptions(text, options)
 	if err != nil {
 		logger.WithError(err).Error("Classification failed")
-		return nil, fmt.Errorf("classification failed: %w", err)
+		return &ExtractResult{Text: text, FrontMatter: frontMatter, Preview: isPreview(options), Warnings: warnings, ChangedPages: changedPages}, fmt.Errorf("%w: %w", ErrClassificationFailed, err)
 	}
 
 	logger.WithFields(log.Fields{
@@ -140,9 +441,113 @@ func ExtractAndClassifyWithOptions(path string, provider classifier.Provider, co
 	return &ExtractResult{
 		Text:           text,
 		Classification: classification,
+		FrontMatter:    frontMatter,
+		Preview:        isPreview(options),
+		Warnings:       warnings,
+		TypePath:       typePath(path, classification.Category, options),
+		ChangedPages:   changedPages,
 	}, nil
 }
 
+// changedPageRanges returns the pages of the PDF at path that changed since the version
+// at previous, restricted to selection when it is not empty, both as ranges and as a list
+func changedPageRanges(path, previous string, selection []extension.PageRange) ([]extension.PageRange, []int, error) {
+	if strings.ToLower(filepath.Ext(path)) != ".pdf" {
+		return nil, nil, fmt.Errorf("%w: changed-page extraction is only supported for PDF files", ErrUnmatchedSelection)
+	}
+	changed, err := pdf.DiffPDF(previous, path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compare with the previous version: %w", err)
+	}
+
+	selected := extension.Options{Pages: selection}
+	var ranges []extension.PageRange
+	var pages []int
+	for _, n := range changed {
+		if !selected.PageSelected(n) {
+			continue
+		}
+		pages = append(pages, n)
+		if last := len(ranges) - 1; last >= 0 && ranges[last].Last == n-1 {
+			ranges[last].Last = n
+		} else {
+			ranges = append(ranges, extension.PageRange{First: n, Last: n})
+		}
+	}
+	if len(pages) == 0 {
+		return nil, nil, ErrNoChangedPages
+	}
+	return ranges, pages, nil
+}
+
+// typePath returns the format of the file at path followed by the path of category in
+// the taxonomy, or the category alone when no taxonomy is used
+func typePath(path, category string, options classifier.ClassificationOptions) []string {
+	typePath := []string{strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")}
+	if len(options.Categories) == 0 {
+		if categories := options.CategorySet.Path(category); categories != nil {
+			return append(typePath, categories...)
+		}
+	}
+	return append(typePath, category)
+}
+
+// isPreview reports whether options limit extraction to a leading portion of the document
+func isPreview(options classifier.ClassificationOptions) bool {
+	return options.PreviewChars > 0 || options.PreviewPages > 0
+}
+
+// previewText cuts text to at most maxChars bytes without splitting a character
+// (0 keeps the whole text)
+func previewText(text string, maxChars int) string {
+	if maxChars <= 0 || len(text) <= maxChars {
+		return text
+	}
+	cut := maxChars
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut]
+}
+
+// frontMatterHintKeys are the front-matter fields used as category hints
+var frontMatterHintKeys = []string{"category", "categories", "tags"}
+
+// ExtractFrontMatter returns the front-matter fields of the file at path, or nil when
+// its extractor does not support front-matter or the document has none
+func ExtractFrontMatter(path string) map[string]interface{} {
+	e, err := DefaultRegistry.Get(strings.ToLower(filepath.Ext(path)))
+	if err != nil {
+		return nil
+	}
+	fm, ok := e.(FrontMatterExtractor)
+	if !ok {
+		return nil
+	}
+	fields, err := fm.FrontMatter(path)
+	if err != nil {
+		log.WithError(err).WithField("path", path).Warn("Failed to read front-matter")
+		return nil
+	}
+	return fields
+}
+
+// frontMatterHints collects the categories and tags declared in front-matter
+func frontMatterHints(fields map[string]interface{}) []string {
+	var hints []string
+	for _, key := range frontMatterHintKeys {
+		switch value := fields[key].(type) {
+		case string:
+			if value != "" {
+				hints = append(hints, value)
+			}
+		case []string:
+			hints = append(hints, value...)
+		}
+	}
+	return hints
+}
+
 // GetSupportedFormats returns a list of all supported file formats
 func GetSupportedFormats() []string {
 	log.Debug("Retrieving list of supported formats")
@@ -0,0 +1,379 @@
+package extractor
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+// promptRecorder keeps the last user prompt sent to a provider stub
+type promptRecorder struct {
+	mu     sync.Mutex
+	prompt string
+	calls  int
+}
+
+// Prompt returns the last prompt the stub received
+func (p *promptRecorder) Prompt() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.prompt
+}
+
+// Calls returns the number of requests the stub received
+func (p *promptRecorder) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+// serveClassification starts an OpenAI-compatible stub answering every request with
+// classification, and returns a config pointing at it along with the prompt it receives
+func serveClassification(t *testing.T, classification string) (classifier.ModelConfig, *promptRecorder) {
+	t.Helper()
+	recorder := &promptRecorder{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content json.RawMessage `json:"content"`
+			} `json:"messages"`
+		}
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil || len(body.Messages) == 0 {
+			t.Errorf("unexpected request body: %s", data)
+		}
+		recorder.mu.Lock()
+		recorder.calls++
+		if len(body.Messages) > 0 {
+			var text string
+			if json.Unmarshal(body.Messages[len(body.Messages)-1].Content, &text) != nil {
+				text = string(body.Messages[len(body.Messages)-1].Content)
+			}
+			recorder.prompt = text
+		}
+		recorder.mu.Unlock()
+
+		content, _ := json.Marshal(classification)
+		w.Write([]byte(`{"choices":[{"message":{"content":` + string(content) + `},"finish_reason":"stop"}]}`))
+	}))
+	t.Cleanup(server.Close)
+	return classifier.ModelConfig{Endpoint: server.URL, APIKey: "key", MaxRetries: -1}, recorder
+}
+
+// writeFile writes data to name in a fresh temporary directory and returns its path
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFormatCategoryHints(t *testing.T) {
+	config, recorder := serveClassification(t, `{"category":"Notes","confidence":0.8,"keywords":[]}`)
+	path := writeFile(t, "notes.md", "# Standup\n\nDiscussed the release plan and open bugs.\n")
+
+	tests := []struct {
+		name      string
+		options   classifier.ClassificationOptions
+		wantHints bool
+	}{
+		{name: "hints enabled", options: classifier.ClassificationOptions{UseFormatHints: true}, wantHints: true},
+		{name: "hints disabled", options: classifier.ClassificationOptions{}},
+		{name: "caller categories win", options: classifier.ClassificationOptions{UseFormatHints: true, Categories: []string{"Notes", "Spec"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ExtractAndClassifyWithOptions(path, classifier.OpenAI, config, tt.options); err != nil {
+				t.Fatalf("ExtractAndClassifyWithOptions: %v", err)
+			}
+			prompt := recorder.Prompt()
+			hinted := strings.Contains(prompt, "often fall into categories such as: Technical Documentation, README, Notes")
+			if hinted != tt.wantHints {
+				t.Errorf("prompt carries the markdown hints = %v, want %v:\n%s", hinted, tt.wantHints, prompt)
+			}
+		})
+	}
+}
+
+func TestVisionClassificationSkipsOCR(t *testing.T) {
+	config, recorder := serveClassification(t, `{"category":"Receipt","confidence":0.8,"keywords":[]}`)
+	// Not a decodable image: OCR would fail, so success shows the image went to the model
+	path := writeFile(t, "receipt.png", "\x89PNG\r\n\x1a\nnot really an image")
+
+	result, err := ExtractAndClassifyWithOptions(path, classifier.OpenAI, config, classifier.ClassificationOptions{Vision: true})
+	if err != nil {
+		t.Fatalf("ExtractAndClassifyWithOptions: %v", err)
+	}
+	if result.Classification.Category != "Receipt" || result.Text != "" {
+		t.Errorf("result = %q with text %q, want Receipt without extracted text", result.Classification.Category, result.Text)
+	}
+	if prompt := recorder.Prompt(); !strings.Contains(prompt, "data:image/png;base64,") {
+		t.Errorf("image not attached to the request: %s", prompt)
+	}
+}
+
+// useQualityCheck sets MinPrintableRatio and RejectLowQualityText for the rest of the test
+func useQualityCheck(t *testing.T, minRatio float64, reject bool) {
+	t.Helper()
+	previousRatio, previousReject := MinPrintableRatio, RejectLowQualityText
+	MinPrintableRatio, RejectLowQualityText = minRatio, reject
+	t.Cleanup(func() { MinPrintableRatio, RejectLowQualityText = previousRatio, previousReject })
+}
+
+func TestLowQualityTextGuard(t *testing.T) {
+	config, recorder := serveClassification(t, `{"category":"Other","confidence":0.2,"keywords":[]}`)
+	// A mis-detected binary file: a few readable bytes among control characters
+	garbage := writeFile(t, "dump.txt", "ELF\x00\x01\x02\x03\x04\x05\x06\x07\x0e\x0f\x10\x11\x12\x13\x14\x15\x16")
+	readable := writeFile(t, "memo.txt", "Please review the attached budget before Friday.")
+
+	useQualityCheck(t, 0.8, true)
+	if _, err := ExtractAndClassifyWithOptions(garbage, classifier.OpenAI, config, classifier.ClassificationOptions{}); !errors.Is(err, ErrLowQualityText) {
+		t.Fatalf("garbage error = %v, want ErrLowQualityText", err)
+	}
+	if recorder.Calls() != 0 {
+		t.Errorf("provider called %d times for rejected text, want 0", recorder.Calls())
+	}
+	if _, err := ExtractAndClassifyWithOptions(readable, classifier.OpenAI, config, classifier.ClassificationOptions{}); err != nil {
+		t.Errorf("readable text rejected: %v", err)
+	}
+
+	// Without rejection the text is still classified
+	useQualityCheck(t, 0.8, false)
+	if _, err := ExtractAndClassifyWithOptions(garbage, classifier.OpenAI, config, classifier.ClassificationOptions{}); err != nil {
+		t.Errorf("garbage with rejection disabled: %v", err)
+	}
+	if recorder.Calls() != 2 {
+		t.Errorf("provider called %d times, want 2", recorder.Calls())
+	}
+}
+
+func TestExtractTextFromReader(t *testing.T) {
+	for _, ext := range []string{".md", "md", ".MD"} {
+		text, err := ExtractTextFromReader(strings.NewReader("# Lease\n\nThe tenant agrees to pay rent."), ext)
+		if err != nil {
+			t.Fatalf("ExtractTextFromReader(%q): %v", ext, err)
+		}
+		if !strings.Contains(text, "The tenant agrees to pay rent.") {
+			t.Errorf("ExtractTextFromReader(%q) = %q, want the markdown text", ext, text)
+		}
+	}
+	if _, err := ExtractTextFromReader(strings.NewReader("data"), ".xyz1"); err == nil {
+		t.Error("ExtractTextFromReader with an unsupported extension succeeded")
+	}
+}
+
+func TestFrontMatterHints(t *testing.T) {
+	config, recorder := serveClassification(t, `{"category":"Changelog","confidence":0.8,"keywords":[]}`)
+	path := writeFile(t, "notes.md", "---\ncategory: Changelog\ntags: [release, api]\nauthor: Dana\n---\n# v2.0\n\nAdded batch uploads.\n")
+
+	result, err := ExtractAndClassifyWithOptions(path, classifier.OpenAI, config, classifier.ClassificationOptions{UseFrontMatterHints: true})
+	if err != nil {
+		t.Fatalf("ExtractAndClassifyWithOptions: %v", err)
+	}
+	if !strings.Contains(recorder.Prompt(), "categories such as: Changelog, release, api.") {
+		t.Errorf("prompt lacks the front-matter hints:\n%s", recorder.Prompt())
+	}
+	if strings.Contains(result.Text, "author") || !strings.Contains(result.Text, "Added batch uploads.") {
+		t.Errorf("text = %q, want the body without front-matter", result.Text)
+	}
+	if result.FrontMatter["author"] != "Dana" {
+		t.Errorf("front-matter = %v, want the author field returned", result.FrontMatter)
+	}
+
+	// Explicit hints win over front-matter
+	_, err = ExtractAndClassifyWithOptions(path, classifier.OpenAI, config, classifier.ClassificationOptions{
+		UseFrontMatterHints: true,
+		CategoryHints:       []string{"Announcement"},
+	})
+	if err != nil {
+		t.Fatalf("ExtractAndClassifyWithOptions: %v", err)
+	}
+	if prompt := recorder.Prompt(); !strings.Contains(prompt, "such as: Announcement.") || strings.Contains(prompt, "Changelog, release") {
+		t.Errorf("prompt does not use the explicit hints:\n%s", prompt)
+	}
+}
+
+func TestPreviewClassification(t *testing.T) {
+	config, recorder := serveClassification(t, `{"category":"Report","confidence":0.7,"keywords":[]}`)
+	path := writeFile(t, "report.txt", "Quarterly summary. "+strings.Repeat("Appendix detail. ", 200))
+
+	result, err := ExtractAndClassifyWithOptions(path, classifier.OpenAI, config, classifier.ClassificationOptions{PreviewChars: 18})
+	if err != nil {
+		t.Fatalf("ExtractAndClassifyWithOptions: %v", err)
+	}
+	if !result.Preview || result.Text != "Quarterly summary." {
+		t.Errorf("result = {Preview: %v, Text: %q}, want a preview of the first 18 bytes", result.Preview, result.Text)
+	}
+	if strings.Contains(recorder.Prompt(), "Appendix") {
+		t.Error("prompt contains text beyond the preview")
+	}
+
+	result, err = ExtractAndClassifyWithOptions(path, classifier.OpenAI, config, classifier.ClassificationOptions{})
+	if err != nil {
+		t.Fatalf("ExtractAndClassifyWithOptions: %v", err)
+	}
+	if result.Preview || !strings.Contains(result.Text, "Appendix") {
+		t.Errorf("full classification marked as preview or truncated")
+	}
+}
+
+func TestTypePath(t *testing.T) {
+	config, _ := serveClassification(t, `{"category":"invoice","confidence":0.9,"keywords":[]}`)
+	path := writeFile(t, "march.TXT", "Invoice 42: total due $40")
+	set, err := classifier.NewCategorySet(
+		classifier.Category{Name: "Finance"},
+		classifier.Category{Name: "Invoice", Parent: "Finance"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ExtractAndClassifyWithOptions(path, classifier.OpenAI, config, classifier.ClassificationOptions{CategorySet: set})
+	if err != nil {
+		t.Fatalf("ExtractAndClassifyWithOptions: %v", err)
+	}
+	if want := []string{"txt", "Finance", "Invoice"}; !slices.Equal(result.TypePath, want) {
+		t.Errorf("TypePath = %q, want %q", result.TypePath, want)
+	}
+
+	// An explicit category list has no hierarchy
+	result, err = ExtractAndClassifyWithOptions(path, classifier.OpenAI, config, classifier.ClassificationOptions{
+		CategorySet: set,
+		Categories:  []string{"Invoice", "Memo"},
+	})
+	if err != nil {
+		t.Fatalf("ExtractAndClassifyWithOptions: %v", err)
+	}
+	if want := []string{"txt", "Invoice"}; !slices.Equal(result.TypePath, want) {
+		t.Errorf("TypePath = %q, want %q", result.TypePath, want)
+	}
+}
+
+func TestPreviousVersionRequiresPDF(t *testing.T) {
+	config, recorder := serveClassification(t, `{"category":"Notes","confidence":0.8,"keywords":[]}`)
+	path := writeFile(t, "notes.txt", "Second draft")
+	previous := writeFile(t, "old.txt", "First draft")
+
+	_, err := ExtractAndClassifyWithOptions(path, classifier.OpenAI, config, classifier.ClassificationOptions{PreviousVersion: previous})
+	if !errors.Is(err, ErrUnmatchedSelection) {
+		t.Errorf("error = %v, want ErrUnmatchedSelection for a text file", err)
+	}
+	if recorder.Calls() != 0 {
+		t.Errorf("provider called %d times, want 0", recorder.Calls())
+	}
+}
+
+func TestMinTextLength(t *testing.T) {
+	config, recorder := serveClassification(t, `{"category":"Notes","confidence":0.8,"keywords":[]}`)
+	// Eight characters once whitespace is left out
+	path := writeFile(t, "scan.txt", "  Page \n\t 1 of 2 \n")
+
+	_, err := ExtractAndClassifyWithOptions(path, classifier.OpenAI, config, classifier.ClassificationOptions{MinTextLength: 9})
+	if !errors.Is(err, ErrInsufficientText) {
+		t.Fatalf("error = %v, want ErrInsufficientText", err)
+	}
+	if !strings.Contains(err.Error(), "8 characters, at least 9 required") {
+		t.Errorf("error = %v, want the counted and required lengths", err)
+	}
+	if recorder.Calls() != 0 {
+		t.Errorf("provider called %d times for too short text, want 0", recorder.Calls())
+	}
+
+	for _, minLength := range []int{0, 8} {
+		if _, err := ExtractAndClassifyWithOptions(path, classifier.OpenAI, config, classifier.ClassificationOptions{MinTextLength: minLength}); err != nil {
+			t.Errorf("MinTextLength %d: %v", minLength, err)
+		}
+	}
+}
+
+func TestPreviewText(t *testing.T) {
+	for _, tc := range []struct {
+		text     string
+		maxChars int
+		want     string
+	}{
+		{"abcdef", 0, "abcdef"},
+		{"abcdef", 10, "abcdef"},
+		{"abcdef", 3, "abc"},
+		// "é" is two bytes; a cut inside it backs off to the previous character
+		{"caféine", 4, "caf"},
+		{"caféine", 5, "café"},
+	} {
+		if got := previewText(tc.text, tc.maxChars); got != tc.want {
+			t.Errorf("previewText(%q, %d) = %q, want %q", tc.text, tc.maxChars, got, tc.want)
+		}
+	}
+}
+
+// warningExtractor extracts the file contents, warning once per extraction while warn is set
+type warningExtractor struct {
+	warn  bool
+	calls int
+}
+
+func (e *warningExtractor) Extract(path string) (string, error) {
+	return e.ExtractWithOptions(path, extension.Options{})
+}
+
+func (e *warningExtractor) ExtractWithOptions(path string, opts extension.Options) (string, error) {
+	e.calls++
+	if e.warn {
+		opts.Warnf("slide %d skipped", 2)
+	}
+	data, err := os.ReadFile(path)
+	return string(data), err
+}
+
+func (e *warningExtractor) SupportedExtensions() []string { return []string{".warn"} }
+
+func TestExtractionWarnings(t *testing.T) {
+	previous := DefaultCache
+	DefaultCache = NewMemoryExtractionCache(0)
+	t.Cleanup(func() { DefaultCache = previous })
+	registry := DefaultRegistry
+	DefaultRegistry = NewRegistry()
+	t.Cleanup(func() { DefaultRegistry = registry })
+	e := &warningExtractor{warn: true}
+	if err := DefaultRegistry.Register(e); err != nil {
+		t.Fatal(err)
+	}
+	path := writeFile(t, "deck.warn", "Quarterly roadmap")
+
+	text, warnings, err := ExtractWithWarnings(path)
+	if err != nil || text != "Quarterly roadmap" {
+		t.Fatalf("ExtractWithWarnings = %q, %v", text, err)
+	}
+	if len(warnings) != 1 || warnings[0] != "slide 2 skipped" {
+		t.Errorf("warnings = %q, want the skipped slide", warnings)
+	}
+
+	// Text extracted with warnings is not cached, so the next call extracts again
+	config, _ := serveClassification(t, `{"category":"Roadmap","confidence":0.8,"keywords":[]}`)
+	result, err := ExtractAndClassifyWithOptions(path, classifier.OpenAI, config, classifier.ClassificationOptions{})
+	if err != nil {
+		t.Fatalf("ExtractAndClassifyWithOptions: %v", err)
+	}
+	if e.calls != 2 || len(result.Warnings) != 1 {
+		t.Errorf("extractor called %d times with warnings %q, want 2 calls and the warning returned", e.calls, result.Warnings)
+	}
+
+	// Clean extractions are cached as before
+	e.warn = false
+	ExtractText(path)
+	if _, warnings, _ := ExtractWithWarnings(path); e.calls != 3 || len(warnings) != 0 {
+		t.Errorf("extractor called %d times with warnings %q, want the clean text served from the cache", e.calls, warnings)
+	}
+}
@@ -3,8 +3,11 @@ package extractor
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/adaptive-scale/superclass/pkg/classifier"
+	"github.com/adaptive-scale/superclass/pkg/extension"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -24,6 +27,10 @@ type DocumentFeatures struct {
 	SentimentScore   float64           `json:"sentiment_score"`
 	LanguageMetrics  LanguageMetrics   `json:"language_metrics"`
 	ContentStructure ContentStructure  `json:"content_structure"`
+
+	// Truncated is set when the document was cut to fit the model's context window;
+	// the features then describe only the leading portion of the text
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 // NamedEntity represents an entity detected in the text
@@ -174,6 +181,54 @@ Analysis criteria:
 Text to analyze:`,
 }
 
+// FeaturePromptKey selects a feature extraction prompt by provider and content type
+type FeaturePromptKey struct {
+	Provider    classifier.Provider
+	ContentType classifier.ContentType
+}
+
+// contentTypeEmphasis is added to the provider prompt to steer the analysis for specific kinds of documents
+var contentTypeEmphasis = map[classifier.ContentType]string{
+	classifier.CodeSnippet: `The text is source code. Count functions, classes and comment blocks as headings, treat each code block or file section as a code block, report identifiers, libraries and frameworks as keywords, and rate technicality from the code's complexity rather than its prose.`,
+	classifier.LegalDocument: `The text is a legal document. Treat numbered clauses and sections as headings, report parties, jurisdictions, courts and statutes as named entities, and list defined terms and obligations as keywords. Formality and readability should reflect legal drafting conventions.`,
+	classifier.AcademicPaper: `The text is an academic paper. Treat abstract, section and subsection titles as headings, report authors, institutions and cited works as named entities, and count figures, tables and equations in the structure metrics.`,
+}
+
+// FeaturePrompts contains feature extraction prompts keyed by provider and content type.
+// Entries for GeneralText are the ModelPrompts defaults; other content types add emphasis
+// suited to that kind of document.
+var FeaturePrompts = defaultFeaturePrompts()
+
+func defaultFeaturePrompts() map[FeaturePromptKey]string {
+	prompts := make(map[FeaturePromptKey]string)
+	for provider, prompt := range ModelPrompts {
+		prompts[FeaturePromptKey{provider, classifier.GeneralText}] = prompt
+		for contentType, emphasis := range contentTypeEmphasis {
+			idx := strings.LastIndex(prompt, "Text to analyze:")
+			if idx < 0 {
+				idx = len(prompt)
+			}
+			prompts[FeaturePromptKey{provider, contentType}] = prompt[:idx] + emphasis + "\n\n" + prompt[idx:]
+		}
+	}
+	return prompts
+}
+
+// featurePrompt returns the prompt for the provider and content type, falling back to the
+// provider's general prompt and then to the OpenAI prompts
+func featurePrompt(provider classifier.Provider, contentType classifier.ContentType) string {
+	for _, key := range []FeaturePromptKey{
+		{provider, contentType},
+		{provider, classifier.GeneralText},
+		{classifier.OpenAI, contentType},
+	} {
+		if prompt, ok := FeaturePrompts[key]; ok {
+			return prompt
+		}
+	}
+	return ModelPrompts[classifier.OpenAI]
+}
+
 // DefaultModelConfig returns the recommended model configuration for feature extraction
 func DefaultModelConfig(provider classifier.Provider) classifier.ModelConfig {
 	switch provider {
@@ -212,12 +267,19 @@ func DefaultModelConfig(provider classifier.Provider) classifier.ModelConfig {
 	}
 }
 
-// ExtractFeatures extracts various features from the document text using the specified model
-func ExtractFeatures(text string, provider classifier.Provider, config classifier.ModelConfig) (*DocumentFeatures, error) {
+// ExtractFeatures extracts various features from the document text using the specified model.
+// An optional content type selects a prompt tailored to that kind of document.
+func ExtractFeatures(text string, provider classifier.Provider, config classifier.ModelConfig, contentType ...classifier.ContentType) (*DocumentFeatures, error) {
+	kind := classifier.GeneralText
+	if len(contentType) > 0 {
+		kind = contentType[0]
+	}
+
 	logger := log.WithFields(log.Fields{
-		"function": "ExtractFeatures",
-		"provider": provider,
-		"model":    config.Model,
+		"function":     "ExtractFeatures",
+		"provider":     provider,
+		"model":        config.Model,
+		"content_type": kind,
 	})
 	logger.Debug("Starting model-based feature extraction")
 
@@ -227,10 +289,13 @@ func ExtractFeatures(text string, provider classifier.Provider, config classifie
 		return nil, fmt.Errorf("failed to create classifier: %w", err)
 	}
 
-	// Get the appropriate prompt for the provider
-	prompt, ok := ModelPrompts[provider]
-	if !ok {
-		prompt = ModelPrompts[classifier.OpenAI] // Fallback to OpenAI prompt
+	// Get the appropriate prompt for the provider and content type
+	prompt := featurePrompt(provider, kind)
+
+	// Keep the prompt within the model's context window
+	text, truncated := classifier.TruncateToTokens(text, featureTextBudget(config, prompt))
+	if truncated {
+		logger.WithField("truncated_length", len(text)).Warn("Document truncated to fit the model context")
 	}
 
 	// Get model's analysis
@@ -245,6 +310,8 @@ func ExtractFeatures(text string, provider classifier.Provider, config classifie
 		return nil, fmt.Errorf("failed to parse model response: %w", err)
 	}
 
+	features.Truncated = truncated
+
 	logger.WithFields(log.Fields{
 		"word_count":     features.WordCount,
 		"sentence_count": features.SentenceCount,
@@ -254,6 +321,65 @@ func ExtractFeatures(text string, provider classifier.Provider, config classifie
 	return &features, nil
 }
 
+// defaultContextTokens is assumed for models missing from the model registry
+const defaultContextTokens = 8192
+
+// defaultResponseTokens is reserved for the response when max_tokens is not configured
+const defaultResponseTokens = 2000
+
+// featureTextBudget returns how many tokens of document text fit alongside the prompt,
+// the classification instructions and the response in the model's context window
+func featureTextBudget(config classifier.ModelConfig, prompt string) int {
+	contextTokens := defaultContextTokens
+	if info, ok := classifier.GetModelInfo(classifier.ModelType(config.Model)); ok && info.MaxTokens > 0 {
+		contextTokens = info.MaxTokens
+	}
+	responseTokens := classifier.IntParameter(config.Parameters, "max_tokens", defaultResponseTokens)
+	// The classifier wraps the text in its own instructions, roughly 200 tokens
+	const instructionTokens = 200
+	return contextTokens - responseTokens - instructionTokens - classifier.EstimateTokens(prompt)
+}
+
+// ExtractHeadings returns the heading outline of a document when its extractor supports
+// it. ok is false for formats without heading support.
+func ExtractHeadings(path string) (headings []extension.Heading, ok bool, err error) {
+	e, err := DefaultRegistry.Get(filepath.Ext(path))
+	if err != nil {
+		return nil, false, nil
+	}
+	he, supported := e.(HeadingExtractor)
+	if !supported {
+		return nil, false, nil
+	}
+	headings, err = he.Headings(path)
+	return headings, true, err
+}
+
+// ExtractTables returns the tables of a document when its extractor supports it. ok is
+// false for formats without table support.
+func ExtractTables(path string) (tables []extension.Table, ok bool, err error) {
+	e, err := DefaultRegistry.Get(filepath.Ext(path))
+	if err != nil {
+		return nil, false, nil
+	}
+	te, supported := e.(TableExtractor)
+	if !supported {
+		return nil, false, nil
+	}
+	tables, err = te.Tables(path)
+	return tables, true, err
+}
+
+// applyHeadings replaces the model's heading counts with the document's actual outline.
+// Sub-headings are indented by two spaces per level.
+func applyHeadings(structure *ContentStructure, headings []extension.Heading) {
+	structure.HeadingCount = len(headings)
+	structure.HeadingHierarchy = make([]string, 0, len(headings))
+	for _, heading := range headings {
+		structure.HeadingHierarchy = append(structure.HeadingHierarchy, strings.Repeat("  ", heading.Level-1)+heading.Text)
+	}
+}
+
 // ExtractFeaturesAndClassify extracts features and classifies the document
 func ExtractFeaturesAndClassify(path string, provider classifier.Provider, config classifier.ModelConfig) (*ExtractResult, *DocumentFeatures, error) {
 	logger := log.WithFields(log.Fields{
@@ -275,5 +401,16 @@ func ExtractFeaturesAndClassify(path string, provider classifier.Provider, confi
 		return result, nil, err
 	}
 
+	if headings, ok, err := ExtractHeadings(path); err != nil {
+		logger.WithError(err).Warn("Failed to read document headings")
+	} else if ok {
+		applyHeadings(&features.ContentStructure, headings)
+	}
+	if tables, ok, err := ExtractTables(path); err != nil {
+		logger.WithError(err).Warn("Failed to read document tables")
+	} else if ok {
+		features.ContentStructure.TableCount = len(tables)
+	}
+
 	return result, features, nil
 } 
\ No newline at end of file
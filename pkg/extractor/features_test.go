@@ -0,0 +1,147 @@
+package extractor
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+func TestFeatureTextBudgetReadsDecodedMaxTokens(t *testing.T) {
+	prompt := "Extract the features."
+	withInt := featureTextBudget(classifier.ModelConfig{
+		Model:      string(classifier.GPT4),
+		Parameters: map[string]interface{}{"max_tokens": 500},
+	}, prompt)
+
+	// Parameters decoded from JSON or YAML hold numbers as float64
+	var parameters map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"max_tokens": 500}`), &parameters); err != nil {
+		t.Fatal(err)
+	}
+	withFloat := featureTextBudget(classifier.ModelConfig{Model: string(classifier.GPT4), Parameters: parameters}, prompt)
+	if withFloat != withInt {
+		t.Errorf("budget with float64 max_tokens = %d, want %d as with int", withFloat, withInt)
+	}
+
+	withDefault := featureTextBudget(classifier.ModelConfig{Model: string(classifier.GPT4)}, prompt)
+	if withDefault == withInt {
+		t.Errorf("budget without max_tokens = %d, want it to differ from max_tokens 500", withDefault)
+	}
+}
+
+func TestExtractFeaturesTruncatesLargeInput(t *testing.T) {
+	var sent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil || len(body.Messages) == 0 {
+			t.Errorf("unexpected request body: %s", data)
+		} else {
+			sent = body.Messages[len(body.Messages)-1].Content
+		}
+		// The feature extractor reads the features from the category field
+		classification, _ := json.Marshal(map[string]interface{}{
+			"category":   `{"word_count": 1}`,
+			"confidence": 0.9,
+			"keywords":   []string{},
+		})
+		reply, _ := json.Marshal(map[string]interface{}{
+			"choices": []map[string]interface{}{{
+				"message":       map[string]string{"content": string(classification)},
+				"finish_reason": "stop",
+			}},
+		})
+		w.Write(reply)
+	}))
+	defer server.Close()
+
+	config := classifier.ModelConfig{
+		Endpoint:   server.URL,
+		APIKey:     "key",
+		Model:      string(classifier.GPT4),
+		Parameters: map[string]interface{}{"max_tokens": 1000.0},
+		MaxRetries: -1,
+	}
+	text := strings.Repeat("lorem ipsum dolor sit amet ", 20000)
+	features, err := ExtractFeatures(text, classifier.OpenAI, config)
+	if err != nil {
+		t.Fatalf("ExtractFeatures: %v", err)
+	}
+	if !features.Truncated {
+		t.Error("features are not marked as truncated")
+	}
+
+	contextTokens := classifier.ModelRegistry[classifier.GPT4].MaxTokens
+	if tokens := classifier.EstimateTokens(sent); tokens > contextTokens-1000 {
+		t.Errorf("sent %d tokens, want at most %d to leave room for the response", tokens, contextTokens-1000)
+	}
+	if len(sent) >= len(text) {
+		t.Errorf("sent %d characters, want fewer than the %d of the document", len(sent), len(text))
+	}
+}
+
+func TestFeaturePromptByContentType(t *testing.T) {
+	legal := contentTypeEmphasis[classifier.LegalDocument]
+
+	prompt := featurePrompt(classifier.Anthropic, classifier.LegalDocument)
+	if !strings.HasPrefix(prompt, "You are Claude") {
+		t.Errorf("legal prompt for Anthropic does not start from the Anthropic prompt:\n%s", prompt)
+	}
+	if i, j := strings.Index(prompt, legal), strings.LastIndex(prompt, "Text to analyze:"); i < 0 || j < i {
+		t.Errorf("legal emphasis missing or not placed before the text:\n%s", prompt)
+	}
+	if got := featurePrompt(classifier.Anthropic, classifier.GeneralText); got != ModelPrompts[classifier.Anthropic] {
+		t.Error("general text prompt differs from the provider's default prompt")
+	}
+	// Providers without their own prompts use the OpenAI prompt for the content type
+	if got := featurePrompt(classifier.Gemini, classifier.LegalDocument); got != FeaturePrompts[FeaturePromptKey{classifier.OpenAI, classifier.LegalDocument}] {
+		t.Errorf("Gemini legal prompt does not fall back to the OpenAI legal prompt:\n%s", got)
+	}
+
+	// Overrides in FeaturePrompts are sent to the model
+	key := FeaturePromptKey{classifier.OpenAI, classifier.CodeSnippet}
+	previous := FeaturePrompts[key]
+	FeaturePrompts[key] = "Describe this code."
+	t.Cleanup(func() { FeaturePrompts[key] = previous })
+
+	config, recorder := serveClassification(t, `{"category":"{\"word_count\": 3}","confidence":0.9,"keywords":[]}`)
+	config.Model = string(classifier.GPT4)
+	if _, err := ExtractFeatures("func main() {}", classifier.OpenAI, config, classifier.CodeSnippet); err != nil {
+		t.Fatalf("ExtractFeatures: %v", err)
+	}
+	if sent := recorder.Prompt(); !strings.Contains(sent, "Describe this code.\n\nfunc main() {}") {
+		t.Errorf("prompt sent = %q, want the code prompt followed by the text", sent)
+	}
+}
+
+func TestApplyHeadings(t *testing.T) {
+	// The model's own estimate is replaced by the document outline
+	structure := ContentStructure{HeadingCount: 7, HeadingHierarchy: []string{"Guessed"}}
+	applyHeadings(&structure, []extension.Heading{
+		{Level: 1, Text: "Results"},
+		{Level: 2, Text: "By region"},
+		{Level: 3, Text: "EMEA"},
+	})
+	if structure.HeadingCount != 3 {
+		t.Errorf("heading count = %d, want 3", structure.HeadingCount)
+	}
+	if got, want := strings.Join(structure.HeadingHierarchy, "|"), "Results|  By region|    EMEA"; got != want {
+		t.Errorf("hierarchy = %q, want %q", got, want)
+	}
+
+	// Formats without heading support leave the model's estimate alone
+	path := writeFile(t, "notes.txt", "Results\nBy region\n")
+	if headings, ok, err := ExtractHeadings(path); ok || err != nil || headings != nil {
+		t.Errorf("ExtractHeadings(.txt) = %v, %v, %v, want no outline support", headings, ok, err)
+	}
+}
@@ -0,0 +1,151 @@
+package extractor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// FormatInfo describes a document format handled by one extractor
+type FormatInfo struct {
+	Name       string   `json:"name"`
+	Extensions []string `json:"extensions"`
+	MIMETypes  []string `json:"mime_types"`
+}
+
+// FormatNamer is implemented by extractors that provide a display name for their format.
+// Other extractors are named after their package.
+type FormatNamer interface {
+	FormatName() string
+}
+
+// MIMETypes maps supported extensions to the MIME types clients may send for them
+var MIMETypes = map[string][]string{
+	".pdf":      {"application/pdf"},
+	".jpg":      {"image/jpeg"},
+	".jpeg":     {"image/jpeg"},
+	".png":      {"image/png"},
+	".gif":      {"image/gif"},
+	".bmp":      {"image/bmp"},
+	".tiff":     {"image/tiff"},
+	".tif":      {"image/tiff"},
+	".webp":     {"image/webp"},
+	".docx":     {"application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+	".pptx":     {"application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+	".xlsx":     {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+	".xlsm":     {"application/vnd.ms-excel.sheet.macroEnabled.12"},
+	".rtf":      {"application/rtf", "text/rtf"},
+	".odt":      {"application/vnd.oasis.opendocument.text"},
+	".html":     {"text/html"},
+	".htm":      {"text/html"},
+	".eml":      {"message/rfc822"},
+	".mhtml":    {"multipart/related", "application/x-mimearchive"},
+	".mht":      {"multipart/related", "application/x-mimearchive"},
+	".warc":     {"application/warc"},
+	".md":       {"text/markdown"},
+	".markdown": {"text/markdown"},
+	".epub":     {"application/epub+zip"},
+	".svg":      {"image/svg+xml"},
+	".pages":    {"application/vnd.apple.pages", "application/x-iwork-pages-sffpages"},
+	".key":      {"application/vnd.apple.keynote", "application/x-iwork-keynote-sffkey"},
+	".numbers":  {"application/vnd.apple.numbers", "application/x-iwork-numbers-sffnumbers"},
+	".go":       {"text/x-go"},
+	".py":       {"text/x-python"},
+	".js":       {"text/javascript"},
+	".ts":       {"application/typescript"},
+	".java":     {"text/x-java-source"},
+	".c":        {"text/x-c"},
+	".cpp":      {"text/x-c++src"},
+	".rs":       {"text/rust"},
+	".sh":       {"application/x-sh"},
+	".sql":      {"application/sql"},
+}
+
+// Formats groups the registered extensions by extractor
+func (r *Registry) Formats() []FormatInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	byExtractor := r.formatsByExtractor()
+	result := make([]FormatInfo, 0, len(byExtractor))
+	for _, info := range byExtractor {
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// formatsByExtractor describes the format handled by each registered extractor.
+// The caller must hold r.mu.
+func (r *Registry) formatsByExtractor() map[TextExtractor]FormatInfo {
+	byExtractor := make(map[TextExtractor]FormatInfo)
+	for ext, e := range r.extractors {
+		info, ok := byExtractor[e]
+		if !ok {
+			info = FormatInfo{Name: formatName(e)}
+		}
+		info.Extensions = append(info.Extensions, ext)
+		byExtractor[e] = info
+	}
+
+	for e, info := range byExtractor {
+		sort.Strings(info.Extensions)
+		seen := make(map[string]bool)
+		info.MIMETypes = []string{}
+		for _, ext := range info.Extensions {
+			for _, mimeType := range MIMETypes[ext] {
+				if !seen[mimeType] {
+					seen[mimeType] = true
+					info.MIMETypes = append(info.MIMETypes, mimeType)
+				}
+			}
+		}
+		byExtractor[e] = info
+	}
+	return byExtractor
+}
+
+// formatName returns the extractor's display name, or its package name such as "pdf"
+func formatName(e TextExtractor) string {
+	if namer, ok := e.(FormatNamer); ok {
+		return namer.FormatName()
+	}
+	name := strings.TrimPrefix(fmt.Sprintf("%T", e), "*")
+	pkg, _, _ := strings.Cut(name, ".")
+	return pkg
+}
+
+// GetSupportedFormatsDetailed returns the supported formats with their extensions and MIME types
+func GetSupportedFormatsDetailed() []FormatInfo {
+	formats := DefaultRegistry.Formats()
+	log.WithField("formats_count", len(formats)).Debug("Retrieved detailed supported formats")
+	return formats
+}
+
+// UnavailableFormat is a format whose extractor is missing an external dependency
+type UnavailableFormat struct {
+	FormatInfo
+	Error string `json:"error"`
+}
+
+// CheckDependencies runs the dependency check of every registered extractor and
+// returns the formats that cannot currently be extracted, sorted by name
+func (r *Registry) CheckDependencies() []UnavailableFormat {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var unavailable []UnavailableFormat
+	for e, info := range r.formatsByExtractor() {
+		checker, ok := e.(DependencyChecker)
+		if !ok {
+			continue
+		}
+		if err := checker.CheckDependencies(); err != nil {
+			unavailable = append(unavailable, UnavailableFormat{FormatInfo: info, Error: err.Error()})
+		}
+	}
+	sort.Slice(unavailable, func(i, j int) bool { return unavailable[i].Name < unavailable[j].Name })
+	return unavailable
+}
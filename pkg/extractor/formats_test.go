@@ -0,0 +1,70 @@
+package extractor
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// titledExtractor names its format through FormatNamer
+type titledExtractor struct{ namedExtractor }
+
+func (e *titledExtractor) FormatName() string { return "Web archive" }
+
+func TestRegistryFormats(t *testing.T) {
+	r := NewRegistry()
+	for _, e := range []TextExtractor{
+		&namedExtractor{extensions: []string{".htm", ".html"}},
+		&titledExtractor{namedExtractor{extensions: []string{".mht", ".mhtml", ".warc"}}},
+	} {
+		if err := r.Register(e); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+
+	want := []FormatInfo{
+		{Name: "Web archive", Extensions: []string{".mht", ".mhtml", ".warc"}, MIMETypes: []string{"multipart/related", "application/x-mimearchive", "application/warc"}},
+		{Name: "extractor", Extensions: []string{".htm", ".html"}, MIMETypes: []string{"text/html"}},
+	}
+	if got := r.Formats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Formats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSupportedFormatsHaveMIMETypes(t *testing.T) {
+	for _, format := range GetSupportedFormatsDetailed() {
+		if len(format.MIMETypes) == 0 {
+			t.Errorf("format %s (%v) lists no MIME types", format.Name, format.Extensions)
+		}
+	}
+}
+
+// missingDependencyExtractor reports err from its dependency check
+type missingDependencyExtractor struct {
+	namedExtractor
+	err error
+}
+
+func (e *missingDependencyExtractor) CheckDependencies() error { return e.err }
+
+func TestCheckDependencies(t *testing.T) {
+	r := NewRegistry()
+	for _, e := range []TextExtractor{
+		&namedExtractor{extensions: []string{".txt"}},
+		&titledExtractor{namedExtractor{extensions: []string{".warc"}}},
+		&missingDependencyExtractor{namedExtractor{extensions: []string{".png"}}, errors.New("tesseract not found")},
+		&missingDependencyExtractor{namedExtractor{extensions: []string{".jpg"}}, nil},
+	} {
+		if err := r.Register(e); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+
+	unavailable := r.CheckDependencies()
+	if len(unavailable) != 1 {
+		t.Fatalf("CheckDependencies() = %+v, want only the failing extractor", unavailable)
+	}
+	if got := unavailable[0]; !reflect.DeepEqual(got.Extensions, []string{".png"}) || got.Error != "tesseract not found" {
+		t.Errorf("unavailable format = %+v, want .png with the dependency error", got)
+	}
+}
@@ -0,0 +1,103 @@
+package extractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
+)
+
+// truncatedZip returns the first half of a zip archive holding the given files, which
+// cuts off the central directory every zip reader starts from
+func truncatedZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()[:buf.Len()/2]
+}
+
+func TestExtractTextRejectsMalformedFiles(t *testing.T) {
+	wordXML := map[string]string{
+		"[Content_Types].xml": `<?xml version="1.0"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`,
+		"word/document.xml":   `<w:document><w:body><w:p><w:r><w:t>Hello</w:t></w:r></w:p></w:body></w:document>`,
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "truncated.pdf", data: []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R")},
+		{name: "garbage.pdf", data: []byte("\x00\x01\x02 not a pdf")},
+		{name: "truncated.docx", data: truncatedZip(t, wordXML)},
+		{name: "truncated.pptx", data: truncatedZip(t, map[string]string{"ppt/slides/slide1.xml": "<p:sld/>"})},
+		{name: "truncated.xlsx", data: truncatedZip(t, map[string]string{"xl/workbook.xml": "<workbook/>"})},
+		{name: "truncated.epub", data: truncatedZip(t, map[string]string{"mimetype": "application/epub+zip"})},
+		{name: "truncated.odt", data: truncatedZip(t, map[string]string{"content.xml": "<office:document-content/>"})},
+		{name: "truncated.pages", data: truncatedZip(t, map[string]string{"Index/Document.iwa": "\x00\x01"})},
+		{name: "unclosed.svg", data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><text>unclosed`)},
+		{name: "garbage.eml", data: []byte("\x00\x01garbage header\xff\n\nbody")},
+		{name: "garbage.mhtml", data: []byte("\x00\x01not mime\xff")},
+		{name: "garbage.warc", data: []byte("\x00\x01not a warc record\xff")},
+		{name: "truncated.png", data: []byte("\x89PNG\r\n\x1a\n\x00\x00")},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			text, err := ExtractText(path)
+			if err == nil {
+				t.Errorf("ExtractText returned %q without an error", text)
+			}
+		})
+	}
+}
+
+func TestExtractTextToleratesBinaryInTextFormats(t *testing.T) {
+	// Markup and source code extractors read any bytes as text rather than failing
+	dir := t.TempDir()
+	for _, name := range []string{"binary.html", "binary.md", "binary.rtf", "binary.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("{\\rtf1 <p>\x00\x01\xff"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ExtractText(path); err != nil {
+			t.Errorf("ExtractText(%s): %v", name, err)
+		}
+	}
+}
+
+// panickingExtractor stands in for a parsing library that panics on a malformed file
+type panickingExtractor struct{}
+
+func (panickingExtractor) Extract(path string) (string, error) {
+	var table []string
+	return table[1], nil
+}
+
+func (panickingExtractor) SupportedExtensions() []string { return []string{".crash"} }
+
+func TestSafeExtractRecoversFromPanics(t *testing.T) {
+	text, err := safeExtract(panickingExtractor{}, "broken.crash", extension.Options{})
+	if !errors.Is(err, ErrMalformedDocument) {
+		t.Fatalf("error = %v, want ErrMalformedDocument", err)
+	}
+	if text != "" {
+		t.Errorf("text = %q, want none", text)
+	}
+}
@@ -0,0 +1,132 @@
+package extractor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ExtractionDurationBuckets are the upper bounds, in seconds, of the extraction duration
+// histogram
+var ExtractionDurationBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// FormatMetrics aggregates the extractor calls for one file extension
+type FormatMetrics struct {
+	// Count is the number of extractor calls, including failed ones
+	Count uint64
+	// Errors is the number of calls that returned an error
+	Errors uint64
+	// DurationSeconds is the total time spent in the extractor
+	DurationSeconds float64
+	// BucketCounts holds the cumulative number of calls that took at most the matching
+	// ExtractionDurationBuckets bound
+	BucketCounts []uint64
+	// InputBytes is the total size of the files read
+	InputBytes int64
+	// OutputBytes is the total size of the text extracted
+	OutputBytes int64
+}
+
+// ExtractionMetrics records the duration and input and output size of extractor calls
+// per file extension, for capacity planning
+type ExtractionMetrics struct {
+	mu      sync.Mutex
+	formats map[string]*FormatMetrics
+}
+
+// NewExtractionMetrics creates an empty set of extraction metrics
+func NewExtractionMetrics() *ExtractionMetrics {
+	return &ExtractionMetrics{formats: make(map[string]*FormatMetrics)}
+}
+
+// DefaultMetrics is updated by ExtractText for every extractor call; nil disables it.
+// Cache hits do not call an extractor and are not recorded.
+var DefaultMetrics = NewExtractionMetrics()
+
+// Observe records one extractor call for ext
+func (m *ExtractionMetrics) Observe(ext string, duration time.Duration, inputBytes, outputBytes int64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	format, ok := m.formats[ext]
+	if !ok {
+		format = &FormatMetrics{BucketCounts: make([]uint64, len(ExtractionDurationBuckets))}
+		m.formats[ext] = format
+	}
+	seconds := duration.Seconds()
+	format.Count++
+	if err != nil {
+		format.Errors++
+	}
+	format.DurationSeconds += seconds
+	for i, bound := range ExtractionDurationBuckets {
+		if seconds <= bound {
+			format.BucketCounts[i]++
+		}
+	}
+	format.InputBytes += inputBytes
+	format.OutputBytes += outputBytes
+}
+
+// Snapshot returns a copy of the metrics keyed by extension
+func (m *ExtractionMetrics) Snapshot() map[string]FormatMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]FormatMetrics, len(m.formats))
+	for ext, format := range m.formats {
+		copied := *format
+		copied.BucketCounts = append([]uint64(nil), format.BucketCounts...)
+		snapshot[ext] = copied
+	}
+	return snapshot
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format, with an
+// extension label on every series
+func (m *ExtractionMetrics) WritePrometheus(w io.Writer) error {
+	snapshot := m.Snapshot()
+	exts := make([]string, 0, len(snapshot))
+	for ext := range snapshot {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# HELP superclass_extraction_duration_seconds Time spent extracting text, by file extension.")
+	fmt.Fprintln(bw, "# TYPE superclass_extraction_duration_seconds histogram")
+	for _, ext := range exts {
+		format := snapshot[ext]
+		for i, bound := range ExtractionDurationBuckets {
+			fmt.Fprintf(bw, "superclass_extraction_duration_seconds_bucket{extension=%q,le=%q} %d\n",
+				ext, strconv.FormatFloat(bound, 'g', -1, 64), format.BucketCounts[i])
+		}
+		fmt.Fprintf(bw, "superclass_extraction_duration_seconds_bucket{extension=%q,le=\"+Inf\"} %d\n", ext, format.Count)
+		fmt.Fprintf(bw, "superclass_extraction_duration_seconds_sum{extension=%q} %g\n", ext, format.DurationSeconds)
+		fmt.Fprintf(bw, "superclass_extraction_duration_seconds_count{extension=%q} %d\n", ext, format.Count)
+	}
+
+	counters := []struct {
+		name, help string
+		value      func(FormatMetrics) uint64
+	}{
+		{"superclass_extraction_errors_total", "Extractor calls that failed, by file extension.",
+			func(f FormatMetrics) uint64 { return f.Errors }},
+		{"superclass_extraction_input_bytes_total", "Bytes of files read by extractors, by file extension.",
+			func(f FormatMetrics) uint64 { return uint64(f.InputBytes) }},
+		{"superclass_extraction_output_bytes_total", "Bytes of text produced by extractors, by file extension.",
+			func(f FormatMetrics) uint64 { return uint64(f.OutputBytes) }},
+	}
+	for _, counter := range counters {
+		fmt.Fprintf(bw, "# HELP %s %s\n", counter.name, counter.help)
+		fmt.Fprintf(bw, "# TYPE %s counter\n", counter.name)
+		for _, ext := range exts {
+			fmt.Fprintf(bw, "%s{extension=%q} %d\n", counter.name, ext, counter.value(snapshot[ext]))
+		}
+	}
+	return bw.Flush()
+}
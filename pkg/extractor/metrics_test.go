@@ -0,0 +1,122 @@
+package extractor
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useMetrics records the extractions of the rest of the test in a fresh set of metrics
+func useMetrics(t *testing.T) *ExtractionMetrics {
+	t.Helper()
+	previous := DefaultMetrics
+	DefaultMetrics = NewExtractionMetrics()
+	t.Cleanup(func() { DefaultMetrics = previous })
+	return DefaultMetrics
+}
+
+func TestExtractTextRecordsMetricsPerExtension(t *testing.T) {
+	metrics := useMetrics(t)
+
+	dir := t.TempDir()
+	markdown := []byte("# Title\n\nSome *markdown* text.\n")
+	html := []byte("<html><body><p>Some HTML text.</p></body></html>")
+	files := map[string][]byte{"notes.md": markdown, "page.html": html}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ExtractText(path); err != nil {
+			t.Fatalf("ExtractText(%s): %v", name, err)
+		}
+	}
+	// A second markdown file adds to the same extension
+	second := filepath.Join(dir, "more.md")
+	if err := os.WriteFile(second, markdown, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	text, err := ExtractText(second)
+	if err != nil {
+		t.Fatalf("ExtractText(more.md): %v", err)
+	}
+
+	snapshot := metrics.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("metrics recorded for %d extensions, want .md and .html: %v", len(snapshot), snapshot)
+	}
+	md := snapshot[".md"]
+	if md.Count != 2 || md.Errors != 0 {
+		t.Errorf(".md count %d with %d errors, want 2 with none", md.Count, md.Errors)
+	}
+	if md.InputBytes != int64(2*len(markdown)) {
+		t.Errorf(".md input bytes = %d, want %d", md.InputBytes, 2*len(markdown))
+	}
+	if md.OutputBytes != int64(2*len(text)) {
+		t.Errorf(".md output bytes = %d, want %d", md.OutputBytes, 2*len(text))
+	}
+	if md.DurationSeconds <= 0 {
+		t.Errorf(".md duration = %v, want it recorded", md.DurationSeconds)
+	}
+	if last := md.BucketCounts[len(md.BucketCounts)-1]; last != 2 {
+		t.Errorf(".md calls in the last bucket = %d, want 2", last)
+	}
+	if got := snapshot[".html"]; got.Count != 1 || got.InputBytes != int64(len(html)) || got.OutputBytes == 0 {
+		t.Errorf(".html metrics = %+v, want one call reading %d bytes", got, len(html))
+	}
+}
+
+func TestExtractTextRecordsPlainTextMetrics(t *testing.T) {
+	metrics := useMetrics(t)
+
+	dir := t.TempDir()
+	notes := []byte("Plain text notes.\n")
+	path := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(path, notes, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExtractText(path); err != nil {
+		t.Fatalf("ExtractText: %v", err)
+	}
+	if _, err := ExtractText(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Fatal("ExtractText of a missing file succeeded")
+	}
+
+	got := metrics.Snapshot()[".txt"]
+	if got.Count != 2 || got.Errors != 1 {
+		t.Errorf(".txt count %d with %d errors, want 2 with one", got.Count, got.Errors)
+	}
+	if got.InputBytes != int64(len(notes)) || got.OutputBytes != int64(len(notes)) {
+		t.Errorf(".txt input and output bytes = %d and %d, want %d each", got.InputBytes, got.OutputBytes, len(notes))
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	metrics := NewExtractionMetrics()
+	metrics.Observe(".pdf", 300*time.Millisecond, 2048, 512, nil)
+	metrics.Observe(".pdf", 2*time.Second, 1024, 0, errors.New("corrupt"))
+
+	var out bytes.Buffer
+	if err := metrics.WritePrometheus(&out); err != nil {
+		t.Fatalf("WritePrometheus: %v", err)
+	}
+	for _, line := range []string{
+		`superclass_extraction_duration_seconds_bucket{extension=".pdf",le="0.25"} 0`,
+		`superclass_extraction_duration_seconds_bucket{extension=".pdf",le="0.5"} 1`,
+		`superclass_extraction_duration_seconds_bucket{extension=".pdf",le="2.5"} 2`,
+		`superclass_extraction_duration_seconds_bucket{extension=".pdf",le="+Inf"} 2`,
+		`superclass_extraction_duration_seconds_sum{extension=".pdf"} 2.3`,
+		`superclass_extraction_duration_seconds_count{extension=".pdf"} 2`,
+		`superclass_extraction_errors_total{extension=".pdf"} 1`,
+		`superclass_extraction_input_bytes_total{extension=".pdf"} 3072`,
+		`superclass_extraction_output_bytes_total{extension=".pdf"} 512`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("output is missing %q:\n%s", line, out.String())
+		}
+	}
+}
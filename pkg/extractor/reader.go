@@ -0,0 +1,54 @@
+package extractor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+)
+
+// spoolReader copies r into a temporary file with the given extension so the
+// path-based extractors can read it. The caller removes the returned file.
+func spoolReader(r io.Reader, ext string) (string, error) {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	tmp, err := os.CreateTemp("", "superclass-*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// ExtractTextFromReader extracts text from r, using ext (e.g. ".pdf") to pick the extractor
+func ExtractTextFromReader(r io.Reader, ext string) (string, error) {
+	path, err := spoolReader(r, ext)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(path)
+	return ExtractText(path)
+}
+
+// ExtractAndClassifyReader extracts text from r, using ext to pick the extractor, and classifies it
+func ExtractAndClassifyReader(r io.Reader, ext string, provider classifier.Provider, config classifier.ModelConfig, options classifier.ClassificationOptions) (*ExtractResult, error) {
+	path, err := spoolReader(r, ext)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path)
+	return ExtractAndClassifyWithOptions(path, provider, config, options)
+}
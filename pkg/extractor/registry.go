@@ -1,9 +1,12 @@
 package extractor
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
+
+	"github.com/adaptive-scale/superclass/pkg/extension"
 )
 
 // TextExtractor defines the interface that all extractors must implement
@@ -14,10 +17,44 @@ type TextExtractor interface {
 	SupportedExtensions() []string
 }
 
+// OptionsExtractor is implemented by extractors that accept per-call options such as
+// progress reporting
+type OptionsExtractor interface {
+	TextExtractor
+	// ExtractWithOptions extracts text from a file at the given path using opts
+	ExtractWithOptions(path string, opts extension.Options) (string, error)
+}
+
+// HeadingExtractor is implemented by extractors that can read a document's heading outline
+type HeadingExtractor interface {
+	// Headings returns the document headings in order
+	Headings(path string) ([]extension.Heading, error)
+}
+
+// TableExtractor is implemented by extractors that can read a document's tables
+type TableExtractor interface {
+	// Tables returns the document tables in order
+	Tables(path string) ([]extension.Table, error)
+}
+
+// FrontMatterExtractor is implemented by extractors that can read document front-matter
+type FrontMatterExtractor interface {
+	// FrontMatter returns the document's front-matter fields, or nil if it has none
+	FrontMatter(path string) (map[string]interface{}, error)
+}
+
+// DependencyChecker is implemented by extractors that rely on external libraries or
+// data, such as Tesseract for OCR
+type DependencyChecker interface {
+	// CheckDependencies returns an error describing a missing dependency
+	CheckDependencies() error
+}
+
 // Registry manages the registered text extractors
 type Registry struct {
 	mu         sync.RWMutex
 	extractors map[string]TextExtractor // map of extension to extractor
+	problems   []error                  // registration conflicts and gaps reported by Validate
 }
 
 // NewRegistry creates a new Registry instance
@@ -27,22 +64,53 @@ func NewRegistry() *Registry {
 	}
 }
 
-// Register adds a new TextExtractor to the registry
+// Register adds a new TextExtractor to the registry. Extensions already claimed
+// by another extractor are skipped and reported in the returned error, which
+// names both extractors; the remaining extensions are still registered.
 func (r *Registry) Register(extractor TextExtractor) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	for _, ext := range extractor.SupportedExtensions() {
+	extensions := extractor.SupportedExtensions()
+	if len(extensions) == 0 {
+		err := fmt.Errorf("extractor %T declares no supported extensions", extractor)
+		r.problems = append(r.problems, err)
+		return err
+	}
+
+	var errs []error
+	for _, ext := range extensions {
 		ext = strings.ToLower(ext)
 		if !strings.HasPrefix(ext, ".") {
 			ext = "." + ext
 		}
-		if _, exists := r.extractors[ext]; exists {
-			return fmt.Errorf("extractor for extension %s is already registered", ext)
+		if existing, exists := r.extractors[ext]; exists {
+			if existing == extractor {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("extractor for extension %s is already registered: %T conflicts with %T", ext, extractor, existing))
+			continue
 		}
 		r.extractors[ext] = extractor
 	}
-	return nil
+
+	err := errors.Join(errs...)
+	if err != nil {
+		r.problems = append(r.problems, errs...)
+	}
+	return err
+}
+
+// Validate reports every conflict and gap seen while registering extractors,
+// or nil when the registry is consistent
+func (r *Registry) Validate() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.extractors) == 0 {
+		return errors.New("no extractors registered")
+	}
+	return errors.Join(r.problems...)
 }
 
 // Get returns the registered TextExtractor for the given file extension
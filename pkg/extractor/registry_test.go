@@ -0,0 +1,63 @@
+package extractor
+
+import (
+	"strings"
+	"testing"
+)
+
+// namedExtractor claims the given extensions
+type namedExtractor struct {
+	extensions []string
+}
+
+func (e *namedExtractor) Extract(path string) (string, error) { return "", nil }
+
+func (e *namedExtractor) SupportedExtensions() []string { return e.extensions }
+
+// otherExtractor has a different type from namedExtractor, so conflicts name both
+type otherExtractor struct{ namedExtractor }
+
+func TestRegistryReportsConflicts(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Validate(); err == nil {
+		t.Error("Validate of an empty registry succeeded")
+	}
+
+	first := &namedExtractor{extensions: []string{"ABC", ".def"}}
+	if err := r.Register(first); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	// Registering the same extractor again is not a conflict
+	if err := r.Register(first); err != nil {
+		t.Errorf("re-registering the same extractor: %v", err)
+	}
+	if err := r.Validate(); err != nil {
+		t.Errorf("Validate of a consistent registry: %v", err)
+	}
+
+	err := r.Register(&otherExtractor{namedExtractor{extensions: []string{".abc", ".ghi"}}})
+	if err == nil || !strings.Contains(err.Error(), ".abc") ||
+		!strings.Contains(err.Error(), "*extractor.otherExtractor") || !strings.Contains(err.Error(), "*extractor.namedExtractor") {
+		t.Fatalf("conflicting Register = %v, want an error naming .abc and both extractors", err)
+	}
+	if e, _ := r.Get(".ABC"); e != first {
+		t.Errorf(".abc is handled by %T, want the first extractor", e)
+	}
+	if _, err := r.Get("ghi"); err != nil {
+		t.Errorf("the conflicting extractor's other extension was not registered: %v", err)
+	}
+
+	if err := r.Register(&namedExtractor{}); err == nil {
+		t.Error("registering an extractor without extensions succeeded")
+	}
+	problems := r.Validate()
+	if problems == nil || !strings.Contains(problems.Error(), ".abc") || !strings.Contains(problems.Error(), "no supported extensions") {
+		t.Errorf("Validate = %v, want the conflict and the empty extractor", problems)
+	}
+}
+
+func TestDefaultRegistryIsConsistent(t *testing.T) {
+	if err := DefaultRegistry.Validate(); err != nil {
+		t.Errorf("built-in extractors conflict: %v", err)
+	}
+}
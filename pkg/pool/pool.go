@@ -0,0 +1,44 @@
+package pool
+
+import "sync"
+
+// Pool runs submitted tasks with at most a fixed number executing concurrently
+type Pool struct {
+	sem  chan struct{}
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error
+}
+
+// New creates a pool running at most size tasks at once. A size below 1 is treated as 1.
+func New(size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{sem: make(chan struct{}, size)}
+}
+
+// Submit schedules fn, blocking while the pool is at capacity
+func (p *Pool) Submit(fn func() error) {
+	p.sem <- struct{}{}
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		if err := fn(); err != nil {
+			p.mu.Lock()
+			p.errs = append(p.errs, err)
+			p.mu.Unlock()
+		}
+	}()
+}
+
+// Wait blocks until every submitted task has finished and returns the errors they reported
+func (p *Pool) Wait() []error {
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.errs
+}
@@ -0,0 +1,89 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPoolNeverExceedsSize(t *testing.T) {
+	const size = 3
+	p := New(size)
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	for i := 0; i < 20; i++ {
+		p.Submit(func() error {
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		})
+	}
+	if errs := p.Wait(); len(errs) != 0 {
+		t.Fatalf("Wait returned %v, want no errors", errs)
+	}
+	if peak != size {
+		t.Errorf("at most %d tasks ran at once, want %d", peak, size)
+	}
+}
+
+func TestPoolCollectsAllErrors(t *testing.T) {
+	p := New(2)
+	want := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		if i%3 == 0 {
+			err := fmt.Errorf("task %d failed", i)
+			want[err.Error()] = true
+			p.Submit(func() error { return err })
+			continue
+		}
+		p.Submit(func() error { return nil })
+	}
+
+	errs := p.Wait()
+	if len(errs) != len(want) {
+		t.Fatalf("Wait returned %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for _, err := range errs {
+		if !want[err.Error()] {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+}
+
+func TestNewTreatsSizeBelowOneAsOne(t *testing.T) {
+	p := New(0)
+	var mu sync.Mutex
+	running := 0
+	for i := 0; i < 5; i++ {
+		p.Submit(func() error {
+			mu.Lock()
+			running++
+			concurrent := running
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				running--
+				mu.Unlock()
+			}()
+			if concurrent > 1 {
+				return errors.New("tasks ran concurrently")
+			}
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+	}
+	if errs := p.Wait(); len(errs) != 0 {
+		t.Errorf("Wait returned %v", errs)
+	}
+}
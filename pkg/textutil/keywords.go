@@ -0,0 +1,122 @@
+package textutil
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// stopwords are common English words that never start, end or make up a keyword
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`a about above after again against all also am an and any are as at be
+		because been before being below between both but by can could did do does doing down during each
+		few for from further had has have having he her here hers herself him himself his how i if in into
+		is it its itself just let me more most my myself no nor not now of off on once only or other our
+		ours ourselves out over own same she should so some such than that the their theirs them themselves
+		then there these they this those through to too under until up upon us very was we were what when
+		where which while who whom why will with within without would you your yours yourself yourselves
+		may might must shall one two new use used using via per etc`) {
+		stopwords[w] = true
+	}
+}
+
+// ExtractKeywordsLocal returns up to n keywords from text using RAKE (Rapid Automatic
+// Keyword Extraction): candidate phrases are the word runs between stopwords and
+// punctuation, scored by the sum of each word's degree-to-frequency ratio. The result
+// is deterministic and needs no model call.
+func ExtractKeywordsLocal(text string, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+
+	phrases := candidatePhrases(text)
+
+	frequency := make(map[string]int)
+	degree := make(map[string]int)
+	for _, phrase := range phrases {
+		for _, word := range phrase {
+			frequency[word]++
+			degree[word] += len(phrase)
+		}
+	}
+
+	type scored struct {
+		phrase string
+		score  float64
+	}
+	seen := make(map[string]bool)
+	var candidates []scored
+	for _, phrase := range phrases {
+		key := strings.Join(phrase, " ")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		var score float64
+		for _, word := range phrase {
+			score += float64(degree[word]) / float64(frequency[word])
+		}
+		candidates = append(candidates, scored{key, score})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].phrase < candidates[j].phrase
+	})
+
+	keywords := make([]string, 0, n)
+	for _, c := range candidates {
+		if len(keywords) == n {
+			break
+		}
+		keywords = append(keywords, c.phrase)
+	}
+	return keywords
+}
+
+// candidatePhrases splits text into lowercase word runs delimited by stopwords,
+// punctuation and numbers. Phrases longer than three words are dropped as noise.
+func candidatePhrases(text string) [][]string {
+	var phrases [][]string
+	var current []string
+	flush := func() {
+		if len(current) > 0 && len(current) <= 3 {
+			phrases = append(phrases, current)
+		}
+		current = nil
+	}
+
+	var word strings.Builder
+	endWord := func(boundary bool) {
+		if word.Len() > 0 {
+			w := strings.ToLower(word.String())
+			word.Reset()
+			if stopwords[w] || len([]rune(w)) < 3 {
+				flush()
+			} else {
+				current = append(current, w)
+			}
+		}
+		if boundary {
+			flush()
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || r == '\'' || r == '-':
+			word.WriteRune(r)
+		case unicode.IsSpace(r):
+			endWord(false)
+		default:
+			endWord(true)
+		}
+	}
+	endWord(true)
+	return phrases
+}
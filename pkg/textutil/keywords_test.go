@@ -0,0 +1,37 @@
+package textutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractKeywordsLocal(t *testing.T) {
+	text := "Invoice for cloud storage fees, support fees and a cloud storage discount. " +
+		"The support fees are billed monthly."
+
+	got := ExtractKeywordsLocal(text, 3)
+	want := "cloud storage discount|cloud storage fees|support fees"
+	if strings.Join(got, "|") != want {
+		t.Errorf("keywords = %q, want %q", got, strings.Split(want, "|"))
+	}
+	for i := 0; i < 5; i++ {
+		if again := ExtractKeywordsLocal(text, 3); strings.Join(again, "|") != want {
+			t.Fatalf("keywords changed between calls: %q", again)
+		}
+	}
+
+	all := ExtractKeywordsLocal(text, 20)
+	seen := make(map[string]bool)
+	for _, keyword := range all {
+		if seen[keyword] {
+			t.Errorf("keyword %q returned twice in %q", keyword, all)
+		}
+		seen[keyword] = true
+		if stopwords[strings.Fields(keyword)[0]] {
+			t.Errorf("keyword %q starts with a stopword", keyword)
+		}
+	}
+	if ExtractKeywordsLocal(text, 0) != nil {
+		t.Error("n = 0 returned keywords")
+	}
+}
@@ -0,0 +1,82 @@
+package textutil
+
+import (
+	"strings"
+	"unicode"
+)
+
+// languageStopwords are frequent function words of the languages DetectLanguage knows,
+// keyed by ISO 639-1 code. Words shared by several languages are left out.
+var languageStopwords = map[string]map[string]bool{}
+
+// LanguageNames maps the ISO 639-1 codes returned by DetectLanguage to English names
+var LanguageNames = map[string]string{
+	"en": "English",
+	"fr": "French",
+	"de": "German",
+	"es": "Spanish",
+	"it": "Italian",
+	"pt": "Portuguese",
+	"nl": "Dutch",
+}
+
+func init() {
+	for code, words := range map[string]string{
+		"en": `the and of to is that for with was this are be have from which by not were they been their has would`,
+		"fr": `le la les des du et est une pour dans qui que sur pas au avec sont ce cette nous vous ont été aux`,
+		"de": `der die das und ist nicht ein eine mit den dem sich auf für von zu auch ich wir sie sind wird`,
+		"es": `el los las del y es por con para una que se su al lo como más pero sus fue han está son`,
+		"it": `il lo gli della delle dei e è per con una che non sono nel alla anche come più questo hanno`,
+		"pt": `o os das do dos e é para com uma que não em no na ao pelo pela são foi mais como seu`,
+		"nl": `de het een en van is dat op te voor met zijn niet aan ook als bij er maar om wordt door`,
+	} {
+		set := make(map[string]bool)
+		for _, w := range strings.Fields(words) {
+			set[w] = true
+		}
+		languageStopwords[code] = set
+	}
+}
+
+// minLanguageEvidence is the number of stopword hits needed before DetectLanguage
+// names a language; shorter texts are too ambiguous
+const minLanguageEvidence = 5
+
+// DetectLanguage guesses the language of text from the frequency of common function
+// words and returns its ISO 639-1 code, or "" when the text is too short or no language
+// clearly dominates. Only the languages in LanguageNames are recognized.
+func DetectLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) > 2000 {
+		words = words[:2000]
+	}
+
+	scores := make(map[string]int)
+	for _, word := range words {
+		for code, set := range languageStopwords {
+			if set[word] {
+				scores[code]++
+			}
+		}
+	}
+
+	best := ""
+	for code, score := range scores {
+		if best == "" || score > scores[best] || (score == scores[best] && code < best) {
+			best = code
+		}
+	}
+	second := 0
+	for code, score := range scores {
+		if code != best {
+			second = max(second, score)
+		}
+	}
+	// Require a clear lead so mixed or ambiguous texts are not mislabeled
+	if best == "" || scores[best] < minLanguageEvidence || float64(scores[best]) < 1.5*float64(second) {
+		return ""
+	}
+	return best
+}
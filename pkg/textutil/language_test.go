@@ -0,0 +1,41 @@
+package textutil
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "English",
+			text: "The invoice for the consulting work is attached, and the payment is due by the end of this month.",
+			want: "en",
+		},
+		{
+			name: "French",
+			text: "La facture pour les travaux est jointe. Le paiement est dû à la fin du mois, avec les frais de livraison.",
+			want: "fr",
+		},
+		{
+			name: "German",
+			text: "Die Rechnung für die Beratung ist beigefügt. Der Betrag ist bis Ende des Monats zu zahlen, und wir sind für Fragen da.",
+			want: "de",
+		},
+		{
+			name: "Spanish",
+			text: "El contrato de servicios se firmó con la empresa y los pagos se harán por transferencia para el proveedor del sistema.",
+			want: "es",
+		},
+		{name: "too short", text: "Le contrat est signé.", want: ""},
+		{name: "no function words", text: "Invoice 42 Total 120 EUR", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLanguage(tt.text); got != tt.want {
+				t.Errorf("DetectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
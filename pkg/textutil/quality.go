@@ -0,0 +1,28 @@
+package textutil
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// PrintableRatio returns the fraction of runes in text that are printable or whitespace.
+// Invalid UTF-8 bytes count as non-printable. Empty text has a ratio of 1.
+func PrintableRatio(text string) float64 {
+	if text == "" {
+		return 1
+	}
+
+	var total, printable int
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		total++
+		if r == utf8.RuneError && size == 1 {
+			continue
+		}
+		if unicode.IsPrint(r) || unicode.IsSpace(r) {
+			printable++
+		}
+	}
+	return float64(printable) / float64(total)
+}
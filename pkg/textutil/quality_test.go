@@ -0,0 +1,24 @@
+package textutil
+
+import "testing"
+
+func TestPrintableRatio(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want float64
+	}{
+		{name: "empty", text: "", want: 1},
+		{name: "prose with whitespace", text: "Total due:\t$120\n", want: 1},
+		{name: "accented letters", text: "Café société", want: 1},
+		{name: "control characters", text: "ab\x00\x01", want: 0.5},
+		{name: "invalid UTF-8", text: "ok\xff\xfe", want: 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PrintableRatio(tt.text); got != tt.want {
+				t.Errorf("PrintableRatio(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
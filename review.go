@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReviewItem is a classification whose confidence fell below the review threshold
+type ReviewItem struct {
+	ID             string                     `json:"id"`
+	HistoryID      string                     `json:"history_id,omitempty"`
+	Filename       string                     `json:"filename"`
+	Provider       classifier.Provider        `json:"provider"`
+	Model          string                     `json:"model"`
+	Classification *classifier.Classification `json:"classification"`
+	Threshold      float64                    `json:"threshold"`
+	CreatedAt      time.Time                  `json:"created_at"`
+}
+
+// ReviewStore queues classifications for human review
+type ReviewStore interface {
+	// Add queues the item, assigning an ID when it has none
+	Add(item *ReviewItem) error
+	// List returns the queued items, oldest first
+	List() ([]*ReviewItem, error)
+}
+
+// MemoryReviewStore keeps the most recent review items in memory
+type MemoryReviewStore struct {
+	mu       sync.RWMutex
+	maxItems int
+	items    []*ReviewItem
+}
+
+// NewMemoryReviewStore creates an in-memory queue holding at most maxItems items
+func NewMemoryReviewStore(maxItems int) *MemoryReviewStore {
+	return &MemoryReviewStore{maxItems: maxItems}
+}
+
+// Add queues the item, dropping the oldest one when the queue is full
+func (m *MemoryReviewStore) Add(item *ReviewItem) error {
+	if item.ID == "" {
+		id, err := newRecordID()
+		if err != nil {
+			return err
+		}
+		item.ID = id
+	}
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = time.Now()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.maxItems > 0 && len(m.items) >= m.maxItems {
+		m.items = m.items[1:]
+	}
+	m.items = append(m.items, item)
+	return nil
+}
+
+// List returns the queued items, oldest first
+func (m *MemoryReviewStore) List() ([]*ReviewItem, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]*ReviewItem(nil), m.items...), nil
+}
+
+// flagForReview queues item when review is enabled and its confidence is below the
+// threshold, and reports whether it needs review. Results flagged but not stored are
+// still reported as needing review.
+func (s *Server) flagForReview(item *ReviewItem) bool {
+	if s.review == nil || item.Classification == nil || item.Classification.Confidence >= s.reviewThreshold {
+		return false
+	}
+	item.Provider = s.provider
+	item.Model = s.config.Model
+	item.Threshold = s.reviewThreshold
+	logger := log.WithFields(log.Fields{
+		"function":   "flagForReview",
+		"filename":   item.Filename,
+		"confidence": item.Classification.Confidence,
+		"threshold":  s.reviewThreshold,
+	})
+	if err := s.review.Add(item); err != nil {
+		logger.WithError(err).Warn("Failed to queue classification for review")
+	} else {
+		logger.Info("Queued low-confidence classification for review")
+	}
+	return true
+}
+
+func (s *Server) handleReview(w http.ResponseWriter, r *http.Request) {
+	logger := log.WithFields(log.Fields{
+		"handler": "review",
+		"method":  r.Method,
+		"remote":  r.RemoteAddr,
+	})
+
+	if r.Method != http.MethodGet {
+		logger.Warn("Method not allowed")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.review == nil {
+		http.Error(w, "Review queue is disabled", http.StatusNotFound)
+		return
+	}
+
+	items, err := s.review.List()
+	if err != nil {
+		logger.WithError(err).Error("Failed to list review queue")
+		http.Error(w, "Failed to list review queue", http.StatusInternalServerError)
+		return
+	}
+	if items == nil {
+		items = []*ReviewItem{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMemoryReviewStoreDropsOldest(t *testing.T) {
+	store := NewMemoryReviewStore(2)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := store.Add(&ReviewItem{Filename: name}); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	items, _ := store.List()
+	if len(items) != 2 || items[0].Filename != "b.txt" || items[1].Filename != "c.txt" {
+		t.Fatalf("items = %+v, want b.txt and c.txt", items)
+	}
+	if items[0].ID == "" || items[0].ID == items[1].ID || items[0].CreatedAt.IsZero() {
+		t.Errorf("items = %+v, want distinct IDs and creation times assigned", items)
+	}
+}
+
+func TestClassifyQueuesLowConfidenceForReview(t *testing.T) {
+	s := newTestServer(t, openAIReply("Invoice"))
+	s.history = NewMemoryHistoryStore(10)
+	s.review = NewMemoryReviewStore(10)
+
+	classify := func(threshold float64) ClassificationResponse {
+		t.Helper()
+		s.reviewThreshold = threshold
+		rec := httptest.NewRecorder()
+		s.handleClassify(rec, newUploadRequest(t, "/classify", "scan.txt", []byte("Total due: $40"), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+		}
+		var response ClassificationResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return response
+	}
+
+	// The stub answers with a confidence of 0.9
+	if response := classify(0.5); response.NeedsReview {
+		t.Error("needs_review set for a confidence above the threshold")
+	}
+	response := classify(0.95)
+	if !response.NeedsReview {
+		t.Error("needs_review not set for a confidence below the threshold")
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleReview(rec, httptest.NewRequest(http.MethodGet, "/review", nil))
+	var items []ReviewItem
+	if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil {
+		t.Fatalf("review body %q: %v", rec.Body, err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("review queue has %d items, want only the flagged one", len(items))
+	}
+	item := items[0]
+	if item.Filename != "scan.txt" || item.HistoryID != response.HistoryID || item.Threshold != 0.95 ||
+		item.Classification == nil || item.Classification.Category != "Invoice" {
+		t.Errorf("item = %+v, want scan.txt classified Invoice, linked to history %q", item, response.HistoryID)
+	}
+}
+
+func TestReviewQueueDisabled(t *testing.T) {
+	s := newTestServer(t, openAIReply("Invoice"))
+
+	rec := httptest.NewRecorder()
+	s.handleClassify(rec, newUploadRequest(t, "/classify", "scan.txt", []byte("Total due: $40"), nil))
+	var response ClassificationResponse
+	json.Unmarshal(rec.Body.Bytes(), &response)
+	if response.NeedsReview {
+		t.Error("needs_review set with the review queue disabled")
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleReview(rec, httptest.NewRequest(http.MethodGet, "/review", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 with the review queue disabled", rec.Code)
+	}
+}
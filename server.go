@@ -1,25 +1,74 @@
 package main
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/adaptive-scale/superclass/pkg/classifier"
+	"github.com/adaptive-scale/superclass/pkg/extension"
+	"github.com/adaptive-scale/superclass/pkg/extension/code"
+	"github.com/adaptive-scale/superclass/pkg/extension/docx"
+	"github.com/adaptive-scale/superclass/pkg/extension/email"
+	"github.com/adaptive-scale/superclass/pkg/extension/html"
+	"github.com/adaptive-scale/superclass/pkg/extension/image"
+	"github.com/adaptive-scale/superclass/pkg/extension/pdf"
+	"github.com/adaptive-scale/superclass/pkg/extension/svg"
 	"github.com/adaptive-scale/superclass/pkg/extractor"
 	log "github.com/sirupsen/logrus"
 )
 
 type Server struct {
-	uploadDir string
-	provider  classifier.Provider
-	config    classifier.ModelConfig
+	uploadDir  string
+	provider   classifier.Provider
+	config     classifier.ModelConfig
+	defaults   classifier.ClassificationOptions
+	adminToken string
+	history    HistoryStore
+	// allowMissingCredentials lets the server start without a usable API key or endpoint
+	allowMissingCredentials bool
+	// allowedExtensions restricts accepted uploads; nil allows every registered format
+	allowedExtensions map[string]bool
+	// batchConcurrency bounds how many files of a batch are classified at once
+	batchConcurrency int
+	// batchDedup classifies files of a batch with identical contents only once
+	batchDedup bool
+	// gzipResponses compresses responses for clients sending Accept-Encoding: gzip
+	gzipResponses bool
+	// maxRequestBytes limits request bodies (0 means unlimited)
+	maxRequestBytes int64
+	// previewPages and previewChars bound extraction for requests with preview=true
+	previewPages int
+	previewChars int
+	// promptFilename passes the uploaded filename to the model as a classification hint
+	promptFilename bool
+	// disableUI turns off the upload form served at the root path
+	disableUI bool
+	// routePrefix is prepended to every route, e.g. "/api/v1"
+	routePrefix string
+	// middlewares wrap every route, outermost first
+	middlewares []Middleware
+	// budget caps the estimated daily spend on classifications (nil means unlimited)
+	budget *BudgetTracker
+	// warmup classifies a tiny document at startup to check credentials and open a
+	// provider connection; warmupRequired makes a failed warmup stop the server
+	warmup         bool
+	warmupRequired bool
+	// review queues classifications with a confidence below reviewThreshold for human
+	// review (nil disables the queue)
+	review          ReviewStore
+	reviewThreshold float64
 }
 
 type ClassificationRequest struct {
@@ -27,19 +76,46 @@ type ClassificationRequest struct {
 }
 
 type ClassificationResponse struct {
-	Category   string   `json:"category"`
-	Confidence float64  `json:"confidence"`
-	Summary    string   `json:"summary"`
-	Keywords   []string `json:"keywords"`
-	RawText    string   `json:"raw_text,omitempty"`
-	Error      string   `json:"error,omitempty"`
+	Category   string            `json:"category"`
+	Confidence float64           `json:"confidence"`
+	Summary    string            `json:"summary"`
+	Summaries  map[string]string `json:"summaries,omitempty"`
+	Keywords   []string          `json:"keywords"`
+	// Per-category scores, only set by /classify/multi-score
+	Scores      map[string]float64 `json:"scores,omitempty"`
+	RawText     string             `json:"raw_text,omitempty"`
+	RawResponse string             `json:"raw_response,omitempty"`
+	HistoryID   string             `json:"history_id,omitempty"`
+	// Usage and EstimatedCost are the provider-reported token usage and its price in USD
+	Usage         *classifier.TokenUsage `json:"usage,omitempty"`
+	EstimatedCost float64                `json:"estimated_cost,omitempty"`
+	Metadata      *classifier.Metadata   `json:"metadata,omitempty"`
+	// Preview is set when only the leading portion of the document was classified
+	Preview bool `json:"preview,omitempty"`
+	// Warnings lists non-fatal extraction problems, such as skipped slides
+	Warnings []string `json:"warnings,omitempty"`
+	// TypePath is the file format followed by the category path, e.g. ["pdf", "Finance", "Invoice"]
+	TypePath []string `json:"type_path,omitempty"`
+	// NeedsReview is set when the confidence is below REVIEW_CONFIDENCE_THRESHOLD and the
+	// result was queued for human review
+	NeedsReview bool `json:"needs_review,omitempty"`
+	// ChangedPages lists the pages that differ from the uploaded previous version
+	ChangedPages []int  `json:"changed_pages,omitempty"`
+	Error        string `json:"error,omitempty"`
+	// Set instead of Error when fallback_to_extract returned only the extracted text
+	ClassificationError string `json:"classification_error,omitempty"`
 }
 
 func NewServer(uploadDir string, provider classifier.Provider, config classifier.ModelConfig) *Server {
 	return &Server{
-		uploadDir: uploadDir,
-		provider:  provider,
-		config:    config,
+		uploadDir:        uploadDir,
+		provider:         provider,
+		config:           config,
+		batchConcurrency: 4,
+		batchDedup:       true,
+		previewPages:     2,
+		previewChars:     4000,
+		promptFilename:   true,
 	}
 }
 
@@ -71,53 +147,561 @@ func getEnvIntWithDefault(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvBoolWithDefault gets a bool environment variable with a default value
+func getEnvBoolWithDefault(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvListWithDefault gets a list environment variable separated by commas with a default value
+func getEnvListWithDefault(key string, defaultValue []string) []string {
+	return getEnvSplitWithDefault(key, ",", defaultValue)
+}
+
+// getEnvLanguagesWithDefault gets a list of OCR languages separated by commas or by '+',
+// as in Tesseract's own eng+ara syntax, with a default value
+func getEnvLanguagesWithDefault(key string, defaultValue []string) []string {
+	return getEnvSplitWithDefault(key, ",+", defaultValue)
+}
+
+// getEnvSplitWithDefault splits an environment variable on any of the separators,
+// dropping empty items, and returns defaultValue when the variable is unset
+func getEnvSplitWithDefault(key, separators string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var items []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(separators, r) }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvHeaders parses a comma-separated list of Name=value pairs into a header map
+func getEnvHeaders(key string) map[string]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		name, val, ok := strings.Cut(pair, "=")
+		if name = strings.TrimSpace(name); !ok || name == "" {
+			log.WithField("entry", pair).Warn("Ignoring malformed header entry")
+			continue
+		}
+		headers[name] = strings.TrimSpace(val)
+	}
+	return headers
+}
+
+// getEnvFloatMap parses "Name=0.7,Other=0.5" into a map, skipping malformed entries
+func getEnvFloatMap(key string) map[string]float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	values := make(map[string]float64)
+	for _, pair := range strings.Split(value, ",") {
+		name, raw, ok := strings.Cut(pair, "=")
+		number, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if name = strings.TrimSpace(name); !ok || name == "" || err != nil {
+			log.WithFields(log.Fields{"key": key, "entry": pair}).Warn("Ignoring malformed entry")
+			continue
+		}
+		values[name] = number
+	}
+	return values
+}
+
+// loadCategorySet reads a JSON array of classifier.Category entries
+func loadCategorySet(path string) (*classifier.CategorySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var categories []classifier.Category
+	if err := json.Unmarshal(data, &categories); err != nil {
+		return nil, fmt.Errorf("invalid taxonomy file: %w", err)
+	}
+	return classifier.NewCategorySet(categories...)
+}
+
+// loadLocalRules reads a JSON object mapping categories to keyword lists for the
+// local fallback classifier
+func loadLocalRules(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules map[string][]string
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid local rules file: %w", err)
+	}
+	return rules, nil
+}
+
+// configureExtractors applies environment settings to the registered built-in extractors
+func configureExtractors() {
+	if e, err := extractor.DefaultRegistry.Get(".docx"); err == nil {
+		if d, ok := e.(*docx.Extractor); ok {
+			d.IncludeComments = getEnvBoolWithDefault("DOCX_INCLUDE_COMMENTS", false)
+		}
+	}
+	if e, err := extractor.DefaultRegistry.Get(".pdf"); err == nil {
+		if p, ok := e.(*pdf.Extractor); ok {
+			p.Password = os.Getenv("PDF_PASSWORD")
+		}
+	}
+	if e, err := extractor.DefaultRegistry.Get(".png"); err == nil {
+		if img, ok := e.(*image.Extractor); ok {
+			img.Languages = getEnvLanguagesWithDefault("OCR_LANGUAGES", img.Languages)
+			img.FallbackLanguages = getEnvLanguagesWithDefault("OCR_FALLBACK_LANGUAGES", img.FallbackLanguages)
+			img.MinConfidence = getEnvFloat64WithDefault("OCR_MIN_CONFIDENCE", img.MinConfidence)
+			img.IncludeMetadata = getEnvBoolWithDefault("IMAGE_INCLUDE_METADATA", false)
+			img.IncludeGPS = getEnvBoolWithDefault("IMAGE_INCLUDE_GPS", false)
+			for _, step := range getEnvListWithDefault("OCR_PREPROCESS", nil) {
+				switch strings.ToLower(strings.TrimSpace(step)) {
+				case "grayscale":
+					img.Preprocess.Grayscale = true
+				case "binarize":
+					img.Preprocess.Binarize = true
+				case "deskew":
+					img.Preprocess.Deskew = true
+				default:
+					log.WithField("step", step).Warn("Unknown OCR preprocessing step")
+				}
+			}
+			img.Preprocess.Scale = getEnvFloat64WithDefault("OCR_SCALE", img.Preprocess.Scale)
+			img.Preprocess.DPI = getEnvIntWithDefault("OCR_DPI", img.Preprocess.DPI)
+		}
+	}
+
+	if e, err := extractor.DefaultRegistry.Get(".svg"); err == nil {
+		if svgExtractor, ok := e.(*svg.Extractor); ok {
+			svgExtractor.OCREmbeddedImages = getEnvBoolWithDefault("SVG_OCR_EMBEDDED_IMAGES", false)
+			if img, err := extractor.DefaultRegistry.Get(".png"); err == nil {
+				svgExtractor.ImageExtractor = img
+			}
+		}
+	}
+
+	if e, err := extractor.DefaultRegistry.Get(".html"); err == nil {
+		if h, ok := e.(*html.Extractor); ok {
+			h.IncludeOutline = getEnvBoolWithDefault("HTML_HEADING_OUTLINE", h.IncludeOutline)
+		}
+	}
+
+	if e, err := extractor.DefaultRegistry.Get(".eml"); err == nil {
+		if m, ok := e.(*email.Extractor); ok {
+			m.StripQuotes = getEnvBoolWithDefault("EMAIL_STRIP_QUOTES", m.StripQuotes)
+			m.StripSignature = getEnvBoolWithDefault("EMAIL_STRIP_SIGNATURE", m.StripSignature)
+		}
+	}
+
+	if e, err := extractor.DefaultRegistry.Get(".go"); err == nil {
+		if c, ok := e.(*code.Extractor); ok {
+			c.StripComments = getEnvBoolWithDefault("CODE_STRIP_COMMENTS", false)
+			c.MaxBytes = getEnvIntWithDefault("CODE_MAX_BYTES", c.MaxBytes)
+		}
+	}
+
+	extractor.MinPrintableRatio = getEnvFloat64WithDefault("TEXT_MIN_PRINTABLE_RATIO", extractor.MinPrintableRatio)
+	extractor.RejectLowQualityText = getEnvBoolWithDefault("TEXT_REJECT_LOW_QUALITY", extractor.RejectLowQualityText)
+
+	switch strings.ToLower(os.Getenv("EXTRACTION_CACHE")) {
+	case "memory":
+		extractor.DefaultCache = extractor.NewMemoryExtractionCache(getEnvIntWithDefault("EXTRACTION_CACHE_MAX_ENTRIES", 1000))
+		log.Debug("Using in-memory extraction cache")
+	case "disk":
+		cache, err := extractor.NewDiskExtractionCache(getEnvWithDefault("EXTRACTION_CACHE_DIR", "/tmp/superclass-cache"))
+		if err != nil {
+			log.WithError(err).Error("Failed to create extraction cache, caching disabled")
+			return
+		}
+		extractor.DefaultCache = cache
+		log.WithField("dir", cache.Dir).Debug("Using on-disk extraction cache")
+	}
+}
+
 func NewServerFromEnv() *Server {
 	log.Debug("Starting server initialization from environment")
 
 	// Get configuration from environment variables
 	uploadDir := getEnvWithDefault("UPLOAD_DIR", "/tmp/superclass-uploads")
-	modelType := getEnvWithDefault("MODEL_TYPE", "gpt-4")
 	provider := classifier.ProviderFromString(getEnvWithDefault("MODEL_PROVIDER", "openai"))
+	modelType := getEnvWithDefault("MODEL_TYPE", string(classifier.DefaultModelForProvider(provider)))
 	maxCost := getEnvFloat64WithDefault("MAX_COST", 0.1)
 	maxLatency := getEnvIntWithDefault("MAX_LATENCY", 30)
+	temperature := getEnvFloat64WithDefault("MODEL_TEMPERATURE", 0.3)
+	classifier.MaxLoggedBodyChars = getEnvIntWithDefault("LOG_MAX_BODY_CHARS", classifier.MaxLoggedBodyChars)
+
+	transport := classifier.DefaultTransportConfig()
+	transport.MaxIdleConns = getEnvIntWithDefault("HTTP_MAX_IDLE_CONNS", transport.MaxIdleConns)
+	transport.MaxIdleConnsPerHost = getEnvIntWithDefault("HTTP_MAX_IDLE_CONNS_PER_HOST", transport.MaxIdleConnsPerHost)
+	transport.IdleConnTimeout = time.Duration(getEnvIntWithDefault("HTTP_IDLE_CONN_TIMEOUT", int(transport.IdleConnTimeout.Seconds()))) * time.Second
+	transport.DialTimeout = time.Duration(getEnvIntWithDefault("HTTP_DIAL_TIMEOUT", int(transport.DialTimeout.Seconds()))) * time.Second
+	transport.TLSHandshakeTimeout = time.Duration(getEnvIntWithDefault("HTTP_TLS_HANDSHAKE_TIMEOUT", int(transport.TLSHandshakeTimeout.Seconds()))) * time.Second
+	classifier.ConfigureTransport(transport)
+
+	retries := classifier.DefaultRetryConfig()
+	retries.MaxRetries = getEnvIntWithDefault("PROVIDER_MAX_RETRIES", retries.MaxRetries)
+	retries.BaseDelay = time.Duration(getEnvIntWithDefault("PROVIDER_RETRY_BASE_DELAY_MS", int(retries.BaseDelay.Milliseconds()))) * time.Millisecond
+	classifier.ConfigureRetries(retries)
+	configureExtractors()
 
 	log.WithFields(log.Fields{
-		"uploadDir":  uploadDir,
-		"modelType":  modelType,
-		"provider":   provider,
-		"maxCost":    maxCost,
-		"maxLatency": maxLatency,
+		"uploadDir":          uploadDir,
+		"modelType":          modelType,
+		"provider":           provider,
+		"maxCost":            maxCost,
+		"maxLatency":         maxLatency,
+		"temperature":        temperature,
+		"logMaxBodyChars":    classifier.MaxLoggedBodyChars,
+		"httpMaxIdlePerHost": transport.MaxIdleConnsPerHost,
 	}).Info("Server configuration loaded")
 
 	log.Debug("Creating model configuration")
 	// Create model config
 	config := classifier.ModelConfig{
-		Model:  modelType,
-		APIKey: os.Getenv("OPENAI_API_KEY"), // Will be overridden by provider-specific key
+		Endpoint: os.Getenv("MODEL_ENDPOINT"),
+		Headers:  getEnvHeaders("MODEL_HEADERS"),
+		Model:    modelType,
+		APIKey:   os.Getenv("OPENAI_API_KEY"), // Will be overridden by provider-specific key
 		Parameters: map[string]interface{}{
 			"max_tokens":  2000,
-			"temperature": 0.3,
+			"temperature": temperature,
 			"max_cost":    maxCost,
 			"max_latency": maxLatency,
 		},
 	}
 
+	if seed, err := strconv.Atoi(os.Getenv("MODEL_SEED")); err == nil {
+		config.Seed = &seed
+	}
+	if stop := os.Getenv("MODEL_STOP"); stop != "" {
+		var sequences []string
+		if err := json.Unmarshal([]byte(stop), &sequences); err != nil {
+			log.WithError(err).Warn("Ignoring MODEL_STOP, expected a JSON array of strings")
+		} else {
+			config.Parameters["stop"] = sequences
+		}
+	}
+	if bias := getEnvFloatMap("MODEL_LOGIT_BIAS"); len(bias) > 0 {
+		config.Parameters["logit_bias"] = bias
+	}
+	config.MaxConcurrentRequests = getEnvIntWithDefault("PROVIDER_MAX_CONCURRENCY", 0)
+
 	log.Debug("Setting provider-specific API key")
 	// Set the appropriate API key based on the provider
 	switch provider {
 	case classifier.OpenAI:
 		config.APIKey = os.Getenv("OPENAI_API_KEY")
+		config.VisionModel = os.Getenv("OPENAI_VISION_MODEL")
 		log.Debug("Using OpenAI provider")
 	case classifier.Anthropic:
 		config.APIKey = os.Getenv("ANTHROPIC_API_KEY")
+		caching := getEnvBoolWithDefault("ANTHROPIC_PROMPT_CACHING", false)
+		config.EnablePromptCaching = &caching
 		log.Debug("Using Anthropic provider")
 	case classifier.Azure:
 		config.APIKey = os.Getenv("AZURE_OPENAI_API_KEY")
 		log.Debug("Using Azure OpenAI provider")
+	case classifier.Gemini:
+		config.APIKey = os.Getenv("GEMINI_API_KEY")
+		log.Debug("Using Google Gemini provider")
+	case classifier.Custom:
+		config.APIKey = os.Getenv("CUSTOM_API_KEY")
+		config.Auth = classifier.AuthConfig{
+			Scheme: classifier.AuthSchemeFromString(os.Getenv("CUSTOM_AUTH_SCHEME")),
+			Header: os.Getenv("CUSTOM_AUTH_HEADER"),
+			Secret: os.Getenv("CUSTOM_HMAC_SECRET"),
+		}
+		config.CustomFields = classifier.CustomFields{
+			ResponseContentPath: os.Getenv("CUSTOM_RESPONSE_CONTENT_PATH"),
+			ModelField:          os.Getenv("CUSTOM_MODEL_FIELD"),
+			MessagesField:       os.Getenv("CUSTOM_MESSAGES_FIELD"),
+			ParametersField:     os.Getenv("CUSTOM_PARAMETERS_FIELD"),
+		}
+		log.Debug("Using custom provider")
 	}
 
 	log.Debug("Server initialization completed")
-	return NewServer(uploadDir, provider, config)
+	server := NewServer(uploadDir, provider, config)
+	server.defaults = classifier.ClassificationOptions{
+		UseFormatHints:      getEnvBoolWithDefault("FORMAT_CATEGORY_HINTS", false),
+		UseFrontMatterHints: getEnvBoolWithDefault("FRONT_MATTER_HINTS", false),
+		MaxKeywords:         getEnvIntWithDefault("MAX_KEYWORDS", 0),
+		KeywordNgramMax:     getEnvIntWithDefault("KEYWORD_NGRAM_MAX", 0),
+		MaxSummaryWords:     getEnvIntWithDefault("MAX_SUMMARY_WORDS", 0),
+		LocalKeywords:       getEnvBoolWithDefault("LOCAL_KEYWORDS", false),
+		NativeDocument:      getEnvBoolWithDefault("ANTHROPIC_NATIVE_PDF", false),
+		Vision:              getEnvBoolWithDefault("OPENAI_VISION_IMAGES", false),
+		PreserveModelCasing: getEnvBoolWithDefault("PRESERVE_MODEL_CASING", false),
+		MinTextLength:       getEnvIntWithDefault("MIN_TEXT_LENGTH", 0),
+
+		DefendAgainstInjection: getEnvBoolWithDefault("PROMPT_INJECTION_DEFENSE", false),
+		StripInjectionPhrases:  getEnvBoolWithDefault("STRIP_INJECTION_PHRASES", false),
+
+		OutputLanguage:     os.Getenv("OUTPUT_LANGUAGE"),
+		AutoOutputLanguage: getEnvBoolWithDefault("AUTO_OUTPUT_LANGUAGE", false),
+	}
+	server.defaults.CategoryFloors = getEnvFloatMap("CATEGORY_FLOORS")
+	server.defaults.CategoryPriors = getEnvFloatMap("CATEGORY_PRIORS")
+	server.defaults.PriorTieMargin = getEnvFloat64WithDefault("CATEGORY_PRIOR_MARGIN", 0)
+	server.defaults.ExcludeCategories = getEnvListWithDefault("EXCLUDE_CATEGORIES", nil)
+	server.defaults.Timeout = time.Duration(getEnvIntWithDefault("CLASSIFICATION_TIMEOUT", 0)) * time.Second
+	server.defaults.LocalFallback = getEnvBoolWithDefault("LOCAL_FALLBACK", false)
+	if path := os.Getenv("LOCAL_FALLBACK_RULES_FILE"); path != "" {
+		rules, err := loadLocalRules(path)
+		if err != nil {
+			log.WithError(err).WithField("path", path).Fatal("Failed to load local fallback rules")
+		}
+		server.defaults.LocalRules = rules
+	}
+	if path := os.Getenv("CATEGORY_TAXONOMY_FILE"); path != "" {
+		set, err := loadCategorySet(path)
+		if err != nil {
+			log.WithError(err).WithField("path", path).Fatal("Failed to load category taxonomy")
+		}
+		server.defaults.CategorySet = set
+	}
+	server.adminToken = os.Getenv("ADMIN_TOKEN")
+	server.routePrefix = normalizeRoutePrefix(os.Getenv("ROUTE_PREFIX"))
+	server.disableUI = getEnvBoolWithDefault("DISABLE_UI", false)
+	server.warmup = getEnvBoolWithDefault("WARMUP", false)
+	server.warmupRequired = getEnvBoolWithDefault("WARMUP_REQUIRED", false)
+	server.promptFilename = getEnvBoolWithDefault("PROMPT_INCLUDE_FILENAME", server.promptFilename)
+	server.previewPages = getEnvIntWithDefault("PREVIEW_PAGES", server.previewPages)
+	server.previewChars = getEnvIntWithDefault("PREVIEW_CHARS", server.previewChars)
+	server.batchConcurrency = getEnvIntWithDefault("BATCH_CONCURRENCY", server.batchConcurrency)
+	server.batchDedup = getEnvBoolWithDefault("BATCH_DEDUP", server.batchDedup)
+	server.gzipResponses = getEnvBoolWithDefault("RESPONSE_GZIP", false)
+	server.maxRequestBytes = int64(getEnvIntWithDefault("MAX_REQUEST_BYTES", 0))
+	if limit := getEnvFloat64WithDefault("DAILY_BUDGET_USD", 0); limit > 0 {
+		server.budget = NewBudgetTracker(limit)
+	}
+	server.allowMissingCredentials = getEnvBoolWithDefault("ALLOW_MISSING_CREDENTIALS", false)
+	if allowed := getEnvListWithDefault("ALLOWED_EXTENSIONS", nil); len(allowed) > 0 {
+		server.allowedExtensions = make(map[string]bool, len(allowed))
+		for _, ext := range allowed {
+			ext = strings.ToLower(ext)
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			server.allowedExtensions[ext] = true
+		}
+	}
+	if getEnvBoolWithDefault("HISTORY_ENABLED", false) {
+		server.history = NewMemoryHistoryStore(getEnvIntWithDefault("HISTORY_MAX_RECORDS", 1000))
+	}
+	if server.reviewThreshold = getEnvFloat64WithDefault("REVIEW_CONFIDENCE_THRESHOLD", 0); server.reviewThreshold > 0 {
+		server.review = NewMemoryReviewStore(getEnvIntWithDefault("REVIEW_MAX_ITEMS", 1000))
+	}
+	return server
+}
+
+// validateCredentials checks that the configured provider can actually be called:
+// OpenAI, Anthropic and Gemini need an API key, Azure needs a key and an endpoint, and
+// custom providers need an endpoint
+func (s *Server) validateCredentials() error {
+	switch s.provider {
+	case classifier.Azure:
+		if s.config.APIKey == "" {
+			return fmt.Errorf("provider %s requires AZURE_OPENAI_API_KEY to be set", s.provider)
+		}
+		if s.config.Endpoint == "" && os.Getenv(classifier.BaseURLEnvVars[s.provider]) == "" {
+			return fmt.Errorf("provider %s requires MODEL_ENDPOINT or %s to be set", s.provider, classifier.BaseURLEnvVars[s.provider])
+		}
+	case classifier.Custom:
+		if s.config.Endpoint == "" && os.Getenv(classifier.BaseURLEnvVars[s.provider]) == "" {
+			return fmt.Errorf("provider %s requires MODEL_ENDPOINT or %s to be set", s.provider, classifier.BaseURLEnvVars[s.provider])
+		}
+		if s.config.Auth.Scheme == classifier.AuthHMAC && s.config.Auth.Secret == "" && s.config.APIKey == "" {
+			return fmt.Errorf("provider %s with HMAC authentication requires CUSTOM_HMAC_SECRET to be set", s.provider)
+		}
+	case classifier.Anthropic:
+		if s.config.APIKey == "" {
+			return fmt.Errorf("provider %s requires ANTHROPIC_API_KEY to be set", s.provider)
+		}
+	case classifier.Gemini:
+		if s.config.APIKey == "" {
+			return fmt.Errorf("provider %s requires GEMINI_API_KEY to be set", s.provider)
+		}
+	default:
+		if s.config.APIKey == "" {
+			return fmt.Errorf("provider %s requires OPENAI_API_KEY to be set", s.provider)
+		}
+	}
+	return nil
+}
+
+// extensionAllowed reports whether uploads with the given filename pass the extension allow-list
+func (s *Server) extensionAllowed(filename string) bool {
+	if s.allowedExtensions == nil {
+		return true
+	}
+	return s.allowedExtensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+// selectionExtensions lists the upload extensions each selection form field applies to
+var selectionExtensions = map[string][]string{
+	"sheet": {".xlsx", ".xlsm"},
+	"page":  {".pdf"},
+	"slide": {".pptx"},
+}
+
+// parseSelection reads the sheet, page and slide form fields, which restrict extraction
+// to parts of the document. A selector that does not apply to the upload's format or a
+// malformed range is an error.
+func parseSelection(r *http.Request, filename string) (pages string, sheets []string, err error) {
+	for _, sheet := range r.Form["sheet"] {
+		if sheet = strings.TrimSpace(sheet); sheet != "" {
+			sheets = append(sheets, sheet)
+		}
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, field := range []string{"sheet", "page", "slide"} {
+		if strings.TrimSpace(r.FormValue(field)) != "" && !slices.Contains(selectionExtensions[field], ext) {
+			return "", nil, fmt.Errorf("%s selection is not supported for %s files", field, ext)
+		}
+	}
+
+	pages = r.FormValue("page")
+	if pages == "" {
+		pages = r.FormValue("slide")
+	}
+	if pages != "" {
+		if _, err := extension.ParsePageRanges(pages); err != nil {
+			return "", nil, err
+		}
+	}
+	return pages, sheets, nil
+}
+
+// savePreviousVersion saves the optional previous form file, an earlier version of the
+// uploaded PDF, to a temporary file and returns its path, or "" when none was sent.
+// The caller removes the file.
+func (s *Server) savePreviousVersion(r *http.Request, filename string) (string, error) {
+	file, header, err := r.FormFile("previous")
+	if errors.Is(err, http.ErrMissingFile) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read previous version: %w", err)
+	}
+	defer file.Close()
+
+	if strings.ToLower(filepath.Ext(filename)) != ".pdf" || strings.ToLower(filepath.Ext(header.Filename)) != ".pdf" {
+		return "", errors.New("previous version comparison is only supported for PDF files")
+	}
+	out, err := os.CreateTemp(s.uploadDir, "previous-*.pdf")
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, file); err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+// responseFieldNames returns the JSON names of the ClassificationResponse fields
+func responseFieldNames() []string {
+	var names []string
+	t := reflect.TypeOf(ClassificationResponse{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseResponseFields reads the comma-separated fields parameter, which selects the
+// ClassificationResponse fields to return. It returns nil when all fields are wanted.
+func parseResponseFields(value string) ([]string, error) {
+	known := responseFieldNames()
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		if !slices.Contains(known, field) {
+			return nil, fmt.Errorf("unknown response field %q, expected one of: %s", field, strings.Join(known, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// filterResponse returns response restricted to fields, or response itself when fields
+// is empty. Requested fields that are empty and omitted by the encoding stay omitted.
+func filterResponse(response ClassificationResponse, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return response, nil
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	filtered := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			filtered[field] = value
+		}
+	}
+	return filtered, nil
+}
+
+// isAdmin reports whether the request carries the configured admin token
+func (s *Server) isAdmin(r *http.Request) bool {
+	if s.adminToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Token")), []byte(s.adminToken)) == 1
+}
+
+// classificationErrorStatus maps an extraction or classification error to the status of
+// the response. ok is false for errors without a specific status.
+func classificationErrorStatus(err error) (status int, ok bool) {
+	switch {
+	case errors.Is(err, extractor.ErrEncryptedDocument),
+		errors.Is(err, extractor.ErrLowQualityText),
+		errors.Is(err, extractor.ErrNoChangedPages),
+		errors.Is(err, extractor.ErrInsufficientText),
+		errors.Is(err, classifier.ErrRefused),
+		errors.Is(err, classifier.ErrExcludedCategory):
+		return http.StatusUnprocessableEntity, true
+	case errors.Is(err, extractor.ErrMalformedDocument), errors.Is(err, extractor.ErrUnmatchedSelection):
+		return http.StatusBadRequest, true
+	case errors.Is(err, classifier.ErrResponseTruncated):
+		return http.StatusBadGateway, true
+	case errors.Is(err, classifier.ErrClassificationTimeout):
+		return http.StatusGatewayTimeout, true
+	}
+	return 0, false
+}
+
+// classificationErrorMessage is the error reported to the client for err
+func classificationErrorMessage(err error) string {
+	if errors.Is(err, extractor.ErrEncryptedDocument) {
+		return "document is encrypted or password-protected and cannot be classified"
+	}
+	return err.Error()
 }
 
 func (s *Server) handleClassify(w http.ResponseWriter, r *http.Request) {
@@ -149,6 +733,37 @@ func (s *Server) handleClassify(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	var excludeCategories []string
+	if excludeJSON := r.FormValue("exclude_categories"); excludeJSON != "" {
+		if err := json.Unmarshal([]byte(excludeJSON), &excludeCategories); err != nil {
+			logger.WithError(err).Error("Failed to parse excluded categories")
+			http.Error(w, "Invalid exclude_categories format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var summaryLengths []string
+	for _, length := range strings.Split(r.FormValue("summary_lengths"), ",") {
+		if length = strings.TrimSpace(length); length != "" {
+			summaryLengths = append(summaryLengths, length)
+		}
+	}
+
+	fields, err := parseResponseFields(r.FormValue("fields"))
+	if err != nil {
+		logger.WithError(err).Warn("Invalid response fields")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	debugRaw := r.FormValue("debug_raw") == "true"
+	fallbackToExtract := r.FormValue("fallback_to_extract") == "true"
+	preview := r.FormValue("preview") == "true"
+	if debugRaw && !s.isAdmin(r) {
+		logger.Warn("Raw response requested without admin token")
+		http.Error(w, "debug_raw requires a valid admin token", http.StatusForbidden)
+		return
+	}
 
 	file, header, err := r.FormFile("file")
 	if err != nil {
@@ -166,6 +781,19 @@ func (s *Server) handleClassify(w http.ResponseWriter, r *http.Request) {
 	})
 	logger.Info("Processing uploaded file")
 
+	if !s.extensionAllowed(header.Filename) {
+		logger.Warn("File extension not allowed")
+		http.Error(w, "File type not allowed", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	pages, sheets, err := parseSelection(r, header.Filename)
+	if err != nil {
+		logger.WithError(err).Warn("Invalid selection")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Create temporary file
 	tempFile := filepath.Join(s.uploadDir, header.Filename)
 	out, err := os.Create(tempFile)
@@ -188,27 +816,102 @@ func (s *Server) handleClassify(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	previousVersion, err := s.savePreviousVersion(r, header.Filename)
+	if err != nil {
+		logger.WithError(err).Warn("Invalid previous version")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if previousVersion != "" {
+		defer os.Remove(previousVersion)
+	}
+
+	reservation, ok := s.reserveBudget(w)
+	if !ok {
+		return
+	}
+	defer s.releaseBudget(reservation)
+
 	logger.Debug("Starting classification")
 	// Extract and classify
-	result, err := extractor.ExtractAndClassifyWithOptions(tempFile, s.provider, s.config, classifier.ClassificationOptions{
-		Categories: classificationReq.Categories,
-	})
+	options := s.defaults
+	options.PreviousVersion = previousVersion
+	options.Categories = classificationReq.Categories
+	options.DebugIncludeRaw = debugRaw
+	options.ContextHint = r.FormValue("context")
+	if language := r.FormValue("output_language"); language != "" {
+		options.OutputLanguage = language
+	}
+	if s.promptFilename {
+		options.Filename = header.Filename
+	}
+	if len(excludeCategories) > 0 {
+		options.ExcludeCategories = excludeCategories
+	}
+	if len(summaryLengths) > 0 {
+		options.SummaryLengths = summaryLengths
+	}
+	if preview {
+		options.PreviewPages = s.previewPages
+		options.PreviewChars = s.previewChars
+	}
+	options.Pages = pages
+	options.Sheets = sheets
+	result, err := extractor.ExtractAndClassifyWithOptions(tempFile, s.provider, s.config, options)
 	if err != nil {
 		logger.WithError(err).Error("Classification failed")
+		if fallbackToExtract && errors.Is(err, extractor.ErrClassificationFailed) {
+			logger.Warn("Falling back to extracted text only")
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(ClassificationResponse{
+				RawText:             result.Text,
+				Warnings:            result.Warnings,
+				ClassificationError: err.Error(),
+			})
+			return
+		}
+		if status, ok := classificationErrorStatus(err); ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+		}
 		json.NewEncoder(w).Encode(ClassificationResponse{
-			Error: err.Error(),
+			Error: classificationErrorMessage(err),
 		})
 		return
 	}
 
+	s.recordCost(reservation, s.config.Model, result.Text, result.Classification)
+
 	// Prepare response
 	response := ClassificationResponse{
-		Category:   result.Classification.Category,
-		Confidence: result.Classification.Confidence,
-		Summary:    result.Classification.Summary,
-		Keywords:   result.Classification.Keywords,
-		RawText:    result.Text,
+		Category:      result.Classification.Category,
+		Confidence:    result.Classification.Confidence,
+		Summary:       result.Classification.Summary,
+		Summaries:     result.Classification.Summaries,
+		Keywords:      result.Classification.Keywords,
+		Usage:         result.Classification.Usage,
+		EstimatedCost: result.Classification.EstimatedCost,
+		Metadata:      result.Classification.Metadata,
+		Preview:       result.Preview,
+		Warnings:      result.Warnings,
+		TypePath:      result.TypePath,
+		ChangedPages:  result.ChangedPages,
+		RawText:       result.Text,
+		RawResponse:   result.Classification.RawResponse,
 	}
+	response.HistoryID = s.recordHistory(&HistoryRecord{
+		Filename:       header.Filename,
+		Provider:       s.provider,
+		Model:          s.config.Model,
+		Categories:     classificationReq.Categories,
+		Classification: result.Classification,
+		Text:           result.Text,
+	})
+	response.NeedsReview = s.flagForReview(&ReviewItem{
+		HistoryID:      response.HistoryID,
+		Filename:       header.Filename,
+		Classification: result.Classification,
+	})
 
 	logger.WithFields(log.Fields{
 		"category":        response.Category,
@@ -218,8 +921,14 @@ func (s *Server) handleClassify(w http.ResponseWriter, r *http.Request) {
 	}).Info("Classification completed successfully")
 
 	// Send response
+	body, err := filterResponse(response, fields)
+	if err != nil {
+		logger.WithError(err).Error("Failed to filter response fields")
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := json.NewEncoder(w).Encode(body); err != nil {
 		logger.WithError(err).Error("Failed to encode response")
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
@@ -249,11 +958,86 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	logger.Debug("Health check completed")
 }
 
+// ReadinessResponse reports whether every registered format can be extracted
+type ReadinessResponse struct {
+	// Status is "ready", or "degraded" when some formats are unavailable
+	Status      string                        `json:"status"`
+	Unavailable []extractor.UnavailableFormat `json:"unavailable,omitempty"`
+}
+
+// handleReady checks the external dependencies of the extractors, such as Tesseract
+// language data. Missing dependencies only disable their formats, so the server still
+// answers 200 with status "degraded" and lists the affected formats.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	response := ReadinessResponse{Status: "ready"}
+	if unavailable := extractor.DefaultRegistry.CheckDependencies(); len(unavailable) > 0 {
+		response.Status = "degraded"
+		response.Unavailable = unavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleFormats lists the supported formats with their extensions and MIME types
+func (s *Server) handleFormats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(extractor.GetSupportedFormatsDetailed())
+}
+
+// handleMetrics exposes the extraction metrics in the Prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if extractor.DefaultMetrics == nil {
+		return
+	}
+	if err := extractor.DefaultMetrics.WritePrometheus(w); err != nil {
+		log.WithError(err).Error("Failed to write metrics")
+	}
+}
+
 var startTime time.Time
 
 func (s *Server) Start(port int) error {
 	startTime = time.Now()
 
+	if err := extractor.DefaultRegistry.Validate(); err != nil {
+		log.WithError(err).Warn("Extractor registry has conflicts")
+	}
+	for _, format := range extractor.DefaultRegistry.CheckDependencies() {
+		log.WithFields(log.Fields{
+			"format":     format.Name,
+			"extensions": format.Extensions,
+			"reason":     format.Error,
+		}).Warn("Format unavailable: missing extractor dependency")
+	}
+
+	log.Debug("Validating provider credentials")
+	if err := s.validateCredentials(); err != nil {
+		if !s.allowMissingCredentials {
+			log.WithError(err).Error("Provider credentials are missing")
+			return fmt.Errorf("invalid provider configuration: %w (set ALLOW_MISSING_CREDENTIALS=true to start anyway)", err)
+		}
+		log.WithError(err).Warn("Starting without usable provider credentials")
+	}
+
+	if s.warmup {
+		if err := s.warmupClassifier(); err != nil && s.warmupRequired {
+			return fmt.Errorf("warmup failed: %w", err)
+		}
+	}
+
 	log.Debug("Ensuring upload directory exists")
 	// Create upload directory if it doesn't exist
 	if err := os.MkdirAll(s.uploadDir, 0755); err != nil {
@@ -261,10 +1045,8 @@ func (s *Server) Start(port int) error {
 		return fmt.Errorf("failed to create upload directory: %w", err)
 	}
 
-	log.Debug("Registering HTTP handlers")
-	// Register routes
-	http.HandleFunc("/classify", s.handleClassify)
-	http.HandleFunc("/health", s.handleHealth)
+	log.WithField("route_prefix", s.routePrefix).Debug("Registering HTTP handlers")
+	handler := s.Handler()
 
 	// Start server
 	addr := fmt.Sprintf(":%d", port)
@@ -274,8 +1056,8 @@ func (s *Server) Start(port int) error {
 		"upload_dir": s.uploadDir,
 		"provider":   s.provider,
 		"model":      s.config.Model,
-	}).Info("Server starting on port %d", port)
+	}).Infof("Server starting on port %d", port)
 
 	log.Debug("Starting HTTP server")
-	return http.ListenAndServe(addr, nil)
+	return http.ListenAndServe(addr, handler)
 }
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+	"github.com/adaptive-scale/superclass/pkg/extractor"
+)
+
+// newUploadRequest builds a multipart POST to target uploading data as filename in the
+// file field, along with the given form fields
+func newUploadRequest(t *testing.T, target, filename string, data []byte, fields map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+// serveProvider starts an OpenAI-compatible stub answering every request with status and body
+func serveProvider(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestServer returns a server classifying with an OpenAI-compatible stub that answers
+// every request with reply
+func newTestServer(t *testing.T, reply string) *Server {
+	t.Helper()
+	provider := serveProvider(t, http.StatusOK, reply)
+	return NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{
+		Endpoint:   provider.URL,
+		APIKey:     "key",
+		MaxRetries: -1,
+	})
+}
+
+func TestUploadsOutsideAllowListRejected(t *testing.T) {
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{APIKey: "key", MaxRetries: -1})
+	s.allowedExtensions = map[string]bool{".pdf": true, ".txt": true}
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"><text>Logo</text></svg>`)
+
+	handlers := map[string]http.HandlerFunc{
+		"/classify":             s.handleClassify,
+		"/classify/auto":        s.handleClassifyAuto,
+		"/classify/multi-score": s.handleClassifyMultiScore,
+	}
+	for target, handler := range handlers {
+		t.Run(target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler(rec, newUploadRequest(t, target, "logo.svg", svg,
+				map[string]string{"categories": `["Logo","Chart"]`}))
+			if rec.Code != http.StatusUnsupportedMediaType {
+				t.Errorf("status = %d, want 415 (body %s)", rec.Code, rec.Body)
+			}
+			if entries, _ := os.ReadDir(s.uploadDir); len(entries) != 0 {
+				t.Errorf("upload directory holds %d files, want none", len(entries))
+			}
+		})
+	}
+}
+
+func TestEnvListSeparators(t *testing.T) {
+	t.Setenv("EXCLUDE_CATEGORIES", "C++ Code, Spam")
+	t.Setenv("OCR_LANGUAGES", "eng+ara, fra")
+
+	if got, want := getEnvListWithDefault("EXCLUDE_CATEGORIES", nil), []string{"C++ Code", "Spam"}; !slices.Equal(got, want) {
+		t.Errorf("EXCLUDE_CATEGORIES = %q, want %q", got, want)
+	}
+	if got, want := getEnvLanguagesWithDefault("OCR_LANGUAGES", nil), []string{"eng", "ara", "fra"}; !slices.Equal(got, want) {
+		t.Errorf("OCR_LANGUAGES = %q, want %q", got, want)
+	}
+	if got, want := getEnvListWithDefault("UNSET_LIST", []string{"default"}), []string{"default"}; !slices.Equal(got, want) {
+		t.Errorf("unset list = %q, want the default %q", got, want)
+	}
+}
+
+func TestGetEnvHeaders(t *testing.T) {
+	t.Setenv("MODEL_HEADERS", "X-Tenant=acme, traceparent = 00-abc-01,malformed,=empty")
+
+	got := getEnvHeaders("MODEL_HEADERS")
+	want := map[string]string{"X-Tenant": "acme", "traceparent": "00-abc-01"}
+	if len(got) != len(want) {
+		t.Fatalf("headers = %v, want %v", got, want)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("header %s = %q, want %q", name, got[name], value)
+		}
+	}
+	if got := getEnvHeaders("UNSET_HEADERS"); got != nil {
+		t.Errorf("unset headers = %v, want nil", got)
+	}
+}
+
+func TestNewServerFromEnvDefaultsModelPerProvider(t *testing.T) {
+	useCLIEnvironment(t)
+	t.Setenv("MODEL_TYPE", "")
+
+	tests := []struct {
+		provider string
+		model    string
+	}{
+		{provider: "openai", model: "gpt-4"},
+		{provider: "anthropic", model: "claude-3-opus-20240229"},
+		{provider: "gemini", model: "gemini-1.5-flash"},
+		{provider: "custom", model: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			t.Setenv("MODEL_PROVIDER", tt.provider)
+			if got := NewServerFromEnv().config.Model; got != tt.model {
+				t.Errorf("model = %q, want %q", got, tt.model)
+			}
+		})
+	}
+
+	// An explicit MODEL_TYPE always wins
+	t.Setenv("MODEL_PROVIDER", "anthropic")
+	t.Setenv("MODEL_TYPE", "claude-3-haiku-20240307")
+	if got := NewServerFromEnv().config.Model; got != "claude-3-haiku-20240307" {
+		t.Errorf("model = %q, want the configured MODEL_TYPE", got)
+	}
+}
+
+func TestGetEnvFloatMap(t *testing.T) {
+	t.Setenv("CATEGORY_FLOORS", "Invoice=0.8, Legal Notice = 0.65,Receipt=high,=0.5")
+
+	got := getEnvFloatMap("CATEGORY_FLOORS")
+	if len(got) != 2 || got["Invoice"] != 0.8 || got["Legal Notice"] != 0.65 {
+		t.Errorf("floors = %v, want Invoice=0.8 and Legal Notice=0.65", got)
+	}
+}
+
+func TestClassifyDebugRawRequiresAdminToken(t *testing.T) {
+	s := newTestServer(t, openAIReply("Invoice"))
+	s.adminToken = "admin-secret"
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "without token", status: http.StatusForbidden},
+		{name: "wrong token", token: "guess", status: http.StatusForbidden},
+		{name: "admin token", token: "admin-secret", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newUploadRequest(t, "/classify", "invoice.txt", []byte("Invoice 42, total due $120."),
+				map[string]string{"debug_raw": "true"})
+			if tt.token != "" {
+				req.Header.Set("X-Admin-Token", tt.token)
+			}
+			rec := httptest.NewRecorder()
+			s.handleClassify(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var response ClassificationResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("response is not JSON: %v", err)
+			}
+			if !strings.Contains(response.RawResponse, `"category":"Invoice"`) {
+				t.Errorf("raw_response = %q, want the model's message content", response.RawResponse)
+			}
+		})
+	}
+
+	// Without debug_raw the raw response stays out of the result
+	rec := httptest.NewRecorder()
+	s.handleClassify(rec, newUploadRequest(t, "/classify", "invoice.txt", []byte("Invoice 42."), nil))
+	if strings.Contains(rec.Body.String(), "raw_response") {
+		t.Errorf("raw_response returned without debug_raw: %s", rec.Body)
+	}
+}
+
+func TestClassifyRejectsTooShortText(t *testing.T) {
+	s := newTestServer(t, openAIReply("Invoice"))
+	s.defaults.MinTextLength = 20
+
+	rec := httptest.NewRecorder()
+	s.handleClassify(rec, newUploadRequest(t, "/classify", "blank-scan.txt", []byte("Page 1\n\n"), nil))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422 (body %s)", rec.Code, rec.Body)
+	}
+	var response ClassificationResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || !strings.Contains(response.Error, "too short") {
+		t.Errorf("response = %s, want a JSON error naming the short text", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleClassify(rec, newUploadRequest(t, "/classify", "invoice.txt", []byte("Invoice 42, total due $120."), nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d for long enough text, want 200 (body %s)", rec.Code, rec.Body)
+	}
+}
+
+func TestClassifyReturnsMetadata(t *testing.T) {
+	s := newTestServer(t, openAIReply("Invoice"))
+
+	rec := httptest.NewRecorder()
+	s.handleClassify(rec, newUploadRequest(t, "/classify", "invoice.txt", []byte("Invoice 42, total due $120."), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	var response ClassificationResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("response is not JSON: %v", err)
+	}
+	metadata := response.Metadata
+	if metadata == nil {
+		t.Fatalf("metadata missing from %s", rec.Body)
+	}
+	if metadata.Provider != classifier.OpenAI || metadata.Model == "" {
+		t.Errorf("metadata names %q/%q, want the OpenAI model", metadata.Provider, metadata.Model)
+	}
+	if metadata.Retries != 0 || metadata.DurationMs < 0 {
+		t.Errorf("metadata = %+v, want a single attempt with a duration", metadata)
+	}
+}
+
+func TestClassifyFallbackToExtract(t *testing.T) {
+	provider := serveProvider(t, http.StatusInternalServerError, `{"error":{"message":"overloaded"}}`)
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{Endpoint: provider.URL, APIKey: "key", MaxRetries: -1})
+	text := "Minutes of the board meeting held on 3 May."
+
+	tests := []struct {
+		name         string
+		fields       map[string]string
+		wantRawText  bool
+		wantFallback bool
+	}{
+		{name: "fallback requested", fields: map[string]string{"fallback_to_extract": "true"}, wantRawText: true, wantFallback: true},
+		{name: "fallback not requested"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleClassify(rec, newUploadRequest(t, "/classify", "minutes.txt", []byte(text), tt.fields))
+
+			var response ClassificationResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("response is not JSON: %v (body %s)", err, rec.Body)
+			}
+			if got := response.RawText == text; got != tt.wantRawText {
+				t.Errorf("raw_text = %q, want the extracted text: %v", response.RawText, tt.wantRawText)
+			}
+			if got := response.ClassificationError != ""; got != tt.wantFallback {
+				t.Errorf("classification_error = %q, want set: %v", response.ClassificationError, tt.wantFallback)
+			}
+			if got := response.Error != ""; got == tt.wantFallback {
+				t.Errorf("error = %q, want set: %v", response.Error, !tt.wantFallback)
+			}
+			if response.Category != "" {
+				t.Errorf("category = %q, want none", response.Category)
+			}
+		})
+	}
+}
+
+func TestClassifyRefusedAndTruncatedStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		reply  string
+		status int
+	}{
+		{name: "refused", reply: `{"choices":[{"message":{"content":"","refusal":"I can't help with that."},"finish_reason":"stop"}]}`, status: http.StatusUnprocessableEntity},
+		{name: "truncated", reply: `{"choices":[{"message":{"content":"{\"category\":"},"finish_reason":"length"}]}`, status: http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, tt.reply)
+			rec := httptest.NewRecorder()
+			s.handleClassify(rec, newUploadRequest(t, "/classify", "memo.txt", []byte("Quarterly plan."), nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.status, rec.Body)
+			}
+			var response ClassificationResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil || response.Error == "" {
+				t.Errorf("body = %s, want a JSON error", rec.Body)
+			}
+		})
+	}
+}
+
+func TestHandleFormats(t *testing.T) {
+	s := newTestServer(t, openAIReply("Invoice"))
+
+	rec := httptest.NewRecorder()
+	s.handleFormats(rec, httptest.NewRequest(http.MethodGet, "/formats", nil))
+	var formats []extractor.FormatInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &formats); err != nil {
+		t.Fatalf("response is not a format list: %v (body %s)", err, rec.Body)
+	}
+	found := false
+	for _, format := range formats {
+		if slices.Contains(format.Extensions, ".pdf") {
+			found = slices.Contains(format.MIMETypes, "application/pdf")
+		}
+	}
+	if !found {
+		t.Errorf("formats do not map .pdf to application/pdf: %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleFormats(rec, httptest.NewRequest(http.MethodPost, "/formats", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+}
+
+func TestValidateCredentials(t *testing.T) {
+	for _, provider := range []classifier.Provider{classifier.Azure, classifier.Custom} {
+		t.Setenv(classifier.BaseURLEnvVars[provider], "")
+	}
+
+	tests := []struct {
+		name     string
+		provider classifier.Provider
+		config   classifier.ModelConfig
+		baseURL  string
+		wantErr  string
+	}{
+		{name: "openai without key", provider: classifier.OpenAI, wantErr: "OPENAI_API_KEY"},
+		{name: "openai", provider: classifier.OpenAI, config: classifier.ModelConfig{APIKey: "key"}},
+		{name: "anthropic without key", provider: classifier.Anthropic, wantErr: "ANTHROPIC_API_KEY"},
+		{name: "gemini without key", provider: classifier.Gemini, wantErr: "GEMINI_API_KEY"},
+		{name: "azure without endpoint", provider: classifier.Azure, config: classifier.ModelConfig{APIKey: "key"}, wantErr: "MODEL_ENDPOINT"},
+		{name: "azure with base URL", provider: classifier.Azure, config: classifier.ModelConfig{APIKey: "key"}, baseURL: "https://example.openai.azure.com"},
+		{name: "azure without key", provider: classifier.Azure, config: classifier.ModelConfig{Endpoint: "https://example.openai.azure.com"}, wantErr: "AZURE_OPENAI_API_KEY"},
+		{name: "custom without endpoint", provider: classifier.Custom, wantErr: "MODEL_ENDPOINT"},
+		{name: "custom without key", provider: classifier.Custom, config: classifier.ModelConfig{Endpoint: "https://llm.internal"}},
+		{
+			name:     "custom HMAC without secret",
+			provider: classifier.Custom,
+			config:   classifier.ModelConfig{Endpoint: "https://llm.internal", Auth: classifier.AuthConfig{Scheme: classifier.AuthHMAC}},
+			wantErr:  "CUSTOM_HMAC_SECRET",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.baseURL != "" {
+				t.Setenv(classifier.BaseURLEnvVars[tt.provider], tt.baseURL)
+			}
+			err := NewServer(t.TempDir(), tt.provider, tt.config).validateCredentials()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateCredentials: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateCredentials = %v, want an error naming %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// unavailableExtractor handles .png but reports a missing dependency
+type unavailableExtractor struct{}
+
+func (unavailableExtractor) Extract(path string) (string, error) { return "", nil }
+func (unavailableExtractor) SupportedExtensions() []string       { return []string{".png"} }
+func (unavailableExtractor) CheckDependencies() error            { return errors.New("tesseract not found") }
+
+func TestHandleReady(t *testing.T) {
+	s := newTestServer(t, openAIReply("Invoice"))
+	previous := extractor.DefaultRegistry
+	t.Cleanup(func() { extractor.DefaultRegistry = previous })
+
+	ready := func() ReadinessResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.handleReady(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		var response ReadinessResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("response is not JSON: %v (body %s)", err, rec.Body)
+		}
+		return response
+	}
+
+	extractor.DefaultRegistry = extractor.NewRegistry()
+	if response := ready(); response.Status != "ready" || len(response.Unavailable) != 0 {
+		t.Errorf("empty registry = %+v, want ready", response)
+	}
+
+	extractor.DefaultRegistry.Register(unavailableExtractor{})
+	response := ready()
+	if response.Status != "degraded" || len(response.Unavailable) != 1 || response.Unavailable[0].Error != "tesseract not found" {
+		t.Errorf("registry with a missing dependency = %+v, want degraded with the error", response)
+	}
+}
+
+func TestClassifyPassesFilenameAndContext(t *testing.T) {
+	var prompt string
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt = body.Messages[len(body.Messages)-1].Content
+		w.Write([]byte(openAIReply("Invoice")))
+	}))
+	defer provider.Close()
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{Endpoint: provider.URL, APIKey: "key", MaxRetries: -1})
+
+	classify := func() {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.handleClassify(rec, newUploadRequest(t, "/classify", "acme-invoice.txt", []byte("Total due: $40"), map[string]string{
+			"context": "from the billing inbox",
+		}))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+		}
+	}
+
+	classify()
+	if !strings.Contains(prompt, "- Filename: acme-invoice.txt") || !strings.Contains(prompt, "- Context: from the billing inbox") {
+		t.Errorf("prompt lacks the filename and context:\n%s", prompt)
+	}
+
+	s.promptFilename = false
+	classify()
+	if strings.Contains(prompt, "acme-invoice.txt") || !strings.Contains(prompt, "- Context: from the billing inbox") {
+		t.Errorf("prompt with the filename disabled:\n%s", prompt)
+	}
+}
+
+func TestClassifyRejectsInvalidSelection(t *testing.T) {
+	s := newTestServer(t, openAIReply("Invoice"))
+
+	for _, tc := range []struct {
+		name     string
+		filename string
+		fields   map[string]string
+	}{
+		{name: "page of a text file", filename: "notes.txt", fields: map[string]string{"page": "1"}},
+		{name: "sheet of a PDF", filename: "report.pdf", fields: map[string]string{"sheet": "Summary"}},
+		{name: "malformed range", filename: "report.pdf", fields: map[string]string{"page": "3-1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.handleClassify(rec, newUploadRequest(t, "/classify", tc.filename, []byte("Total due: $40"), tc.fields))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400 (body %s)", rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestClassifyResponseFields(t *testing.T) {
+	s := newTestServer(t, openAIReply("Invoice"))
+
+	rec := httptest.NewRecorder()
+	s.handleClassify(rec, newUploadRequest(t, "/classify?fields=category,+confidence,,history_id", "invoice.txt", []byte("Total due: $40"), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (body %s)", rec.Code, rec.Body)
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("response is not JSON: %v", err)
+	}
+	// history_id is requested but empty, so it stays omitted
+	if len(response) != 2 || response["category"] != "Invoice" || response["confidence"] != 0.9 {
+		t.Errorf("response = %v, want only category and confidence", response)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleClassify(rec, newUploadRequest(t, "/classify?fields=category,secret", "invoice.txt", []byte("Total due: $40"), nil))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"secret"`) {
+		t.Errorf("unknown field = %d %s, want 400 naming the field", rec.Code, rec.Body)
+	}
+}
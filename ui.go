@@ -0,0 +1,23 @@
+package main
+
+import (
+	"embed"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//go:embed ui/index.html
+var uiFiles embed.FS
+
+// handleUI serves the upload form used for manual testing
+func (s *Server) handleUI(w http.ResponseWriter, r *http.Request) {
+	page, err := uiFiles.ReadFile("ui/index.html")
+	if err != nil {
+		log.WithError(err).Error("Failed to read embedded UI")
+		http.Error(w, "UI unavailable", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUIServedAtRoot(t *testing.T) {
+	s := newTestServer(t, openAIReply("Invoice"))
+	s.routePrefix = "/api"
+
+	get := func(handler http.Handler, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := get(s.Handler(), "/api/")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("GET /api/ = %d %q, want the HTML form", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), `id="classify-form"`) {
+		t.Errorf("body does not contain the upload form")
+	}
+	// Only the root itself serves the form, not every unknown path
+	if rec := get(s.Handler(), "/api/unknown"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /api/unknown = %d, want 404", rec.Code)
+	}
+
+	s.disableUI = true
+	if rec := get(s.Handler(), "/api/"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /api/ with the UI disabled = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"time"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+	log "github.com/sirupsen/logrus"
+)
+
+// warmupText is the document classified at startup by WARMUP=true
+const warmupText = "Startup connectivity check."
+
+// warmupTimeout bounds the startup classification
+const warmupTimeout = 30 * time.Second
+
+// warmupClassifier classifies a tiny document so that credentials are checked and a
+// connection to the provider is open before the first real request. Failures are
+// logged and returned; the caller decides whether they are fatal.
+func (s *Server) warmupClassifier() error {
+	logger := log.WithFields(log.Fields{
+		"function": "warmupClassifier",
+		"provider": s.provider,
+		"model":    s.config.Model,
+	})
+	logger.Info("Warming up classifier")
+	start := time.Now()
+
+	clf, err := classifier.NewClassifier(s.provider, s.config)
+	if err != nil {
+		logger.WithError(err).Warn("Warmup failed to create classifier")
+		return err
+	}
+	classification, err := clf.ClassifyWithOptions(warmupText, classifier.ClassificationOptions{
+		Categories:      []string{"Test"},
+		MaxKeywords:     1,
+		MaxSummaryWords: 5,
+		Timeout:         warmupTimeout,
+	})
+	if err != nil {
+		logger.WithError(err).Warn("Warmup classification failed")
+		return err
+	}
+	s.recordCost(nil, s.config.Model, warmupText, classification)
+
+	logger.WithField("duration", time.Since(start).String()).Info("Classifier warmed up")
+	return nil
+}
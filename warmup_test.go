@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/adaptive-scale/superclass/pkg/classifier"
+)
+
+func TestWarmupClassifier(t *testing.T) {
+	if err := newTestServer(t, openAIReply("Test")).warmupClassifier(); err != nil {
+		t.Errorf("warmup with a working provider: %v", err)
+	}
+
+	provider := serveProvider(t, http.StatusUnauthorized, `{"error":{"message":"invalid api key"}}`)
+	s := NewServer(t.TempDir(), classifier.OpenAI, classifier.ModelConfig{
+		Endpoint:   provider.URL,
+		APIKey:     "key",
+		MaxRetries: -1,
+	})
+	if err := s.warmupClassifier(); err == nil {
+		t.Fatal("warmup with a rejecting provider succeeded")
+	}
+
+	// A required warmup stops the server before it listens
+	s.warmup, s.warmupRequired = true, true
+	if err := s.Start(0); err == nil || !strings.Contains(err.Error(), "warmup failed") {
+		t.Errorf("Start() = %v, want a warmup error", err)
+	}
+}